@@ -59,7 +59,11 @@ func (s *ActivationsCleanupManager) Poll() []error {
 	}
 	ret := []error{}
 	for _, activation := range activations {
-		if activation.Status.Status != v1alpha2.Done {
+		// recurring activations run again, they are kept until they are deleted
+		if activation.Spec != nil && activation.Spec.Schedule != nil && activation.Spec.Schedule.IsRecurring() {
+			continue
+		}
+		if activation.Status.Status != v1alpha2.Done && activation.Status.Status != v1alpha2.Cancelled {
 			continue
 		}
 		if activation.Status.UpdateTime == "" {
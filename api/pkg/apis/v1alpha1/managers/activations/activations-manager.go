@@ -10,10 +10,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -187,6 +190,19 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 	})
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
+	parent, err := t.saveStatus(ctx, name, &current)
+	if err != nil {
+		return err
+	}
+	if parent != nil {
+		err = t.reportToParent(ctx, name, *parent, current)
+	}
+	return err
+}
+
+// saveStatus records the status of an activation. It returns the parent of the activation if the
+// status ends a child activation, which the parent waits on.
+func (t *ActivationsManager) saveStatus(ctx context.Context, name string, current *model.ActivationStatus) (*model.ActivationParent, error) {
 	lock.Lock()
 	defer lock.Unlock()
 	getRequest := states.GetRequest{
@@ -199,12 +215,32 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 	}
 	entry, err := t.StateProvider.Get(ctx, getRequest)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	dict := entry.Body.(map[string]interface{})
-	delete(dict, "spec")
+	wasFinished := false
+	// operator actions and the campaign revision stick until the activation is resumed or runs again
+	if previous, ok := getActivationStatus(dict["status"]); ok && (current.ActivationGeneration == "" || current.ActivationGeneration == previous.ActivationGeneration) {
+		if current.ActivationGeneration == "" {
+			current.ActivationGeneration = previous.ActivationGeneration
+		}
+		if current.CampaignRevision == 0 {
+			current.CampaignRevision = previous.CampaignRevision
+		}
+		if current.OperatorAction == "" {
+			current.OperatorAction = previous.OperatorAction
+			current.Operator = previous.Operator
+		}
+		wasFinished = isFinished(&previous)
+	}
+	if current.OperatorAction == model.ActivationCancel {
+		current.Status = v1alpha2.Cancelled
+		current.IsActive = false
+	}
 	current.UpdateTime = time.Now().Format(time.RFC3339)
-	dict["status"] = current
+	spec := dict["spec"]
+	dict = withoutSpec(dict)
+	dict["status"] = *current
 	entry.Body = dict
 	upsertRequest := states.UpsertRequest{
 		Value: entry,
@@ -216,7 +252,262 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, curr
 	}
 	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
 	if err != nil {
+		return nil, err
+	}
+	if wasFinished || !isFinished(current) {
+		return nil, nil
+	}
+	var activation model.ActivationSpec
+	j, _ := json.Marshal(spec)
+	if json.Unmarshal(j, &activation) != nil {
+		return nil, nil
+	}
+	return activation.Parent, nil
+}
+
+// reportToParent resumes the sub-campaign stage waiting on a child activation that ended. The report
+// carries the outputs of the child, and fails the stage if the child failed. It's sent as a job report,
+// like the decision on an approval stage.
+func (t *ActivationsManager) reportToParent(ctx context.Context, name string, parent model.ActivationParent, current model.ActivationStatus) error {
+	state, err := t.GetSpec(ctx, parent.Activation)
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			log.Infof(" M (Activations): parent activation %s of %s is gone", parent.Activation, name)
+			return nil
+		}
 		return err
 	}
-	return nil
+	status := state.Status
+	if status.Status != v1alpha2.Paused || status.Stage != parent.Stage || status.ActivationGeneration != parent.ActivationGeneration || status.Outputs[model.SubActivationNameOutput] != name {
+		// the parent was cancelled or has run again since it started the child
+		log.Infof(" M (Activations): parent activation %s isn't waiting on %s", parent.Activation, name)
+		return nil
+	}
+	report := *status
+	outputs := make(map[string]interface{}, len(status.Outputs)+len(current.Outputs))
+	for k, v := range status.Outputs {
+		outputs[k] = v
+	}
+	delete(outputs, model.SubActivationOutput)
+	for k, v := range current.Outputs {
+		// system outputs of the child, and the names of the child, are left out
+		if !strings.HasPrefix(k, "__") && k != model.SubCampaignOutput && k != model.SubActivationNameOutput {
+			outputs[k] = v
+		}
+	}
+	report.Outputs = outputs
+	if (current.Status == v1alpha2.Done || current.Status == v1alpha2.OK) && current.ErrorMessage == "" {
+		report.Status = v1alpha2.Done
+		report.IsActive = true
+	} else {
+		report.Status = current.Status
+		if report.Status == v1alpha2.Done || report.Status == v1alpha2.OK {
+			report.Status = v1alpha2.InternalError
+		}
+		report.IsActive = false
+		report.ErrorMessage = fmt.Sprintf("sub-campaign activation %s failed: %s", name, current.ErrorMessage)
+	}
+	return t.Context.Publish("job-report", v1alpha2.Event{
+		Body: report,
+	})
+}
+
+// withoutSpec copies an activation without its spec, so that only the status is updated. The state
+// provider may hand out the stored object, so it isn't changed in place.
+func withoutSpec(dict map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(dict))
+	for k, v := range dict {
+		if k != "spec" {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func getActivationStatus(body interface{}) (model.ActivationStatus, bool) {
+	var status model.ActivationStatus
+	if body == nil {
+		return status, false
+	}
+	j, _ := json.Marshal(body)
+	err := json.Unmarshal(j, &status)
+	return status, err == nil
+}
+
+// Pause stops an activation before its next stage. The stage that is running finishes first.
+func (t *ActivationsManager) Pause(ctx context.Context, name string, user string) (model.ActivationControl, error) {
+	return t.control(ctx, name, model.ActivationPause, "Pause", user, func(status *model.ActivationStatus) error {
+		if !isRunning(status) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s isn't running", name), v1alpha2.BadRequest)
+		}
+		if status.OperatorAction == model.ActivationPause {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is already paused", name), v1alpha2.BadRequest)
+		}
+		status.OperatorAction = model.ActivationPause
+		status.Operator = user
+		return nil
+	})
+}
+
+// Resume continues a paused activation
+func (t *ActivationsManager) Resume(ctx context.Context, name string, user string) (model.ActivationControl, error) {
+	return t.control(ctx, name, model.ActivationResume, "Resume", user, func(status *model.ActivationStatus) error {
+		if status.OperatorAction != model.ActivationPause {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s isn't paused", name), v1alpha2.BadRequest)
+		}
+		status.OperatorAction = ""
+		status.Operator = ""
+		return nil
+	})
+}
+
+// Cancel stops an activation for good. The stage that is running finishes first.
+func (t *ActivationsManager) Cancel(ctx context.Context, name string, user string) (model.ActivationControl, error) {
+	return t.control(ctx, name, model.ActivationCancel, "Cancel", user, func(status *model.ActivationStatus) error {
+		if !isRunning(status) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s isn't running", name), v1alpha2.BadRequest)
+		}
+		status.OperatorAction = model.ActivationCancel
+		status.Operator = user
+		status.Status = v1alpha2.Cancelled
+		status.IsActive = false
+		return nil
+	})
+}
+
+// Decide records the decision on the approval stage an activation is waiting on. Only the approvers
+// of the stage, or authenticated users with one of its approver roles, may decide. A rejection ends
+// the activation with a BadRequest status; after an approval, the returned status is the report of
+// the approval stage, which lets the activation continue.
+func (t *ActivationsManager) Decide(ctx context.Context, name string, user string, roles []string, approve bool, comment string) (model.ActivationControl, model.ActivationStatus, error) {
+	action := model.ActivationReject
+	if approve {
+		action = model.ActivationApprove
+	}
+	var report model.ActivationStatus
+	control, err := t.control(ctx, name, action, "Decide", user, func(status *model.ActivationStatus) error {
+		if status.Status != v1alpha2.Paused || status.Outputs[model.ApprovalPendingOutput] != true {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s isn't waiting for an approval", name), v1alpha2.BadRequest)
+		}
+		if !canApprove(status.Outputs, user, roles) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("user '%s' can't approve stage %s of activation %s", user, status.Stage, name), v1alpha2.Unauthorized)
+		}
+		outputs := make(map[string]interface{}, len(status.Outputs)+2)
+		for k, v := range status.Outputs {
+			outputs[k] = v
+		}
+		delete(outputs, model.ApprovalPendingOutput)
+		outputs[model.ApprovedOutput] = approve
+		outputs[model.ApproverOutput] = user
+		outputs[model.CommentOutput] = comment
+		status.Outputs = outputs
+		if approve {
+			status.Status = v1alpha2.Running
+			status.IsActive = true
+			report = *status
+			report.Status = v1alpha2.Done
+		} else {
+			status.Status = v1alpha2.BadRequest
+			status.IsActive = false
+			status.ErrorMessage = fmt.Sprintf("stage %s was rejected by '%s'", status.Stage, user)
+		}
+		return nil
+	})
+	return control, report, err
+}
+
+// control applies an operator action to the status of an activation
+func (t *ActivationsManager) control(ctx context.Context, name string, action string, method string, user string, apply func(status *model.ActivationStatus) error) (model.ActivationControl, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": method,
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID: name,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	})
+	if err != nil {
+		return model.ActivationControl{}, err
+	}
+	state, err := getActivationState(name, entry.Body, entry.ETag)
+	if err != nil {
+		return model.ActivationControl{}, err
+	}
+	status := state.Status
+	err = apply(status)
+	if err != nil {
+		return model.ActivationControl{}, err
+	}
+	status.UpdateTime = time.Now().Format(time.RFC3339)
+	dict := withoutSpec(entry.Body.(map[string]interface{}))
+	dict["status"] = *status
+	entry.Body = dict
+	_, err = t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: entry,
+		Metadata: map[string]string{
+			"version":  "v1",
+			"group":    model.WorkflowGroup,
+			"resource": "activations",
+		},
+	})
+	if err != nil {
+		return model.ActivationControl{}, err
+	}
+	return model.ActivationControl{
+		Campaign:             state.Spec.Campaign,
+		Activation:           name,
+		ActivationGeneration: status.ActivationGeneration,
+		Action:               action,
+		User:                 user,
+	}, nil
+}
+
+// isFinished tells if an activation has ended, successfully or not
+func isFinished(status *model.ActivationStatus) bool {
+	return !status.IsActive && status.Status != v1alpha2.Paused && status.Status != v1alpha2.Untouched && status.Status != 0
+}
+
+// isRunning tells if an activation has started and hasn't finished. A paused activation is running.
+func isRunning(status *model.ActivationStatus) bool {
+	if status.Stage == "" && status.Status == 0 {
+		return false
+	}
+	return status.IsActive || status.Status == v1alpha2.Paused
+}
+
+// canApprove tells if an authenticated user is one of the approvers of a stage, or has one of its
+// approver roles. Anything else is denied.
+func canApprove(outputs map[string]interface{}, user string, roles []string) bool {
+	if user == "" {
+		return false
+	}
+	approvers, err := utils.ReadStringList(outputs[model.ApproversOutput])
+	if err != nil {
+		return false
+	}
+	approverRoles, err := utils.ReadStringList(outputs[model.ApproverRolesOutput])
+	if err != nil {
+		return false
+	}
+	for _, a := range approvers {
+		if a == user {
+			return true
+		}
+	}
+	for _, r := range approverRoles {
+		for _, role := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
 }
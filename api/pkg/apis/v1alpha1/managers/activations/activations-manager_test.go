@@ -9,8 +9,12 @@ package activations
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +57,292 @@ func TestCleanupOldActivationSpec(t *testing.T) {
 	_, err = manager.GetSpec(context.Background(), "test")
 	assert.NotNil(t, err)
 }
+
+func TestCleanupKeepsRecurringActivation(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	cleanupmanager := ActivationsCleanupManager{
+		ActivationsManager: manager,
+		RetentionInMinutes: 0,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{
+		Schedule: &v1alpha2.ScheduleSpec{Cron: "@hourly"},
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	errList := cleanupmanager.Poll()
+	assert.Empty(t, errList)
+	_, err = manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+}
+
+func newRunningActivation(t *testing.T, status model.ActivationStatus) ActivationsManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.ActivationSpec{Campaign: "campaign"})
+	assert.Nil(t, err)
+	status.ActivationGeneration = "1"
+	err = manager.ReportStatus(context.Background(), "test", status)
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestPauseResumeActivation(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running, IsActive: true})
+	control, err := manager.Pause(context.Background(), "test", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationControl{Campaign: "campaign", Activation: "test", ActivationGeneration: "1", Action: model.ActivationPause, User: "alice"}, control)
+	_, err = manager.Pause(context.Background(), "test", "alice")
+	assert.NotNil(t, err)
+
+	// the running stage reports its result after the pause
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Paused, ActivationGeneration: "1"})
+	assert.Nil(t, err)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationPause, state.Status.OperatorAction)
+	assert.Equal(t, "alice", state.Status.Operator)
+
+	control, err = manager.Resume(context.Background(), "test", "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationResume, control.Action)
+	state, err = manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, "", state.Status.OperatorAction)
+	_, err = manager.Resume(context.Background(), "test", "bob")
+	assert.NotNil(t, err)
+}
+
+func TestReportStatusKeepsCampaignRevision(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running, IsActive: true, CampaignRevision: 3})
+	err := manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Done, ActivationGeneration: "1"})
+	assert.Nil(t, err)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, state.Status.CampaignRevision)
+
+	// a new generation runs against its own revision
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running, ActivationGeneration: "2"})
+	assert.Nil(t, err)
+	state, err = manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Status.CampaignRevision)
+}
+
+func TestCancelActivation(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running, IsActive: true})
+	control, err := manager.Cancel(context.Background(), "test", "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationCancel, control.Action)
+
+	// a late report of the running stage doesn't revive the activation
+	err = manager.ReportStatus(context.Background(), "test", model.ActivationStatus{Stage: "deploy", NextStage: "verify", Status: v1alpha2.Running, IsActive: true, ActivationGeneration: "1"})
+	assert.Nil(t, err)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.False(t, state.Status.IsActive)
+
+	_, err = manager.Cancel(context.Background(), "test", "alice")
+	assert.NotNil(t, err)
+	_, err = manager.Pause(context.Background(), "test", "alice")
+	assert.NotNil(t, err)
+}
+
+func TestDecideApproval(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{
+		Stage:  "approve",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			"__campaign":                "campaign",
+			model.ApprovalPendingOutput: true,
+			model.ApproversOutput:       []string{"alice"},
+			model.ApproverRolesOutput:   []string{"operator"},
+		},
+	})
+	_, _, err := manager.Decide(context.Background(), "test", "mallory", []string{"reader"}, true, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	control, report, err := manager.Decide(context.Background(), "test", "bob", []string{"operator"}, true, "looks good")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationApprove, control.Action)
+	assert.Equal(t, v1alpha2.Done, report.Status)
+	assert.Equal(t, "approve", report.Stage)
+	assert.Equal(t, true, report.Outputs[model.ApprovedOutput])
+	assert.Equal(t, "bob", report.Outputs[model.ApproverOutput])
+	assert.Equal(t, "looks good", report.Outputs[model.CommentOutput])
+	assert.Equal(t, "campaign", report.Outputs["__campaign"])
+	_, ok := report.Outputs[model.ApprovalPendingOutput]
+	assert.False(t, ok)
+
+	// the decision can't be made twice
+	_, _, err = manager.Decide(context.Background(), "test", "alice", nil, false, "")
+	assert.NotNil(t, err)
+}
+
+func TestDecideRejection(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{
+		Stage:  "approve",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			model.ApprovalPendingOutput: true,
+			model.ApproversOutput:       []interface{}{"alice"},
+		},
+	})
+	control, _, err := manager.Decide(context.Background(), "test", "alice", nil, false, "not now")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationReject, control.Action)
+	state, err := manager.GetSpec(context.Background(), "test")
+	assert.Nil(t, err)
+	// a rejected activation fails, so it can't be mistaken for a completed one
+	assert.Equal(t, v1alpha2.BadRequest, state.Status.Status)
+	assert.False(t, state.Status.IsActive)
+	assert.Equal(t, "stage approve was rejected by 'alice'", state.Status.ErrorMessage)
+	assert.Equal(t, false, state.Status.Outputs[model.ApprovedOutput])
+}
+
+func TestDecideDeniedByDefault(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{
+		Stage:  "approve",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			model.ApprovalPendingOutput: true,
+		},
+	})
+	// nobody can decide on a stage without approvers
+	_, _, err := manager.Decide(context.Background(), "test", "alice", []string{"administrator"}, true, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	// nor can a caller without a user name
+	manager = newRunningActivation(t, model.ActivationStatus{
+		Stage:  "approve",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			model.ApprovalPendingOutput: true,
+			model.ApproverRolesOutput:   []string{"operator"},
+		},
+	})
+	_, _, err = manager.Decide(context.Background(), "test", "", []string{"operator"}, true, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestDecideNotWaiting(t *testing.T) {
+	manager := newRunningActivation(t, model.ActivationStatus{Stage: "deploy", Status: v1alpha2.Running, IsActive: true})
+	_, _, err := manager.Decide(context.Background(), "test", "alice", nil, true, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
+
+// newChildActivation starts a parent activation waiting on a sub-campaign stage, and its child. It
+// returns the job reports sent to the parent.
+func newChildActivation(t *testing.T) (ActivationsManager, chan model.ActivationStatus) {
+	manager := newRunningActivation(t, model.ActivationStatus{
+		Stage:  "deploy",
+		Status: v1alpha2.Paused,
+		Outputs: map[string]interface{}{
+			"__activation":                "test",
+			"__campaign":                  "campaign",
+			v1alpha2.StatusOutput:         v1alpha2.OK,
+			model.SubCampaignOutput:       "child-campaign",
+			model.SubActivationNameOutput: "child",
+			model.SubActivationOutput:     map[string]interface{}{"name": "child"},
+		},
+	})
+	pubSubProvider := &memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager.Context = &contexts.ManagerContext{}
+	manager.Context.Init(nil, pubSubProvider)
+	reports := make(chan model.ActivationStatus, 2)
+	pubSubProvider.Subscribe("job-report", func(topic string, event v1alpha2.Event) error {
+		reports <- event.Body.(model.ActivationStatus)
+		return nil
+	})
+	err := manager.UpsertSpec(context.Background(), "child", model.ActivationSpec{
+		Campaign: "child-campaign",
+		Parent: &model.ActivationParent{
+			Campaign:             "campaign",
+			Activation:           "test",
+			ActivationGeneration: "1",
+			Stage:                "deploy",
+		},
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "child", model.ActivationStatus{Stage: "build", Status: v1alpha2.Running, IsActive: true, ActivationGeneration: "1"})
+	assert.Nil(t, err)
+	return manager, reports
+}
+
+func TestReportStatusResumesParent(t *testing.T) {
+	manager, reports := newChildActivation(t)
+	err := manager.ReportStatus(context.Background(), "child", model.ActivationStatus{
+		Stage:  "build",
+		Status: v1alpha2.Done,
+		Outputs: map[string]interface{}{
+			"__activation": "child",
+			"image":        "app:2",
+		},
+	})
+	assert.Nil(t, err)
+	select {
+	case report := <-reports:
+		assert.Equal(t, v1alpha2.Done, report.Status)
+		assert.True(t, report.IsActive)
+		assert.Equal(t, "test", report.Outputs["__activation"])
+		assert.Equal(t, "child", report.Outputs[model.SubActivationNameOutput])
+		assert.Equal(t, "app:2", report.Outputs["image"])
+		assert.NotContains(t, report.Outputs, model.SubActivationOutput)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the parent didn't get a job report")
+	}
+
+	// a status that doesn't end the child again isn't reported
+	err = manager.ReportStatus(context.Background(), "child", model.ActivationStatus{Stage: "build", Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	select {
+	case <-reports:
+		assert.Fail(t, "the parent got a second job report")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReportStatusFailsParent(t *testing.T) {
+	manager, reports := newChildActivation(t)
+	err := manager.ReportStatus(context.Background(), "child", model.ActivationStatus{
+		Stage:        "build",
+		Status:       v1alpha2.InternalError,
+		ErrorMessage: "build failed",
+	})
+	assert.Nil(t, err)
+	select {
+	case report := <-reports:
+		assert.Equal(t, v1alpha2.InternalError, report.Status)
+		assert.False(t, report.IsActive)
+		assert.Equal(t, "sub-campaign activation child failed: build failed", report.ErrorMessage)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the parent didn't get a job report")
+	}
+}
+
+func TestReportStatusParentNotWaiting(t *testing.T) {
+	manager, reports := newChildActivation(t)
+	_, err := manager.Cancel(context.Background(), "test", "alice")
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "child", model.ActivationStatus{Stage: "build", Status: v1alpha2.Done})
+	assert.Nil(t, err)
+	select {
+	case <-reports:
+		assert.Fail(t, "a cancelled parent got a job report")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -12,17 +12,24 @@ import (
 	"fmt"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
+var log = logger.NewLogger("coa.runtime")
+
 type CampaignsManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
+	// Revisions is nil if the manager doesn't keep revisions
+	Revisions *utils.RevisionStore
 }
 
 func (s *CampaignsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -36,6 +43,10 @@ func (s *CampaignsManager) Init(context *contexts.VendorContext, config managers
 	} else {
 		return err
 	}
+	s.Revisions, err = utils.NewRevisionStore(config, providers, model.WorkflowGroup, "CampaignRevision", "campaignrevisions")
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -115,6 +126,14 @@ func (m *CampaignsManager) UpsertSpec(ctx context.Context, name string, spec mod
 	if err != nil {
 		return err
 	}
+	if m.Revisions != nil {
+		author, _ := v1alpha2.GetAuthenticatedUser(ctx)
+		_, err = m.Revisions.Record(ctx, name, "", spec, author)
+		if err != nil {
+			log.Errorf(" M (Campaigns): failed to record revision of campaign %s: %+v", name, err)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -166,3 +185,94 @@ func (t *CampaignsManager) ListSpec(ctx context.Context) ([]model.CampaignState,
 	}
 	return ret, nil
 }
+
+func (m *CampaignsManager) revisions() (*utils.RevisionStore, error) {
+	if m.Revisions == nil {
+		return nil, v1alpha2.NewCOAError(nil, "campaign revisions are not enabled", v1alpha2.NotFound)
+	}
+	return m.Revisions, nil
+}
+
+// GetRevisions returns the revisions of a campaign, oldest first
+func (m *CampaignsManager) GetRevisions(ctx context.Context, name string) ([]model.RevisionSpec, error) {
+	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
+		"method": "GetRevisions",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := m.revisions()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := store.List(ctx, name, "")
+	return ret, err
+}
+
+// GetRevision returns a revision of a campaign
+func (m *CampaignsManager) GetRevision(ctx context.Context, name string, revision int) (model.RevisionSpec, error) {
+	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
+		"method": "GetRevision",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := m.revisions()
+	if err != nil {
+		return model.RevisionSpec{}, err
+	}
+	ret, err := store.Get(ctx, name, "", revision)
+	return ret, err
+}
+
+// GetRevisionSpec returns the campaign spec of a revision
+func (m *CampaignsManager) GetRevisionSpec(ctx context.Context, name string, revision int) (model.CampaignSpec, error) {
+	rev, err := m.GetRevision(ctx, name, revision)
+	if err != nil {
+		return model.CampaignSpec{}, err
+	}
+	var ret model.CampaignSpec
+	jData, _ := json.Marshal(rev.Spec)
+	err = json.Unmarshal(jData, &ret)
+	return ret, err
+}
+
+// PinRevision returns the revision of the current campaign spec, which activations run against. The
+// current spec is recorded first if it changed without going through UpsertSpec, e.g. when the campaign
+// was edited in Kubernetes. It returns 0 if the manager doesn't keep revisions.
+func (m *CampaignsManager) PinRevision(ctx context.Context, name string) (int, model.CampaignSpec, error) {
+	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
+		"method": "PinRevision",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	campaign, err := m.GetSpec(ctx, name)
+	if err != nil {
+		return 0, model.CampaignSpec{}, err
+	}
+	if m.Revisions == nil {
+		return 0, *campaign.Spec, nil
+	}
+	rev, err := m.Revisions.Record(ctx, name, "", campaign.Spec, "")
+	if err != nil {
+		return 0, model.CampaignSpec{}, err
+	}
+	return rev.Revision, *campaign.Spec, nil
+}
+
+// DiffRevisions compares two revisions of a campaign
+func (m *CampaignsManager) DiffRevisions(ctx context.Context, name string, from int, to int) (model.RevisionDiff, error) {
+	ctx, span := observability.StartSpan("Campaigns Manager", ctx, &map[string]string{
+		"method": "DiffRevisions",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := m.revisions()
+	if err != nil {
+		return model.RevisionDiff{}, err
+	}
+	ret, err := store.Diff(ctx, name, "", from, to)
+	return ret, err
+}
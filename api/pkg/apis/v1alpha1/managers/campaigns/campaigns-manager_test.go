@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +36,68 @@ func TestCreateGetDeleteCampaignSpec(t *testing.T) {
 	err = manager.DeleteSpec(context.Background(), "test")
 	assert.Nil(t, err)
 }
+
+func TestCampaignRevisions(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	revisionProvider := &memorystate.MemoryStateProvider{}
+	revisionProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := CampaignsManager{
+		StateProvider: stateProvider,
+		Revisions: &utils.RevisionStore{
+			StateProvider: revisionProvider,
+			Group:         model.WorkflowGroup,
+			Kind:          "CampaignRevision",
+			Resource:      "campaignrevisions",
+		},
+	}
+	ctx := context.WithValue(context.Background(), v1alpha2.AuthenticatedUserKey, "alice")
+	err := manager.UpsertSpec(ctx, "test", model.CampaignSpec{FirstStage: "build"})
+	assert.Nil(t, err)
+	err = manager.UpsertSpec(ctx, "test", model.CampaignSpec{FirstStage: "deploy"})
+	assert.Nil(t, err)
+
+	revisions, err := manager.GetRevisions(ctx, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "alice", revisions[1].Author)
+
+	spec, err := manager.GetRevisionSpec(ctx, "test", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "build", spec.FirstStage)
+
+	diff, err := manager.DiffRevisions(ctx, "test", 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SpecChange{
+		{Path: "firstStage", Type: model.SpecChangeModified, From: "build", To: "deploy"},
+	}, diff.Changes)
+
+	// an unchanged campaign is pinned to its latest revision
+	revision, spec, err := manager.PinRevision(ctx, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, revision)
+	assert.Equal(t, "deploy", spec.FirstStage)
+
+	// revisions outlive the campaign
+	err = manager.DeleteSpec(ctx, "test")
+	assert.Nil(t, err)
+	revisions, err = manager.GetRevisions(ctx, "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+}
+
+func TestCampaignRevisionsNotEnabled(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := CampaignsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertSpec(context.Background(), "test", model.CampaignSpec{FirstStage: "build"})
+	assert.Nil(t, err)
+	_, err = manager.GetRevisions(context.Background(), "test")
+	assert.True(t, v1alpha2.IsNotFound(err))
+	revision, spec, err := manager.PinRevision(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, revision)
+	assert.Equal(t, "build", spec.FirstStage)
+}
@@ -58,37 +58,45 @@ func (s *ConfigsManager) Init(context *contexts.VendorContext, cfg managers.Mana
 	return nil
 }
 func (s *ConfigsManager) Get(object string, field string, overlays []string, localContext interface{}) (interface{}, error) {
+	value, _, err := s.GetWithSource(object, field, overlays, localContext)
+	return value, err
+}
+
+// GetWithSource works like Get, and also returns the provider and the object (an overlay, a parent
+// catalog or the object itself) the value was read from.
+func (s *ConfigsManager) GetWithSource(object string, field string, overlays []string, localContext interface{}) (interface{}, config.ConfigSource, error) {
 	if strings.Index(object, ":") > 0 {
 		parts := strings.Split(object, ":")
 		if len(parts) != 2 {
-			return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid object: %s", object), v1alpha2.BadRequest)
+			return "", config.ConfigSource{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid object: %s", object), v1alpha2.BadRequest)
 		}
 		if provider, ok := s.ConfigProviders[parts[0]]; ok {
 			if field == "" {
-				configObj, err := s.getObjectWithOverlay(provider, parts[1], overlays, localContext)
+				configObj, source, err := s.getObjectWithOverlay(provider, parts[1], overlays, localContext)
 				if err != nil {
-					return "", err
+					return "", config.ConfigSource{}, err
 				}
-				return configObj, nil
+				return configObj, config.ConfigSource{Provider: parts[0], Object: source}, nil
 			} else {
-				return s.getWithOverlay(provider, parts[1], field, overlays, localContext)
+				value, source, err := s.getWithOverlay(provider, parts[1], field, overlays, localContext)
+				return value, config.ConfigSource{Provider: parts[0], Object: source}, err
 			}
 		}
-		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid provider: %s", parts[0]), v1alpha2.BadRequest)
+		return "", config.ConfigSource{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid provider: %s", parts[0]), v1alpha2.BadRequest)
 	}
 	if len(s.ConfigProviders) == 1 {
-		for _, provider := range s.ConfigProviders {
+		for key, provider := range s.ConfigProviders {
 			if field == "" {
-				configObj, err := s.getObjectWithOverlay(provider, object, overlays, localContext)
+				configObj, source, err := s.getObjectWithOverlay(provider, object, overlays, localContext)
 				if err != nil {
-					return "", err
+					return "", config.ConfigSource{}, err
 				}
-				return configObj, nil
+				return configObj, config.ConfigSource{Provider: key, Object: source}, nil
 			} else {
-				if value, err := s.getWithOverlay(provider, object, field, overlays, localContext); err == nil {
-					return value, nil
+				if value, source, err := s.getWithOverlay(provider, object, field, overlays, localContext); err == nil {
+					return value, config.ConfigSource{Provider: key, Object: source}, nil
 				} else {
-					return "", err
+					return "", config.ConfigSource{}, err
 				}
 			}
 		}
@@ -96,29 +104,36 @@ func (s *ConfigsManager) Get(object string, field string, overlays []string, loc
 	for _, key := range s.Precedence {
 		if provider, ok := s.ConfigProviders[key]; ok {
 			if field == "" {
-				configObj, err := s.getObjectWithOverlay(provider, object, overlays, localContext)
+				configObj, source, err := s.getObjectWithOverlay(provider, object, overlays, localContext)
 				if err != nil {
-					return "", err
+					return "", config.ConfigSource{}, err
 				}
-				return configObj, nil
+				return configObj, config.ConfigSource{Provider: key, Object: source}, nil
 			} else {
-				if value, err := s.getWithOverlay(provider, object, field, overlays, localContext); err == nil {
-					return value, nil
+				if value, source, err := s.getWithOverlay(provider, object, field, overlays, localContext); err == nil {
+					return value, config.ConfigSource{Provider: key, Object: source}, nil
 				}
 			}
 		}
 	}
-	return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid config object or key: %s, %s", object, field), v1alpha2.BadRequest)
+	return "", config.ConfigSource{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid config object or key: %s, %s", object, field), v1alpha2.BadRequest)
 }
-func (s *ConfigsManager) getWithOverlay(provider config.IConfigProvider, object string, field string, overlays []string, localContext interface{}) (interface{}, error) {
+func (s *ConfigsManager) getWithOverlay(provider config.IConfigProvider, object string, field string, overlays []string, localContext interface{}) (interface{}, string, error) {
 	if len(overlays) > 0 {
 		for _, overlay := range overlays {
-			if overlayObject, err := provider.Read(overlay, field, localContext); err == nil {
-				return overlayObject, nil
+			if overlayObject, source, err := readWithSource(provider, overlay, field, localContext); err == nil {
+				return overlayObject, source, nil
 			}
 		}
 	}
-	return provider.Read(object, field, localContext)
+	return readWithSource(provider, object, field, localContext)
+}
+func readWithSource(provider config.IConfigProvider, object string, field string, localContext interface{}) (interface{}, string, error) {
+	if sourceReader, ok := provider.(config.IConfigSourceReader); ok {
+		return sourceReader.ReadWithSource(object, field, localContext)
+	}
+	value, err := provider.Read(object, field, localContext)
+	return value, object, err
 }
 
 func (s *ConfigsManager) GetObject(object string, overlays []string, localContext interface{}) (map[string]interface{}, error) {
@@ -128,13 +143,14 @@ func (s *ConfigsManager) GetObject(object string, overlays []string, localContex
 			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid object: %s", object), v1alpha2.BadRequest)
 		}
 		if provider, ok := s.ConfigProviders[parts[0]]; ok {
-			return s.getObjectWithOverlay(provider, parts[1], overlays, localContext)
+			value, _, err := s.getObjectWithOverlay(provider, parts[1], overlays, localContext)
+			return value, err
 		}
 		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid provider: %s", parts[0]), v1alpha2.BadRequest)
 	}
 	if len(s.ConfigProviders) == 1 {
 		for _, provider := range s.ConfigProviders {
-			if value, err := s.getObjectWithOverlay(provider, object, overlays, localContext); err == nil {
+			if value, _, err := s.getObjectWithOverlay(provider, object, overlays, localContext); err == nil {
 				return value, nil
 			} else {
 				return nil, err
@@ -143,22 +159,23 @@ func (s *ConfigsManager) GetObject(object string, overlays []string, localContex
 	}
 	for _, key := range s.Precedence {
 		if provider, ok := s.ConfigProviders[key]; ok {
-			if value, err := s.getObjectWithOverlay(provider, object, overlays, localContext); err == nil {
+			if value, _, err := s.getObjectWithOverlay(provider, object, overlays, localContext); err == nil {
 				return value, nil
 			}
 		}
 	}
 	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid config object: %s", object), v1alpha2.BadRequest)
 }
-func (s *ConfigsManager) getObjectWithOverlay(provider config.IConfigProvider, object string, overlays []string, localContext interface{}) (map[string]interface{}, error) {
+func (s *ConfigsManager) getObjectWithOverlay(provider config.IConfigProvider, object string, overlays []string, localContext interface{}) (map[string]interface{}, string, error) {
 	if len(overlays) > 0 {
 		for _, overlay := range overlays {
 			if overlayObject, err := provider.ReadObject(overlay, localContext); err == nil {
-				return overlayObject, nil
+				return overlayObject, overlay, nil
 			}
 		}
 	}
-	return provider.ReadObject(object, localContext)
+	value, err := provider.ReadObject(object, localContext)
+	return value, object, err
 }
 func (s *ConfigsManager) Set(object string, field string, value interface{}) error {
 	if strings.Index(object, ":") > 0 {
@@ -141,3 +141,29 @@ func TestMultipleProvidersSameKey(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "obj::field2", val)
 }
+func TestGetWithSource(t *testing.T) {
+	provider1 := memory.MemoryConfigProvider{}
+	err := provider1.Init(memory.MemoryConfigProviderConfig{})
+	assert.Nil(t, err)
+	provider2 := memory.MemoryConfigProvider{}
+	err = provider2.Init(memory.MemoryConfigProviderConfig{})
+	assert.Nil(t, err)
+	manager := ConfigsManager{
+		ConfigProviders: map[string]config.IConfigProvider{
+			"memory1": &provider1,
+			"memory2": &provider2,
+		},
+		Precedence: []string{"memory2", "memory1"},
+	}
+	provider1.Set("obj", "field", "obj::field")
+	provider1.Set("obj-overlay", "field", "overlay::field")
+	val, source, err := manager.GetWithSource("obj", "field", []string{"obj-overlay"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "overlay::field", val)
+	assert.Equal(t, config.ConfigSource{Provider: "memory1", Object: "obj-overlay"}, source)
+
+	val, source, err = manager.GetWithSource("memory1:obj", "field", []string{"missing"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "obj::field", val)
+	assert.Equal(t, config.ConfigSource{Provider: "memory1", Object: "obj"}, source)
+}
@@ -95,8 +95,15 @@ func (t *InstancesManager) UpsertSpec(ctx context.Context, name string, spec mod
 }
 
 func (t *InstancesManager) ListSpec(ctx context.Context, scope string) ([]model.InstanceState, error) {
+	ret, _, err := t.ListSpecPage(ctx, scope, 0, "")
+	return ret, err
+}
+
+// ListSpecPage lists at most limit instances, starting from continueToken. It returns the token
+// for the next page, which is empty on the last page. A limit of 0 lists everything.
+func (t *InstancesManager) ListSpecPage(ctx context.Context, scope string, limit int64, continueToken string) ([]model.InstanceState, string, error) {
 	ctx, span := observability.StartSpan("Instances Manager", ctx, &map[string]string{
-		"method": "ListSpec",
+		"method": "ListSpecPage",
 	})
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
@@ -108,21 +115,23 @@ func (t *InstancesManager) ListSpec(ctx context.Context, scope string) ([]model.
 			"resource": "instances",
 			"scope":    scope,
 		},
+		Limit:    limit,
+		Continue: continueToken,
 	}
-	instances, _, err := t.StateProvider.List(ctx, listRequest)
+	instances, token, err := t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.InstanceState, 0)
 	for _, t := range instances {
 		var rt model.InstanceState
 		rt, err = getInstanceState(t.ID, t.Body, t.ETag)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, token, nil
 }
 
 func getInstanceState(id string, body interface{}, etag string) (model.InstanceState, error) {
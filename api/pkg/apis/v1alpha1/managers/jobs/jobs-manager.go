@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -27,15 +28,41 @@ import (
 
 var log = logger.NewLogger("coa.runtime")
 
+const (
+	watchRetryInterval = 5 * time.Second
+	// watchedObjectTypes is the number of change feeds the jobs manager follows: instances and targets
+	watchedObjectTypes = 2
+)
+
 type JobsManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
+	WatchProvider states.IWatchableStateProvider
+	// activeWatches counts the change feeds that are currently established
+	activeWatches int32
 }
 
 type LastSuccessTime struct {
 	Time time.Time `json:"time"`
 }
 
+// ScheduledJob is a stage or an activation waiting for its schedule to fire
+type ScheduledJob struct {
+	v1alpha2.ActivationData
+	NextFire time.Time `json:"nextFire,omitempty"`
+	// IsActivation is set when a whole activation is scheduled, rather than one of its stages
+	IsActivation bool `json:"isActivation,omitempty"`
+	// Runs counts how many times a recurring activation has fired
+	Runs int `json:"runs,omitempty"`
+}
+
+func scheduleKey(campaign string, activation string, isActivation bool) string {
+	if isActivation {
+		return fmt.Sprintf("sch_%s-%s-activation", campaign, activation)
+	}
+	return fmt.Sprintf("sch_%s-%s", campaign, activation)
+}
+
 func (s *JobsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
 	err := s.Manager.Init(context, config, providers)
 	if err != nil {
@@ -48,9 +75,94 @@ func (s *JobsManager) Init(context *contexts.VendorContext, config managers.Mana
 	} else {
 		return err
 	}
+
+	// When a watchable state provider holding instances and targets is configured, object
+	// changes are picked up from its change feed instead of polling the REST API. The provider
+	// must share its store with the providers the instances and targets are written through,
+	// otherwise its change feed never reports their changes.
+	if watchProviderName, ok := config.Properties["providers.watch"]; ok {
+		provider, ok := providers[watchProviderName]
+		if !ok {
+			return v1alpha2.NewCOAError(nil, "watch provider is not supplied", v1alpha2.MissingConfig)
+		}
+		watchProvider, ok := provider.(states.IWatchableStateProvider)
+		if !ok {
+			return v1alpha2.NewCOAError(nil, "supplied provider is not a watchable state provider", v1alpha2.BadConfig)
+		}
+		if sharedProvider, ok := provider.(states.ISharedStateProvider); !ok || !sharedProvider.IsShared() {
+			return v1alpha2.NewCOAError(nil, "supplied watch provider doesn't have a shared store", v1alpha2.BadConfig)
+		}
+		s.WatchProvider = watchProvider
+		if s.Config.Properties["poll.enabled"] == "true" {
+			s.startWatches()
+		}
+	}
 	return nil
 }
 
+func (s *JobsManager) startWatches() {
+	go s.watchObjects(context.Background(), "instance", map[string]string{
+		"group":    model.SolutionGroup,
+		"version":  "v1",
+		"resource": "instances",
+	})
+	go s.watchObjects(context.Background(), "target", map[string]string{
+		"group":    model.FabricGroup,
+		"version":  "v1",
+		"resource": "targets",
+	})
+}
+
+// watchObjects publishes an update job for every object change reported by the watch provider.
+// The watch is re-established when the change feed closes, resuming from the last seen resource
+// version. If a watch closes without delivering anything, the next one starts from scratch, which
+// also covers resource versions the provider no longer recognizes. While a change feed is down,
+// Poll falls back to listing the objects.
+func (s *JobsManager) watchObjects(ctx context.Context, objectType string, metadata map[string]string) {
+	resourceVersion := ""
+	for {
+		ch, err := s.WatchProvider.Watch(ctx, states.WatchRequest{
+			ResourceVersion: resourceVersion,
+			Metadata:        metadata,
+		})
+		if err != nil {
+			log.Errorf(" M (Job): failed to watch %s objects: %+v", objectType, err)
+			resourceVersion = ""
+		} else {
+			atomic.AddInt32(&s.activeWatches, 1)
+			received := false
+			for event := range ch {
+				received = true
+				resourceVersion = event.ResourceVersion
+				// Deletions are published by the vendors before the objects are removed, as the delete
+				// jobs need to read the objects being deleted.
+				if event.Type != states.WatchEventUpsert {
+					continue
+				}
+				s.Context.Publish("job", v1alpha2.Event{
+					Metadata: map[string]string{
+						"objectType": objectType,
+						"scope":      event.Metadata["scope"],
+					},
+					Body: v1alpha2.JobData{
+						Id:     event.Entry.ID,
+						Action: "UPDATE",
+					},
+				})
+			}
+			atomic.AddInt32(&s.activeWatches, -1)
+			if !received {
+				resourceVersion = ""
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
 func (s *JobsManager) Enabled() bool {
 	return s.Config.Properties["poll.enabled"] == "true" || s.Config.Properties["schedule.enabled"] == "true"
 }
@@ -148,9 +260,15 @@ func (s *JobsManager) pollObjects() []error {
 
 	return nil
 }
+
+// watching tells if all object changes are picked up from the change feeds of the watch provider.
+func (s *JobsManager) watching() bool {
+	return s.WatchProvider != nil && atomic.LoadInt32(&s.activeWatches) == watchedObjectTypes
+}
+
 func (s *JobsManager) Poll() []error {
 	// TODO: do these in parallel?
-	if s.Config.Properties["poll.enabled"] == "true" {
+	if s.Config.Properties["poll.enabled"] == "true" && !s.watching() {
 		errors := s.pollObjects()
 		if len(errors) > 0 {
 			return errors
@@ -178,36 +296,94 @@ func (s *JobsManager) pollSchedules() []error {
 		return []error{err}
 	}
 
+	now := time.Now()
 	for _, entry := range list {
-		var activationData v1alpha2.ActivationData
+		if !strings.HasPrefix(entry.ID, "sch_") {
+			continue
+		}
+		var job ScheduledJob
 		entryData, _ := json.Marshal(entry.Body)
-		err = json.Unmarshal(entryData, &activationData)
+		err = json.Unmarshal(entryData, &job)
 		if err != nil {
 			return []error{err}
 		}
-		if activationData.Schedule != nil {
-			var fire bool
-			fire, err = activationData.Schedule.ShouldFireNow()
+		if job.Schedule == nil {
+			continue
+		}
+		if job.NextFire.IsZero() {
+			// entries stored before the next fire time was kept
+			job.NextFire, err = job.Schedule.NextTime(now)
 			if err != nil {
 				return []error{err}
 			}
-			if fire {
-				activationData.Schedule = nil
-				err = s.StateProvider.Delete(context, states.DeleteRequest{
-					ID: entry.ID,
-				})
-				if err != nil {
-					return []error{err}
-				}
-				s.Context.Publish("trigger", v1alpha2.Event{
-					Body: activationData,
-				})
+		}
+		if job.NextFire.IsZero() || job.NextFire.After(now) {
+			continue
+		}
+		if job.IsActivation {
+			err = s.fireActivation(context, entry.ID, job, now)
+			if err != nil {
+				return []error{err}
 			}
+			continue
 		}
+		activationData := job.ActivationData
+		activationData.Schedule = nil
+		err = s.StateProvider.Delete(context, states.DeleteRequest{
+			ID: entry.ID,
+		})
+		if err != nil {
+			return []error{err}
+		}
+		s.Context.Publish("trigger", v1alpha2.Event{
+			Body: activationData,
+		})
 	}
 	return nil
 }
 
+// fireActivation starts a scheduled activation. A recurring activation gets a generation of its own for
+// each run, so that operator actions on one run don't carry over to the next, and is scheduled again.
+// Runs missed while the jobs manager wasn't polling aren't caught up.
+func (s *JobsManager) fireActivation(ctx context.Context, id string, job ScheduledJob, now time.Time) error {
+	activationData := job.ActivationData
+	activationData.Schedule = nil
+	if job.Schedule.IsRecurring() {
+		job.Runs++
+		activationData.ActivationGeneration = fmt.Sprintf("%s-%d", job.ActivationGeneration, job.Runs)
+		next, err := job.Schedule.NextTime(now)
+		if err != nil {
+			return err
+		}
+		job.NextFire = next
+	}
+	if job.Schedule.IsRecurring() && !job.NextFire.IsZero() {
+		_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: job,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		err := s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.Context.Publish("activation", v1alpha2.Event{
+		Body: activationData,
+		Metadata: map[string]string{
+			"scheduled": "true",
+		},
+	})
+	return nil
+}
+
 func (s *JobsManager) Reconcil() []error {
 	return nil
 }
@@ -287,15 +463,50 @@ func (s *JobsManager) HandleScheduleEvent(ctx context.Context, event v1alpha2.Ev
 	if err != nil {
 		return v1alpha2.NewCOAError(nil, "event body is not a activation data", v1alpha2.BadRequest)
 	}
-	key := fmt.Sprintf("sch_%s-%s", activationData.Campaign, activationData.Activation)
+	job := ScheduledJob{
+		ActivationData: activationData,
+		IsActivation:   event.Metadata["scope"] == "activation",
+	}
+	if activationData.Schedule != nil {
+		job.NextFire, err = activationData.Schedule.NextTime(time.Now())
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "invalid schedule", v1alpha2.BadRequest)
+		}
+	}
 	_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
 		Value: states.StateEntry{
-			ID:   key,
-			Body: activationData,
+			ID:   scheduleKey(activationData.Campaign, activationData.Activation, job.IsActivation),
+			Body: job,
 		},
 	})
 	return err
 }
+
+// HandleUnscheduleEvent drops the pending schedules of an activation, e.g. when it's deleted
+func (s *JobsManager) HandleUnscheduleEvent(ctx context.Context, event v1alpha2.Event) error {
+	ctx, span := observability.StartSpan("Job Manager", ctx, &map[string]string{
+		"method": "HandleUnscheduleEvent",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var activationData v1alpha2.ActivationData
+	jData, _ := json.Marshal(event.Body)
+	err = json.Unmarshal(jData, &activationData)
+	if err != nil {
+		return v1alpha2.NewCOAError(nil, "event body is not a activation data", v1alpha2.BadRequest)
+	}
+	for _, isActivation := range []bool{true, false} {
+		err = s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: scheduleKey(activationData.Campaign, activationData.Activation, isActivation),
+		})
+		if err != nil && !v1alpha2.IsNotFound(err) {
+			return err
+		}
+	}
+	err = nil
+	return nil
+}
 func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event) error {
 	ctx, span := observability.StartSpan("Job Manager", ctx, &map[string]string{
 		"method": "HandleJobEvent",
@@ -12,15 +12,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -131,6 +136,151 @@ func TestHandleScheduleEvent(t *testing.T) {
 	assert.NotNil(t, schedule)
 }
 
+func newScheduleTestManager(t *testing.T) (*JobsManager, *memorystate.MemoryStateProvider, chan v1alpha2.Event) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	vendorContext := &contexts.VendorContext{
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+
+	activations := make(chan v1alpha2.Event, 2)
+	vendorContext.Subscribe("activation", func(topic string, event v1alpha2.Event) error {
+		activations <- event
+		return nil
+	})
+
+	jobManager := &JobsManager{}
+	err := jobManager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.state":  "state",
+			"schedule.enabled": "true",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+	})
+	assert.Nil(t, err)
+	return jobManager, stateProvider, activations
+}
+
+func getScheduledJob(t *testing.T, stateProvider *memorystate.MemoryStateProvider, id string) ScheduledJob {
+	entry, err := stateProvider.Get(context.Background(), states.GetRequest{ID: id})
+	assert.Nil(t, err)
+	var job ScheduledJob
+	jData, _ := json.Marshal(entry.Body)
+	err = json.Unmarshal(jData, &job)
+	assert.Nil(t, err)
+	return job
+}
+
+func TestHandleActivationScheduleEvent(t *testing.T) {
+	jobManager, stateProvider, _ := newScheduleTestManager(t)
+	err := jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+		Body: v1alpha2.ActivationData{
+			Campaign:   "campaign1",
+			Activation: "activation1",
+			Schedule:   &v1alpha2.ScheduleSpec{Cron: "0 2 * * *", Zone: "Asia/Tokyo"},
+		},
+		Metadata: map[string]string{"scope": "activation"},
+	})
+	assert.Nil(t, err)
+
+	job := getScheduledJob(t, stateProvider, "sch_campaign1-activation1-activation")
+	assert.True(t, job.IsActivation)
+	assert.True(t, job.NextFire.After(time.Now()))
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	assert.Equal(t, 2, job.NextFire.In(tokyo).Hour())
+	assert.Equal(t, 0, job.NextFire.In(tokyo).Minute())
+}
+
+func TestPollRecurringActivation(t *testing.T) {
+	jobManager, stateProvider, activations := newScheduleTestManager(t)
+	_, err := stateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "sch_campaign1-activation1-activation",
+			Body: ScheduledJob{
+				ActivationData: v1alpha2.ActivationData{
+					Campaign:             "campaign1",
+					Activation:           "activation1",
+					ActivationGeneration: "3",
+					Schedule:             &v1alpha2.ScheduleSpec{Cron: "*/5 * * * *"},
+				},
+				NextFire:     time.Now().Add(-time.Minute),
+				IsActivation: true,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	errs := jobManager.Poll()
+	assert.Nil(t, errs)
+
+	select {
+	case event := <-activations:
+		assert.Equal(t, "true", event.Metadata["scheduled"])
+		data := event.Body.(v1alpha2.ActivationData)
+		assert.Equal(t, "activation1", data.Activation)
+		assert.Equal(t, "3-1", data.ActivationGeneration)
+		assert.Nil(t, data.Schedule)
+	case <-time.After(time.Second):
+		assert.Fail(t, "activation was not started")
+	}
+
+	// the activation stays scheduled for its next run
+	job := getScheduledJob(t, stateProvider, "sch_campaign1-activation1-activation")
+	assert.Equal(t, 1, job.Runs)
+	assert.True(t, job.NextFire.After(time.Now()))
+	assert.Equal(t, 0, job.NextFire.Minute()%5)
+}
+
+func TestPollOneOffActivation(t *testing.T) {
+	jobManager, stateProvider, activations := newScheduleTestManager(t)
+	err := jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+		Body: v1alpha2.ActivationData{
+			Campaign:             "campaign1",
+			Activation:           "activation1",
+			ActivationGeneration: "1",
+			Schedule:             &v1alpha2.ScheduleSpec{Date: "2006-01-02", Time: "03:04:05PM"},
+		},
+		Metadata: map[string]string{"scope": "activation"},
+	})
+	assert.Nil(t, err)
+
+	errs := jobManager.Poll()
+	assert.Nil(t, errs)
+
+	select {
+	case event := <-activations:
+		assert.Equal(t, "1", event.Body.(v1alpha2.ActivationData).ActivationGeneration)
+	case <-time.After(time.Second):
+		assert.Fail(t, "activation was not started")
+	}
+	_, err = stateProvider.Get(context.Background(), states.GetRequest{ID: "sch_campaign1-activation1-activation"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestHandleUnscheduleEvent(t *testing.T) {
+	jobManager, stateProvider, _ := newScheduleTestManager(t)
+	err := jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+		Body: v1alpha2.ActivationData{
+			Campaign:   "campaign1",
+			Activation: "activation1",
+			Schedule:   &v1alpha2.ScheduleSpec{Cron: "@daily"},
+		},
+		Metadata: map[string]string{"scope": "activation"},
+	})
+	assert.Nil(t, err)
+
+	err = jobManager.HandleUnscheduleEvent(context.Background(), v1alpha2.Event{
+		Body: v1alpha2.ActivationData{Campaign: "campaign1", Activation: "activation1"},
+	})
+	assert.Nil(t, err)
+	_, err = stateProvider.Get(context.Background(), states.GetRequest{ID: "sch_campaign1-activation1-activation"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
 func TestHandleheartbeatEvent(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
 	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
@@ -219,6 +369,133 @@ func TestDelayOrSkipJobPoll(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// sharedFileStateProvider stands in for a state provider with a shared store, such as the k8s state provider
+type sharedFileStateProvider struct {
+	filestate.FileStateProvider
+}
+
+func (s *sharedFileStateProvider) IsShared() bool {
+	return true
+}
+
+func TestWatchObjects(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	watchProvider := &sharedFileStateProvider{}
+	err := watchProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+
+	vendorContext := &contexts.VendorContext{
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+
+	jobs := make(chan v1alpha2.Event, 2)
+	vendorContext.Subscribe("job", func(topic string, event v1alpha2.Event) error {
+		jobs <- event
+		return nil
+	})
+
+	jobManager := JobsManager{}
+	err = jobManager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.state": "state",
+			"providers.watch": "watch",
+			"poll.enabled":    "true",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+		"watch": watchProvider,
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, jobManager.WatchProvider)
+
+	// the watches are established asynchronously, keep writing until the job shows up
+	var event v1alpha2.Event
+	for received := false; !received; {
+		_, err = watchProvider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   "target1",
+				Body: map[string]interface{}{"spec": map[string]interface{}{}},
+			},
+			Metadata: map[string]string{
+				"scope":    "scope1",
+				"group":    model.FabricGroup,
+				"version":  "v1",
+				"resource": "targets",
+			},
+		})
+		assert.Nil(t, err)
+		select {
+		case event = <-jobs:
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, "target", event.Metadata["objectType"])
+	assert.Equal(t, "scope1", event.Metadata["scope"])
+	assert.Equal(t, "target1", event.Body.(v1alpha2.JobData).Id)
+	assert.Equal(t, "UPDATE", event.Body.(v1alpha2.JobData).Action)
+	assert.Eventually(t, jobManager.watching, time.Second, 10*time.Millisecond)
+}
+
+func TestInitWithUnsharedWatchProvider(t *testing.T) {
+	watchProvider := &filestate.FileStateProvider{}
+	err := watchProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+	jobManager := JobsManager{}
+	err = jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.state": "state",
+			"providers.watch": "watch",
+		},
+	}, map[string]providers.IProvider{
+		"state": &memorystate.MemoryStateProvider{},
+		"watch": watchProvider,
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestPollWhileWatchIsDown(t *testing.T) {
+	jobManager := JobsManager{
+		WatchProvider: &sharedFileStateProvider{},
+	}
+	jobManager.Config = managers.ManagerConfig{
+		Properties: map[string]string{
+			"poll.enabled": "true",
+		},
+	}
+	// without a baseUrl, polling the objects fails
+	jobManager.activeWatches = 1
+	errs := jobManager.Poll()
+	assert.Equal(t, 1, len(errs))
+	jobManager.activeWatches = watchedObjectTypes
+	errs = jobManager.Poll()
+	assert.Equal(t, 0, len(errs))
+}
+
+func TestInitWithInvalidWatchProvider(t *testing.T) {
+	jobManager := JobsManager{}
+	err := jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.state": "state",
+			"providers.watch": "missing",
+		},
+	}, map[string]providers.IProvider{
+		"state": &memorystate.MemoryStateProvider{},
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.MissingConfig, coaErr.State)
+}
+
 type AuthResponse struct {
 	AccessToken string   `json:"accessToken"`
 	TokenType   string   `json:"tokenType"`
@@ -275,10 +275,8 @@ func (s *SitesManager) Poll() []error {
 	jData, _ := json.Marshal(thisSite)
 	utils.UpdateSite(
 		ctx,
-		s.VendorContext.SiteInfo.ParentSite.BaseUrl,
+		s.VendorContext.SiteInfo.ParentSite,
 		s.VendorContext.SiteInfo.SiteId,
-		s.VendorContext.SiteInfo.ParentSite.Username,
-		s.VendorContext.SiteInfo.ParentSite.Password,
 		jData,
 	)
 	return nil
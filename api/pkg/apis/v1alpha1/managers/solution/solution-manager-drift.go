@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package solution
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	sp "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
+	tgt "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+type driftedInstance struct {
+	Instance string
+	Scope    string
+}
+
+// DetectDrift compares what the target providers report against the last deployment applied to an instance,
+// and saves the result as the instance's drift report.
+func (s *SolutionManager) DetectDrift(ctx context.Context, instance string, scope string) (model.DriftReport, error) {
+	iCtx, span := observability.StartSpan("Solution Manager", ctx, &map[string]string{
+		"method": "DetectDrift",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Infof(" M (Solution): detecting drift of %s", instance)
+
+	report := model.DriftReport{
+		Instance:   instance,
+		Time:       time.Now().UTC(),
+		Components: make([]model.ComponentDrift, 0),
+	}
+
+	// a reconcile in progress would show up as drift
+	unlock := lockInstance(scope, instance)
+	defer unlock()
+
+	previous := s.getPreviousState(iCtx, instance, scope)
+	if previous == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("instance '%s' has no deployment state", instance), v1alpha2.NotFound)
+		return report, err
+	}
+	report.Generation = previous.Spec.Generation
+
+	plan, err := PlanForDeployment(previous.Spec, previous.State)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to plan for drift detection: %+v", err)
+		return report, err
+	}
+
+	checked := make(map[string]bool)
+	for _, step := range plan.Steps {
+		references := make([]model.ComponentStep, 0, len(step.Components))
+		references = append(references, step.Components...)
+		// also ask for the components of the same role that aren't assigned to the target, to find the extra ones
+		if !checked[step.Target+"::"+step.Role] {
+			checked[step.Target+"::"+step.Role] = true
+			for _, c := range previous.State.Components {
+				if componentRole(c) == step.Role && !isAssigned(previous.State, c.Name, step.Target) {
+					references = append(references, model.ComponentStep{Action: "delete", Component: c})
+				}
+			}
+		}
+		var override tgt.ITargetProvider
+		if v, ok := s.TargetProviders[step.Target]; ok {
+			override = v
+		}
+		var provider providers.IProvider
+		provider, err = sp.CreateProviderForTargetRole(s.Context, step.Role, previous.Spec.Targets[step.Target], override)
+		if err != nil {
+			log.Errorf(" M (Solution): failed to create provider: %+v", err)
+			return report, err
+		}
+		targetProvider := provider.(tgt.ITargetProvider)
+		var components []model.ComponentSpec
+		components, err = targetProvider.Get(iCtx, previous.Spec, references)
+		if err != nil {
+			log.Errorf(" M (Solution): failed to get: %+v", err)
+			return report, err
+		}
+		for _, d := range driftComponents(step, components, previous.State, targetProvider.GetValidationRule(iCtx)) {
+			// providers that report all their components would report an extra component on every step
+			if !containsDrift(report.Components, d) {
+				report.Components = append(report.Components, d)
+			}
+		}
+	}
+	report.Drifted = len(report.Components) > 0
+	s.saveDriftReport(iCtx, report, scope)
+	return report, nil
+}
+
+// GetDriftReport returns the last drift report of an instance.
+func (s *SolutionManager) GetDriftReport(ctx context.Context, instance string, scope string) (model.DriftReport, error) {
+	iCtx, span := observability.StartSpan("Solution Manager", ctx, &map[string]string{
+		"method": "GetDriftReport",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	state, err := s.StateProvider.Get(iCtx, states.GetRequest{
+		ID: fmt.Sprintf("%s-%s", "drift", instance),
+		Metadata: map[string]string{
+			"scope": scope,
+		},
+	})
+	if err != nil {
+		log.Errorf(" M (Solution): failed to get drift report[%s]: %+v", instance, err)
+		return model.DriftReport{}, err
+	}
+	var report model.DriftReport
+	jData, _ := json.Marshal(state.Body)
+	err = json.Unmarshal(jData, &report)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to deserailze drift report[%s]: %+v", instance, err)
+		return model.DriftReport{}, err
+	}
+	return report, nil
+}
+func (s *SolutionManager) saveDriftReport(ctx context.Context, report model.DriftReport, scope string) {
+	_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   fmt.Sprintf("%s-%s", "drift", report.Instance),
+			Body: report,
+		},
+		Metadata: map[string]string{
+			"scope": scope,
+		},
+	})
+	if err != nil {
+		log.Errorf(" M (Solution): failed to save drift report[%s]: %+v", report.Instance, err)
+	}
+}
+
+// detectAllDrifts checks every instance that has deployment state, and remembers the drifted instances
+// that opted in to auto-heal.
+func (s *SolutionManager) detectAllDrifts(ctx context.Context) []error {
+	entries, _, err := s.StateProvider.List(ctx, states.ListRequest{})
+	if err != nil {
+		return []error{err}
+	}
+	ret := make([]error, 0)
+	s.driftedReports = make([]driftedInstance, 0)
+	for _, entry := range entries {
+		var managerState SolutionManagerDeploymentState
+		jData, _ := json.Marshal(entry.Body)
+		// summaries, histories and drift reports share the state store
+		if json.Unmarshal(jData, &managerState) != nil || managerState.Spec.Instance.Name != entry.ID {
+			continue
+		}
+		scope := managerState.Scope
+		if scope == "" {
+			scope = "default"
+		}
+		report, err := s.DetectDrift(ctx, entry.ID, scope)
+		if err != nil {
+			log.Errorf(" M (Solution): failed to detect drift of %s: %+v", entry.ID, err)
+			ret = append(ret, err)
+			continue
+		}
+		col := api_utils.MergeCollection(managerState.Spec.Solution.Metadata, managerState.Spec.Instance.Metadata)
+		if report.Drifted && col[AUTO_HEAL] == "true" {
+			s.driftedReports = append(s.driftedReports, driftedInstance{Instance: entry.ID, Scope: scope})
+		}
+	}
+	return ret
+}
+
+// healDrifts re-applies the last deployment of the instances found drifted by the last poll.
+func (s *SolutionManager) healDrifts(ctx context.Context) []error {
+	ret := make([]error, 0)
+	for _, d := range s.driftedReports {
+		if err := s.HealDrift(ctx, d.Instance, d.Scope); err != nil {
+			ret = append(ret, err)
+		}
+	}
+	s.driftedReports = nil
+	return ret
+}
+
+// HealDrift re-applies the last deployment of an instance to undo the drift in its last drift report.
+// Missing and changed components are re-applied, and extra components are removed.
+func (s *SolutionManager) HealDrift(ctx context.Context, instance string, scope string) error {
+	iCtx, span := observability.StartSpan("Solution Manager", ctx, &map[string]string{
+		"method": "HealDrift",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	report, err := s.GetDriftReport(iCtx, instance, scope)
+	if err != nil {
+		return err
+	}
+	if !report.Drifted {
+		return nil
+	}
+	previous := s.getPreviousState(iCtx, instance, scope)
+	if previous == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("instance '%s' has no deployment state", instance), v1alpha2.NotFound)
+		return err
+	}
+	log.Infof(" M (Solution): healing drift of %s", instance)
+
+	_, err = s.reconcile(iCtx, previous.Spec, false, scope, true)
+	if err == nil {
+		err = s.removeExtraComponents(iCtx, previous, report)
+	}
+	report.Healed = err == nil
+	report.HealMessage = ""
+	if err != nil {
+		report.HealMessage = err.Error()
+		log.Errorf(" M (Solution): failed to heal drift of %s: %+v", instance, err)
+	}
+	s.saveDriftReport(iCtx, report, scope)
+	return err
+}
+func (s *SolutionManager) removeExtraComponents(ctx context.Context, previous *SolutionManagerDeploymentState, report model.DriftReport) error {
+	steps := make([]model.DeploymentStep, 0)
+	for _, d := range report.Components {
+		if d.Drift != model.DriftExtra {
+			continue
+		}
+		for _, c := range previous.State.Components {
+			if c.Name != d.Name {
+				continue
+			}
+			index := -1
+			for i, step := range steps {
+				if step.Target == d.Target && step.Role == componentRole(c) {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				steps = append(steps, model.DeploymentStep{Target: d.Target, Role: componentRole(c)})
+				index = len(steps) - 1
+			}
+			steps[index].Components = append(steps[index].Components, model.ComponentStep{Action: "delete", Component: c})
+			break
+		}
+	}
+	dep := previous.Spec
+	dep.Instance.Metadata = api_utils.MergeCollection(previous.Spec.Solution.Metadata, previous.Spec.Instance.Metadata)
+	for _, step := range steps {
+		dep.ActiveTarget = step.Target
+		var override tgt.ITargetProvider
+		if v, ok := s.TargetProviders[step.Target]; ok {
+			override = v
+		}
+		provider, err := sp.CreateProviderForTargetRole(s.Context, step.Role, dep.Targets[step.Target], override)
+		if err != nil {
+			return err
+		}
+		_, err = (provider.(tgt.ITargetProvider)).Apply(ctx, dep, step, false)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func driftComponents(step model.DeploymentStep, current []model.ComponentSpec, state model.DeploymentState, rule model.ValidationRule) []model.ComponentDrift {
+	ret := make([]model.ComponentDrift, 0)
+	for _, c := range step.Components {
+		if c.Action != "update" {
+			continue
+		}
+		found := false
+		for _, cc := range current {
+			if cc.Name == c.Component.Name {
+				found = true
+				if properties := rule.ChangedProperties(cc, c.Component); len(properties) > 0 {
+					ret = append(ret, model.ComponentDrift{Target: step.Target, Name: cc.Name, Drift: model.DriftChanged, Properties: properties})
+				}
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, model.ComponentDrift{Target: step.Target, Name: c.Component.Name, Drift: model.DriftMissing})
+		}
+	}
+	for _, cc := range current {
+		if !isAssigned(state, cc.Name, step.Target) {
+			ret = append(ret, model.ComponentDrift{Target: step.Target, Name: cc.Name, Drift: model.DriftExtra})
+		}
+	}
+	return ret
+}
+func containsDrift(drifts []model.ComponentDrift, drift model.ComponentDrift) bool {
+	for _, d := range drifts {
+		if d.Target == drift.Target && d.Name == drift.Name && d.Drift == drift.Drift {
+			return true
+		}
+	}
+	return false
+}
+func isAssigned(state model.DeploymentState, component string, target string) bool {
+	v, ok := state.TargetComponent[fmt.Sprintf("%s::%s", component, target)]
+	return ok && v != "" && v[0] != '-'
+}
+func componentRole(component model.ComponentSpec) string {
+	if component.Type == "" {
+		return "instance"
+	}
+	return component.Type
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package solution
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/mock"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type imageTargetProvider struct {
+	failingTargetProvider
+}
+
+func (i *imageTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		ChangeDetectionProperties: []model.PropertyDesc{{Name: "image"}},
+	}
+}
+
+func applyMock(t *testing.T, id string, action string, component model.ComponentSpec) {
+	provider := &mock.MockTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: id})
+	_, err := provider.Apply(context.Background(), model.DeploymentSpec{}, model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: action, Component: component}},
+	}, false)
+	assert.Nil(t, err)
+}
+func TestDetectDriftNone(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+
+	_, err := manager.Reconcile(context.Background(), createRollbackDeployment(t1, "1", false), false, "default")
+	assert.Nil(t, err)
+
+	report, err := manager.DetectDrift(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.False(t, report.Drifted)
+	assert.Equal(t, "1", report.Generation)
+	assert.Empty(t, report.Components)
+}
+func TestDetectDriftNotFound(t *testing.T) {
+	manager := createRollbackManager(&failingTargetProvider{})
+	_, err := manager.DetectDrift(context.Background(), "instance1", "default")
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = manager.GetDriftReport(context.Background(), "instance1", "default")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+func TestDetectDriftMissingAndExtra(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+
+	_, err := manager.Reconcile(context.Background(), createRollbackDeployment(t1, "1", false), false, "default")
+	assert.Nil(t, err)
+
+	// a is removed from T1, and b, which belongs to T2, shows up on T1
+	applyMock(t, t1, "delete", model.ComponentSpec{Name: "a", Type: "mock"})
+	applyMock(t, t1, "update", model.ComponentSpec{Name: "b", Type: "mock"})
+
+	report, err := manager.DetectDrift(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.True(t, report.Drifted)
+	assert.ElementsMatch(t, []model.ComponentDrift{
+		{Target: "T1", Name: "a", Drift: model.DriftMissing},
+		{Target: "T1", Name: "b", Drift: model.DriftExtra},
+	}, report.Components)
+
+	saved, err := manager.GetDriftReport(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.True(t, saved.Drifted)
+	assert.Equal(t, 2, len(saved.Components))
+}
+func TestDetectDriftChanged(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &imageTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(&t2.failingTargetProvider)
+	manager.TargetProviders["T2"] = t2
+
+	deployment := createRollbackDeployment(t1, "1", false)
+	deployment.Solution.Components[1].Properties = map[string]interface{}{"image": "b:1", "port": "80"}
+	_, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+
+	applyMock(t, t2.Config.ID, "delete", model.ComponentSpec{Name: "b"})
+	applyMock(t, t2.Config.ID, "update", model.ComponentSpec{Name: "b", Type: "mock", Properties: map[string]interface{}{"image": "b:2", "port": "8080"}})
+
+	report, err := manager.DetectDrift(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.True(t, report.Drifted)
+	// only the properties the provider watches are reported
+	assert.Equal(t, []model.ComponentDrift{
+		{Target: "T2", Name: "b", Drift: model.DriftChanged, Properties: []string{"image"}},
+	}, report.Components)
+}
+func TestPollAndHealDrift(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &imageTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(&t2.failingTargetProvider)
+	manager.TargetProviders["T2"] = t2
+	manager.DriftInterval = time.Minute
+	assert.True(t, manager.Enabled())
+
+	deployment := createRollbackDeployment(t1, "1", false)
+	deployment.Instance.Metadata[AUTO_HEAL] = "true"
+	deployment.Solution.Components[1].Properties = map[string]interface{}{"image": "b:1"}
+	_, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+
+	applyMock(t, t1, "delete", model.ComponentSpec{Name: "a"})
+	applyMock(t, t1, "update", model.ComponentSpec{Name: "b", Type: "mock"})
+	applyMock(t, t2.Config.ID, "delete", model.ComponentSpec{Name: "b"})
+	applyMock(t, t2.Config.ID, "update", model.ComponentSpec{Name: "b", Type: "mock", Properties: map[string]interface{}{"image": "b:2"}})
+
+	assert.Empty(t, manager.Poll())
+	assert.Equal(t, 1, len(manager.driftedReports))
+	assert.Empty(t, manager.Reconcil())
+
+	report, err := manager.GetDriftReport(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.True(t, report.Drifted)
+	assert.True(t, report.Healed)
+	assert.ElementsMatch(t, []string{"a"}, getMockComponents(t, t1))
+	assert.ElementsMatch(t, []string{"b"}, getMockComponents(t, t2.Config.ID))
+
+	// the next poll finds nothing to heal, and polls within the interval are skipped
+	manager.lastDriftCheck = time.Time{}
+	assert.Empty(t, manager.Poll())
+	assert.Empty(t, manager.driftedReports)
+	report, err = manager.DetectDrift(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.False(t, report.Drifted)
+}
+func TestDriftWithoutAutoHeal(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+	manager.DriftInterval = time.Minute
+
+	_, err := manager.Reconcile(context.Background(), createRollbackDeployment(t1, "1", false), false, "default")
+	assert.Nil(t, err)
+	applyMock(t, t1, "delete", model.ComponentSpec{Name: "a"})
+
+	assert.Empty(t, manager.Poll())
+	assert.Empty(t, manager.driftedReports)
+	assert.Empty(t, manager.Reconcil())
+	assert.Empty(t, getMockComponents(t, t1))
+	report, err := manager.GetDriftReport(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.True(t, report.Drifted)
+	assert.False(t, report.Healed)
+}
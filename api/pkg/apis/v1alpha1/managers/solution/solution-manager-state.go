@@ -55,6 +55,137 @@ func PlanForDeployment(deployment model.DeploymentSpec, state model.DeploymentSt
 	return ret.RevisedForDeletion(), nil
 }
 
+// PlanWaves groups the steps of a plan into waves of step indexes. Steps of the same wave neither share a target nor
+// depend on each other's components, so they may run concurrently. A wave starts after all previous waves are done.
+func PlanWaves(plan model.DeploymentPlan) [][]int {
+	indexes := make([]int, len(plan.Steps))
+	for i := range plan.Steps {
+		indexes[i] = i
+	}
+	return planWaves(plan, indexes)
+}
+func planWaves(plan model.DeploymentPlan, indexes []int) [][]int {
+	waves := make([][]int, 0)
+	levels := make(map[int]int)
+	for n, i := range indexes {
+		for _, j := range indexes[:n] {
+			if stepsConflict(plan.Steps[j], plan.Steps[i]) && levels[j]+1 > levels[i] {
+				levels[i] = levels[j] + 1
+			}
+		}
+		if levels[i] == len(waves) {
+			waves = append(waves, make([]int, 0))
+		}
+		waves[levels[i]] = append(waves[levels[i]], i)
+	}
+	return waves
+}
+
+// PlanRolloutBatches splits the steps of a plan into batches of step indexes following the rollout strategy, and
+// returns the targets of each batch. Targets are assigned to batches in name order. A step that conflicts with a
+// step of a later batch joins that batch, so dependencies are still honored. Without a rollout strategy, all steps
+// form a single batch.
+func PlanRolloutBatches(plan model.DeploymentPlan, rollout *model.RolloutSpec) ([][]int, [][]string) {
+	targets := make([]string, 0)
+	for _, step := range plan.Steps {
+		found := false
+		for _, t := range targets {
+			if t == step.Target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			targets = append(targets, step.Target)
+		}
+	}
+	sort.Strings(targets)
+
+	size := len(targets)
+	if rollout != nil {
+		size = rolloutBatchSize(*rollout, len(targets))
+	}
+	targetBatches := make(map[string]int)
+	for i, t := range targets {
+		targetBatches[t] = i / size
+	}
+	stepBatches := make([]int, len(plan.Steps))
+	count := 0
+	for i, step := range plan.Steps {
+		stepBatches[i] = targetBatches[step.Target]
+		for j := 0; j < i; j++ {
+			if stepsConflict(plan.Steps[j], step) && stepBatches[j] > stepBatches[i] {
+				stepBatches[i] = stepBatches[j]
+			}
+		}
+		if stepBatches[i]+1 > count {
+			count = stepBatches[i] + 1
+		}
+	}
+
+	batches := make([][]int, 0)
+	batchTargets := make([][]string, 0)
+	for b := 0; b < count; b++ {
+		batch := make([]int, 0)
+		batchTarget := make([]string, 0)
+		for i, step := range plan.Steps {
+			if stepBatches[i] != b {
+				continue
+			}
+			batch = append(batch, i)
+			found := false
+			for _, t := range batchTarget {
+				if t == step.Target {
+					found = true
+					break
+				}
+			}
+			if !found {
+				batchTarget = append(batchTarget, step.Target)
+			}
+		}
+		if len(batch) > 0 {
+			batches = append(batches, batch)
+			batchTargets = append(batchTargets, batchTarget)
+		}
+	}
+	return batches, batchTargets
+}
+func rolloutBatchSize(rollout model.RolloutSpec, targets int) int {
+	if rollout.BatchSize > 0 {
+		return rollout.BatchSize
+	}
+	if rollout.Percentage > 0 {
+		size := (targets*rollout.Percentage + 99) / 100
+		if size < 1 {
+			size = 1
+		}
+		return size
+	}
+	return 1
+}
+func stepsConflict(a model.DeploymentStep, b model.DeploymentStep) bool {
+	if a.Target == b.Target {
+		return true
+	}
+	for _, ca := range a.Components {
+		for _, cb := range b.Components {
+			if dependsOn(ca.Component, cb.Component.Name) || dependsOn(cb.Component, ca.Component.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+func dependsOn(component model.ComponentSpec, name string) bool {
+	for _, d := range component.Dependencies {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
 func NewDeploymentState(deployment model.DeploymentSpec) (model.DeploymentState, error) {
 	ret := model.DeploymentState{
 		Components:      make([]model.ComponentSpec, 0),
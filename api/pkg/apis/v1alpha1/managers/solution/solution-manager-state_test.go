@@ -976,3 +976,99 @@ func TestDockerHelmMixLinearDepedencies(t *testing.T) {
 	assert.Equal(t, "update", plan.Steps[3].Components[0].Action)
 	assert.Equal(t, "d", plan.Steps[3].Components[0].Component.Name)
 }
+func TestPlanWavesIndependentTargets(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T3", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "b"}}}},
+		},
+	}
+	waves := PlanWaves(plan)
+	assert.Equal(t, [][]int{{0, 1, 2}}, waves)
+}
+func TestPlanWavesSameTarget(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T1", Role: "helm", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T1", Role: "instance", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "b"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "c"}}}},
+		},
+	}
+	waves := PlanWaves(plan)
+	assert.Equal(t, [][]int{{0, 2}, {1}}, waves)
+}
+func TestPlanWavesDependencies(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "b", Dependencies: []string{"a"}}}}},
+			{Target: "T3", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "c", Dependencies: []string{"b"}}}}},
+			{Target: "T4", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "d"}}}},
+		},
+	}
+	waves := PlanWaves(plan)
+	assert.Equal(t, [][]int{{0, 3}, {1}, {2}}, waves)
+}
+func TestPlanWavesDeletionOrder(t *testing.T) {
+	// deletion steps come in reverse dependency order, and still must not run together
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T2", Components: []model.ComponentStep{{Action: "delete", Component: model.ComponentSpec{Name: "b", Dependencies: []string{"a"}}}}},
+			{Target: "T1", Components: []model.ComponentStep{{Action: "delete", Component: model.ComponentSpec{Name: "a"}}}},
+		},
+	}
+	waves := PlanWaves(plan)
+	assert.Equal(t, [][]int{{0}, {1}}, waves)
+}
+func TestPlanRolloutBatchesNoRollout(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+		},
+	}
+	batches, targets := PlanRolloutBatches(plan, nil)
+	assert.Equal(t, [][]int{{0, 1}}, batches)
+	assert.ElementsMatch(t, []string{"T1", "T2"}, targets[0])
+}
+func TestPlanRolloutBatchesBySize(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T3", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+		},
+	}
+	batches, targets := PlanRolloutBatches(plan, &model.RolloutSpec{BatchSize: 2})
+	assert.Equal(t, [][]int{{1, 2}, {0}}, batches)
+	assert.Equal(t, [][]string{{"T1", "T2"}, {"T3"}}, targets)
+}
+func TestPlanRolloutBatchesByPercentage(t *testing.T) {
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T3", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+			{Target: "T4", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "a"}}}},
+		},
+	}
+	// 25% of 4 targets is a single canary target
+	batches, _ := PlanRolloutBatches(plan, &model.RolloutSpec{Percentage: 25})
+	assert.Equal(t, 4, len(batches))
+	// 30% of 4 targets rounds up to 2
+	batches, _ = PlanRolloutBatches(plan, &model.RolloutSpec{Percentage: 30})
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}}, batches)
+}
+func TestPlanRolloutBatchesDependencies(t *testing.T) {
+	// b on T1 depends on c on T2, so it can't be rolled out ahead of it
+	plan := model.DeploymentPlan{
+		Steps: []model.DeploymentStep{
+			{Target: "T2", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "c"}}}},
+			{Target: "T1", Components: []model.ComponentStep{{Action: "update", Component: model.ComponentSpec{Name: "b", Dependencies: []string{"c"}}}}},
+		},
+	}
+	batches, targets := PlanRolloutBatches(plan, &model.RolloutSpec{BatchSize: 1})
+	assert.Equal(t, [][]int{{0, 1}}, batches)
+	assert.ElementsMatch(t, []string{"T1", "T2"}, targets[0])
+}
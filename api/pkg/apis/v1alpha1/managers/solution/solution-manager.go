@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,11 +33,25 @@ import (
 )
 
 var log = logger.NewLogger("coa.runtime")
-var lock sync.Mutex
+
+// instanceLocks serializes reconciliations of the same instance, while different instances reconcile concurrently.
+// A lock is removed once no reconciliation holds or waits for it, so deleted instances don't leave locks behind.
+var (
+	instanceLocks     = map[string]*instanceLock{}
+	instanceLocksLock sync.Mutex
+)
+
+type instanceLock struct {
+	sync.Mutex
+	refs int
+}
 
 const (
-	SYMPHONY_AGENT string = "/symphony-agent:"
-	ENV_NAME       string = "SYMPHONY_AGENT_ADDRESS"
+	SYMPHONY_AGENT       string = "/symphony-agent:"
+	ENV_NAME             string = "SYMPHONY_AGENT_ADDRESS"
+	ROLLBACK_ON_FAILURE  string = "rollbackOnFailure"
+	AUTO_HEAL            string = "autoHeal"
+	DEFAULT_HISTORY_SIZE int    = 3
 )
 
 type SolutionManager struct {
@@ -45,11 +60,17 @@ type SolutionManager struct {
 	StateProvider   states.IStateProvider
 	ConfigProvider  config.IExtConfigProvider
 	SecretProvoider secret.ISecretProvider
+	HistorySize     int
+	Parallelism     int
+	DriftInterval   time.Duration
+	lastDriftCheck  time.Time
+	driftedReports  []driftedInstance
 }
 
 type SolutionManagerDeploymentState struct {
 	Spec  model.DeploymentSpec  `json:"spec,omitempty"`
 	State model.DeploymentState `json:"state,omitempty"`
+	Scope string                `json:"scope,omitempty"`
 }
 
 func (s *SolutionManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -86,6 +107,30 @@ func (s *SolutionManager) Init(context *contexts.VendorContext, config managers.
 		return err
 	}
 
+	s.HistorySize = DEFAULT_HISTORY_SIZE
+	if v, ok := config.Properties["history.size"]; ok {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid history.size '%s', expected a positive integer", v), v1alpha2.BadConfig)
+		}
+		s.HistorySize = size
+	}
+	s.Parallelism = 1
+	if v, ok := config.Properties["reconcile.parallelism"]; ok {
+		parallelism, err := strconv.Atoi(v)
+		if err != nil || parallelism <= 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid reconcile.parallelism '%s', expected a positive integer", v), v1alpha2.BadConfig)
+		}
+		s.Parallelism = parallelism
+	}
+	if v, ok := config.Properties["drift.interval"]; ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid drift.interval '%s', expected a positive duration", v), v1alpha2.BadConfig)
+		}
+		s.DriftInterval = interval
+	}
+
 	return nil
 }
 
@@ -107,6 +152,17 @@ func (s *SolutionManager) getPreviousState(ctx context.Context, instance string,
 	}
 	return nil
 }
+
+// GetDeployedInstance returns the instance of the last deployment to an instance, which authorization rules
+// can match. It returns a NotFound error if the instance hasn't been deployed.
+func (s *SolutionManager) GetDeployedInstance(ctx context.Context, instance string, scope string) (model.InstanceSpec, error) {
+	previous := s.getPreviousState(ctx, instance, scope)
+	if previous == nil {
+		return model.InstanceSpec{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("instance '%s' has no deployment state", instance), v1alpha2.NotFound)
+	}
+	return previous.Spec.Instance, nil
+}
+
 func (s *SolutionManager) GetSummary(ctx context.Context, key string, scope string) (model.SummaryResult, error) {
 	// lock.Lock()
 	// defer lock.Unlock()
@@ -166,9 +222,36 @@ func (s *SolutionManager) sendHeartbeat(id string, remove bool, stopCh chan stru
 	}
 }
 
+func lockInstance(scope string, instance string) func() {
+	key := scope + "/" + instance
+	instanceLocksLock.Lock()
+	l, ok := instanceLocks[key]
+	if !ok {
+		l = &instanceLock{}
+		instanceLocks[key] = l
+	}
+	l.refs++
+	instanceLocksLock.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		instanceLocksLock.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(instanceLocks, key)
+		}
+		instanceLocksLock.Unlock()
+	}
+}
+
 func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.DeploymentSpec, remove bool, scope string) (model.SummarySpec, error) {
-	lock.Lock()
-	defer lock.Unlock()
+	return s.reconcile(ctx, deployment, remove, scope, false)
+}
+
+// reconcile applies the deployment. With force set, steps are applied even if the providers report no changes.
+func (s *SolutionManager) reconcile(ctx context.Context, deployment model.DeploymentSpec, remove bool, scope string, force bool) (model.SummarySpec, error) {
+	unlock := lockInstance(scope, deployment.Instance.Name)
+	defer unlock()
 
 	stopCh := make(chan struct{})
 	defer close(stopCh)
@@ -246,78 +329,132 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 	dep := deployment
 	dep.Instance.Metadata = col
 	someStepsRan := false
+	appliedTargets := make(map[string]bool)
 
-	for _, step := range plan.Steps {
-		dep.ActiveTarget = step.Target
-		agent := findAgent(deployment.Targets[step.Target])
-		if agent != "" {
-			col[ENV_NAME] = agent
-		} else {
-			delete(col, ENV_NAME)
+	var testState *model.DeploymentState
+	if previousDesiredState != nil && !force {
+		state := MergeDeploymentStates(&previousDesiredState.State, currentState)
+		testState = &state
+	}
+
+	rollout := deployment.Instance.Rollout
+	if remove {
+		rollout = nil
+	}
+	threshold := 100
+	var pause time.Duration
+	if rollout != nil {
+		if rollout.SuccessThreshold > 0 {
+			threshold = rollout.SuccessThreshold
 		}
-		var override tgt.ITargetProvider
-		if v, ok := s.TargetProviders[step.Target]; ok {
-			override = v
+		if rollout.Pause != "" {
+			pause, err = time.ParseDuration(rollout.Pause)
+			if err != nil {
+				summary.SummaryMessage = "invalid rollout pause: " + err.Error()
+				log.Errorf(" M (Solution): invalid rollout pause: %+v", err)
+				s.saveSummary(iCtx, deployment, summary, scope)
+				return summary, err
+			}
 		}
-		var provider providers.IProvider
-		provider, err = sp.CreateProviderForTargetRole(s.Context, step.Role, deployment.Targets[step.Target], override)
-		if err != nil {
-			summary.SummaryMessage = "failed to create provider:" + err.Error()
-			log.Errorf(" M (Solution): failed to create provider: %+v", err)
-			s.saveSummary(ctx, deployment, summary, scope)
-			return summary, err
+	}
+	// with a success threshold below 100%, a failed target doesn't stop the other targets of its batch
+	continueOnError := rollout != nil && threshold < 100
+	batches, batchTargets := PlanRolloutBatches(plan, rollout)
+	if rollout != nil {
+		summary.Rollout = &model.RolloutResultSpec{
+			TotalBatches: len(batches),
 		}
+	}
 
-		if previousDesiredState != nil {
-			testState := MergeDeploymentStates(&previousDesiredState.State, currentState)
-			if s.canSkipStep(iCtx, step, step.Target, provider.(tgt.ITargetProvider), previousDesiredState.State.Components, testState) {
-				continue
+	var resultLock sync.Mutex
+	var stepError error
+	var providerError bool
+	failedTargets := make(map[string]bool)
+	for b, batch := range batches {
+		if b > 0 && pause > 0 {
+			select {
+			case <-time.After(pause):
+			case <-iCtx.Done():
+				stepError = iCtx.Err()
+			}
+			if stepError != nil {
+				summary.Rollout.Halted = true
+				summary.Rollout.Message = "rollout cancelled: " + stepError.Error()
+				break
 			}
 		}
-		someStepsRan = true
-		retryCount := 1
-		//TODO: set to 1 for now. Although retrying can help to handle transient errors, in more cases
-		// an error condition can't be resolved quickly.
-		var stepError error
-		var componentResults map[string]model.ComponentResultSpec
-
-		// for _, component := range step.Components {
-		// 	for k, v := range component.Component.Properties {
-		// 		if strV, ok := v.(string); ok {
-		// 			parser := api_utils.NewParser(strV)
-		// 			eCtx := s.VendorContext.EvaluationContext.Clone()
-		// 			eCtx.DeploymentSpec = deployment
-		// 			eCtx.Component = component.Component.Name
-		// 			val, err := parser.Eval(*eCtx)
-		// 			if err == nil {
-		// 				component.Component.Properties[k] = val
-		// 			} else {
-		// 				log.Errorf(" M (Solution): failed to evaluate property: %+v", err)
-		// 				summary.SummaryMessage = fmt.Sprintf("failed to evaluate property '%s' on component '%s: %s", k, component.Component.Name, err.Error())
-		// 				s.saveSummary(ctx, deployment, summary)
-		// 				observ_utils.CloseSpanWithError(span, &err)
-		// 				return summary, err
-		// 			}
-		// 		}
-		// 	}
-		// }
-
-		for i := 0; i < retryCount; i++ {
-			componentResults, stepError = (provider.(tgt.ITargetProvider)).Apply(iCtx, dep, step, false)
-			if stepError == nil {
-				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults})
+		// steps of a wave don't depend on each other, so they can run concurrently
+		for _, wave := range planWaves(plan, batch) {
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, s.parallelism())
+			for _, index := range wave {
+				step := plan.Steps[index]
+				sem <- struct{}{}
+				resultLock.Lock()
+				stop := stepError != nil && !continueOnError
+				skip := failedTargets[step.Target]
+				resultLock.Unlock()
+				if stop {
+					<-sem
+					break
+				}
+				if skip {
+					<-sem
+					continue
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					ran, result, err := s.applyStep(iCtx, deployment, col, step, previousDesiredState, testState)
+					resultLock.Lock()
+					defer resultLock.Unlock()
+					if ran {
+						someStepsRan = true
+						appliedTargets[step.Target] = true
+						summary.UpdateTargetResult(step.Target, result)
+					}
+					if err != nil {
+						failedTargets[step.Target] = true
+						if stepError == nil {
+							stepError = err
+							providerError = !ran
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if stepError != nil && !continueOnError {
 				break
-			} else {
-				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "Error", Message: stepError.Error(), ComponentResults: componentResults}) // TODO: this keeps only the last error on the target
-				time.Sleep(5 * time.Second)                                                                                                                      //TODO: make this configurable?
 			}
 		}
-		if stepError != nil {
-			log.Errorf(" M (Solution): failed to execute deployment step: %+v", stepError)
-			s.saveSummary(iCtx, deployment, summary, scope)
-			err = stepError
-			return summary, err
+		if rollout == nil {
+			continue
 		}
+		succeeded := 0
+		for _, t := range batchTargets[b] {
+			if !failedTargets[t] {
+				succeeded++
+			}
+		}
+		if succeeded*100 < threshold*len(batchTargets[b]) || (stepError != nil && !continueOnError) {
+			summary.Rollout.Halted = true
+			summary.Rollout.Message = fmt.Sprintf("rollout halted at batch %d of %d: %d of %d targets succeeded", b+1, len(batches), succeeded, len(batchTargets[b]))
+			log.Errorf(" M (Solution): %s", summary.Rollout.Message)
+			break
+		}
+		summary.Rollout.CompletedBatches++
+	}
+	if stepError != nil {
+		if providerError {
+			summary.SummaryMessage = "failed to create provider:" + stepError.Error()
+		}
+		if !remove && col[ROLLBACK_ON_FAILURE] == "true" && len(appliedTargets) > 0 {
+			summary.Rollback = s.rollback(iCtx, deployment, appliedTargets, scope)
+		}
+		s.saveSummary(iCtx, deployment, summary, scope)
+		err = stepError
+		return summary, err
 	}
 
 	mergedState.ClearAllRemoved()
@@ -329,6 +466,7 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 			Body: SolutionManagerDeploymentState{
 				Spec:  deployment,
 				State: mergedState,
+				Scope: scope,
 			},
 		},
 		Metadata: map[string]string{
@@ -336,6 +474,12 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 		},
 	})
 
+	if remove {
+		s.deleteHistory(iCtx, deployment.Instance.Name, scope)
+	} else {
+		s.saveHistory(iCtx, deployment, scope)
+	}
+
 	summary.Skipped = !someStepsRan
 	if summary.Skipped {
 		summary.SuccessCount = summary.TargetCount
@@ -344,6 +488,59 @@ func (s *SolutionManager) Reconcile(ctx context.Context, deployment model.Deploy
 	s.saveSummary(iCtx, deployment, summary, scope)
 	return summary, nil
 }
+
+// applyStep runs a single deployment step. It returns false when the step is skipped, or when its provider can't be created.
+func (s *SolutionManager) applyStep(ctx context.Context, deployment model.DeploymentSpec, col map[string]string, step model.DeploymentStep, previousDesiredState *SolutionManagerDeploymentState, testState *model.DeploymentState) (bool, model.TargetResultSpec, error) {
+	// steps may run concurrently, so each one gets its own copy of the deployment metadata
+	dep := deployment
+	dep.ActiveTarget = step.Target
+	dep.Instance.Metadata = make(map[string]string)
+	for k, v := range col {
+		dep.Instance.Metadata[k] = v
+	}
+	agent := findAgent(deployment.Targets[step.Target])
+	if agent != "" {
+		dep.Instance.Metadata[ENV_NAME] = agent
+	} else {
+		delete(dep.Instance.Metadata, ENV_NAME)
+	}
+	var override tgt.ITargetProvider
+	if v, ok := s.TargetProviders[step.Target]; ok {
+		override = v
+	}
+	provider, err := sp.CreateProviderForTargetRole(s.Context, step.Role, deployment.Targets[step.Target], override)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to create provider: %+v", err)
+		return false, model.TargetResultSpec{}, err
+	}
+
+	if previousDesiredState != nil && testState != nil {
+		if s.canSkipStep(ctx, step, step.Target, provider.(tgt.ITargetProvider), previousDesiredState.State.Components, *testState) {
+			return false, model.TargetResultSpec{}, nil
+		}
+	}
+	retryCount := 1
+	//TODO: set to 1 for now. Although retrying can help to handle transient errors, in more cases
+	// an error condition can't be resolved quickly.
+	var result model.TargetResultSpec
+	for i := 0; i < retryCount; i++ {
+		var componentResults map[string]model.ComponentResultSpec
+		componentResults, err = (provider.(tgt.ITargetProvider)).Apply(ctx, dep, step, false)
+		if err == nil {
+			return true, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults}, nil
+		}
+		result = model.TargetResultSpec{Status: "Error", Message: err.Error(), ComponentResults: componentResults} // TODO: this keeps only the last error on the target
+		time.Sleep(5 * time.Second)                                                                                //TODO: make this configurable?
+	}
+	log.Errorf(" M (Solution): failed to execute deployment step: %+v", err)
+	return true, result, err
+}
+func (s *SolutionManager) parallelism() int {
+	if s.Parallelism <= 0 {
+		return 1
+	}
+	return s.Parallelism
+}
 func (s *SolutionManager) saveSummary(ctx context.Context, deployment model.DeploymentSpec, summary model.SummarySpec, scope string) {
 	// TODO: delete this state when time expires. This should probably be invoked by the vendor (via GetSummary method, for instance)
 	s.StateProvider.Upsert(ctx, states.UpsertRequest{
@@ -360,6 +557,289 @@ func (s *SolutionManager) saveSummary(ctx context.Context, deployment model.Depl
 		},
 	})
 }
+
+// GetHistory returns the deployments last applied successfully to an instance, oldest first.
+func (s *SolutionManager) GetHistory(ctx context.Context, instance string, scope string) ([]model.DeploymentSpec, error) {
+	state, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: fmt.Sprintf("%s-%s", "history", instance),
+		Metadata: map[string]string{
+			"scope": scope,
+		},
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			return []model.DeploymentSpec{}, nil
+		}
+		return nil, err
+	}
+	var history []model.DeploymentSpec
+	jData, _ := json.Marshal(state.Body)
+	err = json.Unmarshal(jData, &history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+func (s *SolutionManager) saveHistory(ctx context.Context, deployment model.DeploymentSpec, scope string) {
+	history, err := s.GetHistory(ctx, deployment.Instance.Name, scope)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to get deployment history[%s]: %+v", deployment.Instance.Name, err)
+		history = []model.DeploymentSpec{}
+	}
+	if len(history) > 0 {
+		if equal, err := history[len(history)-1].DeepEquals(deployment); err == nil && equal {
+			return
+		}
+	}
+	history = append(history, deployment)
+	size := s.HistorySize
+	if size <= 0 {
+		size = DEFAULT_HISTORY_SIZE
+	}
+	if len(history) > size {
+		history = history[len(history)-size:]
+	}
+	_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   fmt.Sprintf("%s-%s", "history", deployment.Instance.Name),
+			Body: history,
+		},
+		Metadata: map[string]string{
+			"scope": scope,
+		},
+	})
+	if err != nil {
+		log.Errorf(" M (Solution): failed to save deployment history[%s]: %+v", deployment.Instance.Name, err)
+	}
+}
+func (s *SolutionManager) deleteHistory(ctx context.Context, instance string, scope string) {
+	err := s.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID: fmt.Sprintf("%s-%s", "history", instance),
+		Metadata: map[string]string{
+			"scope": scope,
+		},
+	})
+	if err != nil && !v1alpha2.IsNotFound(err) {
+		log.Errorf(" M (Solution): failed to delete deployment history[%s]: %+v", instance, err)
+	}
+}
+
+// rollback re-applies the last good deployment of the instance to the given targets, removing
+// components that only exist in the failed deployment.
+func (s *SolutionManager) rollback(ctx context.Context, failed model.DeploymentSpec, targets map[string]bool, scope string) *model.RollbackResultSpec {
+	ret := &model.RollbackResultSpec{
+		TargetResults: make(map[string]model.TargetResultSpec),
+	}
+	history, err := s.GetHistory(ctx, failed.Instance.Name, scope)
+	if err != nil {
+		ret.Message = "failed to get deployment history: " + err.Error()
+		log.Errorf(" M (Solution): failed to get deployment history: %+v", err)
+		return ret
+	}
+	if len(history) == 0 {
+		ret.Message = "no previous good deployment to roll back to"
+		log.Infof(" M (Solution): no previous good deployment of %s to roll back to", failed.Instance.Name)
+		return ret
+	}
+	previous := history[len(history)-1]
+	ret.Generation = previous.Generation
+	log.Infof(" M (Solution): rolling back %s to generation %s", failed.Instance.Name, previous.Generation)
+
+	failedState, err := NewDeploymentState(failed)
+	if err != nil {
+		ret.Message = "failed to create state from failed deployment: " + err.Error()
+		return ret
+	}
+	goodState, err := NewDeploymentState(previous)
+	if err != nil {
+		ret.Message = "failed to create state from previous deployment: " + err.Error()
+		return ret
+	}
+	plan, err := PlanForDeployment(previous, MergeDeploymentStates(&failedState, goodState))
+	if err != nil {
+		ret.Message = "failed to plan for rollback: " + err.Error()
+		return ret
+	}
+
+	col := api_utils.MergeCollection(previous.Solution.Metadata, previous.Instance.Metadata)
+	dep := previous
+	dep.Instance.Metadata = col
+	// targets that only the failed deployment uses still need to be reachable to remove its components
+	dep.Targets = make(map[string]model.TargetSpec)
+	for k, v := range failed.Targets {
+		dep.Targets[k] = v
+	}
+	for k, v := range previous.Targets {
+		dep.Targets[k] = v
+	}
+	for _, step := range plan.Steps {
+		if !targets[step.Target] {
+			continue
+		}
+		dep.ActiveTarget = step.Target
+		agent := findAgent(dep.Targets[step.Target])
+		if agent != "" {
+			col[ENV_NAME] = agent
+		} else {
+			delete(col, ENV_NAME)
+		}
+		var override tgt.ITargetProvider
+		if v, ok := s.TargetProviders[step.Target]; ok {
+			override = v
+		}
+		provider, err := sp.CreateProviderForTargetRole(s.Context, step.Role, dep.Targets[step.Target], override)
+		if err != nil {
+			ret.Message = "failed to create provider: " + err.Error()
+			log.Errorf(" M (Solution): failed to create provider for rollback: %+v", err)
+			return ret
+		}
+		componentResults, err := (provider.(tgt.ITargetProvider)).Apply(ctx, dep, step, false)
+		if err != nil {
+			ret.TargetResults[step.Target] = model.TargetResultSpec{Status: "Error", Message: err.Error(), ComponentResults: componentResults}
+			ret.Message = "failed to roll back target " + step.Target + ": " + err.Error()
+			log.Errorf(" M (Solution): failed to roll back target %s: %+v", step.Target, err)
+			return ret
+		}
+		ret.TargetResults[step.Target] = model.TargetResultSpec{Status: "OK", ComponentResults: componentResults}
+	}
+	ret.Succeeded = true
+	return ret
+}
+
+// Plan computes the steps a reconcile of the deployment would run, along with per-target component
+// diffs against what target providers currently report. Nothing is applied and no state is saved.
+func (s *SolutionManager) Plan(ctx context.Context, deployment model.DeploymentSpec, remove bool, scope string) (model.PlanPreview, error) {
+	iCtx, span := observability.StartSpan("Solution Manager", ctx, &map[string]string{
+		"method": "Plan",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Info(" M (Solution): planning")
+
+	preview := model.PlanPreview{
+		Instance: deployment.Instance.Name,
+		Steps:    make([]model.PlanStepPreview, 0),
+	}
+
+	if s.VendorContext != nil && s.VendorContext.EvaluationContext != nil {
+		context := s.VendorContext.EvaluationContext.Clone()
+		context.DeploymentSpec = deployment
+		context.Value = deployment
+		context.Component = ""
+		deployment, err = api_utils.EvaluateDeployment(*context)
+		if err != nil && !remove {
+			log.Errorf(" M (Solution): failed to evaluate deployment spec: %+v", err)
+			return preview, err
+		}
+		err = nil
+	}
+
+	previousDesiredState := s.getPreviousState(iCtx, deployment.Instance.Name, scope)
+	currentDesiredState, err := NewDeploymentState(deployment)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to create target manager state from deployment spec: %+v", err)
+		return preview, err
+	}
+	currentState, _, err := s.Get(iCtx, deployment)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to get current state: %+v", err)
+		return preview, err
+	}
+
+	desiredState := currentDesiredState
+	if previousDesiredState != nil {
+		desiredState = MergeDeploymentStates(&previousDesiredState.State, currentDesiredState)
+	}
+	if remove {
+		desiredState.MarkRemoveAll()
+	}
+	mergedState := MergeDeploymentStates(&currentState, desiredState)
+
+	plan, err := PlanForDeployment(deployment, mergedState)
+	if err != nil {
+		log.Errorf(" M (Solution): failed to plan for deployment: %+v", err)
+		return preview, err
+	}
+
+	for _, step := range plan.Steps {
+		var override tgt.ITargetProvider
+		if v, ok := s.TargetProviders[step.Target]; ok {
+			override = v
+		}
+		var provider providers.IProvider
+		provider, err = sp.CreateProviderForTargetRole(s.Context, step.Role, deployment.Targets[step.Target], override)
+		if err != nil {
+			log.Errorf(" M (Solution): failed to create provider: %+v", err)
+			return preview, err
+		}
+		targetProvider := provider.(tgt.ITargetProvider)
+		var components []model.ComponentSpec
+		components, err = targetProvider.Get(iCtx, deployment, step.Components)
+		if err != nil {
+			log.Errorf(" M (Solution): failed to get: %+v", err)
+			return preview, err
+		}
+		stepPreview := model.PlanStepPreview{
+			Target:     step.Target,
+			Role:       step.Role,
+			Provider:   findProviderType(deployment.Targets[step.Target], step.Role),
+			Components: diffComponents(step, components, targetProvider.GetValidationRule(iCtx)),
+		}
+		if previousDesiredState != nil {
+			testState := MergeDeploymentStates(&previousDesiredState.State, currentState)
+			stepPreview.Skipped = s.canSkipStep(iCtx, step, step.Target, targetProvider, previousDesiredState.State.Components, testState)
+		}
+		if !stepPreview.Skipped && stepPreview.HasChanges() {
+			preview.Changed = true
+		}
+		preview.Steps = append(preview.Steps, stepPreview)
+	}
+	return preview, nil
+}
+func diffComponents(step model.DeploymentStep, current []model.ComponentSpec, rule model.ValidationRule) []model.ComponentDiff {
+	ret := make([]model.ComponentDiff, 0)
+	for _, c := range step.Components {
+		desired := c.Component
+		diff := model.ComponentDiff{
+			Name:   desired.Name,
+			Action: model.PlanActionNone,
+		}
+		for i := range current {
+			if current[i].Name == desired.Name {
+				diff.Current = &current[i]
+				break
+			}
+		}
+		if c.Action == "delete" {
+			if diff.Current != nil {
+				diff.Action = model.PlanActionDelete
+			}
+		} else {
+			diff.Desired = &desired
+			if diff.Current == nil {
+				diff.Action = model.PlanActionAdd
+			} else if rule.IsComponentChanged(*diff.Current, desired) {
+				diff.Action = model.PlanActionUpdate
+			}
+		}
+		ret = append(ret, diff)
+	}
+	return ret
+}
+func findProviderType(target model.TargetSpec, role string) string {
+	if role == "" || role == "container" {
+		role = "instance"
+	}
+	for _, topology := range target.Topologies {
+		for _, binding := range topology.Bindings {
+			if binding.Role == role {
+				return binding.Provider
+			}
+		}
+	}
+	return ""
+}
 func (s *SolutionManager) canSkipStep(ctx context.Context, step model.DeploymentStep, target string, provider tgt.ITargetProvider, currentComponents []model.ComponentSpec, state model.DeploymentState) bool {
 
 	for _, newCom := range step.Components {
@@ -452,13 +932,19 @@ func (s *SolutionManager) Get(ctx context.Context, deployment model.DeploymentSp
 	return ret, retComponents, nil
 }
 func (s *SolutionManager) Enabled() bool {
-	return false
+	return s.DriftInterval > 0
 }
+
+// Poll and Reconcil are called one after another from the vendor loop, so the drift bookkeeping isn't locked
 func (s *SolutionManager) Poll() []error {
-	return nil
+	if time.Since(s.lastDriftCheck) < s.DriftInterval {
+		return nil
+	}
+	s.lastDriftCheck = time.Now()
+	return s.detectAllDrifts(context.Background())
 }
 func (s *SolutionManager) Reconcil() []error {
-	return nil
+	return s.healDrifts(context.Background())
 }
 func findAgent(target model.TargetSpec) string {
 	for _, c := range target.Components {
@@ -8,7 +8,10 @@ package solution
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
@@ -569,3 +572,463 @@ func TestMockApplyWithError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, 0, summary.SuccessCount)
 }
+func TestDiffComponents(t *testing.T) {
+	step := model.DeploymentStep{
+		Target: "T1",
+		Components: []model.ComponentStep{
+			{Action: "update", Component: model.ComponentSpec{Name: "a", Properties: map[string]interface{}{"image": "a:2"}}},
+			{Action: "update", Component: model.ComponentSpec{Name: "b", Properties: map[string]interface{}{"image": "b:1"}}},
+			{Action: "update", Component: model.ComponentSpec{Name: "c"}},
+			{Action: "delete", Component: model.ComponentSpec{Name: "d"}},
+			{Action: "delete", Component: model.ComponentSpec{Name: "e"}},
+		},
+	}
+	current := []model.ComponentSpec{
+		{Name: "a", Properties: map[string]interface{}{"image": "a:1"}},
+		{Name: "b", Properties: map[string]interface{}{"image": "b:1"}},
+		{Name: "d"},
+	}
+	rule := model.ValidationRule{
+		ChangeDetectionProperties: []model.PropertyDesc{{Name: "image"}},
+	}
+	diffs := diffComponents(step, current, rule)
+	assert.Equal(t, 5, len(diffs))
+	assert.Equal(t, model.PlanActionUpdate, diffs[0].Action)
+	assert.Equal(t, "a:1", diffs[0].Current.Properties["image"])
+	assert.Equal(t, "a:2", diffs[0].Desired.Properties["image"])
+	assert.Equal(t, model.PlanActionNone, diffs[1].Action)
+	assert.Equal(t, model.PlanActionAdd, diffs[2].Action)
+	assert.Nil(t, diffs[2].Current)
+	assert.Equal(t, model.PlanActionDelete, diffs[3].Action)
+	assert.Nil(t, diffs[3].Desired)
+	assert.Equal(t, model.PlanActionNone, diffs[4].Action)
+}
+func TestFindProviderType(t *testing.T) {
+	target := model.TargetSpec{
+		Topologies: []model.TopologySpec{
+			{
+				Bindings: []model.BindingSpec{
+					{Role: "instance", Provider: "providers.target.k8s"},
+					{Role: "mock", Provider: "providers.target.mock"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, "providers.target.k8s", findProviderType(target, "container"))
+	assert.Equal(t, "providers.target.mock", findProviderType(target, "mock"))
+	assert.Equal(t, "", findProviderType(target, "helm.v3"))
+}
+
+type failingTargetProvider struct {
+	mock.MockTargetProvider
+	FailOn string
+}
+
+// Apply fails after applying the step, like a deployment that fails half-way and leaves the failed component behind
+func (f *failingTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ret, err := f.MockTargetProvider.Apply(ctx, deployment, step, isDryRun)
+	for _, c := range step.Components {
+		if c.Action == "update" && c.Component.Name == f.FailOn {
+			return nil, errors.New("failed to apply " + c.Component.Name)
+		}
+	}
+	return ret, err
+}
+
+func createRollbackDeployment(t1 string, generation string, rollback bool) model.DeploymentSpec {
+	deployment := model.DeploymentSpec{
+		Generation: generation,
+		Instance: model.InstanceSpec{
+			Name:     "instance1",
+			Metadata: map[string]string{},
+		},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{
+				{Name: "a", Type: "mock"},
+				{Name: "b", Type: "mock"},
+			},
+		},
+		Assignments: map[string]string{
+			"T1": "{a}",
+			"T2": "{b}",
+		},
+		Targets: map[string]model.TargetSpec{
+			"T1": {
+				Topologies: []model.TopologySpec{
+					{
+						Bindings: []model.BindingSpec{
+							{
+								Role:     "mock",
+								Provider: "providers.target.mock",
+								Config: map[string]string{
+									"id": t1,
+								},
+							},
+						},
+					},
+				},
+			},
+			"T2": {
+				Topologies: []model.TopologySpec{
+					{
+						Bindings: []model.BindingSpec{
+							{
+								Role:     "mock",
+								Provider: "providers.target.proxy",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if rollback {
+		deployment.Instance.Metadata[ROLLBACK_ON_FAILURE] = "true"
+	}
+	return deployment
+}
+func addComponents(deployment model.DeploymentSpec) model.DeploymentSpec {
+	deployment.Solution.Components = append(deployment.Solution.Components,
+		model.ComponentSpec{Name: "c", Type: "mock"},
+		model.ComponentSpec{Name: "d", Type: "mock"})
+	deployment.Assignments = map[string]string{
+		"T1": "{a}{c}",
+		"T2": "{b}{d}",
+	}
+	return deployment
+}
+func getMockComponents(t *testing.T, id string) []string {
+	provider := &mock.MockTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: id})
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, []model.ComponentStep{
+		{Component: model.ComponentSpec{Name: "a"}},
+		{Component: model.ComponentSpec{Name: "b"}},
+		{Component: model.ComponentSpec{Name: "c"}},
+		{Component: model.ComponentSpec{Name: "d"}},
+	})
+	assert.Nil(t, err)
+	ret := make([]string, 0)
+	for _, c := range components {
+		ret = append(ret, c.Name)
+	}
+	return ret
+}
+func createRollbackManager(t2 *failingTargetProvider) SolutionManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	return SolutionManager{
+		TargetProviders: map[string]target.ITargetProvider{
+			"T2": t2,
+		},
+		StateProvider: stateProvider,
+	}
+}
+func TestRollbackOnFailure(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{FailOn: "d"}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+
+	deployment := createRollbackDeployment(t1, "1", true)
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	assert.Nil(t, summary.Rollback)
+
+	// c is applied to T1 before d fails on T2
+	summary, err = manager.Reconcile(context.Background(), addComponents(createRollbackDeployment(t1, "2", true)), false, "default")
+	assert.NotNil(t, err)
+	assert.NotNil(t, summary.Rollback)
+	assert.True(t, summary.Rollback.Succeeded)
+	assert.Equal(t, "1", summary.Rollback.Generation)
+	assert.Equal(t, "OK", summary.Rollback.TargetResults["T1"].Status)
+	assert.ElementsMatch(t, []string{"a"}, getMockComponents(t, t1))
+	assert.ElementsMatch(t, []string{"b"}, getMockComponents(t, t2.Config.ID))
+
+	history, err := manager.GetHistory(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, "1", history[0].Generation)
+}
+func TestNoRollbackWithoutPolicy(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{FailOn: "d"}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+
+	_, err := manager.Reconcile(context.Background(), createRollbackDeployment(t1, "1", false), false, "default")
+	assert.Nil(t, err)
+	summary, err := manager.Reconcile(context.Background(), addComponents(createRollbackDeployment(t1, "2", false)), false, "default")
+	assert.NotNil(t, err)
+	assert.Nil(t, summary.Rollback)
+	assert.ElementsMatch(t, []string{"a", "c"}, getMockComponents(t, t1))
+}
+func TestRollbackWithoutHistory(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{FailOn: "d"}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+
+	summary, err := manager.Reconcile(context.Background(), addComponents(createRollbackDeployment(t1, "1", true)), false, "default")
+	assert.NotNil(t, err)
+	assert.NotNil(t, summary.Rollback)
+	assert.False(t, summary.Rollback.Succeeded)
+}
+func TestHistoryIsBounded(t *testing.T) {
+	t1 := uuid.New().String()
+	t2 := &failingTargetProvider{}
+	t2.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createRollbackManager(t2)
+	manager.HistorySize = 2
+
+	versioned := func(generation string) model.DeploymentSpec {
+		deployment := createRollbackDeployment(t1, generation, false)
+		deployment.Solution.Components[0].Properties = map[string]interface{}{"version": generation}
+		return deployment
+	}
+	for _, generation := range []string{"1", "2", "3"} {
+		_, err := manager.Reconcile(context.Background(), versioned(generation), false, "default")
+		assert.Nil(t, err)
+	}
+	// re-applying the same deployment doesn't add to the history
+	_, err := manager.Reconcile(context.Background(), versioned("3"), false, "default")
+	assert.Nil(t, err)
+	history, err := manager.GetHistory(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, "2", history[0].Generation)
+	assert.Equal(t, "3", history[1].Generation)
+
+	_, err = manager.Reconcile(context.Background(), versioned("3"), true, "default")
+	assert.Nil(t, err)
+	history, err = manager.GetHistory(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(history))
+}
+
+type concurrencyTargetProvider struct {
+	mock.MockTargetProvider
+	lock      sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (c *concurrencyTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	c.lock.Lock()
+	c.active++
+	if c.active > c.maxActive {
+		c.maxActive = c.active
+	}
+	c.lock.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	c.lock.Lock()
+	c.active--
+	c.lock.Unlock()
+	return c.MockTargetProvider.Apply(ctx, deployment, step, isDryRun)
+}
+
+func createParallelDeployment(instance string, targets []string) model.DeploymentSpec {
+	deployment := model.DeploymentSpec{
+		Instance: model.InstanceSpec{
+			Name: instance,
+		},
+		Solution: model.SolutionSpec{
+			Components: []model.ComponentSpec{},
+		},
+		Assignments: map[string]string{},
+		Targets:     map[string]model.TargetSpec{},
+	}
+	for _, target := range targets {
+		component := "c-" + target
+		deployment.Solution.Components = append(deployment.Solution.Components, model.ComponentSpec{Name: component, Type: "mock"})
+		deployment.Assignments[target] = "{" + component + "}"
+		deployment.Targets[target] = model.TargetSpec{
+			Topologies: []model.TopologySpec{
+				{
+					Bindings: []model.BindingSpec{
+						{
+							Role:     "mock",
+							Provider: "providers.target.proxy",
+						},
+					},
+				},
+			},
+		}
+	}
+	return deployment
+}
+func createParallelManager(provider *concurrencyTargetProvider, targets []string, parallelism int) SolutionManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := SolutionManager{
+		TargetProviders: map[string]target.ITargetProvider{},
+		StateProvider:   stateProvider,
+		Parallelism:     parallelism,
+	}
+	for _, t := range targets {
+		manager.TargetProviders[t] = provider
+	}
+	return manager
+}
+func TestReconcileInParallel(t *testing.T) {
+	targets := []string{"T1", "T2", "T3"}
+	provider := &concurrencyTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createParallelManager(provider, targets, 3)
+
+	summary, err := manager.Reconcile(context.Background(), createParallelDeployment("instance1", targets), false, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, summary.SuccessCount)
+	assert.Equal(t, 3, len(summary.TargetResults))
+	assert.Equal(t, 3, provider.maxActive)
+}
+func TestReconcileSequentialByDefault(t *testing.T) {
+	targets := []string{"T1", "T2", "T3"}
+	provider := &concurrencyTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createParallelManager(provider, targets, 0)
+
+	summary, err := manager.Reconcile(context.Background(), createParallelDeployment("instance1", targets), false, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, summary.SuccessCount)
+	assert.Equal(t, 1, provider.maxActive)
+}
+func TestReconcileInstancesConcurrently(t *testing.T) {
+	targets := []string{"T1"}
+	provider := &concurrencyTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createParallelManager(provider, targets, 1)
+
+	var wg sync.WaitGroup
+	for _, instance := range []string{"instance1", "instance2"} {
+		wg.Add(1)
+		go func(instance string) {
+			defer wg.Done()
+			_, err := manager.Reconcile(context.Background(), createParallelDeployment(instance, targets), false, "default")
+			assert.Nil(t, err)
+		}(instance)
+	}
+	wg.Wait()
+	// different instances don't wait on each other
+	assert.Equal(t, 2, provider.maxActive)
+}
+func TestInstanceLocksArePruned(t *testing.T) {
+	targets := []string{"T1"}
+	provider := &concurrencyTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: uuid.New().String()})
+	manager := createParallelManager(provider, targets, 1)
+
+	deployment := createParallelDeployment("pruned-instance", targets)
+	_, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	_, err = manager.Reconcile(context.Background(), deployment, true, "default")
+	assert.Nil(t, err)
+	instanceLocksLock.Lock()
+	defer instanceLocksLock.Unlock()
+	assert.NotContains(t, instanceLocks, "default/pruned-instance")
+}
+func TestInstanceLockIsKeptWhileWaitedFor(t *testing.T) {
+	unlock := lockInstance("default", "waited-instance")
+	done := make(chan struct{})
+	go func() {
+		lockInstance("default", "waited-instance")()
+		close(done)
+	}()
+	// the lock isn't removed while the second reconciliation waits for it
+	for {
+		instanceLocksLock.Lock()
+		refs := instanceLocks["default/waited-instance"].refs
+		instanceLocksLock.Unlock()
+		if refs == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	unlock()
+	<-done
+	instanceLocksLock.Lock()
+	defer instanceLocksLock.Unlock()
+	assert.NotContains(t, instanceLocks, "default/waited-instance")
+}
+func createRolloutManager(failOn string, targets []string) (SolutionManager, map[string]string) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := SolutionManager{
+		TargetProviders: map[string]target.ITargetProvider{},
+		StateProvider:   stateProvider,
+	}
+	ids := make(map[string]string)
+	for _, t := range targets {
+		provider := &failingTargetProvider{FailOn: failOn}
+		ids[t] = uuid.New().String()
+		provider.Init(mock.MockTargetProviderConfig{ID: ids[t]})
+		manager.TargetProviders[t] = provider
+	}
+	return manager, ids
+}
+func TestRolloutCompletes(t *testing.T) {
+	targets := []string{"T1", "T2", "T3"}
+	manager, ids := createRolloutManager("", targets)
+	deployment := createParallelDeployment("instance1", targets)
+	deployment.Instance.Rollout = &model.RolloutSpec{BatchSize: 1, Pause: "10ms"}
+
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, summary.SuccessCount)
+	assert.Equal(t, 3, summary.Rollout.TotalBatches)
+	assert.Equal(t, 3, summary.Rollout.CompletedBatches)
+	assert.False(t, summary.Rollout.Halted)
+	for _, target := range targets {
+		assert.ElementsMatch(t, []string{"c-" + target}, getTargetComponents(t, ids[target], "c-"+target))
+	}
+}
+func TestRolloutHaltsOnFailedCanary(t *testing.T) {
+	targets := []string{"T1", "T2", "T3"}
+	manager, ids := createRolloutManager("c-T1", targets)
+	deployment := createParallelDeployment("instance1", targets)
+	deployment.Instance.Rollout = &model.RolloutSpec{BatchSize: 1}
+
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.NotNil(t, err)
+	assert.True(t, summary.Rollout.Halted)
+	assert.Equal(t, 0, summary.Rollout.CompletedBatches)
+	assert.Equal(t, "rollout halted at batch 1 of 3: 0 of 1 targets succeeded", summary.Rollout.Message)
+	// the remaining targets are left untouched
+	assert.Empty(t, getTargetComponents(t, ids["T2"], "c-T2"))
+	assert.Empty(t, getTargetComponents(t, ids["T3"], "c-T3"))
+}
+func TestRolloutWithinThreshold(t *testing.T) {
+	targets := []string{"T1", "T2", "T3", "T4"}
+	manager, ids := createRolloutManager("c-T1", targets)
+	deployment := createParallelDeployment("instance1", targets)
+	deployment.Instance.Rollout = &model.RolloutSpec{Percentage: 50, SuccessThreshold: 50}
+
+	// one failure out of the two canary targets is tolerated, the rollout goes on
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.NotNil(t, err)
+	assert.False(t, summary.Rollout.Halted)
+	assert.Equal(t, 2, summary.Rollout.CompletedBatches)
+	assert.Equal(t, 3, summary.SuccessCount)
+	assert.ElementsMatch(t, []string{"c-T4"}, getTargetComponents(t, ids["T4"], "c-T4"))
+}
+func TestRolloutInvalidPause(t *testing.T) {
+	targets := []string{"T1"}
+	manager, _ := createRolloutManager("", targets)
+	deployment := createParallelDeployment("instance1", targets)
+	deployment.Instance.Rollout = &model.RolloutSpec{BatchSize: 1, Pause: "soon"}
+
+	_, err := manager.Reconcile(context.Background(), deployment, false, "default")
+	assert.NotNil(t, err)
+}
+func getTargetComponents(t *testing.T, id string, component string) []string {
+	provider := &mock.MockTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: id})
+	components, err := provider.Get(context.Background(), model.DeploymentSpec{}, []model.ComponentStep{
+		{Component: model.ComponentSpec{Name: component}},
+	})
+	assert.Nil(t, err)
+	ret := make([]string, 0)
+	for _, c := range components {
+		ret = append(ret, c.Name)
+	}
+	return ret
+}
@@ -12,6 +12,8 @@ import (
 	"fmt"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -24,6 +26,8 @@ import (
 type SolutionsManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
+	// Revisions is nil if the manager doesn't keep revisions
+	Revisions *utils.RevisionStore
 }
 
 func (s *SolutionsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -37,6 +41,10 @@ func (s *SolutionsManager) Init(context *contexts.VendorContext, config managers
 	} else {
 		return err
 	}
+	s.Revisions, err = utils.NewRevisionStore(config, providers, model.SolutionGroup, "SolutionRevision", "solutionrevisions")
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -87,12 +95,26 @@ func (t *SolutionsManager) UpsertSpec(ctx context.Context, name string, spec mod
 		},
 	}
 	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	if err != nil {
+		return err
+	}
+	if t.Revisions != nil {
+		author, _ := v1alpha2.GetAuthenticatedUser(ctx)
+		_, err = t.Revisions.Record(ctx, name, scope, spec, author)
+	}
 	return err
 }
 
 func (t *SolutionsManager) ListSpec(ctx context.Context, scope string) ([]model.SolutionState, error) {
+	ret, _, err := t.ListSpecPage(ctx, scope, 0, "")
+	return ret, err
+}
+
+// ListSpecPage lists at most limit solutions, starting from continueToken. It returns the token
+// for the next page, which is empty on the last page. A limit of 0 lists everything.
+func (t *SolutionsManager) ListSpecPage(ctx context.Context, scope string, limit int64, continueToken string) ([]model.SolutionState, string, error) {
 	ctx, span := observability.StartSpan("Solutions Manager", ctx, &map[string]string{
-		"method": "ListSpec",
+		"method": "ListSpecPage",
 	})
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
@@ -104,21 +126,23 @@ func (t *SolutionsManager) ListSpec(ctx context.Context, scope string) ([]model.
 			"resource": "solutions",
 			"scope":    scope,
 		},
+		Limit:    limit,
+		Continue: continueToken,
 	}
-	solutions, _, err := t.StateProvider.List(ctx, listRequest)
+	solutions, token, err := t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.SolutionState, 0)
 	for _, t := range solutions {
 		var rt model.SolutionState
 		rt, err = getSolutionState(t.ID, t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, token, nil
 }
 
 func getSolutionState(id string, body interface{}) (model.SolutionState, error) {
@@ -174,3 +198,58 @@ func (t *SolutionsManager) GetSpec(ctx context.Context, id string, scope string)
 	}
 	return ret, nil
 }
+
+func (t *SolutionsManager) revisions() (*utils.RevisionStore, error) {
+	if t.Revisions == nil {
+		return nil, v1alpha2.NewCOAError(nil, "solution revisions are not enabled", v1alpha2.NotFound)
+	}
+	return t.Revisions, nil
+}
+
+// GetRevisions returns the revisions of a solution, oldest first
+func (t *SolutionsManager) GetRevisions(ctx context.Context, name string, scope string) ([]model.RevisionSpec, error) {
+	ctx, span := observability.StartSpan("Solutions Manager", ctx, &map[string]string{
+		"method": "GetRevisions",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := t.revisions()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := store.List(ctx, name, scope)
+	return ret, err
+}
+
+// GetRevision returns a revision of a solution
+func (t *SolutionsManager) GetRevision(ctx context.Context, name string, scope string, revision int) (model.RevisionSpec, error) {
+	ctx, span := observability.StartSpan("Solutions Manager", ctx, &map[string]string{
+		"method": "GetRevision",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := t.revisions()
+	if err != nil {
+		return model.RevisionSpec{}, err
+	}
+	ret, err := store.Get(ctx, name, scope, revision)
+	return ret, err
+}
+
+// DiffRevisions compares two revisions of a solution
+func (t *SolutionsManager) DiffRevisions(ctx context.Context, name string, scope string, from int, to int) (model.RevisionDiff, error) {
+	ctx, span := observability.StartSpan("Solutions Manager", ctx, &map[string]string{
+		"method": "DiffRevisions",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	store, err := t.revisions()
+	if err != nil {
+		return model.RevisionDiff{}, err
+	}
+	ret, err := store.Diff(ctx, name, scope, from, to)
+	return ret, err
+}
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -36,3 +37,40 @@ func TestCreateGetDeleteSolutionsSpec(t *testing.T) {
 	spec, err = manager.GetSpec(context.Background(), "test", "default")
 	assert.NotNil(t, err)
 }
+
+func TestSolutionRevisions(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	revisionProvider := &memorystate.MemoryStateProvider{}
+	revisionProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := SolutionsManager{
+		StateProvider: stateProvider,
+		Revisions: &utils.RevisionStore{
+			StateProvider: revisionProvider,
+			Group:         model.SolutionGroup,
+			Kind:          "SolutionRevision",
+			Resource:      "solutionrevisions",
+		},
+	}
+	ctx := context.Background()
+	err := manager.UpsertSpec(ctx, "test", model.SolutionSpec{DisplayName: "v1"}, "default")
+	assert.Nil(t, err)
+	err = manager.UpsertSpec(ctx, "test", model.SolutionSpec{DisplayName: "v1"}, "default")
+	assert.Nil(t, err)
+	err = manager.UpsertSpec(ctx, "test", model.SolutionSpec{DisplayName: "v2"}, "default")
+	assert.Nil(t, err)
+
+	revisions, err := manager.GetRevisions(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+
+	revision, err := manager.GetRevision(ctx, "test", "default", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", revision.Spec.(map[string]interface{})["displayName"])
+
+	diff, err := manager.DiffRevisions(ctx, "test", "default", 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SpecChange{
+		{Path: "displayName", Type: model.SpecChangeModified, From: "v1", To: "v2"},
+	}, diff.Changes)
+}
@@ -9,10 +9,14 @@ package stage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
@@ -37,9 +41,11 @@ type StageManager struct {
 }
 
 type TaskResult struct {
-	Outputs map[string]interface{}
-	Site    string
-	Error   error
+	Outputs  map[string]interface{}
+	Site     string
+	Error    error
+	Attempts int
+	Duration time.Duration
 }
 
 func (t *TaskResult) GetError() error {
@@ -83,11 +89,60 @@ func (t *TaskResult) GetError() error {
 	return nil
 }
 
+// stagePolicy is the parsed retry policy and timeout of a stage
+type stagePolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+}
+
+func getStagePolicy(stage model.StageSpec) (stagePolicy, error) {
+	policy := stagePolicy{maxAttempts: 1}
+	var err error
+	if stage.Timeout != "" {
+		policy.timeout, err = time.ParseDuration(stage.Timeout)
+		if err != nil || policy.timeout <= 0 {
+			return policy, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid timeout '%s', expected a positive duration", stage.Timeout), v1alpha2.BadRequest)
+		}
+	}
+	if stage.RetryPolicy == nil {
+		return policy, nil
+	}
+	if stage.RetryPolicy.MaxAttempts > 1 {
+		policy.maxAttempts = stage.RetryPolicy.MaxAttempts
+	}
+	if stage.RetryPolicy.Backoff != "" {
+		policy.backoff, err = time.ParseDuration(stage.RetryPolicy.Backoff)
+		if err != nil || policy.backoff < 0 {
+			return policy, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid backoff '%s', expected a duration", stage.RetryPolicy.Backoff), v1alpha2.BadRequest)
+		}
+	}
+	if stage.RetryPolicy.MaxBackoff != "" {
+		policy.maxBackoff, err = time.ParseDuration(stage.RetryPolicy.MaxBackoff)
+		if err != nil || policy.maxBackoff < 0 {
+			return policy, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid maxBackoff '%s', expected a duration", stage.RetryPolicy.MaxBackoff), v1alpha2.BadRequest)
+		}
+	}
+	return policy, nil
+}
+
 type PendingTask struct {
 	Sites         []string                          `json:"sites"`
 	OutputContext map[string]map[string]interface{} `json:"outputContext,omitempty"`
 }
 
+// ControlTask records an operator action on a running activation. The next stage of a paused
+// activation is parked in Pending until the activation is resumed.
+type ControlTask struct {
+	Action  string                   `json:"action"`
+	Pending *v1alpha2.ActivationData `json:"pending,omitempty"`
+}
+
+func controlTaskId(campaign string, activation string, activationGeneration string) string {
+	return fmt.Sprintf("%s-%s-%s-control", campaign, activation, activationGeneration)
+}
+
 func (s *StageManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
 	err := s.Manager.Init(context, config, providers)
 	if err != nil {
@@ -228,6 +283,126 @@ func (s *StageManager) ResumeStage(status model.ActivationStatus, cam model.Camp
 
 	return nil, nil
 }
+
+// HandleControlEvent applies an operator action to the stages of an activation. When a paused
+// activation is resumed, it returns the stage that was parked, if any.
+func (s *StageManager) HandleControlEvent(ctx context.Context, control model.ActivationControl) (*v1alpha2.ActivationData, error) {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "HandleControlEvent",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	log.Infof(" M (Stage): HandleControlEvent: %s %s", control.Action, control.Activation)
+	id := controlTaskId(control.Campaign, control.Activation, control.ActivationGeneration)
+	switch control.Action {
+	case model.ActivationPause:
+		_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: ControlTask{Action: control.Action},
+			},
+		})
+		return nil, err
+	case model.ActivationCancel:
+		task, terr := s.getControlTask(ctx, id)
+		if terr == nil && task.Pending != nil {
+			// the activation was paused between stages, so no stage is left to pick up the cancellation
+			err = s.StateProvider.Delete(ctx, states.DeleteRequest{ID: id})
+			return nil, err
+		}
+		_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: ControlTask{Action: control.Action},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// drop the stage the activation is waiting on, if any
+		s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: fmt.Sprintf("%s-%s-%s", control.Campaign, control.Activation, control.ActivationGeneration),
+		})
+		return nil, nil
+	case model.ActivationResume:
+		var task ControlTask
+		task, err = s.getControlTask(ctx, id)
+		if err != nil {
+			if v1alpha2.IsNotFound(err) {
+				err = nil
+			}
+			return nil, err
+		}
+		err = s.StateProvider.Delete(ctx, states.DeleteRequest{ID: id})
+		if err != nil {
+			return nil, err
+		}
+		return task.Pending, nil
+	case model.ActivationReject:
+		err = s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: fmt.Sprintf("%s-%s-%s", control.Campaign, control.Activation, control.ActivationGeneration),
+		})
+		if v1alpha2.IsNotFound(err) {
+			err = nil
+		}
+		return nil, err
+	}
+	err = v1alpha2.NewCOAError(nil, fmt.Sprintf("unknown activation action '%s'", control.Action), v1alpha2.BadRequest)
+	return nil, err
+}
+
+func (s *StageManager) getControlTask(ctx context.Context, id string) (ControlTask, error) {
+	var task ControlTask
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: id,
+	})
+	if err != nil {
+		return task, err
+	}
+	jData, _ := json.Marshal(entry.Body)
+	err = json.Unmarshal(jData, &task)
+	return task, err
+}
+
+// holdActivation parks the stage of a paused activation and drops the stage of a cancelled one.
+// It returns true if the stage must not run.
+func (s *StageManager) holdActivation(ctx context.Context, triggerData v1alpha2.ActivationData, status *model.ActivationStatus) bool {
+	id := controlTaskId(triggerData.Campaign, triggerData.Activation, triggerData.ActivationGeneration)
+	task, err := s.getControlTask(ctx, id)
+	if err != nil {
+		return false
+	}
+	switch task.Action {
+	case model.ActivationPause:
+		task.Pending = &triggerData
+		_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: task,
+			},
+		})
+		if err != nil {
+			log.Errorf(" M (Stage): failed to park stage %s of paused activation %s: %v", triggerData.Stage, triggerData.Activation, err)
+			status.Status = v1alpha2.InternalError
+			status.ErrorMessage = err.Error()
+		} else {
+			status.Status = v1alpha2.Paused
+		}
+		status.IsActive = false
+		return true
+	case model.ActivationCancel:
+		err = s.StateProvider.Delete(ctx, states.DeleteRequest{ID: id})
+		if err != nil {
+			log.Errorf(" M (Stage): failed to clear cancellation of activation %s: %v", triggerData.Activation, err)
+		}
+		status.Status = v1alpha2.Cancelled
+		status.IsActive = false
+		return true
+	}
+	return false
+}
+
 func (s *StageManager) HandleDirectTriggerEvent(ctx context.Context, triggerData v1alpha2.ActivationData) model.ActivationStatus {
 	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
 		"method": "HandleDirectTriggerEvent",
@@ -336,15 +511,29 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 
 	log.Info(" M (Stage): HandleTriggerEvent")
 	status := model.ActivationStatus{
-		Stage:        triggerData.Stage,
-		NextStage:    "",
-		Outputs:      nil,
-		Status:       v1alpha2.Untouched,
-		ErrorMessage: "",
-		IsActive:     true,
+		Stage:                triggerData.Stage,
+		NextStage:            "",
+		Outputs:              nil,
+		Status:               v1alpha2.Untouched,
+		ErrorMessage:         "",
+		IsActive:             true,
+		ActivationGeneration: triggerData.ActivationGeneration,
 	}
 	var activationData *v1alpha2.ActivationData
+	// branches of parallel stages run with SelfDriving off, so they aren't held
+	if campaign.SelfDriving && s.holdActivation(ctx, triggerData, &status) {
+		return status, activationData
+	}
 	if currentStage, ok := campaign.Stages[triggerData.Stage]; ok {
+		var policy stagePolicy
+		policy, err = getStagePolicy(currentStage)
+		if err != nil {
+			status.Status = v1alpha2.BadRequest
+			status.ErrorMessage = err.Error()
+			status.IsActive = false
+			log.Errorf(" M (Stage): invalid retry policy or timeout: %v", err)
+			return status, activationData
+		}
 		sites := make([]string, 0)
 		if currentStage.Contexts != "" {
 			parser := utils.NewParser(currentStage.Contexts)
@@ -411,6 +600,10 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 			inputs["__schedule"] = string(jSchedule)
 		}
 		for k, v := range inputs {
+			if _, ok := currentStage.Inputs[k]; ok && currentStage.ForEach != nil {
+				// the inputs of a forEach stage are evaluated for each item
+				continue
+			}
 			var val interface{}
 			val, err = s.traceValue(v, inputs, triggerData.Outputs)
 			if err != nil {
@@ -433,122 +626,175 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 
 		log.Debugf(" M (Stage): HandleTriggerEvent after evaluation inputs 2: %v", inputs)
 
-		factory := symproviders.SymphonyProviderFactory{}
-		var provider providers.IProvider
-		provider, err = factory.CreateProvider(triggerData.Provider, triggerData.Config)
-		if err != nil {
-			status.Status = v1alpha2.InternalError
-			status.ErrorMessage = err.Error()
-			status.IsActive = false
-			log.Errorf(" M (Stage): failed to create provider: %v", err)
-			return status, activationData
-		}
-
-		if _, ok := provider.(contexts.IWithManagerContext); ok {
-			provider.(contexts.IWithManagerContext).SetContext(s.Manager.Context)
+		outputs := make(map[string]interface{})
+		delayedExit := false
+		pauseRequested := false
+		if currentStage.Parallel != nil {
+			delayedExit, err = s.handleParallelStage(ctx, campaign, currentStage, triggerData, inputs, outputs)
+			if err != nil {
+				status.Status = v1alpha2.InternalError
+				if cErr, ok := err.(v1alpha2.COAError); ok {
+					status.Status = cErr.State
+				}
+				status.ErrorMessage = err.Error()
+				status.IsActive = false
+				log.Errorf(" M (Stage): failed to run parallel branches: %v", err)
+				return status, activationData
+			}
+			if delayedExit {
+				status.Status = v1alpha2.InternalError
+				status.ErrorMessage = outputs["__error"].(string)
+				status.IsActive = false
+			}
+		} else if currentStage.ForEach != nil && triggerData.Schedule != nil {
+			// the items run when the stage is due
+			s.Context.Publish("schedule", v1alpha2.Event{
+				Body: triggerData,
+			})
+			pauseRequested = true
+			outputs["__status"] = v1alpha2.OK
+		} else if currentStage.ForEach != nil {
+			delayedExit, err = s.handleForEachStage(ctx, currentStage, triggerData, inputs, outputs, policy)
+			// the unevaluated inputs of the stage aren't passed on to the next stage
+			for k := range currentStage.Inputs {
+				delete(inputs, k)
+			}
+			if err != nil {
+				status.Status = v1alpha2.InternalError
+				if cErr, ok := err.(v1alpha2.COAError); ok {
+					status.Status = cErr.State
+				}
+				status.ErrorMessage = err.Error()
+				status.IsActive = false
+				log.Errorf(" M (Stage): failed to run forEach stage: %v", err)
+				return status, activationData
+			}
+			if delayedExit {
+				status.Status = v1alpha2.InternalError
+				status.ErrorMessage = outputs["__error"].(string)
+				status.IsActive = false
+			}
 		} else {
-			log.Errorf(" M (Stage): provider %s does not implement IWithManagerContext", triggerData.Provider)
-		}
+			factory := symproviders.SymphonyProviderFactory{}
+			var provider providers.IProvider
+			provider, err = factory.CreateProvider(triggerData.Provider, triggerData.Config)
+			if err != nil {
+				status.Status = v1alpha2.InternalError
+				status.ErrorMessage = err.Error()
+				status.IsActive = false
+				log.Errorf(" M (Stage): failed to create provider: %v", err)
+				return status, activationData
+			}
 
-		numTasks := len(sites)
-		waitGroup := sync.WaitGroup{}
-		results := make(chan TaskResult, numTasks)
-		pauseRequested := false
+			if _, ok := provider.(contexts.IWithManagerContext); ok {
+				provider.(contexts.IWithManagerContext).SetContext(s.Manager.Context)
+			} else {
+				log.Errorf(" M (Stage): provider %s does not implement IWithManagerContext", triggerData.Provider)
+			}
 
-		for _, site := range sites {
-			waitGroup.Add(1)
-			go func(wg *sync.WaitGroup, site string, results chan<- TaskResult) {
-				defer wg.Done()
-				inputCopy := make(map[string]interface{})
-				for k, v := range inputs {
-					inputCopy[k] = v
-				}
-				inputCopy["__site"] = site
+			numTasks := len(sites)
+			waitGroup := sync.WaitGroup{}
+			results := make(chan TaskResult, numTasks)
 
-				for k, v := range inputCopy {
-					var val interface{}
-					val, err = s.traceValue(v, inputCopy, triggerData.Outputs)
-					if err != nil {
-						status.Status = v1alpha2.InternalError
-						status.ErrorMessage = err.Error()
-						status.IsActive = false
-						log.Errorf(" M (Stage): failed to evaluate input: %v", err)
+			for _, site := range sites {
+				waitGroup.Add(1)
+				go func(wg *sync.WaitGroup, site string, results chan<- TaskResult) {
+					defer wg.Done()
+					inputCopy := make(map[string]interface{})
+					for k, v := range inputs {
+						inputCopy[k] = v
+					}
+					inputCopy["__site"] = site
+
+					for k, v := range inputCopy {
+						var val interface{}
+						val, err = s.traceValue(v, inputCopy, triggerData.Outputs)
+						if err != nil {
+							status.Status = v1alpha2.InternalError
+							status.ErrorMessage = err.Error()
+							status.IsActive = false
+							log.Errorf(" M (Stage): failed to evaluate input: %v", err)
+							results <- TaskResult{
+								Outputs: nil,
+								Error:   err,
+								Site:    site,
+							}
+							return
+						}
+						inputCopy[k] = val
+					}
+
+					if _, ok := provider.(*remote.RemoteStageProvider); ok {
+						provider.(*remote.RemoteStageProvider).SetOutputsContext(triggerData.Outputs)
+					}
+
+					if triggerData.Schedule != nil {
+						s.Context.Publish("schedule", v1alpha2.Event{
+							Body: triggerData,
+						})
+						pauseRequested = true
 						results <- TaskResult{
 							Outputs: nil,
-							Error:   err,
+							Error:   nil,
 							Site:    site,
 						}
-						return
+					} else {
+						result, pause := s.processWithRetry(ctx, provider.(stage.IStageProvider), inputCopy, policy)
+						if pause {
+							if parallel, ok := ctx.Value(parallelStageKey{}).(string); ok {
+								// branches can't be resumed on their own, so they can't pause
+								result.Error = v1alpha2.NewCOAError(nil, fmt.Sprintf("provider %s can't pause in parallel stage %s", triggerData.Provider, parallel), v1alpha2.BadRequest)
+							} else {
+								pauseRequested = true
+							}
+						}
+						result.Site = site
+						results <- result
 					}
-					inputCopy[k] = val
-				}
-
-				if _, ok := provider.(*remote.RemoteStageProvider); ok {
-					provider.(*remote.RemoteStageProvider).SetOutputsContext(triggerData.Outputs)
-				}
+				}(&waitGroup, site, results)
+			}
 
-				if triggerData.Schedule != nil {
-					s.Context.Publish("schedule", v1alpha2.Event{
-						Body: triggerData,
-					})
-					pauseRequested = true
-					results <- TaskResult{
-						Outputs: nil,
-						Error:   nil,
-						Site:    site,
-					}
-				} else {
-					var outputs map[string]interface{}
-					var pause bool
-					outputs, pause, err = provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputCopy)
+			waitGroup.Wait()
+			close(results)
 
-					if pause {
-						pauseRequested = true
+			for result := range results {
+				err = result.GetError()
+				if err != nil {
+					status.Status = v1alpha2.InternalError
+					status.ErrorMessage = fmt.Sprintf("%s: %s", result.Site, err.Error())
+					status.IsActive = false
+					site := result.Site
+					if result.Site == s.Context.SiteInfo.SiteId {
+						site = ""
 					}
-					results <- TaskResult{
-						Outputs: outputs,
-						Error:   err,
-						Site:    site,
+					status.Outputs = carryOutPutsToErrorStatus(nil, err, site)
+					result.Outputs = carryOutPutsToErrorStatus(nil, err, site)
+					log.Errorf(" M (Stage): failed to process stage outputs: %v", err)
+					delayedExit = true
+				}
+				if result.Attempts > 0 {
+					if result.Outputs == nil {
+						result.Outputs = make(map[string]interface{})
 					}
+					result.Outputs["__attempts"] = result.Attempts
+					result.Outputs["__duration"] = result.Duration.String()
 				}
-			}(&waitGroup, site, results)
-		}
-
-		waitGroup.Wait()
-		close(results)
-
-		outputs := make(map[string]interface{})
-		delayedExit := false
-		for result := range results {
-			err = result.GetError()
-			if err != nil {
-				status.Status = v1alpha2.InternalError
-				status.ErrorMessage = fmt.Sprintf("%s: %s", result.Site, err.Error())
-				status.IsActive = false
-				site := result.Site
-				if result.Site == s.Context.SiteInfo.SiteId {
-					site = ""
+				for k, v := range result.Outputs {
+					if result.Site == s.Context.SiteInfo.SiteId {
+						outputs[k] = v
+					} else {
+						outputs[fmt.Sprintf("%s.%s", result.Site, k)] = v
+					}
 				}
-				status.Outputs = carryOutPutsToErrorStatus(nil, err, site)
-				result.Outputs = carryOutPutsToErrorStatus(nil, err, site)
-				log.Errorf(" M (Stage): failed to process stage outputs: %v", err)
-				delayedExit = true
-			}
-			for k, v := range result.Outputs {
 				if result.Site == s.Context.SiteInfo.SiteId {
-					outputs[k] = v
+					if _, ok := result.Outputs["__status"]; !ok {
+						outputs["__status"] = v1alpha2.OK
+					}
 				} else {
-					outputs[fmt.Sprintf("%s.%s", result.Site, k)] = v
-				}
-			}
-			if result.Site == s.Context.SiteInfo.SiteId {
-				if _, ok := result.Outputs["__status"]; !ok {
-					outputs["__status"] = v1alpha2.OK
-				}
-			} else {
-				key := fmt.Sprintf("%s.__status", result.Site)
-				if _, ok := result.Outputs[key]; !ok {
-					outputs[fmt.Sprintf("%s.__status", result.Site)] = v1alpha2.OK
+					key := fmt.Sprintf("%s.__status", result.Site)
+					if _, ok := result.Outputs[key]; !ok {
+						outputs[fmt.Sprintf("%s.__status", result.Site)] = v1alpha2.OK
+					}
 				}
 			}
 		}
@@ -562,6 +808,14 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 			triggerData.Outputs = make(map[string]map[string]interface{})
 		}
 		triggerData.Outputs[triggerData.Stage] = outputs
+		if currentStage.Parallel != nil {
+			// later stages read the outputs of a branch by its name, like the outputs of any other stage
+			for _, branch := range currentStage.Parallel.Branches {
+				if branchOutputs, ok := outputs[branch].(map[string]interface{}); ok {
+					triggerData.Outputs[branch] = branchOutputs
+				}
+			}
+		}
 		if campaign.SelfDriving {
 			if pauseRequested {
 				pendingTask := PendingTask{
@@ -582,6 +836,7 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 					return status, activationData
 				}
 				status.Status = v1alpha2.Paused
+				status.Inputs = triggerData.Inputs
 				status.IsActive = false
 				return status, activationData
 			}
@@ -625,6 +880,9 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 							Schedule:             nextStage.Schedule,
 						}
 					} else {
+						if currentStage.Compensation != "" {
+							return s.compensate(campaign, currentStage, triggerData, status)
+						}
 						status.Status = v1alpha2.InternalError
 						status.ErrorMessage = fmt.Sprintf("stage %s failed", triggerData.Stage)
 						status.IsActive = false
@@ -640,6 +898,9 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 					return status, activationData
 				}
 			}
+			if sVal == "" && delayedExit && currentStage.Compensation != "" {
+				return s.compensate(campaign, currentStage, triggerData, status)
+			}
 			status.NextStage = sVal
 			if sVal == "" {
 				status.IsActive = false
@@ -671,6 +932,394 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 	return status, activationData
 }
 
+// compensate moves an activation that failed at a stage to the stage's compensation stage. The compensation
+// stage reads the failed stage and its error from the __failedStage and __failedError inputs.
+func (s *StageManager) compensate(campaign model.CampaignSpec, currentStage model.StageSpec, triggerData v1alpha2.ActivationData, status model.ActivationStatus) (model.ActivationStatus, *v1alpha2.ActivationData) {
+	compensation, ok := campaign.Stages[currentStage.Compensation]
+	if !ok {
+		status.Status = v1alpha2.BadRequest
+		status.ErrorMessage = fmt.Sprintf("compensation stage %s is not found", currentStage.Compensation)
+		status.IsActive = false
+		log.Errorf(" M (Stage): failed to find compensation stage: %v", status.ErrorMessage)
+		return status, nil
+	}
+	inputs := make(map[string]interface{}, len(triggerData.Inputs)+2)
+	for k, v := range triggerData.Inputs {
+		inputs[k] = v
+	}
+	inputs["__failedStage"] = triggerData.Stage
+	inputs["__failedError"] = status.ErrorMessage
+	status.NextStage = currentStage.Compensation
+	status.Status = v1alpha2.Running
+	status.IsActive = true
+	log.Infof(" M (Stage): stage %s failed, running compensation stage %s", triggerData.Stage, currentStage.Compensation)
+	return status, &v1alpha2.ActivationData{
+		Campaign:             triggerData.Campaign,
+		Activation:           triggerData.Activation,
+		ActivationGeneration: triggerData.ActivationGeneration,
+		Stage:                currentStage.Compensation,
+		Inputs:               inputs,
+		Outputs:              triggerData.Outputs,
+		Provider:             compensation.Provider,
+		Config:               compensation.Config,
+		TriggeringStage:      triggerData.Stage,
+		Schedule:             compensation.Schedule,
+	}
+}
+
+// parallelStageKey is the context key of the name of the parallel stage that a branch runs in
+type parallelStageKey struct{}
+
+// handleParallelStage runs the branches of a parallel stage concurrently. Each branch runs like a stage of a
+// campaign that isn't self-driving, with the inputs of the parallel stage and the branch's own inputs on top.
+// The outputs of the branches are collected under their names. Branches whose providers pause fail. It
+// returns true if fewer branches than required have succeeded.
+func (s *StageManager) handleParallelStage(ctx context.Context, campaign model.CampaignSpec, stage model.StageSpec, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, outputs map[string]interface{}) (bool, error) {
+	branches := stage.Parallel.Branches
+	if len(branches) == 0 {
+		return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("parallel stage %s has no branches", triggerData.Stage), v1alpha2.BadRequest)
+	}
+	minSuccess := stage.Parallel.MinSuccess
+	if minSuccess <= 0 {
+		minSuccess = len(branches)
+	}
+	if minSuccess > len(branches) {
+		return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("parallel stage %s requires %d successful branches but has %d", triggerData.Stage, minSuccess, len(branches)), v1alpha2.BadRequest)
+	}
+	for _, name := range branches {
+		branch, ok := campaign.Stages[name]
+		if !ok {
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("branch stage %s is not found", name), v1alpha2.BadRequest)
+		}
+		if branch.Parallel != nil {
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("branch stage %s can't be a parallel stage", name), v1alpha2.BadRequest)
+		}
+		if branch.Schedule != nil {
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("branch stage %s can't be scheduled", name), v1alpha2.BadRequest)
+		}
+	}
+
+	// branches don't pick a next stage, the parallel stage does once they are all done
+	branchCampaign := campaign
+	branchCampaign.SelfDriving = false
+
+	branchCtx := context.WithValue(ctx, parallelStageKey{}, triggerData.Stage)
+	results := make([]model.ActivationStatus, len(branches))
+	waitGroup := sync.WaitGroup{}
+	for i, name := range branches {
+		waitGroup.Add(1)
+		go func(i int, name string) {
+			defer waitGroup.Done()
+			// stages write to the inputs and outputs they are given, so each branch gets its own copies
+			branchInputs := make(map[string]interface{}, len(inputs))
+			for k, v := range inputs {
+				branchInputs[k] = v
+			}
+			branchOutputs := make(map[string]map[string]interface{}, len(triggerData.Outputs))
+			for k, v := range triggerData.Outputs {
+				branchOutputs[k] = v
+			}
+			results[i], _ = s.HandleTriggerEvent(branchCtx, branchCampaign, v1alpha2.ActivationData{
+				Campaign:             triggerData.Campaign,
+				Activation:           triggerData.Activation,
+				ActivationGeneration: triggerData.ActivationGeneration,
+				Stage:                name,
+				Inputs:               branchInputs,
+				Outputs:              branchOutputs,
+				Provider:             campaign.Stages[name].Provider,
+				Config:               campaign.Stages[name].Config,
+				TriggeringStage:      triggerData.Stage,
+			})
+		}(i, name)
+	}
+	waitGroup.Wait()
+
+	succeeded := 0
+	failures := make([]string, 0)
+	for i, name := range branches {
+		result := results[i]
+		if result.ErrorMessage == "" {
+			succeeded++
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, result.ErrorMessage))
+			if result.Outputs == nil {
+				result.Outputs = carryOutPutsToErrorStatus(nil, errors.New(result.ErrorMessage), "")
+			}
+		}
+		outputs[name] = result.Outputs
+	}
+	outputs["__succeeded"] = succeeded
+	outputs["__failed"] = len(branches) - succeeded
+	if succeeded < minSuccess {
+		outputs["__status"] = v1alpha2.InternalError
+		outputs["__error"] = fmt.Sprintf("%d of %d branches succeeded, %d required (%s)", succeeded, len(branches), minSuccess, strings.Join(failures, "; "))
+		return true, nil
+	}
+	outputs["__status"] = v1alpha2.OK
+	return false, nil
+}
+
+// forEachItem is an item of the collection of a forEach stage, with its position in the collection, or its
+// key if the collection is a map
+type forEachItem struct {
+	index interface{}
+	value interface{}
+}
+
+// getForEachItems evaluates the collection of a forEach stage. Lists are run in order, maps in the order
+// of their keys.
+func (s *StageManager) getForEachItems(expression string, inputs map[string]interface{}, outputs map[string]map[string]interface{}) ([]forEachItem, error) {
+	parser := utils.NewParser(expression)
+	eCtx := s.VendorContext.EvaluationContext.Clone()
+	eCtx.Inputs = inputs
+	if eCtx.Inputs != nil {
+		if v, ok := eCtx.Inputs["context"]; ok {
+			eCtx.Value = v
+		}
+	}
+	eCtx.Outputs = outputs
+	val, err := parser.Eval(*eCtx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]forEachItem, 0)
+	if val == nil {
+		return items, nil
+	}
+	rVal := reflect.ValueOf(val)
+	switch rVal.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rVal.Len(); i++ {
+			items = append(items, forEachItem{index: i, value: toItemValue(rVal.Index(i).Interface())})
+		}
+	case reflect.Map:
+		keys := make([]string, 0, rVal.Len())
+		values := make(map[string]interface{}, rVal.Len())
+		for _, k := range rVal.MapKeys() {
+			key := fmt.Sprintf("%v", k.Interface())
+			keys = append(keys, key)
+			values[key] = toItemValue(rVal.MapIndex(k).Interface())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			items = append(items, forEachItem{index: k, value: values[k]})
+		}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("forEach items '%s' evaluate to '%v', which isn't a list or a map", expression, val), v1alpha2.BadRequest)
+	}
+	return items, nil
+}
+
+// toItemValue turns objects, such as the instances listed by a list stage, into maps so that $item() reads
+// their fields by their JSON names
+func toItemValue(v interface{}) interface{} {
+	kind := reflect.ValueOf(v).Kind()
+	if kind != reflect.Struct && kind != reflect.Ptr {
+		return v
+	}
+	jData, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var ret interface{}
+	if json.Unmarshal(jData, &ret) != nil {
+		return v
+	}
+	return ret
+}
+
+// handleForEachStage runs the provider of a forEach stage once for each item of its collection, up to
+// Concurrency items at a time. The inputs of the stage are evaluated for each item, with the item and its
+// index in the __item and __index inputs. The outputs of the items are collected in order in the items
+// output. It returns true if fewer items than required have succeeded.
+func (s *StageManager) handleForEachStage(ctx context.Context, currentStage model.StageSpec, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, outputs map[string]interface{}, policy stagePolicy) (bool, error) {
+	if currentStage.Parallel != nil {
+		return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("stage %s can't be both a parallel and a forEach stage", triggerData.Stage), v1alpha2.BadRequest)
+	}
+	if currentStage.Contexts != "" {
+		return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("forEach stage %s can't run on other sites", triggerData.Stage), v1alpha2.BadRequest)
+	}
+	// items may read the inputs of the stage, so the inputs are evaluated first. Inputs that read the item
+	// can only be evaluated for each item, so items see them as they are
+	itemsInputs := make(map[string]interface{}, len(inputs))
+	for k, v := range inputs {
+		itemsInputs[k] = v
+		if val, err := s.traceValue(v, inputs, triggerData.Outputs); err == nil {
+			itemsInputs[k] = val
+		}
+	}
+	items, err := s.getForEachItems(currentStage.ForEach.Items, itemsInputs, triggerData.Outputs)
+	if err != nil {
+		return false, err
+	}
+	concurrency := currentStage.ForEach.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	minSuccess := currentStage.ForEach.MinSuccess
+	if minSuccess <= 0 {
+		minSuccess = len(items)
+	}
+
+	factory := symproviders.SymphonyProviderFactory{}
+	provider, err := factory.CreateProvider(triggerData.Provider, triggerData.Config)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := provider.(contexts.IWithManagerContext); ok {
+		provider.(contexts.IWithManagerContext).SetContext(s.Manager.Context)
+	}
+
+	results := make([]TaskResult, len(items))
+	slots := make(chan struct{}, concurrency)
+	waitGroup := sync.WaitGroup{}
+	for i, item := range items {
+		waitGroup.Add(1)
+		slots <- struct{}{}
+		go func(i int, item forEachItem) {
+			defer waitGroup.Done()
+			defer func() { <-slots }()
+			itemInputs := make(map[string]interface{}, len(inputs)+2)
+			for k, v := range inputs {
+				itemInputs[k] = v
+			}
+			// items are data, they aren't evaluated
+			itemInputs["__item"] = item.value
+			itemInputs["__index"] = item.index
+			for k, v := range itemInputs {
+				if k == "__item" || k == "__index" {
+					continue
+				}
+				val, err := s.traceValue(v, itemInputs, triggerData.Outputs)
+				if err != nil {
+					results[i] = TaskResult{Error: err}
+					return
+				}
+				itemInputs[k] = val
+			}
+			result, pause := s.processWithRetry(ctx, provider.(stage.IStageProvider), itemInputs, policy)
+			if pause {
+				result.Error = v1alpha2.NewCOAError(nil, fmt.Sprintf("provider %s can't pause in a forEach stage", triggerData.Provider), v1alpha2.BadRequest)
+			}
+			results[i] = result
+		}(i, item)
+	}
+	waitGroup.Wait()
+
+	succeeded := 0
+	failures := make([]string, 0)
+	itemOutputs := make([]interface{}, len(items))
+	for i := range results {
+		result := results[i]
+		err := result.GetError()
+		if err == nil {
+			succeeded++
+			if result.Outputs == nil {
+				result.Outputs = make(map[string]interface{})
+			}
+			if _, ok := result.Outputs["__status"]; !ok {
+				result.Outputs["__status"] = v1alpha2.OK
+			}
+		} else {
+			failures = append(failures, fmt.Sprintf("%v: %s", items[i].index, err.Error()))
+			result.Outputs = carryOutPutsToErrorStatus(result.Outputs, err, "")
+		}
+		result.Outputs["__index"] = items[i].index
+		itemOutputs[i] = result.Outputs
+	}
+	outputs["items"] = itemOutputs
+	outputs["__succeeded"] = succeeded
+	outputs["__failed"] = len(items) - succeeded
+	if succeeded < minSuccess {
+		outputs["__status"] = v1alpha2.InternalError
+		outputs["__error"] = fmt.Sprintf("%d of %d items succeeded, %d required (%s)", succeeded, len(items), minSuccess, strings.Join(failures, "; "))
+		return true, nil
+	}
+	outputs["__status"] = v1alpha2.OK
+	return false, nil
+}
+
+// processWithRetry runs a stage provider until it succeeds, pauses or runs out of attempts
+func (s *StageManager) processWithRetry(ctx context.Context, provider stage.IStageProvider, inputs map[string]interface{}, policy stagePolicy) (TaskResult, bool) {
+	start := time.Now()
+	backoff := policy.backoff
+	var result TaskResult
+	var pause bool
+	for attempt := 1; ; attempt++ {
+		// providers may change the inputs they are given
+		inputCopy := make(map[string]interface{}, len(inputs))
+		for k, v := range inputs {
+			inputCopy[k] = v
+		}
+		outputs, p, running, err := s.processWithTimeout(ctx, provider, inputCopy, policy.timeout)
+		pause = p
+		result = TaskResult{
+			Outputs:  outputs,
+			Error:    err,
+			Attempts: attempt,
+		}
+		if pause || attempt >= policy.maxAttempts {
+			break
+		}
+		err = result.GetError()
+		if err == nil {
+			break
+		}
+		if running != nil {
+			// the timed out attempt may still be running, so wait for it to return before the next attempt
+			log.Infof(" M (Stage): attempt %d of stage %v timed out, waiting for it to return before retrying", attempt, inputs["__stage"])
+			select {
+			case <-ctx.Done():
+				result.Duration = time.Since(start)
+				return result, pause
+			case <-running:
+			}
+		}
+		log.Infof(" M (Stage): attempt %d of stage %v failed, retrying in %s: %v", attempt, inputs["__stage"], backoff, err)
+		select {
+		case <-ctx.Done():
+			result.Duration = time.Since(start)
+			return result, pause
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if policy.maxBackoff > 0 && backoff > policy.maxBackoff {
+			backoff = policy.maxBackoff
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, pause
+}
+
+// processWithTimeout runs a stage provider, and stops waiting for it when the timeout expires. When the
+// attempt times out, the returned channel is closed once the provider returns
+func (s *StageManager) processWithTimeout(ctx context.Context, provider stage.IStageProvider, inputs map[string]interface{}, timeout time.Duration) (map[string]interface{}, bool, <-chan struct{}, error) {
+	if timeout <= 0 {
+		outputs, pause, err := provider.Process(ctx, *s.Manager.Context, inputs)
+		return outputs, pause, nil, err
+	}
+	tCtx, cancel := context.WithTimeout(ctx, timeout)
+	type processResult struct {
+		outputs map[string]interface{}
+		pause   bool
+		err     error
+	}
+	done := make(chan processResult, 1)
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		defer cancel()
+		outputs, pause, err := provider.Process(tCtx, *s.Manager.Context, inputs)
+		done <- processResult{outputs: outputs, pause: pause, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.outputs, r.pause, nil, r.err
+	case <-tCtx.Done():
+		return nil, false, returned, v1alpha2.NewCOAError(nil, fmt.Sprintf("stage timed out after %s", timeout), v1alpha2.InternalError)
+	}
+}
+
 func (s *StageManager) traceValue(v interface{}, inputs map[string]interface{}, outputs map[string]map[string]interface{}) (interface{}, error) {
 	switch val := v.(type) {
 	case string:
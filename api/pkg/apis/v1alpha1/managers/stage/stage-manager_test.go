@@ -9,6 +9,7 @@ package stage
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1117,3 +1118,791 @@ func TestTriggerEventWithSchedule(t *testing.T) {
 	assert.Equal(t, v1alpha2.Paused, status.Status)
 	assert.Equal(t, false, status.IsActive)
 }
+func TestParallelStages(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "regions",
+		Stages: map[string]model.StageSpec{
+			"regions": {
+				StageSelector: "join",
+				Parallel: &model.ParallelSpec{
+					Branches: []string{"east", "west"},
+				},
+				Inputs: map[string]interface{}{
+					"image": "nginx",
+				},
+			},
+			"east": {
+				Provider: "providers.stage.mock",
+				Inputs: map[string]interface{}{
+					"region": "east",
+					"foo":    1,
+				},
+			},
+			"west": {
+				Provider: "providers.stage.mock",
+				Inputs: map[string]interface{}{
+					"region": "west",
+					"foo":    10,
+				},
+			},
+			"join": {
+				Provider: "providers.stage.mock",
+				Inputs: map[string]interface{}{
+					"total": "${{$output(east, foo) + $output(west, foo)}}",
+				},
+			},
+		},
+	}
+	activation := &v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "regions",
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, *activation)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "join", status.NextStage)
+	assert.Equal(t, 2, status.Outputs["__succeeded"])
+	assert.Equal(t, 0, status.Outputs["__failed"])
+	assert.Equal(t, v1alpha2.OK, status.Outputs["__status"])
+	east := status.Outputs["east"].(map[string]interface{})
+	assert.Equal(t, "east", east["region"])
+	assert.Equal(t, "nginx", east["image"])
+	assert.Equal(t, int64(2), east["foo"])
+	west := status.Outputs["west"].(map[string]interface{})
+	assert.Equal(t, "west", west["region"])
+	assert.Equal(t, int64(11), west["foo"])
+
+	status, activation = manager.HandleTriggerEvent(context.Background(), campaign, *activation)
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, int64(13), status.Outputs["total"])
+}
+func TestParallelStagesMinSuccess(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "regions",
+		Stages: map[string]model.StageSpec{
+			"regions": {
+				StageSelector: "join",
+				Parallel: &model.ParallelSpec{
+					Branches:   []string{"east", "west", "north"},
+					MinSuccess: 2,
+				},
+			},
+			"east": {
+				Provider: "providers.stage.mock",
+			},
+			"west": {
+				Provider: "providers.stage.mock",
+			},
+			"north": {
+				Provider: "providers.stage.mock",
+				Inputs: map[string]interface{}{
+					"__status": 400,
+					"__error":  "bad",
+				},
+			},
+			"join": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	activation := &v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "regions",
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, *activation)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "join", status.NextStage)
+	assert.Equal(t, 2, status.Outputs["__succeeded"])
+	assert.Equal(t, 1, status.Outputs["__failed"])
+	north := status.Outputs["north"].(map[string]interface{})
+	assert.Equal(t, v1alpha2.BadRequest, north["__status"])
+	assert.Equal(t, "bad", north["__error"])
+	assert.Equal(t, v1alpha2.BadRequest, activation.Outputs["north"]["__status"])
+
+	// all branches are required by default
+	stage := campaign.Stages["regions"]
+	stage.Parallel.MinSuccess = 0
+	campaign.Stages["regions"] = stage
+	status, activation = manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "regions",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Equal(t, "stage regions failed", status.ErrorMessage)
+	assert.Equal(t, v1alpha2.InternalError, status.Outputs["__status"])
+	assert.Equal(t, "2 of 3 branches succeeded, 3 required (north: fake: bad)", status.Outputs["__error"])
+}
+func TestParallelStagesPausingBranch(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "regions",
+		Stages: map[string]model.StageSpec{
+			"regions": {
+				StageSelector: "join",
+				Parallel: &model.ParallelSpec{
+					Branches:   []string{"east", "west"},
+					MinSuccess: 1,
+				},
+			},
+			"east": {
+				Provider: "providers.stage.mock",
+			},
+			"west": {
+				Provider: "providers.stage.approval",
+				Inputs: map[string]interface{}{
+					"approvers": "alice",
+				},
+			},
+			"join": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "regions",
+	})
+	assert.NotNil(t, activation)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, 1, status.Outputs["__succeeded"])
+	assert.Equal(t, 1, status.Outputs["__failed"])
+	west := status.Outputs["west"].(map[string]interface{})
+	assert.Equal(t, v1alpha2.BadRequest, west["__status"])
+	assert.Equal(t, "provider providers.stage.approval can't pause in parallel stage regions", west["__error"])
+}
+func TestParallelStagesMissingBranch(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "regions",
+		Stages: map[string]model.StageSpec{
+			"regions": {
+				Parallel: &model.ParallelSpec{
+					Branches: []string{"east", "west"},
+				},
+			},
+			"east": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "regions",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+	assert.Equal(t, "branch stage west is not found", status.ErrorMessage)
+}
+func newForEachStageManager() StageManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	return manager
+}
+func TestForEachStage(t *testing.T) {
+	manager := newForEachStageManager()
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider: "providers.stage.mock",
+				ForEach: &model.ForEachSpec{
+					Items:       "${{$input(regions)}}",
+					Concurrency: 2,
+				},
+				Inputs: map[string]interface{}{
+					"region": "${{$item(name)}}",
+					"foo":    "${{$index()}}",
+				},
+				StageSelector: "verify",
+			},
+			"verify": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.mock",
+		Inputs: map[string]interface{}{
+			"regions": []interface{}{
+				map[string]interface{}{"name": "east"},
+				map[string]interface{}{"name": "west"},
+				map[string]interface{}{"name": "north"},
+			},
+		},
+	})
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "verify", status.NextStage)
+	assert.Equal(t, 3, status.Outputs["__succeeded"])
+	assert.Equal(t, 0, status.Outputs["__failed"])
+	assert.Equal(t, v1alpha2.OK, status.Outputs["__status"])
+	items := status.Outputs["items"].([]interface{})
+	assert.Equal(t, 3, len(items))
+	for i, region := range []string{"east", "west", "north"} {
+		item := items[i].(map[string]interface{})
+		assert.Equal(t, region, item["region"])
+		assert.Equal(t, int64(i+1), item["foo"])
+		assert.Equal(t, i, item["__index"])
+		assert.Equal(t, v1alpha2.OK, item["__status"])
+	}
+	// the next stage doesn't get the inputs of the items
+	assert.NotContains(t, activation.Inputs, "region")
+	assert.NotContains(t, activation.Inputs, "__item")
+}
+func TestForEachStageMinSuccess(t *testing.T) {
+	manager := newForEachStageManager()
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider: "providers.stage.mock",
+				ForEach: &model.ForEachSpec{
+					Items:      "${{$input(targets)}}",
+					MinSuccess: 1,
+				},
+				Inputs: map[string]interface{}{
+					"__status": "${{$item()}}",
+					"__error":  "failed",
+				},
+				StageSelector: "verify",
+			},
+			"verify": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	triggerData := v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.mock",
+		Inputs: map[string]interface{}{
+			"targets": map[string]interface{}{
+				"a": 200,
+				"b": 400,
+			},
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, triggerData)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "verify", status.NextStage)
+	assert.Equal(t, 1, status.Outputs["__succeeded"])
+	assert.Equal(t, 1, status.Outputs["__failed"])
+	items := status.Outputs["items"].([]interface{})
+	assert.Equal(t, "a", items[0].(map[string]interface{})["__index"])
+	failed := items[1].(map[string]interface{})
+	assert.Equal(t, "b", failed["__index"])
+	assert.Equal(t, v1alpha2.BadRequest, failed["__status"])
+	assert.Equal(t, "failed", failed["__error"])
+	assert.Equal(t, v1alpha2.BadRequest, activation.Outputs["deploy"]["items"].([]interface{})[1].(map[string]interface{})["__status"])
+
+	// all items are required by default
+	stage := campaign.Stages["deploy"]
+	stage.ForEach.MinSuccess = 0
+	campaign.Stages["deploy"] = stage
+	triggerData.Inputs = map[string]interface{}{
+		"targets": map[string]interface{}{
+			"a": 200,
+			"b": 400,
+		},
+	}
+	status, activation = manager.HandleTriggerEvent(context.Background(), campaign, triggerData)
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Equal(t, "stage deploy failed", status.ErrorMessage)
+	assert.Equal(t, "1 of 2 items succeeded, 2 required (b: failed)", status.Outputs["__error"])
+}
+func TestForEachStageItemsReadEvaluatedInputs(t *testing.T) {
+	manager := newForEachStageManager()
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider: "providers.stage.mock",
+				ForEach: &model.ForEachSpec{
+					Items: "${{$input(targets)}}",
+				},
+				Inputs: map[string]interface{}{
+					"targets": "${{$output(list,items)}}",
+					"target":  "${{$item()}}",
+				},
+			},
+		},
+	}
+	status, _ := manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.mock",
+		Outputs: map[string]map[string]interface{}{
+			"list": {
+				"items": []interface{}{"a", "b"},
+			},
+		},
+	})
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, 2, status.Outputs["__succeeded"])
+	items := status.Outputs["items"].([]interface{})
+	assert.Equal(t, "a", items[0].(map[string]interface{})["target"])
+	assert.Equal(t, "b", items[1].(map[string]interface{})["target"])
+}
+func TestForEachStageItems(t *testing.T) {
+	manager := newForEachStageManager()
+	items, err := manager.getForEachItems("${{$output(list,items)}}", map[string]interface{}{}, map[string]map[string]interface{}{
+		"list": {
+			"items": []model.InstanceState{
+				{Id: "instance1", Spec: &model.InstanceSpec{Name: "instance1"}},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, 0, items[0].index)
+	assert.Equal(t, "instance1", items[0].value.(map[string]interface{})["id"])
+
+	items, err = manager.getForEachItems("${{$input(none)}}", map[string]interface{}{"none": nil}, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+}
+func TestForEachStageInvalidItems(t *testing.T) {
+	manager := newForEachStageManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider: "providers.stage.mock",
+				ForEach: &model.ForEachSpec{
+					Items: "${{$input(count)}}",
+				},
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.mock",
+		Inputs: map[string]interface{}{
+			"count": 3,
+		},
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+	assert.Equal(t, "forEach items '${{$input(count)}}' evaluate to '3', which isn't a list or a map", status.ErrorMessage)
+}
+
+type flakyStageProvider struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return map[string]interface{}{
+			"__status": v1alpha2.InternalError,
+			"__error":  fmt.Sprintf("attempt %d failed", f.calls),
+		}, false, nil
+	}
+	return map[string]interface{}{"calls": f.calls}, false, nil
+}
+
+func TestProcessWithRetry(t *testing.T) {
+	manager := StageManager{}
+	manager.Context = &contexts.ManagerContext{}
+	provider := &flakyStageProvider{failures: 2}
+	result, pause := manager.processWithRetry(context.Background(), provider, map[string]interface{}{}, stagePolicy{
+		maxAttempts: 3,
+		backoff:     10 * time.Millisecond,
+	})
+	assert.False(t, pause)
+	assert.Nil(t, result.GetError())
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 3, result.Outputs["calls"])
+	assert.True(t, result.Duration >= 30*time.Millisecond)
+
+	provider = &flakyStageProvider{failures: 5}
+	result, _ = manager.processWithRetry(context.Background(), provider, map[string]interface{}{}, stagePolicy{
+		maxAttempts: 2,
+	})
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, "attempt 2 failed", result.GetError().Error())
+}
+
+type slowStageProvider struct {
+	delay   time.Duration
+	running int32
+	overlap int32
+	calls   int32
+}
+
+func (s *slowStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	atomic.AddInt32(&s.calls, 1)
+	if atomic.AddInt32(&s.running, 1) > 1 {
+		atomic.StoreInt32(&s.overlap, 1)
+	}
+	defer atomic.AddInt32(&s.running, -1)
+	// ignores ctx on purpose, like a provider that doesn't observe cancellation
+	time.Sleep(s.delay)
+	return map[string]interface{}{}, false, nil
+}
+
+func TestProcessWithRetryWaitsForTimedOutAttempt(t *testing.T) {
+	manager := StageManager{}
+	manager.Context = &contexts.ManagerContext{}
+	provider := &slowStageProvider{delay: 100 * time.Millisecond}
+	result, pause := manager.processWithRetry(context.Background(), provider, map[string]interface{}{}, stagePolicy{
+		maxAttempts: 3,
+		timeout:     10 * time.Millisecond,
+	})
+	assert.False(t, pause)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, "stage timed out after 10ms", result.GetError().Error())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&provider.calls))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&provider.overlap))
+	assert.True(t, result.Duration >= 200*time.Millisecond)
+}
+
+func TestStageTimeoutAndCompensation(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	campaign := model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider:      "providers.stage.delay",
+				StageSelector: "verify",
+				Inputs: map[string]interface{}{
+					"delay": "1s",
+				},
+				Timeout: "50ms",
+				RetryPolicy: &model.RetryPolicy{
+					MaxAttempts: 2,
+					Backoff:     "10ms",
+				},
+				Compensation: "rollback",
+			},
+			"verify": {
+				Provider: "providers.stage.mock",
+			},
+			"rollback": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.delay",
+	})
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "rollback", status.NextStage)
+	assert.Equal(t, "fake: stage timed out after 50ms", status.ErrorMessage)
+	assert.Equal(t, 2, status.Outputs["__attempts"])
+	assert.NotEmpty(t, status.Outputs["__duration"])
+	assert.Equal(t, "rollback", activation.Stage)
+
+	status, activation = manager.HandleTriggerEvent(context.Background(), campaign, *activation)
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "deploy", status.Outputs["__failedStage"])
+	assert.Equal(t, "fake: stage timed out after 50ms", status.Outputs["__failedError"])
+	assert.Equal(t, 1, status.Outputs["__attempts"])
+}
+func TestStageInvalidTimeout(t *testing.T) {
+	manager := newControlTestManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), model.CampaignSpec{
+		Name:        "test-campaign",
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Provider: "providers.stage.mock",
+				Timeout:  "soon",
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "deploy",
+		Provider:   "providers.stage.mock",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+	assert.Equal(t, "invalid timeout 'soon', expected a positive duration", status.ErrorMessage)
+}
+func newControlTestManager() StageManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	return manager
+}
+
+var controlTestCampaign = model.CampaignSpec{
+	Name:        "test-campaign",
+	SelfDriving: true,
+	FirstStage:  "build",
+	Stages: map[string]model.StageSpec{
+		"build": {
+			Provider:      "providers.stage.mock",
+			StageSelector: "approve",
+		},
+		"approve": {
+			Provider:      "providers.stage.approval",
+			StageSelector: "deploy",
+			Inputs: map[string]interface{}{
+				"approvers": "alice",
+			},
+		},
+		"deploy": {
+			Provider: "providers.stage.mock",
+		},
+	},
+}
+
+func TestPauseAndResumeActivation(t *testing.T) {
+	manager := newControlTestManager()
+	control := model.ActivationControl{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Action:               model.ActivationPause,
+	}
+	activation, err := manager.HandleControlEvent(context.Background(), control)
+	assert.Nil(t, err)
+	assert.Nil(t, activation)
+
+	trigger := v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "build",
+		Provider:             "providers.stage.mock",
+	}
+	status, activation := manager.HandleTriggerEvent(context.Background(), controlTestCampaign, trigger)
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	assert.False(t, status.IsActive)
+	assert.Nil(t, status.Outputs)
+
+	control.Action = model.ActivationResume
+	activation, err = manager.HandleControlEvent(context.Background(), control)
+	assert.Nil(t, err)
+	assert.NotNil(t, activation)
+	assert.Equal(t, "build", activation.Stage)
+
+	status, activation = manager.HandleTriggerEvent(context.Background(), controlTestCampaign, *activation)
+	assert.Equal(t, v1alpha2.Running, status.Status)
+	assert.Equal(t, "approve", activation.Stage)
+
+	// resuming again has nothing to run
+	activation, err = manager.HandleControlEvent(context.Background(), control)
+	assert.Nil(t, err)
+	assert.Nil(t, activation)
+}
+
+func TestCancelActivation(t *testing.T) {
+	manager := newControlTestManager()
+	control := model.ActivationControl{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Action:               model.ActivationCancel,
+	}
+	_, err := manager.HandleControlEvent(context.Background(), control)
+	assert.Nil(t, err)
+
+	status, activation := manager.HandleTriggerEvent(context.Background(), controlTestCampaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "build",
+		Provider:             "providers.stage.mock",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Cancelled, status.Status)
+	assert.False(t, status.IsActive)
+	_, err = manager.StateProvider.Get(context.Background(), states.GetRequest{ID: "test-campaign-test-activation-1-control"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestApprovalStage(t *testing.T) {
+	manager := newControlTestManager()
+	status, activation := manager.HandleTriggerEvent(context.Background(), controlTestCampaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "approve",
+		Provider:             "providers.stage.approval",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	assert.Equal(t, true, status.Outputs[model.ApprovalPendingOutput])
+	assert.Equal(t, []string{"alice"}, status.Outputs[model.ApproversOutput])
+
+	// the activations manager turns the decision into the report of the stage
+	report := status
+	report.Status = v1alpha2.Done
+	report.Outputs[model.ApprovedOutput] = true
+	report.Outputs[model.ApproverOutput] = "alice"
+	delete(report.Outputs, model.ApprovalPendingOutput)
+	activation, err := manager.ResumeStage(report, controlTestCampaign)
+	assert.Nil(t, err)
+	assert.Equal(t, "deploy", activation.Stage)
+	assert.Equal(t, true, activation.Outputs["approve"][model.ApprovedOutput])
+}
+
+func TestRejectApprovalStage(t *testing.T) {
+	manager := newControlTestManager()
+	status, _ := manager.HandleTriggerEvent(context.Background(), controlTestCampaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "approve",
+		Provider:             "providers.stage.approval",
+	})
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	_, err := manager.HandleControlEvent(context.Background(), model.ActivationControl{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Action:               model.ActivationReject,
+	})
+	assert.Nil(t, err)
+	_, err = manager.StateProvider.Get(context.Background(), states.GetRequest{ID: "test-campaign-test-activation-1"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
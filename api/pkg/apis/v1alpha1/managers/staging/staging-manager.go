@@ -67,12 +67,51 @@ func (s *StagingManager) Poll() []error {
 	if s.QueueProvider.Size(Site_Job_Queue) == 0 {
 		return nil
 	}
+	// with a queue that supports acknowledgements, the site stays queued until its catalogs are staged
+	if ackQueue, ok := s.QueueProvider.(queue.IAckQueueProvider); ok {
+		var message queue.QueueMessage
+		message, err = ackQueue.Receive(Site_Job_Queue, 0)
+		if err != nil {
+			if v1alpha2.IsNotFound(err) {
+				err = nil
+				return nil
+			}
+			log.Errorf(" M (Staging): Failed to poll: %s", err.Error())
+			return []error{err}
+		}
+		err = s.stageCatalogs(ctx, message.Element)
+		if err != nil {
+			if nErr := ackQueue.Nack(Site_Job_Queue, message.ID); nErr != nil {
+				log.Errorf(" M (Staging): Failed to return site to the queue: %s", nErr.Error())
+			}
+			return []error{err}
+		}
+		err = ackQueue.Ack(Site_Job_Queue, message.ID)
+		if err != nil {
+			log.Errorf(" M (Staging): Failed to acknowledge site: %s", err.Error())
+			return []error{err}
+		}
+		return nil
+	}
 	site, err := s.QueueProvider.Dequeue(Site_Job_Queue)
 	if err != nil {
 		log.Errorf(" M (Staging): Failed to poll: %s", err.Error())
 		return []error{err}
 	}
-	siteId := site.(string)
+	err = s.stageCatalogs(ctx, site)
+	if err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// stageCatalogs queues an update job for each catalog that changed since it was last staged for the site
+func (s *StagingManager) stageCatalogs(ctx context.Context, site interface{}) error {
+	siteId, ok := site.(string)
+	if !ok {
+		log.Errorf(" M (Staging): Invalid site in site job queue: %v", site)
+		return v1alpha2.NewCOAError(nil, "site job queue element is not a site", v1alpha2.BadRequest)
+	}
 	catalogs, err := utils.GetCatalogs(
 		ctx,
 		s.VendorContext.SiteInfo.CurrentSite.BaseUrl,
@@ -80,8 +119,7 @@ func (s *StagingManager) Poll() []error {
 		s.VendorContext.SiteInfo.CurrentSite.Password)
 	if err != nil {
 		log.Errorf(" M (Staging): Failed to get catalogs: %s", err.Error())
-		observ_utils.CloseSpanWithError(span, &err)
-		return []error{err}
+		return err
 	}
 	for _, catalog := range catalogs {
 		cacheId := siteId + "-" + catalog.Spec.Name
@@ -101,11 +139,15 @@ func (s *StagingManager) Poll() []error {
 		if err != nil && !v1alpha2.IsNotFound(err) {
 			log.Errorf(" M (Staging): Failed to get catalog %s: %s", catalog.Spec.Name, err.Error())
 		}
-		s.QueueProvider.Enqueue(siteId, v1alpha2.JobData{
+		err = s.QueueProvider.Enqueue(siteId, v1alpha2.JobData{
 			Id:     catalog.Spec.Name,
 			Action: "UPDATE",
 			Body:   catalog,
 		})
+		if err != nil {
+			log.Errorf(" M (Staging): Failed to queue catalog %s: %s", catalog.Spec.Name, err.Error())
+			return err
+		}
 		_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
 			Value: states.StateEntry{
 				ID:   cacheId,
@@ -144,28 +186,117 @@ func (s *StagingManager) HandleJobEvent(ctx context.Context, event v1alpha2.Even
 	s.QueueProvider.Enqueue(Site_Job_Queue, event.Metadata["site"])
 	return s.QueueProvider.Enqueue(event.Metadata["site"], job)
 }
+
+// GetABatchForSite takes up to count jobs for a site off its queue.
 func (s *StagingManager) GetABatchForSite(site string, count int) ([]v1alpha2.JobData, error) {
-	//TODO: this should return a group of jobs as optimization
+	items, ids, err := s.LeaseABatchForSite(site, count)
+	if err != nil {
+		return nil, err
+	}
+	err = s.AckBatch(site, ids)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// LeaseABatchForSite returns up to count jobs for a site. With a queue provider that supports
+// acknowledgements, the jobs stay on the queue, hidden, until they are acknowledged with AckBatch
+// or returned with NackBatch. Jobs that are never acknowledged are delivered again after the
+// queue's visibility timeout. Otherwise, the jobs are taken off the queue and no IDs are returned.
+func (s *StagingManager) LeaseABatchForSite(site string, count int) ([]v1alpha2.JobData, []string, error) {
 	s.QueueProvider.Enqueue(Site_Job_Queue, site)
 	if s.QueueProvider.Size(site) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 	items := []v1alpha2.JobData{}
-	itemCount := 0
-	for {
-		queueElement, err := s.QueueProvider.Dequeue(site)
+	ackQueue, ok := s.QueueProvider.(queue.IAckQueueProvider)
+	if !ok {
+		for {
+			queueElement, err := s.QueueProvider.Dequeue(site)
+			if err != nil {
+				return nil, nil, err
+			}
+			if job, ok := toJobData(queueElement); ok {
+				items = append(items, job)
+			} else {
+				s.QueueProvider.Enqueue(site, queueElement)
+			}
+			if len(items) == count || s.QueueProvider.Size(site) == 0 {
+				break
+			}
+		}
+		return items, nil, nil
+	}
+	ids := []string{}
+	skipped := []string{}
+	for len(items) < count {
+		message, err := ackQueue.Receive(site, 0)
 		if err != nil {
-			return nil, err
+			if v1alpha2.IsNotFound(err) {
+				break
+			}
+			s.NackBatch(site, append(ids, skipped...))
+			return nil, nil, err
 		}
-		if job, ok := queueElement.(v1alpha2.JobData); ok {
+		if job, ok := toJobData(message.Element); ok {
 			items = append(items, job)
-			itemCount++
+			ids = append(ids, message.ID)
 		} else {
-			s.QueueProvider.Enqueue(site, queueElement)
+			skipped = append(skipped, message.ID)
 		}
-		if itemCount == count || s.QueueProvider.Size(site) == 0 {
-			break
+	}
+	// elements that aren't jobs are left for other consumers of the queue
+	s.NackBatch(site, skipped)
+	return items, ids, nil
+}
+
+// AckBatch removes the jobs of a leased batch from the site's queue once they are processed.
+// Jobs that are already acknowledged are skipped, so a batch can be acknowledged again.
+func (s *StagingManager) AckBatch(site string, ids []string) error {
+	ackQueue, ok := s.QueueProvider.(queue.IAckQueueProvider)
+	if !ok {
+		return nil
+	}
+	for _, id := range ids {
+		if err := ackQueue.Ack(site, id); err != nil {
+			if v1alpha2.IsNotFound(err) {
+				continue
+			}
+			log.Errorf(" M (Staging): Failed to acknowledge job %s of site %s: %s", id, site, err.Error())
+			return err
 		}
 	}
-	return items, nil
+	return nil
+}
+
+// NackBatch returns the jobs of a leased batch that failed to process to the site's queue.
+func (s *StagingManager) NackBatch(site string, ids []string) error {
+	ackQueue, ok := s.QueueProvider.(queue.IAckQueueProvider)
+	if !ok {
+		return nil
+	}
+	var ret error
+	for _, id := range ids {
+		if err := ackQueue.Nack(site, id); err != nil {
+			log.Errorf(" M (Staging): Failed to return job %s of site %s: %s", id, site, err.Error())
+			ret = err
+		}
+	}
+	return ret
+}
+
+// toJobData accepts jobs as they were queued, or as generic JSON values read back from a durable queue
+func toJobData(element interface{}) (v1alpha2.JobData, bool) {
+	if job, ok := element.(v1alpha2.JobData); ok {
+		return job, true
+	}
+	if m, ok := element.(map[string]interface{}); ok {
+		var job v1alpha2.JobData
+		jData, _ := json.Marshal(m)
+		if json.Unmarshal(jData, &job) == nil && job.Id != "" {
+			return job, true
+		}
+	}
+	return v1alpha2.JobData{}, false
 }
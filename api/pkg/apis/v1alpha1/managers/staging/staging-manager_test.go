@@ -11,11 +11,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	filequeue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/file"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
@@ -136,6 +139,87 @@ func TestGetABatchForSite(t *testing.T) {
 	assert.Equal(t, "catalog2", jobs[0].Id)
 	assert.Equal(t, "UPDATE", jobs[0].Action)
 }
+func TestLeaseABatchForSiteWithAck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	queueProvider := &filequeue.FileQueueProvider{}
+	err := queueProvider.Init(filequeue.FileQueueProviderConfig{Path: path, VisibilityTimeout: "50ms"})
+	assert.Nil(t, err)
+	manager := StagingManager{
+		QueueProvider: queueProvider,
+	}
+	queueProvider.Enqueue("fake", v1alpha2.JobData{Id: "catalog1", Action: "UPDATE"})
+	queueProvider.Enqueue("fake", "not a job")
+	queueProvider.Enqueue("fake", v1alpha2.JobData{Id: "catalog2", Action: "UPDATE"})
+
+	jobs, ids, err := manager.LeaseABatchForSite("fake", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, 2, len(ids))
+	assert.Equal(t, "catalog1", jobs[0].Id)
+	assert.Equal(t, "catalog2", jobs[1].Id)
+	// the leased jobs are hidden, only the element that isn't a job is left
+	assert.Equal(t, 1, queueProvider.Size("fake"))
+
+	// the batch wasn't acknowledged, so the jobs come back after the visibility timeout, even after a restart
+	time.Sleep(100 * time.Millisecond)
+	queueProvider = &filequeue.FileQueueProvider{}
+	err = queueProvider.Init(filequeue.FileQueueProviderConfig{Path: path})
+	assert.Nil(t, err)
+	manager.QueueProvider = queueProvider
+	jobs, ids, err = manager.LeaseABatchForSite("fake", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "catalog1", jobs[0].Id)
+	assert.Nil(t, manager.AckBatch("fake", ids))
+	// acknowledging the batch again is a no-op
+	assert.Nil(t, manager.AckBatch("fake", ids))
+
+	jobs, ids, err = manager.LeaseABatchForSite("fake", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "catalog2", jobs[0].Id)
+	assert.Nil(t, manager.NackBatch("fake", ids))
+	jobs, err = manager.GetABatchForSite("fake", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "catalog2", jobs[0].Id)
+	assert.Equal(t, 1, queueProvider.Size("fake"))
+}
+func TestPollWithAck(t *testing.T) {
+	ts := InitializeMockSymphonyAPI()
+	queueProvider := &filequeue.FileQueueProvider{}
+	err := queueProvider.Init(filequeue.FileQueueProviderConfig{Path: filepath.Join(t.TempDir(), "queue.json")})
+	assert.Nil(t, err)
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	manager := StagingManager{
+		StateProvider: stateProvider,
+		QueueProvider: queueProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+			CurrentSite: v1alpha2.SiteConnection{
+				BaseUrl:  "http://127.0.0.1:0/",
+				Username: "admin",
+			},
+		},
+	}
+	queueProvider.Enqueue("site-job-queue", "fake")
+
+	// the catalogs can't be fetched, so the site stays queued
+	errList := manager.Poll()
+	assert.Equal(t, 1, len(errList))
+	assert.Equal(t, 1, queueProvider.Size("site-job-queue"))
+
+	manager.VendorContext.SiteInfo.CurrentSite.BaseUrl = ts.URL + "/"
+	errList = manager.Poll()
+	assert.Nil(t, errList)
+	assert.Equal(t, 0, queueProvider.Size("site-job-queue"))
+	jobs, err := manager.GetABatchForSite("fake", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "catalog1", jobs[0].Id)
+}
 
 type AuthResponse struct {
 	AccessToken string   `json:"accessToken"`
@@ -8,7 +8,9 @@ package sync
 
 import (
 	"context"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -16,10 +18,17 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
+var log = logger.NewLogger("coa.runtime")
+
+const watchRetryInterval = 5 * time.Second
+
 type SyncManager struct {
 	managers.Manager
+	WatchProvider states.IWatchableStateProvider
 }
 
 func (s *SyncManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -30,8 +39,82 @@ func (s *SyncManager) Init(context *contexts.VendorContext, config managers.Mana
 	if s.Context.SiteInfo.SiteId == "" {
 		return v1alpha2.NewCOAError(nil, "siteId is required", v1alpha2.BadConfig)
 	}
+
+	// When a watchable state provider sharing the store of the parent site is configured, the parent
+	// site is polled right away when one of its catalogs changes, in addition to the periodical polls.
+	if watchProviderName, ok := config.Properties["providers.watch"]; ok {
+		provider, ok := providers[watchProviderName]
+		if !ok {
+			return v1alpha2.NewCOAError(nil, "watch provider is not supplied", v1alpha2.MissingConfig)
+		}
+		watchProvider, ok := provider.(states.IWatchableStateProvider)
+		if !ok {
+			return v1alpha2.NewCOAError(nil, "supplied provider is not a watchable state provider", v1alpha2.BadConfig)
+		}
+		if sharedProvider, ok := provider.(states.ISharedStateProvider); !ok || !sharedProvider.IsShared() {
+			return v1alpha2.NewCOAError(nil, "supplied watch provider doesn't have a shared store", v1alpha2.BadConfig)
+		}
+		s.WatchProvider = watchProvider
+		if s.Enabled() {
+			s.startWatch()
+		}
+	}
 	return nil
 }
+
+func (s *SyncManager) startWatch() {
+	go s.watchCatalogs(context.Background())
+}
+
+// watchCatalogs polls the parent site whenever the watch provider reports catalog changes. Changes
+// that arrive while a poll is running are handled by a single poll. The watch is re-established when
+// the change feed closes.
+func (s *SyncManager) watchCatalogs(ctx context.Context) {
+	resourceVersion := ""
+	for {
+		ch, err := s.WatchProvider.Watch(ctx, states.WatchRequest{
+			ResourceVersion: resourceVersion,
+			Metadata: map[string]string{
+				"group":    model.FederationGroup,
+				"version":  "v1",
+				"resource": "catalogs",
+			},
+		})
+		if err != nil {
+			log.Errorf(" M (Sync): failed to watch catalogs: %+v", err)
+			resourceVersion = ""
+		} else {
+			received := false
+			for event := range ch {
+				received = true
+				resourceVersion = event.ResourceVersion
+			drain:
+				for {
+					select {
+					case next, ok := <-ch:
+						if !ok {
+							break drain
+						}
+						resourceVersion = next.ResourceVersion
+					default:
+						break drain
+					}
+				}
+				if errs := s.Poll(); len(errs) > 0 {
+					log.Errorf(" M (Sync): failed to poll the parent site: %+v", errs)
+				}
+			}
+			if !received {
+				resourceVersion = ""
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
 func (s *SyncManager) Enabled() bool {
 	return s.Config.Properties["sync.enabled"] == "true"
 }
@@ -46,16 +129,15 @@ func (s *SyncManager) Poll() []error {
 	}
 	batch, err := utils.GetABatchForSite(
 		ctx,
-		s.VendorContext.SiteInfo.ParentSite.BaseUrl,
-		s.VendorContext.SiteInfo.SiteId,
-		s.VendorContext.SiteInfo.ParentSite.Username,
-		s.VendorContext.SiteInfo.ParentSite.Password)
+		s.VendorContext.SiteInfo.ParentSite,
+		s.VendorContext.SiteInfo.SiteId)
 	if err != nil {
 		return []error{err}
 	}
+	errs := []error{}
 	if batch.Catalogs != nil {
 		for _, catalog := range batch.Catalogs {
-			s.Context.Publish("catalog-sync", v1alpha2.Event{
+			err = s.Context.Publish("catalog-sync", v1alpha2.Event{
 				Metadata: map[string]string{
 					"objectType": catalog.Type,
 				},
@@ -65,20 +147,38 @@ func (s *SyncManager) Poll() []error {
 					Body:   catalog,
 				},
 			})
+			if err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 	if batch.Jobs != nil {
 		for _, job := range batch.Jobs {
-			s.Context.Publish("remote-job", v1alpha2.Event{
+			err = s.Context.Publish("remote-job", v1alpha2.Event{
 				Metadata: map[string]string{
 					"origin": batch.Origin,
 				},
 				Body: job,
 			})
+			if err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
-	if err != nil {
-		return []error{err}
+	if len(errs) > 0 {
+		// the batch isn't acknowledged, so the parent site delivers it again
+		err = errs[0]
+		return errs
+	}
+	if len(batch.Leases) > 0 {
+		err = utils.AckABatchForSite(
+			ctx,
+			s.VendorContext.SiteInfo.ParentSite,
+			s.VendorContext.SiteInfo.SiteId,
+			batch.Leases)
+		if err != nil {
+			return []error{err}
+		}
 	}
 	return nil
 }
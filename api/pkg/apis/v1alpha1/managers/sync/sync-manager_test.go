@@ -7,18 +7,24 @@
 package sync
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -97,6 +103,11 @@ type AuthResponse struct {
 }
 
 func InitiazlizeMockSymphonyAPI(siteId string) *httptest.Server {
+	return initializeMockSymphonyAPIWithAcks(siteId, nil)
+}
+
+// initializeMockSymphonyAPIWithAcks sends the leases each sync batch is acknowledged with to acks
+func initializeMockSymphonyAPIWithAcks(siteId string, acks chan []string) *httptest.Server {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var response interface{}
 		fmt.Println("Mock Symphony API called", "path", r.URL.Path)
@@ -123,6 +134,14 @@ func InitiazlizeMockSymphonyAPI(siteId string) *httptest.Server {
 					},
 				},
 				Origin: "batch-origin",
+				Leases: []string{"lease1", "lease2"},
+			}
+		case "/federation/ack/" + siteId:
+			var leases []string
+			json.NewDecoder(r.Body).Decode(&leases)
+			select {
+			case acks <- leases:
+			default:
 			}
 		case "/users/auth":
 			response = AuthResponse{
@@ -140,7 +159,8 @@ func InitiazlizeMockSymphonyAPI(siteId string) *httptest.Server {
 
 func TestPoll(t *testing.T) {
 	siteId := "fake"
-	ts := InitiazlizeMockSymphonyAPI(siteId)
+	acks := make(chan []string, 1)
+	ts := initializeMockSymphonyAPIWithAcks(siteId, acks)
 	defer ts.Close()
 	_, err := url.Parse(ts.URL)
 	assert.Nil(t, err)
@@ -197,4 +217,104 @@ func TestPoll(t *testing.T) {
 	assert.Equal(t, 1, jobCount)
 	assert.Equal(t, "catalog1", catalog1.Name)
 	assert.Equal(t, "job1", job1.Id)
+	// the batch is acknowledged once it's published
+	assert.Equal(t, []string{"lease1", "lease2"}, <-acks)
+}
+
+// sharedFileStateProvider stands in for a state provider with a shared store, such as the k8s state provider
+type sharedFileStateProvider struct {
+	filestate.FileStateProvider
+}
+
+func (s *sharedFileStateProvider) IsShared() bool {
+	return true
+}
+
+func TestPollOnCatalogChange(t *testing.T) {
+	siteId := "fake"
+	ts := InitiazlizeMockSymphonyAPI(siteId)
+	defer ts.Close()
+
+	watchProvider := &sharedFileStateProvider{}
+	err := watchProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+
+	manager := SyncManager{}
+	vendorContext := &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: siteId,
+			ParentSite: v1alpha2.SiteConnection{
+				BaseUrl:  ts.URL + "/",
+				Username: "admin",
+				Password: "",
+			},
+		},
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+	catalogs := make(chan v1alpha2.Event, 10)
+	vendorContext.Subscribe("catalog-sync", func(topic string, event v1alpha2.Event) error {
+		catalogs <- event
+		return nil
+	})
+	err = manager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"sync.enabled":    "true",
+			"providers.watch": "watch",
+		},
+	}, map[string]providers.IProvider{
+		"watch": watchProvider,
+	})
+	assert.Nil(t, err)
+
+	// the watch is established asynchronously, keep writing until the parent site is polled
+	for received := false; !received; {
+		_, err = watchProvider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   "catalog1",
+				Body: map[string]interface{}{"spec": map[string]interface{}{}},
+			},
+			Metadata: map[string]string{
+				"group":    model.FederationGroup,
+				"version":  "v1",
+				"resource": "catalogs",
+			},
+		})
+		assert.Nil(t, err)
+		select {
+		case event := <-catalogs:
+			assert.Equal(t, "catalog1", event.Body.(v1alpha2.JobData).Id)
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestInitWithUnsharedWatchProvider(t *testing.T) {
+	watchProvider := &filestate.FileStateProvider{}
+	err := watchProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+	manager := SyncManager{}
+	err = manager.Init(&contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+		Logger: logger.NewLogger("coa.runtime"),
+	}, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.watch": "watch",
+		},
+	}, map[string]providers.IProvider{
+		"watch": watchProvider,
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
 }
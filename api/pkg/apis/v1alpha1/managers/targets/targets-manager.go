@@ -168,8 +168,15 @@ func (t *TargetsManager) ReportState(ctx context.Context, current model.TargetSt
 	}, nil
 }
 func (t *TargetsManager) ListSpec(ctx context.Context, scope string) ([]model.TargetState, error) {
+	ret, _, err := t.ListSpecPage(ctx, scope, 0, "")
+	return ret, err
+}
+
+// ListSpecPage lists at most limit targets, starting from continueToken. It returns the token
+// for the next page, which is empty on the last page. A limit of 0 lists everything.
+func (t *TargetsManager) ListSpecPage(ctx context.Context, scope string, limit int64, continueToken string) ([]model.TargetState, string, error) {
 	ctx, span := observability.StartSpan("Targets Manager", ctx, &map[string]string{
-		"method": "ListSpec",
+		"method": "ListSpecPage",
 	})
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
@@ -181,21 +188,23 @@ func (t *TargetsManager) ListSpec(ctx context.Context, scope string) ([]model.Ta
 			"resource": "targets",
 			"scope":    scope,
 		},
+		Limit:    limit,
+		Continue: continueToken,
 	}
-	targets, _, err := t.StateProvider.List(ctx, listRequest)
+	targets, token, err := t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.TargetState, 0)
 	for _, t := range targets {
 		var rt model.TargetState
 		rt, err = getTargetState(t.ID, t.Body, t.ETag)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, token, nil
 }
 
 func getTargetState(id string, body interface{}, etag string) (model.TargetState, error) {
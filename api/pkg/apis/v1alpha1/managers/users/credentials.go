@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package users
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id parameters of new password hashes. Hashes made with other parameters still verify, and are
+// upgraded on the next successful login.
+const (
+	argon2Time    uint32 = 3
+	argon2Memory  uint32 = 64 * 1024
+	argon2Threads uint8  = 2
+	argon2KeyLen  uint32 = 32
+	saltLen              = 16
+)
+
+// hashPassword hashes a password with argon2id and a random salt, encoded in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword checks a password against a stored hash. It also tells if the hash needs to be upgraded,
+// which is the case of legacy hashes and of hashes made with other parameters.
+func verifyPassword(name string, password string, encoded string) (bool, bool) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		ok := subtle.ConstantTimeCompare([]byte(legacyHash(name, password)), []byte(encoded)) == 1
+		return ok, ok
+	}
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false
+	}
+	check := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, check) != 1 {
+		return false, false
+	}
+	upgrade := memory != argon2Memory || time != argon2Time || threads != argon2Threads || uint32(len(key)) != argon2KeyLen
+	return true, upgrade
+}
+
+var (
+	dummyOnce    sync.Once
+	dummyEncoded string
+)
+
+// dummyHash is a hash of a random password that the passwords of unknown users are verified against,
+// so that a login of an unknown user costs as much as the login of a user with a wrong password
+func dummyHash() string {
+	dummyOnce.Do(func() {
+		b := make([]byte, saltLen)
+		rand.Read(b)
+		dummyEncoded, _ = hashPassword(base64.RawStdEncoding.EncodeToString(b))
+	})
+	return dummyEncoded
+}
+
+// legacyHash is how passwords were hashed before argon2id was used
+func legacyHash(name string, s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name + "." + s + ".salt"))
+	return fmt.Sprintf("H%d", h.Sum32())
+}
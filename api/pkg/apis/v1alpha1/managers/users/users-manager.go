@@ -8,9 +8,15 @@ package users
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -18,19 +24,38 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
 )
 
 var log = logger.NewLogger("coa.runtime")
 
+const (
+	DEFAULT_LOCKOUT_ATTEMPTS = 5
+	DEFAULT_LOCKOUT_DURATION = 15 * time.Minute
+	// userLockCount is the number of locks that serialize the changes to users, picked by the hash of their names
+	userLockCount = 64
+	// maxUpdateAttempts is how many times a change to a user is applied when other replicas change the user
+	maxUpdateAttempts = 3
+)
+
 type UsersManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
+	// LockoutAttempts is the number of failed logins in a row that lock a user, 0 disables lockout
+	LockoutAttempts int
+	LockoutDuration time.Duration
+	userLocks       [userLockCount]sync.Mutex
 }
 
 type UserState struct {
-	Id           string   `json:"id"`
-	PasswordHash string   `json:"passwordHash,omitempty"`
-	Roles        []string `json:"roles,omitempty"`
+	Id             string     `json:"id"`
+	PasswordHash   string     `json:"passwordHash,omitempty"`
+	Roles          []string   `json:"roles,omitempty"`
+	FailedAttempts int        `json:"failedAttempts,omitempty"`
+	LockedUntil    *time.Time `json:"lockedUntil,omitempty"`
+	// TokenGeneration is carried by the refresh tokens of the user. It changes when the user is
+	// replaced, changes its password or is locked out, which revokes the refresh tokens issued before.
+	TokenGeneration string `json:"tokenGeneration,omitempty"`
 }
 
 func (s *UsersManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -42,6 +67,22 @@ func (s *UsersManager) Init(context *contexts.VendorContext, config managers.Man
 		return err
 	}
 
+	s.LockoutAttempts = DEFAULT_LOCKOUT_ATTEMPTS
+	if v, ok := config.Properties["lockout.attempts"]; ok {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts < 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid lockout.attempts '%s', expected a non-negative integer", v), v1alpha2.BadConfig)
+		}
+		s.LockoutAttempts = attempts
+	}
+	s.LockoutDuration = DEFAULT_LOCKOUT_DURATION
+	if v, ok := config.Properties["lockout.duration"]; ok {
+		duration, err := time.ParseDuration(v)
+		if err != nil || duration <= 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid lockout.duration '%s', expected a positive duration", v), v1alpha2.BadConfig)
+		}
+		s.LockoutDuration = duration
+	}
 	return nil
 }
 func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
@@ -52,6 +93,7 @@ func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	defer observ_utils.CloseSpanWithError(span, &err)
 	log.Infof(" M (Users): DeleteUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
 
+	defer t.lockUser(name).Unlock()
 	err = t.StateProvider.Delete(ctx, states.DeleteRequest{
 		ID: name,
 	})
@@ -62,12 +104,6 @@ func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	return nil
 }
 
-func hash(name string, s string) string {
-	h := fnv.New32a()
-	h.Write([]byte(name + "." + s + ".salt"))
-	return fmt.Sprintf("H%d", h.Sum32())
-}
-
 func (t *UsersManager) UpsertUser(ctx context.Context, name string, password string, roles []string) error {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "UpsertUser",
@@ -76,46 +112,337 @@ func (t *UsersManager) UpsertUser(ctx context.Context, name string, password str
 	defer observ_utils.CloseSpanWithError(span, &err)
 	log.Infof(" M (Users): UpsertUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
 
-	upsertRequest := states.UpsertRequest{
-		Value: states.StateEntry{
-			ID: name,
-			Body: UserState{
-				Id:           name,
-				PasswordHash: hash(name, password),
-				Roles:        roles,
-			},
-		},
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		log.Errorf(" M (Users) : failed to hash password %v, traceId: %s", err, span.SpanContext().TraceID().String())
+		return err
 	}
-	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	defer t.lockUser(name).Unlock()
+	err = t.saveUser(ctx, UserState{
+		Id:              name,
+		PasswordHash:    passwordHash,
+		Roles:           roles,
+		TokenGeneration: uuid.New().String(),
+	}, "")
 	if err != nil {
 		log.Debugf(" M (Users) : failed to upsert user %v, traceId: %s", err, span.SpanContext().TraceID().String())
 		return err
 	}
 	return nil
 }
-func (t *UsersManager) CheckUser(ctx context.Context, name string, password string) ([]string, bool) {
+
+// GetUser returns a user, without its password hash
+func (t *UsersManager) GetUser(ctx context.Context, name string) (UserState, error) {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
-		"method": "CheckUser",
+		"method": "GetUser",
 	})
 	var err error = nil
 	defer observ_utils.CloseSpanWithError(span, &err)
-	log.Infof(" M (Users): CheckUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+	log.Infof(" M (Users): GetUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
 
-	getRequest := states.GetRequest{
-		ID: name,
+	user, _, err := t.getUser(ctx, name)
+	if err != nil {
+		return UserState{}, err
 	}
-	user, err := t.StateProvider.Get(ctx, getRequest)
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// ListUsers returns all users sorted by name, without their password hashes
+func (t *UsersManager) ListUsers(ctx context.Context) ([]UserState, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ListUsers",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): ListUsers, traceId: %s", span.SpanContext().TraceID().String())
+
+	entries, _, err := t.StateProvider.List(ctx, states.ListRequest{})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]UserState, 0, len(entries))
+	for _, entry := range entries {
+		var user UserState
+		user, err = toUserState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = ""
+		ret = append(ret, user)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Id < ret[j].Id
+	})
+	return ret, nil
+}
+
+// SetRoles replaces the roles of a user
+func (t *UsersManager) SetRoles(ctx context.Context, name string, roles []string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "SetRoles",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): SetRoles name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+
+	defer t.lockUser(name).Unlock()
+	_, err = t.updateUser(ctx, name, func(user *UserState) (bool, error) {
+		user.Roles = roles
+		return true, nil
+	})
+	return err
+}
+
+// ChangePassword sets a new password for a user who knows the current password. A wrong password
+// counts as a failed login.
+func (t *UsersManager) ChangePassword(ctx context.Context, name string, password string, newPassword string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ChangePassword",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): ChangePassword name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	defer t.lockUser(name).Unlock()
+	verified, err := t.authenticate(ctx, name, password)
+	if err != nil {
+		return err
+	}
+	_, err = t.updateUser(ctx, name, func(user *UserState) (bool, error) {
+		if user.PasswordHash != verified.PasswordHash {
+			return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("password of user %s has been changed", name), v1alpha2.Conflict)
+		}
+		user.PasswordHash = passwordHash
+		user.TokenGeneration = uuid.New().String()
+		return true, nil
+	})
+	return err
+}
+
+// UnlockUser clears the failed logins of a user and lifts its lockout
+func (t *UsersManager) UnlockUser(ctx context.Context, name string) error {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "UnlockUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): UnlockUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+
+	defer t.lockUser(name).Unlock()
+	_, err = t.updateUser(ctx, name, func(user *UserState) (bool, error) {
+		user.FailedAttempts = 0
+		user.LockedUntil = nil
+		return true, nil
+	})
+	return err
+}
+
+func (t *UsersManager) CheckUser(ctx context.Context, name string, password string) ([]string, bool) {
+	user, err := t.AuthenticateUser(ctx, name, password)
 	if err != nil {
-		log.Debugf(" M (Users) : failed to get user %s states, traceId: %s", err, span.SpanContext().TraceID().String())
 		return nil, false
 	}
+	return user.Roles, true
+}
 
-	if v, ok := user.Body.(UserState); ok {
-		if hash(name, password) == v.PasswordHash {
-			log.Debugf(" M (Users) : user authenticated, traceId: %s", span.SpanContext().TraceID().String())
-			return v.Roles, true
+// AuthenticateUser checks the password of a user and returns the user, without its password hash
+func (t *UsersManager) AuthenticateUser(ctx context.Context, name string, password string) (UserState, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "AuthenticateUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): AuthenticateUser name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+
+	defer t.lockUser(name).Unlock()
+	user, err := t.authenticate(ctx, name, password)
+	if err != nil {
+		log.Debugf(" M (Users) : authentication failed %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		return UserState{}, err
+	}
+	log.Debugf(" M (Users) : user authenticated, traceId: %s", span.SpanContext().TraceID().String())
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// CheckRefresh checks that a user can still be issued tokens with a refresh token of a token
+// generation: the user must exist, must not be locked out, and must not have revoked the generation.
+// It returns the user, without its password hash.
+func (t *UsersManager) CheckRefresh(ctx context.Context, name string, generation string) (UserState, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "CheckRefresh",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	log.Infof(" M (Users): CheckRefresh name %s, traceId: %s", name, span.SpanContext().TraceID().String())
+
+	user, _, err := t.getUser(ctx, name)
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("user %s is not found", name), v1alpha2.Unauthorized)
+		}
+		return UserState{}, err
+	}
+	if user.LockedUntil != nil && time.Now().UTC().Before(*user.LockedUntil) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("user %s is locked until %s", name, user.LockedUntil.Format(time.RFC3339)), v1alpha2.Unauthorized)
+		return UserState{}, err
+	}
+	if user.TokenGeneration != generation {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("tokens of user %s are revoked", name), v1alpha2.Unauthorized)
+		return UserState{}, err
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// authenticate checks the password of a user, keeping track of failed logins and upgrading the password
+// hash if needed. The caller must hold the lock of the user.
+//
+// Every failure returns the same error, and the password is verified before anything else is checked,
+// against a dummy hash for unknown users, so that callers can't tell which users exist.
+func (t *UsersManager) authenticate(ctx context.Context, name string, password string) (UserState, error) {
+	user, _, err := t.getUser(ctx, name)
+	if err != nil {
+		verifyPassword(name, password, dummyHash())
+		log.Debugf(" M (Users) : login of user %s failed: %s", name, err.Error())
+		return UserState{}, loginFailed()
+	}
+	// the password is verified once, and the outcome is recorded in the user unless its password has
+	// been changed in the meantime
+	verifiedHash := user.PasswordHash
+	ok, upgrade := verifyPassword(name, password, verifiedHash)
+	if err = checkLockout(user); err != nil {
+		return UserState{}, err
+	}
+	upgradedHash := ""
+	if ok && upgrade {
+		if upgradedHash, err = hashPassword(password); err != nil {
+			return UserState{}, err
+		}
+	}
+	user, err = t.updateUser(ctx, name, func(user *UserState) (bool, error) {
+		if user.PasswordHash != verifiedHash {
+			return false, loginFailed()
+		}
+		if err := checkLockout(*user); err != nil {
+			return false, err
+		}
+		if !ok {
+			user.FailedAttempts++
+			if t.LockoutAttempts > 0 && user.FailedAttempts >= t.LockoutAttempts {
+				lockedUntil := time.Now().UTC().Add(t.LockoutDuration)
+				user.LockedUntil = &lockedUntil
+				user.FailedAttempts = 0
+				user.TokenGeneration = uuid.New().String()
+				log.Infof(" M (Users) : user %s is locked after %d failed logins", name, t.LockoutAttempts)
+			}
+			return true, nil
+		}
+		save := upgradedHash != "" || user.FailedAttempts > 0 || user.LockedUntil != nil
+		if upgradedHash != "" {
+			user.PasswordHash = upgradedHash
+		}
+		user.FailedAttempts = 0
+		user.LockedUntil = nil
+		return save, nil
+	})
+	if err != nil {
+		return UserState{}, err
+	}
+	if !ok {
+		return UserState{}, loginFailed()
+	}
+	return user, nil
+}
+
+// checkLockout fails the login of a locked user with the same error as a wrong password
+func checkLockout(user UserState) error {
+	if user.LockedUntil != nil && time.Now().UTC().Before(*user.LockedUntil) {
+		log.Debugf(" M (Users) : login of user %s failed: locked until %s", user.Id, user.LockedUntil.Format(time.RFC3339))
+		return loginFailed()
+	}
+	return nil
+}
+
+func loginFailed() error {
+	return v1alpha2.NewCOAError(nil, "login failed", v1alpha2.Unauthorized)
+}
+
+// lockUser locks the changes to a user in this replica, so that logins of a user don't wait for the
+// password checks of other users. Changes of other replicas are detected by the ETags of users.
+func (t *UsersManager) lockUser(name string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	l := &t.userLocks[h.Sum32()%userLockCount]
+	l.Lock()
+	return l
+}
+
+// updateUser applies a change to a user and saves the user if the change asks to. The user is saved
+// with the ETag it was read with, so that state providers that check ETags reject the save when another
+// replica has changed the user in the meantime; the change is then applied to the user again.
+func (t *UsersManager) updateUser(ctx context.Context, name string, change func(user *UserState) (bool, error)) (UserState, error) {
+	for attempt := 1; ; attempt++ {
+		user, etag, err := t.getUser(ctx, name)
+		if err != nil {
+			return UserState{}, err
+		}
+		save, err := change(&user)
+		if err != nil || !save {
+			return user, err
+		}
+		err = t.saveUser(ctx, user, etag)
+		if err == nil || !v1alpha2.IsConflict(err) || attempt == maxUpdateAttempts {
+			return user, err
+		}
+	}
+}
+
+// getUser returns a user and the ETag of its entry
+func (t *UsersManager) getUser(ctx context.Context, name string) (UserState, string, error) {
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID: name,
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			return UserState{}, "", v1alpha2.NewCOAError(nil, fmt.Sprintf("user %s is not found", name), v1alpha2.NotFound)
 		}
+		return UserState{}, "", err
+	}
+	user, err := toUserState(entry.Body)
+	return user, entry.ETag, err
+}
+
+// saveUser saves a user. With an ETag, the user is only saved if its entry still has the ETag.
+func (t *UsersManager) saveUser(ctx context.Context, user UserState, etag string) error {
+	request := states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   user.Id,
+			Body: user,
+		},
+	}
+	if etag != "" {
+		request.ETag = &etag
+		request.Options.Concurrency = "first-write"
+	}
+	_, err := t.StateProvider.Upsert(ctx, request)
+	return err
+}
+
+func toUserState(body interface{}) (UserState, error) {
+	if user, ok := body.(UserState); ok {
+		return user, nil
+	}
+	var ret UserState
+	data, err := json.Marshal(body)
+	if err != nil {
+		return ret, err
 	}
-	log.Debugf(" M (Users) : authentication failed, traceId: %s", span.SpanContext().TraceID().String())
-	return nil, false
+	err = json.Unmarshal(data, &ret)
+	return ret, err
 }
@@ -8,10 +8,15 @@ package users
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -81,3 +86,226 @@ func TestUpsertAndCheck(t *testing.T) {
 	err = manager.DeleteUser(context.Background(), "test")
 	assert.Nil(t, err)
 }
+
+func newUsersManager(t *testing.T, properties map[string]string) *UsersManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &UsersManager{}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.state": "StateProvider",
+		},
+	}
+	for k, v := range properties {
+		config.Properties[k] = v
+	}
+	err := manager.Init(nil, config, map[string]providers.IProvider{
+		"StateProvider": stateProvider,
+	})
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestPasswordHashes(t *testing.T) {
+	manager := newUsersManager(t, nil)
+	ctx := context.Background()
+	err := manager.UpsertUser(ctx, "alice", "password", nil)
+	assert.Nil(t, err)
+	err = manager.UpsertUser(ctx, "bob", "password", nil)
+	assert.Nil(t, err)
+	alice, _, err := manager.getUser(ctx, "alice")
+	assert.Nil(t, err)
+	bob, _, err := manager.getUser(ctx, "bob")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(alice.PasswordHash, "$argon2id$"))
+	// salts are random, so the same password doesn't make the same hash
+	assert.NotEqual(t, alice.PasswordHash, bob.PasswordHash)
+
+	ok, upgrade := verifyPassword("alice", "password", alice.PasswordHash)
+	assert.True(t, ok)
+	assert.False(t, upgrade)
+	ok, _ = verifyPassword("alice", "Password", alice.PasswordHash)
+	assert.False(t, ok)
+	ok, _ = verifyPassword("alice", "password", "$argon2id$v=19$m=65536,t=3,p=2$broken")
+	assert.False(t, ok)
+}
+
+func TestLegacyHashUpgrade(t *testing.T) {
+	manager := newUsersManager(t, nil)
+	ctx := context.Background()
+	err := manager.saveUser(ctx, UserState{
+		Id:           "alice",
+		PasswordHash: legacyHash("alice", "password"),
+		Roles:        []string{"reader"},
+	}, "")
+	assert.Nil(t, err)
+
+	roles, ok := manager.CheckUser(ctx, "alice", "wrong")
+	assert.False(t, ok)
+	assert.Nil(t, roles)
+	roles, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"reader"}, roles)
+
+	user, _, err := manager.getUser(ctx, "alice")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(user.PasswordHash, "$argon2id$"))
+	assert.Equal(t, 0, user.FailedAttempts)
+	_, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.True(t, ok)
+}
+
+func TestLockout(t *testing.T) {
+	manager := newUsersManager(t, map[string]string{
+		"lockout.attempts": "3",
+		"lockout.duration": "1h",
+	})
+	ctx := context.Background()
+	err := manager.UpsertUser(ctx, "alice", "password", nil)
+	assert.Nil(t, err)
+
+	// a successful login resets the failed logins
+	_, ok := manager.CheckUser(ctx, "alice", "wrong")
+	assert.False(t, ok)
+	_, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		_, ok = manager.CheckUser(ctx, "alice", "wrong")
+		assert.False(t, ok)
+	}
+	user, err := manager.GetUser(ctx, "alice")
+	assert.Nil(t, err)
+	assert.NotNil(t, user.LockedUntil)
+	assert.Empty(t, user.PasswordHash)
+	_, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.False(t, ok)
+
+	// the lockout ends by itself
+	locked, _, err := manager.getUser(ctx, "alice")
+	assert.Nil(t, err)
+	past := time.Now().Add(-time.Minute)
+	locked.LockedUntil = &past
+	err = manager.saveUser(ctx, locked, "")
+	assert.Nil(t, err)
+	_, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		manager.CheckUser(ctx, "alice", "wrong")
+	}
+	err = manager.UnlockUser(ctx, "alice")
+	assert.Nil(t, err)
+	_, ok = manager.CheckUser(ctx, "alice", "password")
+	assert.True(t, ok)
+}
+
+func TestLockoutConfig(t *testing.T) {
+	manager := newUsersManager(t, nil)
+	assert.Equal(t, DEFAULT_LOCKOUT_ATTEMPTS, manager.LockoutAttempts)
+	assert.Equal(t, DEFAULT_LOCKOUT_DURATION, manager.LockoutDuration)
+
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	for _, properties := range []map[string]string{
+		{"lockout.attempts": "-1"},
+		{"lockout.duration": "forever"},
+	} {
+		properties["providers.state"] = "StateProvider"
+		err := (&UsersManager{}).Init(nil, managers.ManagerConfig{Properties: properties}, map[string]providers.IProvider{
+			"StateProvider": stateProvider,
+		})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestUserManagement(t *testing.T) {
+	manager := newUsersManager(t, nil)
+	ctx := context.Background()
+	err := manager.UpsertUser(ctx, "bob", "password", []string{"reader"})
+	assert.Nil(t, err)
+	err = manager.UpsertUser(ctx, "alice", "password", nil)
+	assert.Nil(t, err)
+
+	users, err := manager.ListUsers(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(users))
+	assert.Equal(t, "alice", users[0].Id)
+	assert.Equal(t, "bob", users[1].Id)
+	assert.Empty(t, users[1].PasswordHash)
+
+	err = manager.SetRoles(ctx, "alice", []string{"administrator"})
+	assert.Nil(t, err)
+	user, err := manager.GetUser(ctx, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, user.Roles)
+	err = manager.SetRoles(ctx, "carol", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	err = manager.ChangePassword(ctx, "alice", "wrong", "new-password")
+	assert.NotNil(t, err)
+	err = manager.ChangePassword(ctx, "alice", "password", "new-password")
+	assert.Nil(t, err)
+	_, ok := manager.CheckUser(ctx, "alice", "password")
+	assert.False(t, ok)
+	roles, ok := manager.CheckUser(ctx, "alice", "new-password")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"administrator"}, roles)
+
+	err = manager.DeleteUser(ctx, "alice")
+	assert.Nil(t, err)
+	_, err = manager.GetUser(ctx, "alice")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestUpdateUserConflict(t *testing.T) {
+	stateProvider := &filestate.FileStateProvider{}
+	err := stateProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "users.json"),
+	})
+	assert.Nil(t, err)
+	manager := &UsersManager{StateProvider: stateProvider}
+	ctx := context.Background()
+	err = manager.UpsertUser(ctx, "alice", "password", nil)
+	assert.Nil(t, err)
+
+	attempts := 0
+	user, err := manager.updateUser(ctx, "alice", func(user *UserState) (bool, error) {
+		attempts++
+		if attempts == 1 {
+			// another replica changes the user after it was read
+			other := *user
+			other.Roles = []string{"reader"}
+			assert.Nil(t, manager.saveUser(ctx, other, ""))
+		}
+		user.FailedAttempts++
+		return true, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"reader"}, user.Roles)
+	assert.Equal(t, 1, user.FailedAttempts)
+}
+
+func TestLoginFailuresLookTheSame(t *testing.T) {
+	manager := newUsersManager(t, map[string]string{
+		"lockout.attempts": "1",
+		"lockout.duration": "1h",
+	})
+	ctx := context.Background()
+	err := manager.UpsertUser(ctx, "alice", "password", nil)
+	assert.Nil(t, err)
+	err = manager.UpsertUser(ctx, "bob", "password", nil)
+	assert.Nil(t, err)
+	_, ok := manager.CheckUser(ctx, "bob", "wrong")
+	assert.False(t, ok)
+
+	// unknown users are verified against a dummy hash
+	assert.True(t, strings.HasPrefix(dummyHash(), "$argon2id$"))
+	_, unknownErr := manager.AuthenticateUser(ctx, "mallory", "password")
+	_, wrongErr := manager.AuthenticateUser(ctx, "alice", "wrong")
+	_, lockedErr := manager.AuthenticateUser(ctx, "bob", "password")
+	assert.NotNil(t, unknownErr)
+	assert.Equal(t, unknownErr, wrongErr)
+	assert.Equal(t, unknownErr, lockedErr)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// Operator actions on an activation, posted to activations/registry/{name}/{action}
+const (
+	ActivationPause   = "pause"
+	ActivationResume  = "resume"
+	ActivationCancel  = "cancel"
+	ActivationApprove = "approve"
+	ActivationReject  = "reject"
+)
+
+// Outputs of an approval stage
+const (
+	// Set while the stage waits for a decision
+	ApprovalPendingOutput = "__approvalPending"
+	ApproversOutput       = "approvers"
+	ApproverRolesOutput   = "approverRoles"
+	ApprovedOutput        = "approved"
+	ApproverOutput        = "approver"
+	CommentOutput         = "comment"
+)
+
+// Outputs of a sub-campaign stage
+const (
+	// Set while the stage waits for its child activation, holds the spec of the child activation to start
+	SubActivationOutput     = "__subActivation"
+	SubCampaignOutput       = "campaign"
+	SubActivationNameOutput = "activation"
+)
+
+// ActivationControl carries an operator action on an activation to the stage manager
+type ActivationControl struct {
+	Campaign             string `json:"campaign"`
+	Activation           string `json:"activation"`
+	ActivationGeneration string `json:"activationGeneration,omitempty"`
+	Action               string `json:"action"`
+	User                 string `json:"user,omitempty"`
+}
+
+// ActivationActionRequest is the optional body of an operator action
+type ActivationActionRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// ActivationSchedule lists the upcoming runs of a scheduled activation
+type ActivationSchedule struct {
+	Activation string                 `json:"activation"`
+	Schedule   *v1alpha2.ScheduleSpec `json:"schedule,omitempty"`
+	Runs       []time.Time            `json:"runs"`
+}
@@ -8,7 +8,9 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 )
@@ -33,6 +35,40 @@ type StageSpec struct {
 	Inputs        map[string]interface{} `json:"inputs,omitempty"`
 	HandleErrors  bool                   `json:"handleErrors,omitempty"`
 	Schedule      *v1alpha2.ScheduleSpec `json:"schedule,omitempty"`
+	Parallel      *ParallelSpec          `json:"parallel,omitempty"`
+	ForEach       *ForEachSpec           `json:"forEach,omitempty"`
+	RetryPolicy   *RetryPolicy           `json:"retryPolicy,omitempty"`
+	Timeout       string                 `json:"timeout,omitempty"`
+	Compensation  string                 `json:"compensation,omitempty"`
+}
+
+// RetryPolicy runs a failed stage again. The delay between attempts starts at Backoff and doubles after
+// each attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	// Number of times the stage is run before it fails, including the first attempt
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Delay before the second attempt, such as "5s"
+	Backoff string `json:"backoff,omitempty"`
+	// Longest delay between attempts, such as "1m". The delay isn't capped if it's not set
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+}
+
+// ParallelSpec fans a stage out to branch stages that run concurrently. The stage picked by the
+// stage selector joins the branches once MinSuccess of them (all by default) have succeeded.
+type ParallelSpec struct {
+	Branches   []string `json:"branches"`
+	MinSuccess int      `json:"minSuccess,omitempty"`
+}
+
+// ForEachSpec runs a stage once for each item of a collection. The inputs of the stage read the item and
+// its position with $item() and $index(). The stage succeeds once MinSuccess of the items (all by default)
+// have succeeded.
+type ForEachSpec struct {
+	// Items is evaluated to the collection, such as "${{$output(list, items)}}"
+	Items string `json:"items"`
+	// Number of items that run at the same time, 1 (one after the other) by default
+	Concurrency int `json:"concurrency,omitempty"`
+	MinSuccess  int `json:"minSuccess,omitempty"`
 }
 
 func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -65,6 +101,26 @@ func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if !reflect.DeepEqual(s.Parallel, otherS.Parallel) {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(s.ForEach, otherS.ForEach) {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(s.RetryPolicy, otherS.RetryPolicy) {
+		return false, nil
+	}
+
+	if s.Timeout != otherS.Timeout {
+		return false, nil
+	}
+
+	if s.Compensation != otherS.Compensation {
+		return false, nil
+	}
+
 	return true, nil
 }
 
@@ -78,6 +134,11 @@ type ActivationStatus struct {
 	IsActive             bool                   `json:"isActive,omitempty"`
 	ActivationGeneration string                 `json:"activationGeneration,omitempty"`
 	UpdateTime           string                 `json:"updateTime,omitempty"`
+	// Pause or cancel, when an operator has paused or cancelled the activation
+	OperatorAction string `json:"operatorAction,omitempty"`
+	Operator       string `json:"operator,omitempty"`
+	// The campaign revision the activation runs against, 0 if the campaign manager doesn't keep revisions
+	CampaignRevision int `json:"campaignRevision,omitempty"`
 }
 
 type ActivationSpec struct {
@@ -86,6 +147,21 @@ type ActivationSpec struct {
 	Stage      string                 `json:"stage,omitempty"`
 	Inputs     map[string]interface{} `json:"inputs,omitempty"`
 	Generation string                 `json:"generation,omitempty"`
+	// Schedule defers the activation to a given time, or runs it repeatedly when it has a cron expression
+	Schedule *v1alpha2.ScheduleSpec `json:"schedule,omitempty"`
+	// CampaignRevision runs the activation against a past revision of the campaign instead of the current one
+	CampaignRevision int `json:"campaignRevision,omitempty"`
+	// Parent is the sub-campaign stage that started the activation, which resumes when the activation ends
+	Parent *ActivationParent `json:"parent,omitempty"`
+}
+
+// ActivationParent identifies the stage of an activation that waits on a child activation
+type ActivationParent struct {
+	Campaign             string `json:"campaign"`
+	Activation           string `json:"activation"`
+	ActivationGeneration string `json:"activationGeneration,omitempty"`
+	Stage                string `json:"stage"`
+	Site                 string `json:"site,omitempty"`
 }
 
 func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -110,6 +186,18 @@ func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if !reflect.DeepEqual(c.Schedule, otherC.Schedule) {
+		return false, nil
+	}
+
+	if c.CampaignRevision != otherC.CampaignRevision {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(c.Parent, otherC.Parent) {
+		return false, nil
+	}
+
 	return true, nil
 }
 
@@ -120,6 +208,25 @@ type CampaignSpec struct {
 	SelfDriving bool                 `json:"selfDriving,omitempty"`
 }
 
+// ValidateSchedules checks the schedules of the stages
+func (c CampaignSpec) ValidateSchedules() error {
+	names := make([]string, 0, len(c.Stages))
+	for name := range c.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schedule := c.Stages[name].Schedule
+		if schedule == nil {
+			continue
+		}
+		if err := schedule.Validate(); err != nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("stage '%s' has an invalid schedule: %s", name, err.Error()), v1alpha2.BadRequest)
+		}
+	}
+	return nil
+}
+
 func (c CampaignSpec) DeepEquals(other IDeepEquals) (bool, error) {
 	otherC, ok := other.(CampaignSpec)
 	if !ok {
@@ -337,3 +337,53 @@ func TestActivationNotMatch(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, equal)
 }
+
+func TestActivationScheduleNotMatch(t *testing.T) {
+	activation1 := ActivationSpec{
+		Name: "multisite-deploy",
+		Schedule: &v1alpha2.ScheduleSpec{
+			Cron: "0 2 * * *",
+		},
+	}
+	activation2 := ActivationSpec{
+		Name: "multisite-deploy",
+		Schedule: &v1alpha2.ScheduleSpec{
+			Cron: "0 3 * * *",
+		},
+	}
+	equal, err := activation1.DeepEquals(activation2)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+
+	activation2.Schedule = nil
+	equal, err = activation1.DeepEquals(activation2)
+	assert.Nil(t, err)
+	assert.False(t, equal)
+}
+
+func TestCampaignValidateSchedules(t *testing.T) {
+	campaign := CampaignSpec{
+		Stages: map[string]StageSpec{
+			"deploy": {
+				Schedule: &v1alpha2.ScheduleSpec{
+					Cron: "0 2 * * 1-5",
+					Zone: "Europe/Berlin",
+				},
+			},
+			"verify": {},
+		},
+	}
+	assert.Nil(t, campaign.ValidateSchedules())
+
+	campaign.Stages["verify"] = StageSpec{
+		Schedule: &v1alpha2.ScheduleSpec{
+			Cron: "0 25 * * *",
+		},
+	}
+	err := campaign.ValidateSchedules()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stage 'verify'")
+	cErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, cErr.State)
+}
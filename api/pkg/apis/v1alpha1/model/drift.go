@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"time"
+)
+
+const (
+	DriftMissing = "missing"
+	DriftExtra   = "extra"
+	DriftChanged = "changed"
+)
+
+// DriftReport compares what the targets of an instance report against its last applied deployment
+type DriftReport struct {
+	Instance    string           `json:"instance"`
+	Generation  string           `json:"generation,omitempty"`
+	Time        time.Time        `json:"time"`
+	Drifted     bool             `json:"drifted"`
+	Components  []ComponentDrift `json:"components,omitempty"`
+	Message     string           `json:"message,omitempty"`
+	Healed      bool             `json:"healed,omitempty"`
+	HealMessage string           `json:"healMessage,omitempty"`
+}
+
+// ComponentDrift describes a component that is missing from a target, is on a target it isn't
+// assigned to, or has properties that differ from the desired ones
+type ComponentDrift struct {
+	Target     string   `json:"target"`
+	Name       string   `json:"name"`
+	Drift      string   `json:"drift"`
+	Properties []string `json:"properties,omitempty"`
+}
@@ -0,0 +1,24 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+// ExplainRequest asks for an expression to be evaluated and explained against the given context
+type ExplainRequest struct {
+	Expression string         `json:"expression"`
+	Context    ExplainContext `json:"context,omitempty"`
+}
+
+// ExplainContext carries the evaluation context values an expression may read. Config and secret
+// providers are the ones configured on the server.
+type ExplainContext struct {
+	Properties map[string]string                 `json:"properties,omitempty"`
+	Inputs     map[string]interface{}            `json:"inputs,omitempty"`
+	Outputs    map[string]map[string]interface{} `json:"outputs,omitempty"`
+	Component  string                            `json:"component,omitempty"`
+	Value      interface{}                       `json:"value,omitempty"`
+	Deployment *DeploymentSpec                   `json:"deployment,omitempty"`
+}
@@ -33,7 +33,21 @@ type (
 		Arguments   map[string]map[string]string `json:"arguments,omitempty"`
 		Generation  string                       `json:"generation,omitempty"`
 		// Defines the version of a particular resource
-		Version string `json:"version,omitempty"`
+		Version string       `json:"version,omitempty"`
+		Rollout *RolloutSpec `json:"rollout,omitempty"`
+	}
+
+	// RolloutSpec defines how an instance is rolled out to the targets it matches, one batch at a time
+	// +kubebuilder:object:generate=true
+	RolloutSpec struct {
+		// Number of targets in each batch
+		BatchSize int `json:"batchSize,omitempty"`
+		// Percentage of matched targets in each batch, used when BatchSize is not set
+		Percentage int `json:"percentage,omitempty"`
+		// Pause between batches, such as "30s"
+		Pause string `json:"pause,omitempty"`
+		// Percentage of targets in a batch that must succeed for the rollout to proceed. Defaults to 100
+		SuccessThreshold int `json:"successThreshold,omitempty"`
 	}
 
 	// TargertRefSpec defines the target the instance will deploy to
@@ -164,5 +178,9 @@ func (c InstanceSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if (c.Rollout == nil) != (otherC.Rollout == nil) || (c.Rollout != nil && *c.Rollout != *otherC.Rollout) {
+		return false, nil
+	}
+
 	return true, nil
 }
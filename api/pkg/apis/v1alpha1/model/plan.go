@@ -183,3 +183,39 @@ func makeReversedDeletionStep(step DeploymentStep) DeploymentStep {
 	}
 	return ret
 }
+
+const (
+	PlanActionAdd    = "add"
+	PlanActionUpdate = "update"
+	PlanActionDelete = "delete"
+	PlanActionNone   = "none"
+)
+
+// PlanPreview describes what a reconcile would do, without applying any changes
+type PlanPreview struct {
+	Instance string            `json:"instance"`
+	Steps    []PlanStepPreview `json:"steps"`
+	Changed  bool              `json:"changed"`
+}
+type PlanStepPreview struct {
+	Target     string          `json:"target"`
+	Role       string          `json:"role"`
+	Provider   string          `json:"provider"`
+	Skipped    bool            `json:"skipped"`
+	Components []ComponentDiff `json:"components"`
+}
+type ComponentDiff struct {
+	Name    string         `json:"name"`
+	Action  string         `json:"action"`
+	Current *ComponentSpec `json:"current,omitempty"`
+	Desired *ComponentSpec `json:"desired,omitempty"`
+}
+
+func (s PlanStepPreview) HasChanges() bool {
+	for _, c := range s.Components {
+		if c.Action != PlanActionNone {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"time"
+)
+
+const (
+	SpecChangeAdded    = "added"
+	SpecChangeRemoved  = "removed"
+	SpecChangeModified = "modified"
+)
+
+// RevisionSpec is an immutable snapshot of the spec of a campaign or a solution. Revisions of an object
+// are numbered from 1, and a new revision is only recorded when the spec changes.
+type RevisionSpec struct {
+	Object    string      `json:"object"`
+	Revision  int         `json:"revision"`
+	Author    string      `json:"author,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Spec      interface{} `json:"spec,omitempty"`
+}
+
+// RevisionDiff lists the changes between two revisions of an object
+type RevisionDiff struct {
+	Object  string       `json:"object"`
+	From    int          `json:"from"`
+	To      int          `json:"to"`
+	Changes []SpecChange `json:"changes"`
+}
+
+// SpecChange is a field that was added, removed or modified. Path is the dotted path of the field, with
+// list items in brackets, such as "stages.deploy.inputs.images[0]".
+type SpecChange struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
@@ -28,6 +28,24 @@ type SummarySpec struct {
 	SummaryMessage string                      `json:"message,omitempty"`
 	Skipped        bool                        `json:"skipped"`
 	IsRemoval      bool                        `json:"isRemoval"`
+	Rollback       *RollbackResultSpec         `json:"rollback,omitempty"`
+	Rollout        *RolloutResultSpec          `json:"rollout,omitempty"`
+}
+
+// RolloutResultSpec records the progress of a batched rollout
+type RolloutResultSpec struct {
+	TotalBatches     int    `json:"totalBatches"`
+	CompletedBatches int    `json:"completedBatches"`
+	Halted           bool   `json:"halted"`
+	Message          string `json:"message,omitempty"`
+}
+
+// RollbackResultSpec records the re-application of the last good deployment after a failed reconciliation
+type RollbackResultSpec struct {
+	Generation    string                      `json:"generation,omitempty"`
+	Succeeded     bool                        `json:"succeeded"`
+	Message       string                      `json:"message,omitempty"`
+	TargetResults map[string]TargetResultSpec `json:"targets,omitempty"`
 }
 type SummaryResult struct {
 	Summary    SummarySpec `json:"summary"`
@@ -12,4 +12,7 @@ type SyncPackage struct {
 	Origin   string             `json:"origin,omitempty"`
 	Catalogs []CatalogSpec      `json:"catalogs,omitempty"`
 	Jobs     []v1alpha2.JobData `json:"jobs,omitempty"`
+	// Leases are the IDs of the queued items in the package. The receiving site acknowledges them
+	// once it has taken the package, otherwise the items are delivered again.
+	Leases []string `json:"leases,omitempty"`
 }
@@ -9,6 +9,7 @@ package model
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -58,53 +59,68 @@ func (v ValidationRule) Validate(components []ComponentSpec) error {
 }
 
 func (v ValidationRule) IsComponentChanged(old ComponentSpec, new ComponentSpec) bool {
+	return len(v.ChangedProperties(old, new)) > 0
+}
+
+// ChangedProperties returns the change detection properties that differ between the two components.
+// Metadata keys are prefixed with "metadata.", and a changed component name is reported as "name".
+func (v ValidationRule) ChangedProperties(old ComponentSpec, new ComponentSpec) []string {
+	ret := make([]string, 0)
 	for _, c := range v.ChangeDetectionProperties {
 		if strings.Contains(c.Name, "*") {
-			escapedPattern := regexp.QuoteMeta(c.Name)
-			// Replace the wildcard (*) with a regular expression pattern
-			regexpPattern := strings.ReplaceAll(escapedPattern, `\*`, ".*")
-			// Compile the regular expression
-			regexpObject := regexp.MustCompile("^" + regexpPattern + "$")
+			regexpObject := wildcardRegexp(c.Name)
 			for k := range old.Properties {
 				if regexpObject.MatchString(k) {
 					if compareProperties(c, old, new, k) {
-						return true
+						ret = appendUnique(ret, k)
 					}
 				}
 			}
 		} else {
 			if c.IsComponentName {
 				if !compareStrings(old.Name, new.Name, c.IgnoreCase, c.PrefixMatch) {
-					return true
+					ret = appendUnique(ret, "name")
 				}
 			} else {
 				if compareProperties(c, old, new, c.Name) {
-					return true
+					ret = appendUnique(ret, c.Name)
 				}
 			}
 		}
 	}
 	for _, c := range v.ChangeDetectionMetadata {
 		if strings.Contains(c.Name, "*") {
-			escapedPattern := regexp.QuoteMeta(c.Name)
-			// Replace the wildcard (*) with a regular expression pattern
-			regexpPattern := strings.ReplaceAll(escapedPattern, `\*`, ".*")
-			// Compile the regular expression
-			regexpObject := regexp.MustCompile("^" + regexpPattern + "$")
+			regexpObject := wildcardRegexp(c.Name)
 			for k := range old.Metadata {
 				if regexpObject.MatchString(k) {
 					if compareMetadata(c, old, new, k) {
-						return true
+						ret = appendUnique(ret, "metadata."+k)
 					}
 				}
 			}
 		} else {
 			if compareMetadata(c, old, new, c.Name) {
-				return true
+				ret = appendUnique(ret, "metadata."+c.Name)
 			}
 		}
 	}
-	return false
+	sort.Strings(ret)
+	return ret
+}
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	escapedPattern := regexp.QuoteMeta(pattern)
+	// Replace the wildcard (*) with a regular expression pattern
+	regexpPattern := strings.ReplaceAll(escapedPattern, `\*`, ".*")
+	// Compile the regular expression
+	return regexp.MustCompile("^" + regexpPattern + "$")
+}
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
 }
 func compareStrings(a, b string, ignoreCase bool, prefixMatch bool) bool {
 	ta := a
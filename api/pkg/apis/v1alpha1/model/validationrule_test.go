@@ -477,3 +477,36 @@ func TestComponentIsChanged_ComponentNameHasPrefix(t *testing.T) {
 	changed := validationRule.IsComponentChanged(old, new)
 	assert.False(t, changed)
 }
+
+func TestChangedProperties(t *testing.T) {
+	validationRule := ValidationRule{
+		ChangeDetectionProperties: []PropertyDesc{
+			{Name: "container.image"},
+			{Name: "env.*"},
+			{Name: "ports", SkipIfMissing: true},
+		},
+		ChangeDetectionMetadata: []PropertyDesc{
+			{Name: "owner", IgnoreCase: true},
+		},
+	}
+	old := ComponentSpec{
+		Name:       "a",
+		Properties: map[string]interface{}{"container.image": "a:1", "env.A": "1", "env.B": "2", "replicas": 1},
+		Metadata:   map[string]string{"owner": "Team"},
+	}
+	new := ComponentSpec{
+		Name:       "a",
+		Properties: map[string]interface{}{"container.image": "a:2", "env.A": "1", "env.B": "3", "replicas": 2},
+		Metadata:   map[string]string{"owner": "team"},
+	}
+
+	changed := validationRule.ChangedProperties(old, new)
+	assert.Equal(t, []string{"container.image", "env.B"}, changed)
+	assert.True(t, validationRule.IsComponentChanged(old, new))
+
+	new.Metadata["owner"] = "other"
+	changed = validationRule.ChangedProperties(old, new)
+	assert.Equal(t, []string{"container.image", "env.B", "metadata.owner"}, changed)
+
+	assert.Empty(t, validationRule.ChangedProperties(old, old))
+}
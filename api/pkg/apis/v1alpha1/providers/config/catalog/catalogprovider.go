@@ -87,39 +87,47 @@ func CatalogConfigProviderConfigFromMap(properties map[string]string) (CatalogCo
 	ret.Password = password
 	return ret, nil
 }
-func (m *CatalogConfigProvider) unwindOverrides(override string, field string) (string, error) {
+func (m *CatalogConfigProvider) unwindOverrides(override string, field string) (string, string, error) {
 	catalog, err := utils.GetCatalog(context.TODO(), m.Config.BaseUrl, override, m.Config.User, m.Config.Password)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if v, ok := catalog.Spec.Properties[field]; ok {
-		return v.(string), nil
+		return v.(string), override, nil
 	}
 	if catalog.Spec.ParentName != "" {
 		return m.unwindOverrides(catalog.Spec.ParentName, field)
 	}
-	return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("field '%s' is not found in configuration '%s'", field, override), v1alpha2.NotFound)
+	return "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("field '%s' is not found in configuration '%s'", field, override), v1alpha2.NotFound)
 }
 func (m *CatalogConfigProvider) Read(object string, field string, localcontext interface{}) (interface{}, error) {
+	value, _, err := m.ReadWithSource(object, field, localcontext)
+	return value, err
+}
+
+// ReadWithSource reads a field like Read, and also returns the name of the catalog that holds the field,
+// which is a parent catalog if the field is inherited.
+func (m *CatalogConfigProvider) ReadWithSource(object string, field string, localcontext interface{}) (interface{}, string, error) {
 	catalog, err := utils.GetCatalog(context.TODO(), m.Config.BaseUrl, object, m.Config.User, m.Config.Password)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if v, ok := catalog.Spec.Properties[field]; ok {
-		return m.traceValue(v, localcontext)
+		value, err := m.traceValue(v, localcontext)
+		return value, object, err
 	}
 
 	if catalog.Spec.ParentName != "" {
-		overrid, err := m.unwindOverrides(catalog.Spec.ParentName, field)
+		overrid, source, err := m.unwindOverrides(catalog.Spec.ParentName, field)
 		if err != nil {
-			return "", err
+			return "", "", err
 		} else {
-			return overrid, nil
+			return overrid, source, nil
 		}
 	}
 
-	return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("field '%s' is not found in configuration '%s'", field, object), v1alpha2.NotFound)
+	return "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("field '%s' is not found in configuration '%s'", field, object), v1alpha2.NotFound)
 }
 func (m *CatalogConfigProvider) ReadObject(object string, localcontext interface{}) (map[string]interface{}, error) {
 	catalog, err := utils.GetCatalog(context.TODO(), m.Config.BaseUrl, object, m.Config.User, m.Config.Password)
@@ -78,3 +78,25 @@ func TestRead(t *testing.T) {
 	// value, err = provider.Read("combined", "loop")
 	// assert.NotNil(t, err)
 }
+func TestReadWithSource(t *testing.T) {
+	// To make this test work, you'll need the configurations described in TestRead
+	catalogAPIUrl := os.Getenv("CATALOG_API_URL")
+	if catalogAPIUrl == "" {
+		t.Skip("Skipping becasue CATALOG_API_URL is missing or not set to 'yes'")
+	}
+	catalogAPIUser := os.Getenv("CATALOG_API_USER")
+	catalogAPIPassword := os.Getenv("CATALOG_API_PASSWORD")
+
+	provider := CatalogConfigProvider{}
+	err := provider.Init(CatalogConfigProviderConfig{BaseUrl: catalogAPIUrl, User: catalogAPIUser, Password: catalogAPIPassword})
+	assert.Nil(t, err)
+
+	value, source, err := provider.ReadWithSource("ai-config-line", "flavor", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "mobile", value)
+	assert.Equal(t, "ai-config-line", source)
+	value, source, err = provider.ReadWithSource("ai-config-line", "model", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "LLaMA", value)
+	assert.Equal(t, "ai-config-site", source)
+}
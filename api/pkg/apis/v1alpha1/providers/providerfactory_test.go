@@ -7,11 +7,16 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	catalogconfig "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/config/catalog"
 	memorygraph "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph/memory"
+	approvalstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
+	campaignstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/campaign"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/counter"
 	symphonystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/create"
 	delaystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/delay"
@@ -38,10 +43,12 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/script"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/staging"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/win10/sideload"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	mockconfig "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/mock"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/probe/rtsp"
 	mempubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	filequeue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/file"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
 	cvref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/customvision"
 	httpref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/http"
@@ -49,12 +56,41 @@ import (
 	httpreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/http"
 	k8sreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/k8s"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/httpstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/uploader/azure/blob"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestCreateFileStateProviderFromConfig(t *testing.T) {
+	// a provider config as it appears in a Symphony API config file
+	var config managers.ProviderConfig
+	err := json.Unmarshal([]byte(`{
+		"type": "providers.state.file",
+		"config": {
+			"name": "file-state",
+			"path": "`+filepath.ToSlash(filepath.Join(t.TempDir(), "instances.json"))+`"
+		}
+	}`), &config)
+	assert.Nil(t, err)
+
+	providerfactory := SymphonyProviderFactory{}
+	provider, err := providerfactory.CreateProvider(config.Type, config.Config)
+	assert.Nil(t, err)
+	_, err = provider.(*filestate.FileStateProvider).Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "instance1", Body: "body"},
+	})
+	assert.Nil(t, err)
+
+	// states are kept across restarts
+	provider, err = providerfactory.CreateProvider(config.Type, config.Config)
+	assert.Nil(t, err)
+	entry, err := provider.(*filestate.FileStateProvider).Get(context.Background(), states.GetRequest{ID: "instance1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "body", entry.Body)
+}
 func TestCreateProvider(t *testing.T) {
 	providerfactory := SymphonyProviderFactory{}
 	provider, err := providerfactory.CreateProvider("providers.state.memory", memorystate.MemoryStateProviderConfig{})
@@ -65,6 +101,12 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*k8sstate.K8sStateProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.state.file", filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*filestate.FileStateProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.config.k8scatalog", k8sstate.K8sStateProviderConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*k8sstate.K8sStateProvider))
@@ -217,6 +259,14 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*delaystage.DelayStageProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.stage.approval", approvalstage.ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, *provider.(*approvalstage.ApprovalStageProvider))
+
+	provider, err = providerfactory.CreateProvider("providers.stage.campaign", campaignstage.CampaignStageProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, *provider.(*campaignstage.CampaignStageProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.stage.materialize", materialize.MaterializeStageProviderConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*materialize.MaterializeStageProvider))
@@ -225,6 +275,12 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*memoryqueue.MemoryQueueProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.queue.file", filequeue.FileQueueProviderConfig{
+		Path: filepath.Join(t.TempDir(), "queue.json"),
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*filequeue.FileQueueProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.graph.memory", memorygraph.MemoryGraphProviderConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*memorygraph.MemoryGraphProvider))
@@ -246,6 +302,13 @@ func TestCreateProviderForTargetRole(t *testing.T) {
 						Provider: "providers.state.k8s",
 						Config:   map[string]string{},
 					},
+					{
+						Role:     "filestate",
+						Provider: "providers.state.file",
+						Config: map[string]string{
+							"path": filepath.Join(t.TempDir(), "states.json"),
+						},
+					},
 					{
 						Role:     "k8scatalog",
 						Provider: "providers.config.k8scatalog",
@@ -410,6 +473,13 @@ func TestCreateProviderForTargetRole(t *testing.T) {
 						Provider: "providers.queue.memory",
 						Config:   map[string]string{},
 					},
+					{
+						Role:     "filequeue",
+						Provider: "providers.queue.file",
+						Config: map[string]string{
+							"path": filepath.Join(t.TempDir(), "queue.json"),
+						},
+					},
 					{
 						Role:     "memorygraph",
 						Provider: "providers.graph.memory",
@@ -528,6 +598,10 @@ func TestCreateProviderForTargetRole(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*k8sstate.K8sStateProvider))
 
+	provider, err = CreateProviderForTargetRole(nil, "filestate", targetSpec, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*filestate.FileStateProvider))
+
 	provider, err = CreateProviderForTargetRole(nil, "k8scatalog", targetSpec, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*k8sstate.K8sStateProvider))
@@ -628,6 +702,10 @@ func TestCreateProviderForTargetRole(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*memoryqueue.MemoryQueueProvider))
 
+	provider, err = CreateProviderForTargetRole(nil, "filequeue", targetSpec, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*filequeue.FileQueueProvider))
+
 	provider, err = CreateProviderForTargetRole(nil, "memorygraph", targetSpec, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*memorygraph.MemoryGraphProvider))
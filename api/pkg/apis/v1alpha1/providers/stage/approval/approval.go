@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+var msLock sync.Mutex
+
+type ApprovalStageProviderConfig struct {
+	ID string `json:"id"`
+}
+
+// ApprovalStageProvider pauses an activation until a user approves or rejects it through
+// activations/registry/{name}/approve or activations/registry/{name}/reject
+type ApprovalStageProvider struct {
+	Config  ApprovalStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (m *ApprovalStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+
+	approvalConfig, err := toApprovalStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	m.Config = approvalConfig
+	return nil
+}
+func (s *ApprovalStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toApprovalStageProviderConfig(config providers.IProviderConfig) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+func (i *ApprovalStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := ApprovalStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func ApprovalStageProviderConfigFromMap(properties map[string]string) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	ret.ID = properties["id"]
+	return ret, nil
+}
+func (i *ApprovalStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	_, span := observability.StartSpan("[Stage] Approval provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	outputs := make(map[string]interface{})
+	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+	outputs[model.ApprovalPendingOutput] = true
+	approvers, err := utils.ReadStringList(inputs[model.ApproversOutput])
+	if err != nil {
+		return nil, false, err
+	}
+	approverRoles, err := utils.ReadStringList(inputs[model.ApproverRolesOutput])
+	if err != nil {
+		return nil, false, err
+	}
+	// nobody can decide on a stage without approvers
+	if len(approvers) == 0 && len(approverRoles) == 0 {
+		err = v1alpha2.NewCOAError(nil, "approval stage requires approvers or approverRoles", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+	outputs[model.ApproversOutput] = approvers
+	outputs[model.ApproverRolesOutput] = approverRoles
+	if v, ok := inputs["message"]; ok {
+		outputs["message"] = v
+	}
+	// the activation stays paused until the decision is posted
+	return outputs, true, nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalInitFromVendorMap(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	input := map[string]string{
+		"id": "test",
+	}
+	err := provider.InitWithMap(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", provider.Config.ID)
+}
+func TestApprovalProcess(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approvers":     "alice, bob",
+		"approverRoles": []interface{}{"operator"},
+		"message":       "deploy to production?",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+	assert.Equal(t, true, outputs[model.ApprovalPendingOutput])
+	assert.Equal(t, []string{"alice", "bob"}, outputs[model.ApproversOutput])
+	assert.Equal(t, []string{"operator"}, outputs[model.ApproverRolesOutput])
+	assert.Equal(t, "deploy to production?", outputs["message"])
+}
+func TestApprovalProcessInvalidApprovers(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approvers": 3,
+	})
+	assert.NotNil(t, err)
+	// a stage without approvers can't be decided
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approvers": " , ",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+var msLock sync.Mutex
+
+type CampaignStageProviderConfig struct {
+	ID string `json:"id"`
+}
+
+// CampaignStageProvider runs another campaign as a stage. It pauses the activation while a child
+// activation of the campaign runs; the stage vendor starts the child once the pause is recorded, and
+// the activation resumes with the outputs of the child when it ends.
+type CampaignStageProvider struct {
+	Config  CampaignStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (m *CampaignStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+
+	campaignConfig, err := toCampaignStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	m.Config = campaignConfig
+	return nil
+}
+func (s *CampaignStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toCampaignStageProviderConfig(config providers.IProviderConfig) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+func (i *CampaignStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := CampaignStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func CampaignStageProviderConfigFromMap(properties map[string]string) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+	ret.ID = properties["id"]
+	return ret, nil
+}
+func (i *CampaignStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	_, span := observability.StartSpan("[Stage] Campaign provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	campaign, ok := inputs["campaign"].(string)
+	if !ok || campaign == "" {
+		err = v1alpha2.NewCOAError(nil, "campaign is required", v1alpha2.BadRequest)
+		return nil, false, err
+	}
+	childInputs, err := toInputs(inputs["inputs"])
+	if err != nil {
+		return nil, false, err
+	}
+	name := fmt.Sprintf("%v-%v", inputs["__activation"], inputs["__stage"])
+	if v, ok := inputs["activation"]; ok {
+		if name, ok = v.(string); !ok || name == "" {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid activation name: %v", v), v1alpha2.BadRequest)
+			return nil, false, err
+		}
+	}
+	stage := ""
+	if v, ok := inputs["stage"]; ok {
+		stage = fmt.Sprintf("%v", v)
+	}
+
+	child := model.ActivationSpec{
+		Campaign: campaign,
+		Name:     name,
+		Stage:    stage,
+		Inputs:   childInputs,
+		Parent: &model.ActivationParent{
+			Campaign:             toString(inputs["__campaign"]),
+			Activation:           toString(inputs["__activation"]),
+			ActivationGeneration: toString(inputs["__activationGeneration"]),
+			Stage:                toString(inputs["__stage"]),
+			Site:                 toString(inputs["__site"]),
+		},
+	}
+	outputs := make(map[string]interface{})
+	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+	outputs[model.SubCampaignOutput] = campaign
+	outputs[model.SubActivationNameOutput] = name
+	outputs[model.SubActivationOutput] = child
+	// the activation stays paused until the child activation ends
+	return outputs, true, nil
+}
+
+// toInputs reads the inputs mapped to the child activation
+func toInputs(v interface{}) (map[string]interface{}, error) {
+	switch vs := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return vs, nil
+	case map[string]string:
+		ret := make(map[string]interface{}, len(vs))
+		for k, s := range vs {
+			ret[k] = s
+		}
+		return ret, nil
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid inputs of the child activation: %v", v), v1alpha2.BadRequest)
+	}
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
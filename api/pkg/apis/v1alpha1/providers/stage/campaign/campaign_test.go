@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaignInitFromVendorMap(t *testing.T) {
+	provider := CampaignStageProvider{}
+	input := map[string]string{
+		"id": "test",
+	}
+	err := provider.InitWithMap(input)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", provider.Config.ID)
+}
+func TestCampaignProcess(t *testing.T) {
+	provider := CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaign": "deploy-region",
+		"inputs": map[string]interface{}{
+			"region": "westus",
+		},
+		"__campaign":             "rollout",
+		"__activation":           "rollout-1",
+		"__activationGeneration": "2",
+		"__stage":                "westus",
+		"__site":                 "hq",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+	assert.Equal(t, "deploy-region", outputs[model.SubCampaignOutput])
+	assert.Equal(t, "rollout-1-westus", outputs[model.SubActivationNameOutput])
+	assert.Equal(t, model.ActivationSpec{
+		Campaign: "deploy-region",
+		Name:     "rollout-1-westus",
+		Inputs: map[string]interface{}{
+			"region": "westus",
+		},
+		Parent: &model.ActivationParent{
+			Campaign:             "rollout",
+			Activation:           "rollout-1",
+			ActivationGeneration: "2",
+			Stage:                "westus",
+			Site:                 "hq",
+		},
+	}, outputs[model.SubActivationOutput])
+}
+func TestCampaignProcessNamedActivation(t *testing.T) {
+	provider := CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaign":     "deploy-region",
+		"activation":   "deploy-westus",
+		"stage":        "deploy",
+		"__activation": "rollout-1",
+		"__stage":      "westus",
+	})
+	assert.Nil(t, err)
+	child := outputs[model.SubActivationOutput].(model.ActivationSpec)
+	assert.Equal(t, "deploy-westus", child.Name)
+	assert.Equal(t, "deploy", child.Stage)
+	assert.Nil(t, child.Inputs)
+}
+func TestCampaignProcessMissingCampaign(t *testing.T) {
+	provider := CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaign": "deploy-region",
+		"inputs":   "region=westus",
+	})
+	assert.NotNil(t, err)
+}
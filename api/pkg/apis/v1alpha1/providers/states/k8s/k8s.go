@@ -27,6 +27,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -166,6 +167,12 @@ func toK8sStateProviderConfig(config providers.IProviderConfig) (K8sStateProvide
 	return ret, err
 }
 
+// isCreateOnly tells if an upsert must only create the object, which is asked with the first-write
+// concurrency and an empty ETag
+func isCreateOnly(entry states.UpsertRequest) bool {
+	return entry.Options.Concurrency == "first-write" && entry.ETag != nil && *entry.ETag == ""
+}
+
 func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertRequest) (string, error) {
 	ctx, span := observability.StartSpan("K8s State Provider", ctx, &map[string]string{
 		"method": "Upsert",
@@ -231,9 +238,15 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 		_, err = s.DynamicClient.Resource(resourceId).Namespace(scope).Create(ctx, unc, metav1.CreateOptions{})
 		if err != nil {
 			sLog.Errorf("  P (K8s State): failed to create object: %v", err)
+			if errors.IsAlreadyExists(err) && isCreateOnly(entry) {
+				err = v1alpha2.NewCOAError(err, fmt.Sprintf("object '%s' already exists", entry.Value.ID), v1alpha2.Conflict)
+			}
 			return "", err
 		}
 		//Note: state is ignored for new object
+	} else if isCreateOnly(entry) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("object '%s' already exists", entry.Value.ID), v1alpha2.Conflict)
+		return "", err
 	} else {
 		j, _ := json.Marshal(entry.Value.Body)
 		var dict map[string]interface{}
@@ -305,6 +318,28 @@ func (s *K8sStateProvider) List(ctx context.Context, request states.ListRequest)
 	version := model.ReadProperty(request.Metadata, "version", nil)
 	resource := model.ReadProperty(request.Metadata, "resource", nil)
 
+	if request.Limit > 0 || request.Continue != "" {
+		// paged lists go through a single call so the API server's continue token stays valid;
+		// an empty namespace lists across all namespaces
+		resourceId := schema.GroupVersionResource{
+			Group:    group,
+			Version:  version,
+			Resource: resource,
+		}
+		items, err := s.DynamicClient.Resource(resourceId).Namespace(scope).List(ctx, metav1.ListOptions{
+			Limit:    request.Limit,
+			Continue: request.Continue,
+		})
+		if err != nil {
+			sLog.Errorf("  P (K8s State): failed to list objects in namespace %s: %v ", scope, err)
+			return nil, "", err
+		}
+		for _, v := range items.Items {
+			entities = append(entities, toStateEntry(v, v.GetNamespace()))
+		}
+		return entities, items.GetContinue(), nil
+	}
+
 	var namespaces []string
 	if scope == "" {
 		ret, err := s.ListAllNamespaces(ctx, version)
@@ -328,22 +363,24 @@ func (s *K8sStateProvider) List(ctx context.Context, request states.ListRequest)
 			return nil, "", err
 		}
 		for _, v := range items.Items {
-			generation := v.GetGeneration()
-			entry := states.StateEntry{
-				ETag: strconv.FormatInt(generation, 10),
-				ID:   v.GetName(),
-				Body: map[string]interface{}{
-					"spec":   v.Object["spec"],
-					"status": v.Object["status"],
-					"scope":  namespace,
-				},
-			}
-			entities = append(entities, entry)
+			entities = append(entities, toStateEntry(v, namespace))
 		}
 	}
 	return entities, "", nil
 }
 
+func toStateEntry(v unstructured.Unstructured, namespace string) states.StateEntry {
+	return states.StateEntry{
+		ETag: strconv.FormatInt(v.GetGeneration(), 10),
+		ID:   v.GetName(),
+		Body: map[string]interface{}{
+			"spec":   v.Object["spec"],
+			"status": v.Object["status"],
+			"scope":  namespace,
+		},
+	}
+}
+
 func (s *K8sStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
 	ctx, span := observability.StartSpan("K8s State Provider", ctx, &map[string]string{
 		"method": "Delete",
@@ -432,6 +469,92 @@ func (s *K8sStateProvider) Get(ctx context.Context, request states.GetRequest) (
 	return ret, nil
 }
 
+// Watch streams changes of the resource given in the request metadata using a Kubernetes watch.
+// An empty scope watches all namespaces.
+func (s *K8sStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	ctx, span := observability.StartSpan("K8s State Provider", ctx, &map[string]string{
+		"method": "Watch",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Info("  P (K8s State): watch state")
+
+	scope := model.ReadProperty(request.Metadata, "scope", nil)
+	group := model.ReadProperty(request.Metadata, "group", nil)
+	version := model.ReadProperty(request.Metadata, "version", nil)
+	resource := model.ReadProperty(request.Metadata, "resource", nil)
+
+	resourceId := schema.GroupVersionResource{
+		Group:    group,
+		Version:  version,
+		Resource: resource,
+	}
+
+	watcher, err := s.DynamicClient.Resource(resourceId).Namespace(scope).Watch(ctx, metav1.ListOptions{
+		ResourceVersion: request.ResourceVersion,
+	})
+	if err != nil {
+		sLog.Errorf("  P (K8s State): failed to watch objects: %v", err)
+		return nil, err
+	}
+
+	ret := make(chan states.WatchEvent)
+	go func() {
+		defer close(ret)
+		defer watcher.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				var eventType states.WatchEventType
+				switch event.Type {
+				case watch.Added, watch.Modified:
+					eventType = states.WatchEventUpsert
+				case watch.Deleted:
+					eventType = states.WatchEventDelete
+				default:
+					if event.Type == watch.Error {
+						sLog.Errorf("  P (K8s State): watch returned an error: %v", event.Object)
+						return
+					}
+					continue
+				}
+				item, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				namespace := item.GetNamespace()
+				select {
+				case ret <- states.WatchEvent{
+					Type:            eventType,
+					ResourceVersion: item.GetResourceVersion(),
+					Entry:           toStateEntry(*item, namespace),
+					Metadata: map[string]string{
+						"scope":    namespace,
+						"group":    group,
+						"version":  version,
+						"resource": resource,
+					},
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ret, nil
+}
+
+// IsShared tells that the objects are stored in the cluster, so the change feed reports all changes.
+func (s *K8sStateProvider) IsShared() bool {
+	return true
+}
+
 // Implmeement the IConfigProvider interface
 func (s *K8sStateProvider) Read(object string, field string) (string, error) {
 	obj, err := s.Get(context.TODO(), states.GetRequest{
@@ -15,8 +15,14 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
 )
 
 func TestK8sStateProviderConfigFromMapNil(t *testing.T) {
@@ -395,3 +401,132 @@ func checkTargetCRDApplied() error {
 	}
 	return nil
 }
+
+func TestWatch(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.FabricGroup, Version: "v1", Resource: "targets"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "TargetList",
+	})
+	provider := K8sStateProvider{
+		DynamicClient: client,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := provider.Watch(ctx, states.WatchRequest{
+		Metadata: map[string]string{
+			"scope":    "default",
+			"group":    model.FabricGroup,
+			"version":  "v1",
+			"resource": "targets",
+		},
+	})
+	assert.Nil(t, err)
+
+	target := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": model.FabricGroup + "/v1",
+			"kind":       "Target",
+			"metadata": map[string]interface{}{
+				"name":      "target1",
+				"namespace": "default",
+			},
+			"spec": map[string]interface{}{
+				"displayName": "target1",
+			},
+		},
+	}
+	_, err = client.Resource(gvr).Namespace("default").Create(ctx, target, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	event := <-ch
+	assert.Equal(t, states.WatchEventUpsert, event.Type)
+	assert.Equal(t, "target1", event.Entry.ID)
+	assert.Equal(t, "default", event.Metadata["scope"])
+	assert.Equal(t, "target1", event.Entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["displayName"])
+
+	err = client.Resource(gvr).Namespace("default").Delete(ctx, "target1", metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	event = <-ch
+	assert.Equal(t, states.WatchEventDelete, event.Type)
+	assert.Equal(t, "target1", event.Entry.ID)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestListWithPaging(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.FabricGroup, Version: "v1", Resource: "targets"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "TargetList",
+	})
+	provider := K8sStateProvider{
+		DynamicClient: client,
+	}
+	for _, namespace := range []string{"default", "other"} {
+		target := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": model.FabricGroup + "/v1",
+				"kind":       "Target",
+				"metadata": map[string]interface{}{
+					"name":      "target1",
+					"namespace": namespace,
+				},
+			},
+		}
+		_, err := client.Resource(gvr).Namespace(namespace).Create(context.Background(), target, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+	// a paged list with no scope goes across namespaces in a single call
+	entries, token, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]string{
+			"group":    model.FabricGroup,
+			"version":  "v1",
+			"resource": "targets",
+		},
+		Limit: 10,
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, token)
+	assert.Equal(t, 2, len(entries))
+	scopes := []string{
+		entries[0].Body.(map[string]interface{})["scope"].(string),
+		entries[1].Body.(map[string]interface{})["scope"].(string),
+	}
+	assert.ElementsMatch(t, []string{"default", "other"}, scopes)
+}
+
+func TestUpsertCreateOnly(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.WorkflowGroup, Version: "v1", Resource: "campaignrevisions"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "CampaignRevisionList",
+	})
+	provider := K8sStateProvider{
+		DynamicClient: client,
+	}
+	etag := ""
+	request := states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "campaign1-v1",
+			Body: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"revision": 1,
+				},
+			},
+		},
+		ETag: &etag,
+		Metadata: map[string]string{
+			"template": fmt.Sprintf(`{"apiVersion":"%s/v1", "kind": "CampaignRevision", "metadata": {"name": "campaign1-v1"}}`, model.WorkflowGroup),
+			"scope":    "default",
+			"group":    model.WorkflowGroup,
+			"version":  "v1",
+			"resource": "campaignrevisions",
+		},
+		Options: states.UpsertOption{
+			Concurrency: "first-write",
+		},
+	}
+	_, err := provider.Upsert(context.Background(), request)
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), request)
+	assert.True(t, v1alpha2.IsConflict(err))
+}
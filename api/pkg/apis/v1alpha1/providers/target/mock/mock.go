@@ -126,6 +126,8 @@ func (m *MockTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				found = true
 				if c.Action == "delete" {
 					cache[m.Config.ID] = append(cache[m.Config.ID][:i], cache[m.Config.ID][i+1:]...)
+				} else {
+					cache[m.Config.ID][i] = c.Component
 				}
 				break
 			}
@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// ExpressionExplanation is the result of Parser.Explain: the value of a property, and how each of its
+// ${{ }} expressions was evaluated.
+type ExpressionExplanation struct {
+	Expression string              `json:"expression"`
+	Value      interface{}         `json:"value"`
+	Error      string              `json:"error,omitempty"`
+	Segments   []ExpressionSegment `json:"segments"`
+}
+
+// ExpressionSegment is a literal part of a property, or one of its ${{ }} expressions.
+type ExpressionSegment struct {
+	Text  string           `json:"text"`
+	Value interface{}      `json:"value"`
+	Error string           `json:"error,omitempty"`
+	Steps []ExpressionStep `json:"steps,omitempty"`
+}
+
+// ExpressionStep is a node of a parsed expression with its value, or the error it failed with. Source tells
+// which config provider and object (an overlay or a catalog) a $config() value was read from.
+type ExpressionStep struct {
+	Expression string               `json:"expression"`
+	Kind       string               `json:"kind"`
+	Value      interface{}          `json:"value"`
+	Error      string               `json:"error,omitempty"`
+	Source     *config.ConfigSource `json:"source,omitempty"`
+	Steps      []ExpressionStep     `json:"steps,omitempty"`
+}
+
+// redactedValue replaces the values that are read from, or computed with, a $secret() value.
+const redactedValue = "***"
+
+// Explain evaluates the property like Eval does, and also returns the parsed expressions with the
+// intermediate values. Evaluation errors are reported in the explanation instead of being returned.
+// Values that are read from, or computed with, a $secret() value are redacted.
+func (p *Parser) Explain(context utils.EvaluationContext) ExpressionExplanation {
+	ret := ExpressionExplanation{
+		Expression: strings.Join(p.Segments, ""),
+		Segments:   make([]ExpressionSegment, 0, len(p.Segments)),
+	}
+	if context.SecretProvider != nil {
+		context.SecretProvider = &secretTracker{provider: context.SecretProvider}
+	}
+	redacted := false
+	results := make([]interface{}, 0)
+	for _, s := range p.Segments {
+		segment := ExpressionSegment{Text: s}
+		if strings.HasPrefix(s, "${{") && strings.HasSuffix(s, "}}") {
+			parser := newExpressionParser(s[3 : len(s)-2])
+			val, steps, isSecret, err := parser.explain(context)
+			segment.Steps = steps
+			redacted = redacted || isSecret
+			if err != nil {
+				segment.Error = err.Error()
+				if ret.Error == "" {
+					ret.Error = err.Error()
+				}
+			} else if isSecret {
+				segment.Value = redactedValue
+			} else {
+				segment.Value = val
+			}
+			results = append(results, val)
+		} else {
+			segment.Value = s
+			results = append(results, s)
+		}
+		ret.Segments = append(ret.Segments, segment)
+	}
+	if ret.Error == "" {
+		if redacted {
+			ret.Value = redactedValue
+		} else {
+			ret.Value = joinSegments(results)
+		}
+	}
+	return ret
+}
+
+func (p *ExpressionParser) explain(context utils.EvaluationContext) (interface{}, []ExpressionStep, bool, error) {
+	var ret interface{}
+	isSecret := false
+	steps := make([]ExpressionStep, 0)
+	for {
+		n, err := p.expr(false)
+		if err != nil {
+			return nil, steps, isSecret, err
+		}
+		if _, ok := n.(*NullNode); ok {
+			return ret, steps, isSecret, nil
+		}
+		explained := explainNode(n, context)
+		steps = append(steps, explained.step)
+		isSecret = isSecret || explained.secret
+		if explained.err != nil {
+			return nil, steps, isSecret, explained.err
+		}
+		ret = appendResult(ret, explained.value)
+		p.next()
+	}
+}
+
+// explainedNode is a node that has been evaluated. It stands in for the node when its parent is evaluated,
+// so that every node of an expression is evaluated only once. secret tells if the value was read from, or
+// computed with, a $secret() value.
+type explainedNode struct {
+	step   ExpressionStep
+	value  interface{}
+	err    error
+	secret bool
+}
+
+func (n *explainedNode) Eval(context utils.EvaluationContext) (interface{}, error) {
+	return n.value, n.err
+}
+
+// secretTracker counts the secrets that are read while a node is evaluated, including the secrets that
+// a config provider reads to resolve a $config() value.
+type secretTracker struct {
+	provider secret.ISecretProvider
+	reads    int
+}
+
+func (s *secretTracker) Init(config providers.IProviderConfig) error {
+	return s.provider.Init(config)
+}
+
+func (s *secretTracker) Get(object string, field string) (string, error) {
+	s.reads++
+	return s.provider.Get(object, field)
+}
+
+func explainNode(n Node, context utils.EvaluationContext) *explainedNode {
+	ret := &explainedNode{
+		step: ExpressionStep{
+			Expression: describeNode(n),
+		},
+	}
+	var node Node
+	var children []*Node
+	switch t := n.(type) {
+	case *FunctionNode:
+		ret.step.Kind = "function"
+		f := *t
+		f.Args = append([]Node{}, t.Args...)
+		node = &f
+		for i := range f.Args {
+			children = append(children, &f.Args[i])
+		}
+		if t.Name == "if" && len(t.Args) == 3 {
+			// only the branch that is taken is evaluated
+			cond := explainChild(&f.Args[0], context, ret)
+			if cond.err != nil {
+				children = nil
+			} else if fmt.Sprintf("%v", cond.value) == "true" {
+				children = []*Node{&f.Args[1]}
+			} else {
+				children = []*Node{&f.Args[2]}
+			}
+		}
+	case *BinaryNode:
+		ret.step.Kind = "operator"
+		b := *t
+		node = &b
+		children = []*Node{&b.Left, &b.Right}
+	case *UnaryNode:
+		ret.step.Kind = "operator"
+		u := *t
+		node = &u
+		children = []*Node{&u.Expr}
+	case *IntNode, *NumberNode:
+		ret.step.Kind = "number"
+		node = n
+	case *IdentifierNode:
+		ret.step.Kind = "string"
+		node = n
+	default:
+		ret.step.Kind = "empty"
+		node = n
+	}
+	for _, c := range children {
+		explainChild(c, context, ret)
+	}
+
+	tracker, tracked := context.SecretProvider.(*secretTracker)
+	reads := 0
+	if tracked {
+		reads = tracker.reads
+	}
+	if f, ok := node.(*FunctionNode); ok && f.Name == "config" {
+		ret.value, ret.err = explainConfig(f, context, &ret.step)
+	} else {
+		ret.value, ret.err = node.Eval(context)
+	}
+	if tracked && tracker.reads > reads {
+		ret.secret = true
+	}
+
+	if ret.err != nil {
+		ret.step.Error = ret.err.Error()
+	} else if ret.secret {
+		ret.step.Value = redactedValue
+	} else {
+		ret.step.Value = ret.value
+	}
+	return ret
+}
+
+// explainChild explains a child of the parent node, and replaces the child with its value.
+func explainChild(child *Node, context utils.EvaluationContext, parent *explainedNode) *explainedNode {
+	if *child == nil {
+		return nil
+	}
+	explained := explainNode(*child, context)
+	parent.step.Steps = append(parent.step.Steps, explained.step)
+	parent.secret = parent.secret || explained.secret
+	*child = explained
+	return explained
+}
+
+// explainConfig reads a $config() value with the config source when the config provider can tell it.
+func explainConfig(n *FunctionNode, context utils.EvaluationContext, step *ExpressionStep) (interface{}, error) {
+	provider, ok := context.ConfigProvider.(config.IConfigSourceProvider)
+	if !ok || len(n.Args) < 2 {
+		return n.Eval(context)
+	}
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := arg.Eval(context)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := val.(string)
+		if !ok {
+			return n.Eval(context)
+		}
+		args[i] = s
+	}
+	val, source, err := provider.GetWithSource(args[0], args[1], args[2:], context)
+	if err != nil {
+		return nil, err
+	}
+	step.Source = &source
+	return val, nil
+}
+
+// describeNode renders a parsed node. Nested operations are parenthesized to show how they are grouped.
+func describeNode(n Node) string {
+	switch t := n.(type) {
+	case *FunctionNode:
+		args := make([]string, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = describeNode(arg)
+		}
+		return fmt.Sprintf("$%s(%s)", t.Name, strings.Join(args, ", "))
+	case *BinaryNode:
+		return describeOperand(t.Left) + opNames[t.Op] + describeOperand(t.Right)
+	case *UnaryNode:
+		switch t.Op {
+		case OBRACKET:
+			return "[" + describeNode(t.Expr) + "]"
+		case OCURLY:
+			return "{" + describeNode(t.Expr) + "}"
+		}
+		if t.Expr == nil {
+			return opNames[t.Op]
+		}
+		return opNames[t.Op] + describeOperand(t.Expr)
+	case *IntNode:
+		return strconv.FormatInt(t.Value, 10)
+	case *NumberNode:
+		return strconv.FormatFloat(t.Value, 'f', -1, 64)
+	case *IdentifierNode:
+		return t.Value
+	}
+	return ""
+}
+
+func describeOperand(n Node) string {
+	if _, ok := n.(*BinaryNode); ok {
+		return "(" + describeNode(n) + ")"
+	}
+	return describeNode(n)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/mock"
+	secretmock "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplainArithmetic(t *testing.T) {
+	parser := NewParser("${{3-(1+2)/(2+1)}}")
+	explanation := parser.Explain(utils.EvaluationContext{})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, int64(2), explanation.Value)
+	assert.Equal(t, 1, len(explanation.Segments))
+	step := explanation.Segments[0].Steps[0]
+	assert.Equal(t, "3-((1+2)/(2+1))", step.Expression)
+	assert.Equal(t, "operator", step.Kind)
+	assert.Equal(t, int64(2), step.Value)
+	assert.Equal(t, "(1+2)/(2+1)", step.Steps[1].Expression)
+	assert.Equal(t, int64(1), step.Steps[1].Value)
+}
+func TestExplainSegments(t *testing.T) {
+	parser := NewParser("image: ${{$property(image)}}:${{$property(tag)}}")
+	context := utils.EvaluationContext{
+		Properties: map[string]string{
+			"image": "nginx",
+			"tag":   "1.25",
+		},
+	}
+	val, err := parser.Eval(context)
+	assert.Nil(t, err)
+	explanation := parser.Explain(context)
+	assert.Equal(t, val, explanation.Value)
+	assert.Equal(t, "image: ${{$property(image)}}:${{$property(tag)}}", explanation.Expression)
+	assert.Equal(t, 4, len(explanation.Segments))
+	assert.Equal(t, "image: ", explanation.Segments[0].Value)
+	assert.Equal(t, "$property(image)", explanation.Segments[1].Steps[0].Expression)
+	assert.Equal(t, "function", explanation.Segments[1].Steps[0].Kind)
+	assert.Equal(t, "nginx", explanation.Segments[1].Value)
+	assert.Equal(t, "image", explanation.Segments[1].Steps[0].Steps[0].Value)
+}
+func TestExplainError(t *testing.T) {
+	parser := NewParser("${{$concat($property(app), '-', $property(version))}}")
+	explanation := parser.Explain(utils.EvaluationContext{
+		Properties: map[string]string{
+			"app": "web",
+		},
+	})
+	assert.Equal(t, "property version is not found", explanation.Error)
+	assert.Nil(t, explanation.Value)
+	step := explanation.Segments[0].Steps[0]
+	assert.Equal(t, "property version is not found", step.Error)
+	assert.Equal(t, 3, len(step.Steps))
+	assert.Equal(t, "web", step.Steps[0].Value)
+	assert.Equal(t, "-", step.Steps[1].Value)
+	assert.Equal(t, "property version is not found", step.Steps[2].Error)
+}
+func TestExplainIf(t *testing.T) {
+	parser := NewParser("${{$if($equal($input(zone), west), $input(west), $input(east))}}")
+	explanation := parser.Explain(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"zone": "west",
+			"west": "w1",
+		},
+	})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, "w1", explanation.Value)
+	step := explanation.Segments[0].Steps[0]
+	assert.Equal(t, 2, len(step.Steps))
+	assert.Equal(t, true, step.Steps[0].Value)
+	assert.Equal(t, "$input(west)", step.Steps[1].Expression)
+}
+func TestExplainConfigSource(t *testing.T) {
+	provider := &mock.MockConfigProvider{}
+	err := provider.Init(mock.MockConfigProviderConfig{Name: "mock"})
+	assert.Nil(t, err)
+	parser := NewParser("${{$config(line-config, SERVICE_PORT)}}")
+	explanation := parser.Explain(utils.EvaluationContext{ConfigProvider: provider})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, "line-config::SERVICE_PORT", explanation.Value)
+	step := explanation.Segments[0].Steps[0]
+	assert.Equal(t, &config.ConfigSource{Provider: "mock", Object: "line-config"}, step.Source)
+}
+func TestExplainSingleEvaluation(t *testing.T) {
+	provider := &countingConfigProvider{}
+	parser := NewParser("${{$concat($config(line-config, $config(line-config, port-field)), '-', $config(line-config, name))}}")
+	explanation := parser.Explain(utils.EvaluationContext{ConfigProvider: provider})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, "line-config::line-config::port-field-line-config::name", explanation.Value)
+	assert.Equal(t, 3, provider.reads)
+	step := explanation.Segments[0].Steps[0]
+	assert.Equal(t, &config.ConfigSource{Provider: "counting", Object: "line-config"}, step.Steps[0].Source)
+}
+func TestExplainSecret(t *testing.T) {
+	provider := &secretmock.MockSecretProvider{}
+	err := provider.Init(secretmock.MockSecretProviderConfig{})
+	assert.Nil(t, err)
+	parser := NewParser("user: ${{$property(user)}} password: ${{$concat('pwd-', $secret(db, password))}}")
+	explanation := parser.Explain(utils.EvaluationContext{
+		SecretProvider: provider,
+		Properties: map[string]string{
+			"user": "admin",
+		},
+	})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, "***", explanation.Value)
+	assert.Equal(t, "admin", explanation.Segments[1].Value)
+	assert.Equal(t, "***", explanation.Segments[3].Value)
+	step := explanation.Segments[3].Steps[0]
+	assert.Equal(t, "***", step.Value)
+	assert.Equal(t, "pwd-", step.Steps[0].Value)
+	assert.Equal(t, "***", step.Steps[1].Value)
+	assert.Equal(t, "db", step.Steps[1].Steps[0].Value)
+}
+func TestExplainSecretInConfig(t *testing.T) {
+	secretProvider := &secretmock.MockSecretProvider{}
+	err := secretProvider.Init(secretmock.MockSecretProviderConfig{})
+	assert.Nil(t, err)
+	parser := NewParser("${{$config(line-config, $secret(db, user))}}:${{$config(line-config, password)}}")
+	explanation := parser.Explain(utils.EvaluationContext{
+		ConfigProvider: &countingConfigProvider{},
+		SecretProvider: secretProvider,
+	})
+	assert.Equal(t, "", explanation.Error)
+	assert.Equal(t, "***", explanation.Value)
+	assert.Equal(t, "***", explanation.Segments[0].Value)
+	assert.Equal(t, "***", explanation.Segments[0].Steps[0].Steps[1].Value)
+	assert.Equal(t, "***", explanation.Segments[2].Value)
+	assert.Equal(t, "password", explanation.Segments[2].Steps[0].Steps[1].Value)
+}
+func TestExplainParseError(t *testing.T) {
+	parser := NewParser("${{$property(a}}")
+	explanation := parser.Explain(utils.EvaluationContext{})
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+	assert.Equal(t, err.Error(), explanation.Error)
+}
+
+// countingConfigProvider counts the config values that are read. Its password fields are secrets.
+type countingConfigProvider struct {
+	reads int
+}
+
+func (c *countingConfigProvider) Get(object string, field string, overrides []string, localContext interface{}) (interface{}, error) {
+	c.reads++
+	if field == "password" {
+		if context, ok := localContext.(utils.EvaluationContext); ok && context.SecretProvider != nil {
+			return context.SecretProvider.Get(object, field)
+		}
+	}
+	return object + "::" + field, nil
+}
+
+func (c *countingConfigProvider) GetObject(object string, overrides []string, localContext interface{}) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (c *countingConfigProvider) GetWithSource(object string, field string, overrides []string, localContext interface{}) (interface{}, config.ConfigSource, error) {
+	val, err := c.Get(object, field, overrides, localContext)
+	return val, config.ConfigSource{Provider: "counting", Object: object}, err
+}
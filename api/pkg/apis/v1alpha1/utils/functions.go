@@ -0,0 +1,319 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExpressionFunction is a function that can be called from property expressions as $name(args...).
+type ExpressionFunction struct {
+	// MinArgs and MaxArgs bound the number of arguments. A MaxArgs of -1 means no upper bound.
+	MinArgs int
+	MaxArgs int
+	// Lenient functions receive nil in place of arguments that failed to evaluate, instead of failing.
+	Lenient bool
+	Eval    func(args []interface{}) (interface{}, error)
+}
+
+// coreFunctions are evaluated by FunctionNode itself, as they need the evaluation context.
+var coreFunctions = map[string]ExpressionFunction{
+	"param":    {MinArgs: 1, MaxArgs: 1},
+	"property": {MinArgs: 1, MaxArgs: 1},
+	"input":    {MinArgs: 1, MaxArgs: 1},
+	"output":   {MinArgs: 2, MaxArgs: 2},
+	"equal":    {MinArgs: 2, MaxArgs: 2},
+	"and":      {MinArgs: 2, MaxArgs: 2},
+	"or":       {MinArgs: 2, MaxArgs: 2},
+	"not":      {MinArgs: 1, MaxArgs: 1},
+	"gt":       {MinArgs: 2, MaxArgs: 2},
+	"ge":       {MinArgs: 2, MaxArgs: 2},
+	"if":       {MinArgs: 3, MaxArgs: 3},
+	"in":       {MinArgs: 2, MaxArgs: -1},
+	"lt":       {MinArgs: 2, MaxArgs: 2},
+	"between":  {MinArgs: 3, MaxArgs: 3},
+	"le":       {MinArgs: 2, MaxArgs: 2},
+	"config":   {MinArgs: 2, MaxArgs: -1},
+	"secret":   {MinArgs: 2, MaxArgs: 2},
+	"instance": {MinArgs: 0, MaxArgs: 0},
+	"val":      {MinArgs: 0, MaxArgs: 1},
+	"context":  {MinArgs: 0, MaxArgs: 1},
+	"json":     {MinArgs: 1, MaxArgs: 1},
+	"item":     {MinArgs: 0, MaxArgs: 1},
+	"index":    {MinArgs: 0, MaxArgs: 0},
+}
+
+var (
+	functionLock sync.RWMutex
+	functions    = map[string]ExpressionFunction{}
+)
+
+func init() {
+	registerBuiltInFunctions()
+}
+
+// RegisterFunction adds a function to the expression language. Names of existing functions can't be reused.
+func RegisterFunction(name string, function ExpressionFunction) error {
+	if name == "" || function.Eval == nil {
+		return fmt.Errorf("a function needs a name and an implementation")
+	}
+	if function.MaxArgs >= 0 && function.MaxArgs < function.MinArgs {
+		return fmt.Errorf("$%s() can't take fewer than %d arguments and at most %d", name, function.MinArgs, function.MaxArgs)
+	}
+	functionLock.Lock()
+	defer functionLock.Unlock()
+	if _, ok := coreFunctions[name]; ok {
+		return fmt.Errorf("function '%s' is already registered", name)
+	}
+	if _, ok := functions[name]; ok {
+		return fmt.Errorf("function '%s' is already registered", name)
+	}
+	functions[name] = function
+	return nil
+}
+
+// LookupFunction returns the definition of a function known to the expression language.
+func LookupFunction(name string) (ExpressionFunction, bool) {
+	if f, ok := coreFunctions[name]; ok {
+		return f, true
+	}
+	functionLock.RLock()
+	defer functionLock.RUnlock()
+	f, ok := functions[name]
+	return f, ok
+}
+
+// FunctionNames returns the sorted names of all functions known to the expression language.
+func FunctionNames() []string {
+	functionLock.RLock()
+	defer functionLock.RUnlock()
+	names := make([]string, 0, len(coreFunctions)+len(functions))
+	for name := range coreFunctions {
+		names = append(names, name)
+	}
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkArgCount(name string, function ExpressionFunction, count int) error {
+	if count < function.MinArgs || (function.MaxArgs >= 0 && count > function.MaxArgs) {
+		switch {
+		case function.MinArgs == function.MaxArgs:
+			return fmt.Errorf("$%s() expects %d arguments, found %d", name, function.MinArgs, count)
+		case function.MaxArgs < 0:
+			return fmt.Errorf("$%s() expects at least %d arguments, found %d", name, function.MinArgs, count)
+		default:
+			return fmt.Errorf("$%s() expects %d to %d arguments, found %d", name, function.MinArgs, function.MaxArgs, count)
+		}
+	}
+	return nil
+}
+
+func registerBuiltInFunctions() {
+	builtIns := map[string]ExpressionFunction{
+		"concat": {MinArgs: 1, MaxArgs: -1, Eval: func(args []interface{}) (interface{}, error) {
+			var sb strings.Builder
+			for _, arg := range args {
+				sb.WriteString(FormatAsString(arg))
+			}
+			return sb.String(), nil
+		}},
+		"replace": {MinArgs: 3, MaxArgs: 3, Eval: func(args []interface{}) (interface{}, error) {
+			return strings.ReplaceAll(FormatAsString(args[0]), FormatAsString(args[1]), FormatAsString(args[2])), nil
+		}},
+		"split": {MinArgs: 2, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			parts := strings.Split(FormatAsString(args[0]), FormatAsString(args[1]))
+			ret := make([]interface{}, len(parts))
+			for i, p := range parts {
+				ret[i] = p
+			}
+			return ret, nil
+		}},
+		"join": {MinArgs: 2, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			items, err := toList(args[0])
+			if err != nil {
+				return nil, err
+			}
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = FormatAsString(item)
+			}
+			return strings.Join(parts, FormatAsString(args[1])), nil
+		}},
+		"lower": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			return strings.ToLower(FormatAsString(args[0])), nil
+		}},
+		"upper": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			return strings.ToUpper(FormatAsString(args[0])), nil
+		}},
+		"trim": {MinArgs: 1, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			if len(args) == 2 {
+				return strings.Trim(FormatAsString(args[0]), FormatAsString(args[1])), nil
+			}
+			return strings.TrimSpace(FormatAsString(args[0])), nil
+		}},
+		"substring": {MinArgs: 2, MaxArgs: 3, Eval: func(args []interface{}) (interface{}, error) {
+			runes := []rune(FormatAsString(args[0]))
+			start, err := toInt(args[1])
+			if err != nil {
+				return nil, err
+			}
+			end := len(runes)
+			if len(args) == 3 {
+				length, err := toInt(args[2])
+				if err != nil {
+					return nil, err
+				}
+				if length < 0 {
+					return nil, fmt.Errorf("$substring() length can't be negative, found %d", length)
+				}
+				end = start + length
+			}
+			if start < 0 || start > len(runes) || end > len(runes) {
+				return nil, fmt.Errorf("$substring() range [%d, %d) is out of bounds for a string of length %d", start, end, len(runes))
+			}
+			return string(runes[start:end]), nil
+		}},
+		"len": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			if s, ok := args[0].(string); ok {
+				return int64(len([]rune(s))), nil
+			}
+			v := reflect.ValueOf(args[0])
+			switch v.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				return int64(v.Len()), nil
+			case reflect.Invalid:
+				return int64(0), nil
+			}
+			return int64(len([]rune(FormatAsString(args[0])))), nil
+		}},
+		"contains": {MinArgs: 2, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			v := reflect.ValueOf(args[0])
+			switch v.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < v.Len(); i++ {
+					if compareInterfaces(v.Index(i).Interface(), args[1]) {
+						return true, nil
+					}
+				}
+				return false, nil
+			case reflect.Map:
+				for _, key := range v.MapKeys() {
+					if FormatAsString(key.Interface()) == FormatAsString(args[1]) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return strings.Contains(FormatAsString(args[0]), FormatAsString(args[1])), nil
+		}},
+		"base64enc": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			return base64.StdEncoding.EncodeToString([]byte(FormatAsString(args[0]))), nil
+		}},
+		"base64dec": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			data, err := base64.StdEncoding.DecodeString(FormatAsString(args[0]))
+			if err != nil {
+				return nil, fmt.Errorf("$base64dec() failed to decode '%v': %v", args[0], err)
+			}
+			return string(data), nil
+		}},
+		"sha256": {MinArgs: 1, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			sum := sha256.Sum256([]byte(FormatAsString(args[0])))
+			return hex.EncodeToString(sum[:]), nil
+		}},
+		"now": {MinArgs: 0, MaxArgs: 1, Eval: func(args []interface{}) (interface{}, error) {
+			layout := time.RFC3339
+			if len(args) == 1 {
+				layout = FormatAsString(args[0])
+			}
+			return time.Now().UTC().Format(layout), nil
+		}},
+		"formatTime": {MinArgs: 2, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			t, err := time.Parse(time.RFC3339, FormatAsString(args[0]))
+			if err != nil {
+				return nil, fmt.Errorf("$formatTime() expects an RFC 3339 time, found '%v'", args[0])
+			}
+			return t.Format(FormatAsString(args[1])), nil
+		}},
+		"default": {MinArgs: 2, MaxArgs: 2, Lenient: true, Eval: func(args []interface{}) (interface{}, error) {
+			if isEmptyValue(args[0]) {
+				return args[1], nil
+			}
+			return args[0], nil
+		}},
+		"coalesce": {MinArgs: 1, MaxArgs: -1, Lenient: true, Eval: func(args []interface{}) (interface{}, error) {
+			for _, arg := range args {
+				if !isEmptyValue(arg) {
+					return arg, nil
+				}
+			}
+			return "", nil
+		}},
+		"regexMatch": {MinArgs: 2, MaxArgs: 2, Eval: func(args []interface{}) (interface{}, error) {
+			re, err := regexp.Compile(FormatAsString(args[0]))
+			if err != nil {
+				return nil, fmt.Errorf("$regexMatch() found an invalid regular expression '%v': %v", args[0], err)
+			}
+			return re.MatchString(FormatAsString(args[1])), nil
+		}},
+	}
+	for name, function := range builtIns {
+		if err := RegisterFunction(name, function); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func isEmptyValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	if s, ok := val.(string); ok {
+		return s == ""
+	}
+	return false
+}
+
+func toInt(val interface{}) (int, error) {
+	if f, ok := toNumber(val); ok && f == float64(int(f)) {
+		return int(f), nil
+	}
+	return 0, fmt.Errorf("%v is not a valid integer", val)
+}
+
+// toList accepts arrays as well as strings that hold JSON arrays, such as the results of $json().
+func toList(val interface{}) ([]interface{}, error) {
+	if s, ok := val.(string); ok {
+		var ret []interface{}
+		if err := json.Unmarshal([]byte(s), &ret); err != nil {
+			return nil, fmt.Errorf("'%s' is not a list", s)
+		}
+		return ret, nil
+	}
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("'%v' is not a list", val)
+	}
+	ret := make([]interface{}, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		ret[i] = v.Index(i).Interface()
+	}
+	return ret, nil
+}
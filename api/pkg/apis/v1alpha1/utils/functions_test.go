@@ -0,0 +1,295 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcat(t *testing.T) {
+	parser := NewParser("${{$concat($property(app), '-', $property(version))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Properties: map[string]string{
+			"app":     "web",
+			"version": "1.2",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "web-1.2", val)
+}
+func TestConcatNoArgs(t *testing.T) {
+	parser := NewParser("${{$concat()}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "$concat() expects at least 1 arguments, found 0", err.Error())
+}
+func TestReplace(t *testing.T) {
+	parser := NewParser("${{$replace('a.b.c', '.', '/')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "a/b/c", val)
+}
+func TestSplit(t *testing.T) {
+	parser := NewParser("${{$split('a,b,c', ',')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, val)
+}
+func TestJoin(t *testing.T) {
+	parser := NewParser("${{$join($split('a,b,c', ','), ';')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "a;b;c", val)
+}
+func TestJoinInput(t *testing.T) {
+	parser := NewParser("${{$join($input(hosts), ',')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"hosts": []interface{}{"h1", "h2"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "h1,h2", val)
+}
+func TestJoinJsonString(t *testing.T) {
+	parser := NewParser("${{$join($property(hosts), ',')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Properties: map[string]string{
+			"hosts": "[\"h1\",\"h2\"]",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "h1,h2", val)
+}
+func TestJoinNotAList(t *testing.T) {
+	parser := NewParser("${{$join(abc, ',')}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestLowerUpper(t *testing.T) {
+	parser := NewParser("${{$lower(HeLLo)}}-${{$upper(HeLLo)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello-HELLO", val)
+}
+func TestTrim(t *testing.T) {
+	parser := NewParser("${{$trim('  abc ')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", val)
+}
+func TestTrimCutset(t *testing.T) {
+	parser := NewParser("${{$trim('--abc-', '-')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", val)
+}
+func TestSubstring(t *testing.T) {
+	parser := NewParser("${{$substring('symphony', 3)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "phony", val)
+}
+func TestSubstringLength(t *testing.T) {
+	parser := NewParser("${{$substring('symphony', 0, 3)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "sym", val)
+}
+func TestSubstringOutOfBounds(t *testing.T) {
+	parser := NewParser("${{$substring('symphony', 5, 10)}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestSubstringInvalidStart(t *testing.T) {
+	parser := NewParser("${{$substring('symphony', abc)}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestLen(t *testing.T) {
+	parser := NewParser("${{$len('hello')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), val)
+}
+func TestLenList(t *testing.T) {
+	parser := NewParser("${{$len($input(hosts))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"hosts": []interface{}{"h1", "h2", "h3"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), val)
+}
+func TestLenInCondition(t *testing.T) {
+	parser := NewParser("${{$gt($len($split('a,b', ',')), 1)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, true, val)
+}
+func TestContainsString(t *testing.T) {
+	parser := NewParser("${{$contains('symphony', 'phon')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, true, val)
+}
+func TestContainsList(t *testing.T) {
+	parser := NewParser("${{$contains($input(hosts), h2)}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"hosts": []interface{}{"h1", "h2"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, val)
+}
+func TestContainsMapKey(t *testing.T) {
+	parser := NewParser("${{$contains($input(labels), zone)}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"labels": map[string]interface{}{"region": "west"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, false, val)
+}
+func TestBase64(t *testing.T) {
+	parser := NewParser("${{$base64enc('hello world')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "aGVsbG8gd29ybGQ=", val)
+
+	parser = NewParser("${{$base64dec($base64enc('hello world'))}}")
+	val, err = parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello world", val)
+}
+func TestBase64DecInvalid(t *testing.T) {
+	parser := NewParser("${{$base64dec('not base64!')}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestSha256(t *testing.T) {
+	parser := NewParser("${{$sha256(abc)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", val)
+}
+func TestNow(t *testing.T) {
+	parser := NewParser("${{$now()}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	ts, err := time.Parse(time.RFC3339, val.(string))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now(), ts, time.Minute)
+}
+func TestNowLayout(t *testing.T) {
+	parser := NewParser("${{$now('2006-01-02')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`), val)
+}
+func TestFormatTime(t *testing.T) {
+	parser := NewParser("${{$formatTime('2023-05-17T08:30:00Z', '02 Jan 2006 15:04')}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "17 May 2023 08:30", val)
+}
+func TestFormatTimeInvalid(t *testing.T) {
+	parser := NewParser("${{$formatTime(yesterday, '2006')}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestDefault(t *testing.T) {
+	parser := NewParser("${{$default($property(replicas), 3)}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Properties: map[string]string{
+			"image": "nginx",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), val)
+}
+func TestDefaultWithValue(t *testing.T) {
+	parser := NewParser("${{$default($property(replicas), 3)}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Properties: map[string]string{
+			"replicas": "5",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "5", val)
+}
+func TestCoalesce(t *testing.T) {
+	parser := NewParser("${{$coalesce($input(a), $input(b), c)}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"a": "",
+			"b": "bee",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "bee", val)
+}
+func TestRegexMatch(t *testing.T) {
+	parser := NewParser("${{$regexMatch('^v[0-9]+$', $property(tag))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Properties: map[string]string{
+			"tag": "v12",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, val)
+}
+func TestRegexMatchInvalid(t *testing.T) {
+	parser := NewParser("${{$regexMatch('[a-', abc)}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
+func TestFunctionArgCount(t *testing.T) {
+	parser := NewParser("${{$replace(a, b)}}")
+	_, err := parser.Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "$replace() expects 3 arguments, found 2", err.Error())
+}
+func TestRegisterFunction(t *testing.T) {
+	err := RegisterFunction("reverse", ExpressionFunction{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Eval: func(args []interface{}) (interface{}, error) {
+			runes := []rune(FormatAsString(args[0]))
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes), nil
+		},
+	})
+	assert.Nil(t, err)
+	parser := NewParser("${{$reverse(abc)}}")
+	val, err := parser.Eval(utils.EvaluationContext{})
+	assert.Nil(t, err)
+	assert.Equal(t, "cba", val)
+	assert.Contains(t, FunctionNames(), "reverse")
+}
+func TestRegisterFunctionDuplicate(t *testing.T) {
+	eval := func(args []interface{}) (interface{}, error) {
+		return nil, errors.New("not implemented")
+	}
+	err := RegisterFunction("concat", ExpressionFunction{MinArgs: 1, MaxArgs: 1, Eval: eval})
+	assert.NotNil(t, err)
+	err = RegisterFunction("property", ExpressionFunction{MinArgs: 1, MaxArgs: 1, Eval: eval})
+	assert.NotNil(t, err)
+	err = RegisterFunction("broken", ExpressionFunction{MinArgs: 2, MaxArgs: 1, Eval: eval})
+	assert.NotNil(t, err)
+}
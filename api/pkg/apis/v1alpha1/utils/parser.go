@@ -841,10 +841,66 @@ func (n *FunctionNode) Eval(context utils.EvaluationContext) (interface{}, error
 			return string(jData), nil
 		}
 		return nil, fmt.Errorf("$json() expects 1 argument, fount %d", len(n.Args))
+	case "item":
+		item, ok := context.Inputs["__item"]
+		if !ok {
+			return nil, errors.New("$item() can only be used in a forEach stage")
+		}
+		if len(n.Args) == 0 {
+			return item, nil
+		}
+		if len(n.Args) == 1 {
+			obj, err := n.Args[0].Eval(context)
+			if err != nil {
+				return nil, err
+			}
+			path := fmt.Sprintf("%v", obj)
+			if strings.HasPrefix(path, "$") || strings.HasPrefix(path, "{$") {
+				return JsonPathQuery(item, path)
+			}
+			if mobj, ok := item.(map[string]interface{}); ok {
+				if v, ok := mobj[path]; ok {
+					return v, nil
+				}
+				return nil, fmt.Errorf("key %s is not found in item", path)
+			}
+			return nil, fmt.Errorf("item '%v' is not a map", item)
+		}
+		return nil, fmt.Errorf("$item() expects 0 or 1 argument, found %d", len(n.Args))
+	case "index":
+		if len(n.Args) == 0 {
+			index, ok := context.Inputs["__index"]
+			if !ok {
+				return nil, errors.New("$index() can only be used in a forEach stage")
+			}
+			return index, nil
+		}
+		return nil, fmt.Errorf("$index() expects 0 arguments, found %d", len(n.Args))
+	}
+	if function, ok := LookupFunction(n.Name); ok {
+		return n.evalRegistered(function, context)
 	}
 	return nil, fmt.Errorf("invalid function name: '%s'", n.Name)
 }
 
+func (n *FunctionNode) evalRegistered(function ExpressionFunction, context utils.EvaluationContext) (interface{}, error) {
+	if err := checkArgCount(n.Name, function, len(n.Args)); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := arg.Eval(context)
+		if err != nil {
+			if !function.Lenient {
+				return nil, err
+			}
+			val = nil
+		}
+		args[i] = val
+	}
+	return function.Eval(args)
+}
+
 type Parser struct {
 	Segments     []string
 	OriginalText string
@@ -894,8 +950,12 @@ func (p *Parser) Eval(context utils.EvaluationContext) (interface{}, error) {
 			results = append(results, s)
 		}
 	}
+	return joinSegments(results), nil
+}
+
+func joinSegments(results []interface{}) interface{} {
 	if len(results) == 1 {
-		return results[0], nil
+		return results[0]
 	}
 	//join the results as string
 	var ret interface{}
@@ -906,7 +966,7 @@ func (p *Parser) Eval(context utils.EvaluationContext) (interface{}, error) {
 			ret = fmt.Sprintf("%v%v", ret, v)
 		}
 	}
-	return ret, nil
+	return ret
 }
 
 func newExpressionParser(text string) *ExpressionParser {
@@ -933,45 +993,7 @@ func (p *ExpressionParser) Eval(context utils.EvaluationContext) (interface{}, e
 			if r != nil {
 				return "", r
 			}
-			if vt, ok := v.([]string); ok {
-				if ret == nil {
-					ret = vt
-				} else if vr, o := ret.([]string); o {
-					vr = append(vr, vt...)
-					ret = vr
-				} else {
-					jData, _ := json.Marshal(v)
-					ret = fmt.Sprintf("%v%v", ret, string(jData))
-				}
-			} else if vt, ok := v.([]interface{}); ok {
-				if ret == nil {
-					ret = vt
-				} else if vr, o := ret.([]interface{}); o {
-					vr = append(vr, vt...)
-					ret = vr
-				} else {
-					jData, _ := json.Marshal(v)
-					ret = fmt.Sprintf("%v%v", ret, string(jData))
-				}
-			} else if vt, ok := v.(map[string]interface{}); ok {
-				if ret == nil {
-					ret = vt
-				} else if vr, o := ret.(map[string]interface{}); o {
-					for k, v := range vt {
-						vr[k] = v
-					}
-					ret = vr
-				} else {
-					jData, _ := json.Marshal(v)
-					ret = fmt.Sprintf("%v%v", ret, string(jData))
-				}
-			} else {
-				if ret == nil {
-					ret = v
-				} else {
-					ret = fmt.Sprintf("%v%v", ret, v)
-				}
-			}
+			ret = appendResult(ret, v)
 		} else {
 			return ret, nil
 		}
@@ -979,6 +1001,50 @@ func (p *ExpressionParser) Eval(context utils.EvaluationContext) (interface{}, e
 	}
 }
 
+// appendResult combines the value of an expression with the values of the expressions before it.
+func appendResult(ret interface{}, v interface{}) interface{} {
+	if vt, ok := v.([]string); ok {
+		if ret == nil {
+			ret = vt
+		} else if vr, o := ret.([]string); o {
+			vr = append(vr, vt...)
+			ret = vr
+		} else {
+			jData, _ := json.Marshal(v)
+			ret = fmt.Sprintf("%v%v", ret, string(jData))
+		}
+	} else if vt, ok := v.([]interface{}); ok {
+		if ret == nil {
+			ret = vt
+		} else if vr, o := ret.([]interface{}); o {
+			vr = append(vr, vt...)
+			ret = vr
+		} else {
+			jData, _ := json.Marshal(v)
+			ret = fmt.Sprintf("%v%v", ret, string(jData))
+		}
+	} else if vt, ok := v.(map[string]interface{}); ok {
+		if ret == nil {
+			ret = vt
+		} else if vr, o := ret.(map[string]interface{}); o {
+			for k, v := range vt {
+				vr[k] = v
+			}
+			ret = vr
+		} else {
+			jData, _ := json.Marshal(v)
+			ret = fmt.Sprintf("%v%v", ret, string(jData))
+		}
+	} else {
+		if ret == nil {
+			ret = v
+		} else {
+			ret = fmt.Sprintf("%v%v", ret, v)
+		}
+	}
+	return ret
+}
+
 func (p *ExpressionParser) next() {
 	p.token = p.scan()
 }
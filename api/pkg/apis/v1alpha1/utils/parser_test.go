@@ -2056,6 +2056,39 @@ func TestInputOr(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, true, val)
 }
+func TestItem(t *testing.T) {
+	context := utils.EvaluationContext{
+		Inputs: map[string]interface{}{
+			"__item": map[string]interface{}{
+				"name": "east",
+				"tags": map[string]interface{}{
+					"tier": "web",
+				},
+			},
+			"__index": 2,
+		},
+	}
+	val, err := NewParser("${{$item(name)}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, "east", val)
+	val, err = NewParser("${{$item('$.tags.tier')}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, "web", val)
+	val, err = NewParser("${{$index()}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, val)
+	val, err = NewParser("${{$item()}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, context.Inputs["__item"], val)
+	_, err = NewParser("${{$item(region)}}").Eval(context)
+	assert.NotNil(t, err)
+}
+func TestItemOutsideForEach(t *testing.T) {
+	_, err := NewParser("${{$item()}}").Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+	_, err = NewParser("${{$index()}}").Eval(utils.EvaluationContext{})
+	assert.NotNil(t, err)
+}
 func TestStringLiteral(t *testing.T) {
 	parser := NewParser("stage-1")
 	val, err := parser.Eval(utils.EvaluationContext{})
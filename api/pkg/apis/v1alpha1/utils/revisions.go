@@ -0,0 +1,316 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+// RevisionStore keeps the revisions of one kind of objects in a state provider, one entry per revision.
+// The state provider must not hold other objects, as revisions are found by listing it.
+type RevisionStore struct {
+	StateProvider states.IStateProvider
+	Group         string
+	Kind          string
+	Resource      string
+	// Limit is the number of revisions kept for an object, older revisions are dropped. 0 keeps all of them.
+	Limit int
+}
+
+// NewRevisionStore creates the revision store of a manager from its providers.revisions and revisions.limit
+// properties. It returns nil if the manager doesn't keep revisions.
+func NewRevisionStore(config managers.ManagerConfig, providers map[string]providers.IProvider, group string, kind string, resource string) (*RevisionStore, error) {
+	providerName, ok := config.Properties["providers.revisions"]
+	if !ok {
+		return nil, nil
+	}
+	provider, ok := providers[providerName]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "revision provider is not supplied", v1alpha2.MissingConfig)
+	}
+	stateProvider, ok := provider.(states.IStateProvider)
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "supplied revision provider is not a state provider", v1alpha2.BadConfig)
+	}
+	store := &RevisionStore{
+		StateProvider: stateProvider,
+		Group:         group,
+		Kind:          kind,
+		Resource:      resource,
+	}
+	if v, ok := config.Properties["revisions.limit"]; ok {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid revisions.limit '%s', expected a non-negative integer", v), v1alpha2.BadConfig)
+		}
+		store.Limit = limit
+	}
+	return store, nil
+}
+
+// maxRecordAttempts is the number of revision numbers Record tries when other writers take them first
+const maxRecordAttempts = 5
+
+func revisionId(name string, revision int) string {
+	return fmt.Sprintf("%s-v%d", name, revision)
+}
+
+func revisionScope(scope string) string {
+	if scope == "" {
+		return "default"
+	}
+	return scope
+}
+
+func (r *RevisionStore) metadata(scope string) map[string]string {
+	return map[string]string{
+		"scope":    revisionScope(scope),
+		"group":    r.Group,
+		"version":  "v1",
+		"resource": r.Resource,
+	}
+}
+
+// List returns the revisions of an object, oldest first
+func (r *RevisionStore) List(ctx context.Context, name string, scope string) ([]model.RevisionSpec, error) {
+	entries, _, err := r.StateProvider.List(ctx, states.ListRequest{
+		Metadata: r.metadata(scope),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]model.RevisionSpec, 0)
+	for _, entry := range entries {
+		revision, err := getRevisionSpec(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		if revision.Object == name {
+			ret = append(ret, revision)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Revision < ret[j].Revision
+	})
+	return ret, nil
+}
+
+// Get returns a revision of an object
+func (r *RevisionStore) Get(ctx context.Context, name string, scope string, revision int) (model.RevisionSpec, error) {
+	entry, err := r.StateProvider.Get(ctx, states.GetRequest{
+		ID:       revisionId(name, revision),
+		Metadata: r.metadata(scope),
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			return model.RevisionSpec{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("revision %d of '%s' is not found", revision, name), v1alpha2.NotFound)
+		}
+		return model.RevisionSpec{}, err
+	}
+	ret, err := getRevisionSpec(entry.Body)
+	if err != nil {
+		return model.RevisionSpec{}, err
+	}
+	if ret.Object != name {
+		return model.RevisionSpec{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("revision %d of '%s' is not found", revision, name), v1alpha2.NotFound)
+	}
+	return ret, nil
+}
+
+// Record adds a revision with the given spec, unless the latest revision has the same spec. It returns
+// the latest revision. Revisions are written with create-only writes, and a revision number that another
+// writer took first is retried with the next number. This relies on the state provider checking ETags, as
+// the file and k8s state providers do. The memory state provider doesn't check them, so concurrent records
+// may overwrite each other's revisions there.
+func (r *RevisionStore) Record(ctx context.Context, name string, scope string, spec interface{}, author string) (model.RevisionSpec, error) {
+	jData, _ := json.Marshal(spec)
+	var current interface{}
+	err := json.Unmarshal(jData, &current)
+	if err != nil {
+		return model.RevisionSpec{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		revisions, err := r.List(ctx, name, scope)
+		if err != nil {
+			return model.RevisionSpec{}, err
+		}
+		next := 1
+		if len(revisions) > 0 {
+			latest := revisions[len(revisions)-1]
+			if reflect.DeepEqual(latest.Spec, current) {
+				return latest, nil
+			}
+			next = latest.Revision + 1
+		}
+		revision := model.RevisionSpec{
+			Object:    name,
+			Revision:  next,
+			Author:    author,
+			Timestamp: time.Now().UTC(),
+			Spec:      current,
+		}
+		err = r.create(ctx, scope, revision)
+		if err != nil {
+			if v1alpha2.IsConflict(err) && attempt < maxRecordAttempts {
+				continue
+			}
+			return model.RevisionSpec{}, err
+		}
+		if r.Limit > 0 {
+			for i := 0; i < len(revisions)+1-r.Limit; i++ {
+				err = r.StateProvider.Delete(ctx, states.DeleteRequest{
+					ID:       revisionId(name, revisions[i].Revision),
+					Metadata: r.metadata(scope),
+				})
+				if err != nil && !v1alpha2.IsNotFound(err) {
+					return model.RevisionSpec{}, err
+				}
+			}
+		}
+		return revision, nil
+	}
+}
+
+// create writes a revision, failing with a Conflict error if the revision already exists
+func (r *RevisionStore) create(ctx context.Context, scope string, revision model.RevisionSpec) error {
+	id := revisionId(revision.Object, revision.Revision)
+	etag := ""
+	_, err := r.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: id,
+			Body: map[string]interface{}{
+				"apiVersion": r.Group + "/v1",
+				"kind":       r.Kind,
+				"metadata": map[string]interface{}{
+					"name": id,
+				},
+				"spec": revision,
+			},
+		},
+		ETag: &etag,
+		Metadata: map[string]string{
+			"template": fmt.Sprintf(`{"apiVersion":"%s/v1", "kind": "%s", "metadata": {"name": "%s"}}`, r.Group, r.Kind, id),
+			"scope":    revisionScope(scope),
+			"group":    r.Group,
+			"version":  "v1",
+			"resource": r.Resource,
+		},
+		Options: states.UpsertOption{
+			Concurrency: "first-write",
+		},
+	})
+	return err
+}
+
+// Diff compares two revisions of an object
+func (r *RevisionStore) Diff(ctx context.Context, name string, scope string, from int, to int) (model.RevisionDiff, error) {
+	fromRevision, err := r.Get(ctx, name, scope, from)
+	if err != nil {
+		return model.RevisionDiff{}, err
+	}
+	toRevision, err := r.Get(ctx, name, scope, to)
+	if err != nil {
+		return model.RevisionDiff{}, err
+	}
+	return model.RevisionDiff{
+		Object:  name,
+		From:    from,
+		To:      to,
+		Changes: DiffSpecs(fromRevision.Spec, toRevision.Spec),
+	}, nil
+}
+
+func getRevisionSpec(body interface{}) (model.RevisionSpec, error) {
+	var ret model.RevisionSpec
+	dict, ok := body.(map[string]interface{})
+	if !ok {
+		return ret, v1alpha2.NewCOAError(nil, "found invalid revision entry", v1alpha2.InternalError)
+	}
+	jData, _ := json.Marshal(dict["spec"])
+	err := json.Unmarshal(jData, &ret)
+	return ret, err
+}
+
+// DiffSpecs compares two objects field by field, after converting them to JSON. Lists are compared item
+// by item.
+func DiffSpecs(from interface{}, to interface{}) []model.SpecChange {
+	changes := make([]model.SpecChange, 0)
+	diffValues("", toJsonValue(from), toJsonValue(to), &changes)
+	return changes
+}
+
+func toJsonValue(obj interface{}) interface{} {
+	jData, _ := json.Marshal(obj)
+	var ret interface{}
+	json.Unmarshal(jData, &ret)
+	return ret
+}
+
+func diffValues(path string, from interface{}, to interface{}, changes *[]model.SpecChange) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := make([]string, 0, len(fromMap)+len(toMap))
+		for k := range fromMap {
+			keys = append(keys, k)
+		}
+		for k := range toMap {
+			if _, ok := fromMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			fromValue, inFrom := fromMap[k]
+			toValue, inTo := toMap[k]
+			switch {
+			case !inFrom:
+				*changes = append(*changes, model.SpecChange{Path: childPath, Type: model.SpecChangeAdded, To: toValue})
+			case !inTo:
+				*changes = append(*changes, model.SpecChange{Path: childPath, Type: model.SpecChangeRemoved, From: fromValue})
+			default:
+				diffValues(childPath, fromValue, toValue, changes)
+			}
+		}
+		return
+	}
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(fromList):
+				*changes = append(*changes, model.SpecChange{Path: childPath, Type: model.SpecChangeAdded, To: toList[i]})
+			case i >= len(toList):
+				*changes = append(*changes, model.SpecChange{Path: childPath, Type: model.SpecChangeRemoved, From: fromList[i]})
+			default:
+				diffValues(childPath, fromList[i], toList[i], changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, model.SpecChange{Path: path, Type: model.SpecChangeModified, From: from, To: to})
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRevisionStore(limit int) *RevisionStore {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	return &RevisionStore{
+		StateProvider: stateProvider,
+		Group:         model.WorkflowGroup,
+		Kind:          "CampaignRevision",
+		Resource:      "campaignrevisions",
+		Limit:         limit,
+	}
+}
+
+func TestRevisionStoreRecord(t *testing.T) {
+	store := newRevisionStore(0)
+	ctx := context.Background()
+
+	revision, err := store.Record(ctx, "campaign1", "", model.CampaignSpec{FirstStage: "build"}, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, revision.Revision)
+	assert.Equal(t, "alice", revision.Author)
+
+	// the same spec doesn't make a new revision
+	revision, err = store.Record(ctx, "campaign1", "", model.CampaignSpec{FirstStage: "build"}, "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, revision.Revision)
+	assert.Equal(t, "alice", revision.Author)
+
+	revision, err = store.Record(ctx, "campaign1", "", model.CampaignSpec{FirstStage: "deploy"}, "bob")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, revision.Revision)
+
+	_, err = store.Record(ctx, "campaign2", "", model.CampaignSpec{FirstStage: "deploy"}, "bob")
+	assert.Nil(t, err)
+
+	revisions, err := store.List(ctx, "campaign1", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, 1, revisions[0].Revision)
+	assert.Equal(t, 2, revisions[1].Revision)
+	assert.Equal(t, "bob", revisions[1].Author)
+
+	revision, err = store.Get(ctx, "campaign1", "", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "build", revision.Spec.(map[string]interface{})["firstStage"])
+
+	_, err = store.Get(ctx, "campaign1", "", 3)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestRevisionStoreLimit(t *testing.T) {
+	store := newRevisionStore(2)
+	ctx := context.Background()
+	for _, stage := range []string{"a", "b", "c"} {
+		_, err := store.Record(ctx, "campaign1", "", model.CampaignSpec{FirstStage: stage}, "alice")
+		assert.Nil(t, err)
+	}
+	revisions, err := store.List(ctx, "campaign1", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, 2, revisions[0].Revision)
+	assert.Equal(t, 3, revisions[1].Revision)
+}
+
+// racingStateProvider records a revision of another writer right before the first revision it is asked to write
+type racingStateProvider struct {
+	*filestate.FileStateProvider
+	store *RevisionStore
+	raced bool
+}
+
+func (r *racingStateProvider) Upsert(ctx context.Context, request states.UpsertRequest) (string, error) {
+	if !r.raced {
+		r.raced = true
+		if _, err := r.store.Record(ctx, "campaign1", "", model.CampaignSpec{FirstStage: "other"}, "bob"); err != nil {
+			return "", err
+		}
+	}
+	return r.FileStateProvider.Upsert(ctx, request)
+}
+
+func TestRevisionStoreRecordConflict(t *testing.T) {
+	stateProvider := &filestate.FileStateProvider{}
+	err := stateProvider.Init(filestate.FileStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "revisions.json"),
+	})
+	assert.Nil(t, err)
+	store := &RevisionStore{
+		StateProvider: stateProvider,
+		Group:         model.WorkflowGroup,
+		Kind:          "CampaignRevision",
+		Resource:      "campaignrevisions",
+	}
+	racingStore := *store
+	racingStore.StateProvider = &racingStateProvider{FileStateProvider: stateProvider, store: store}
+
+	revision, err := racingStore.Record(context.Background(), "campaign1", "", model.CampaignSpec{FirstStage: "build"}, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, revision.Revision)
+	revisions, err := store.List(context.Background(), "campaign1", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "bob", revisions[0].Author)
+	assert.Equal(t, "alice", revisions[1].Author)
+}
+
+func TestRevisionStoreDiff(t *testing.T) {
+	store := newRevisionStore(0)
+	ctx := context.Background()
+	_, err := store.Record(ctx, "campaign1", "", model.CampaignSpec{
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build": {Name: "build", Provider: "providers.stage.mock"},
+		},
+	}, "alice")
+	assert.Nil(t, err)
+	_, err = store.Record(ctx, "campaign1", "", model.CampaignSpec{
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build":  {Name: "build", Provider: "providers.stage.http"},
+			"deploy": {Name: "deploy"},
+		},
+	}, "bob")
+	assert.Nil(t, err)
+
+	diff, err := store.Diff(ctx, "campaign1", "", 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	assert.Equal(t, []model.SpecChange{
+		{Path: "stages.build.provider", Type: model.SpecChangeModified, From: "providers.stage.mock", To: "providers.stage.http"},
+		{Path: "stages.deploy", Type: model.SpecChangeAdded, To: map[string]interface{}{"name": "deploy"}},
+	}, diff.Changes)
+
+	_, err = store.Diff(ctx, "campaign1", "", 1, 5)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestDiffSpecs(t *testing.T) {
+	from := map[string]interface{}{
+		"name":   "app",
+		"images": []string{"a:1", "b:1"},
+		"labels": map[string]string{"tier": "web"},
+	}
+	to := map[string]interface{}{
+		"name":   "app",
+		"images": []string{"a:2"},
+		"ports":  []int{80},
+	}
+	assert.Equal(t, []model.SpecChange{
+		{Path: "images[0]", Type: model.SpecChangeModified, From: "a:1", To: "a:2"},
+		{Path: "images[1]", Type: model.SpecChangeRemoved, From: "b:1"},
+		{Path: "labels", Type: model.SpecChangeRemoved, From: map[string]interface{}{"tier": "web"}},
+		{Path: "ports", Type: model.SpecChangeAdded, To: []interface{}{float64(80)}},
+	}, DiffSpecs(from, to))
+	assert.Empty(t, DiffSpecs(from, from))
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+type siteTransportKey struct {
+	clientCert string
+	clientKey  string
+	caCert     string
+}
+
+// siteTransports caches a transport per TLS configuration, so that connections to other sites are reused
+var siteTransports sync.Map
+
+// siteClient returns the HTTP client of a connection to another site. The client certificate is read
+// on each handshake, so that a renewed certificate is picked up without a restart.
+func siteClient(conn v1alpha2.SiteConnection) (*http.Client, error) {
+	if !conn.UsesClientCert() && conn.CACert == "" {
+		return &http.Client{}, nil
+	}
+	key := siteTransportKey{clientCert: conn.ClientCert, clientKey: conn.ClientKey, caCert: conn.CACert}
+	if transport, ok := siteTransports.Load(key); ok {
+		return &http.Client{Transport: transport.(*http.Transport)}, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conn.CACert != "" {
+		caData, err := os.ReadFile(conn.CACert)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to read the ca certificate of the site connection", v1alpha2.BadConfig)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, v1alpha2.NewCOAError(nil, "ca certificate of the site connection is not valid", v1alpha2.BadConfig)
+		}
+	}
+	if conn.UsesClientCert() {
+		// fail early on a bad certificate, rather than on each handshake
+		if _, err := tls.LoadX509KeyPair(conn.ClientCert, conn.ClientKey); err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to load the client certificate of the site connection", v1alpha2.BadConfig)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(conn.ClientCert, conn.ClientKey)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	actual, _ := siteTransports.LoadOrStore(key, transport)
+	return &http.Client{Transport: actual.(*http.Transport)}, nil
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert issues a certificate, signed by the parent or self-signed, and writes it and its key as PEM files
+func writeCert(t *testing.T, dir string, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return cert, key
+}
+
+// newParentSite starts a parent site that requires client certificates of its CA, and returns the
+// connection of a child site to it
+func newParentSite(t *testing.T, handler http.Handler) v1alpha2.SiteConnection {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "symphony-test-ca"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	writeCert(t, dir, "server", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "hq"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	writeCert(t, dir, "tokyo", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "tokyo"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	assert.Nil(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return v1alpha2.SiteConnection{
+		BaseUrl:    server.URL + "/v1alpha2/",
+		Username:   "admin",
+		ClientCert: filepath.Join(dir, "tokyo.crt"),
+		ClientKey:  filepath.Join(dir, "tokyo.key"),
+		CACert:     filepath.Join(dir, "ca.crt"),
+	}
+}
+
+func TestGetABatchForSiteWithClientCert(t *testing.T) {
+	parent := newParentSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a site with a certificate doesn't sign in
+		if r.URL.Path != "/v1alpha2/federation/sync/tokyo" || r.Header.Get("Authorization") != "" || len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(model.SyncPackage{Origin: r.TLS.PeerCertificates[0].Subject.CommonName})
+	}))
+	batch, err := GetABatchForSite(context.Background(), parent, "tokyo")
+	assert.Nil(t, err)
+	assert.Equal(t, "tokyo", batch.Origin)
+}
+
+func TestGetABatchForSiteWithPassword(t *testing.T) {
+	parent := newParentSite(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1alpha2/users/auth" {
+			json.NewEncoder(w).Encode(authResponse{AccessToken: "token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" || len(r.TLS.PeerCertificates) != 0 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(model.SyncPackage{Origin: "hq"})
+	}))
+	// the site verifies the parent with the ca certificate, and signs in without a certificate
+	parent.ClientCert = ""
+	parent.ClientKey = ""
+	batch, err := GetABatchForSite(context.Background(), parent, "tokyo")
+	assert.Nil(t, err)
+	assert.Equal(t, "hq", batch.Origin)
+
+	// the certificate of the parent isn't trusted without the ca certificate
+	parent.CACert = ""
+	_, err = GetABatchForSite(context.Background(), parent, "tokyo")
+	assert.NotNil(t, err)
+}
+
+func TestSiteClientBadConfig(t *testing.T) {
+	dir := t.TempDir()
+	_, err := siteClient(v1alpha2.SiteConnection{ClientCert: filepath.Join(dir, "missing.crt"), ClientKey: filepath.Join(dir, "missing.key")})
+	assert.NotNil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("not a certificate"), 0600))
+	_, err = siteClient(v1alpha2.SiteConnection{CACert: filepath.Join(dir, "ca.crt")})
+	assert.NotNil(t, err)
+}
@@ -112,14 +112,14 @@ func GetSites(context context.Context, baseUrl string, user string, password str
 
 	return ret, nil
 }
-func SyncActivationStatus(context context.Context, baseUrl string, user string, password string, status model.ActivationStatus) error {
-	token, err := auth(context, baseUrl, user, password)
+func SyncActivationStatus(context context.Context, parent v1alpha2.SiteConnection, status model.ActivationStatus) error {
+	client, token, err := siteAuth(context, parent)
 
 	if err != nil {
 		return err
 	}
 	jData, _ := json.Marshal(status)
-	_, err = callRestAPI(context, baseUrl, "federation/sync", "POST", jData, token)
+	_, err = callRestAPIWithClient(context, client, parent.BaseUrl, "federation/sync", "POST", jData, token)
 	if err != nil {
 		return err
 	}
@@ -201,15 +201,15 @@ func PublishActivationEvent(context context.Context, baseUrl string, user string
 
 	return nil
 }
-func GetABatchForSite(context context.Context, baseUrl string, site string, user string, password string) (model.SyncPackage, error) {
+func GetABatchForSite(context context.Context, parent v1alpha2.SiteConnection, site string) (model.SyncPackage, error) {
 	ret := model.SyncPackage{}
-	token, err := auth(context, baseUrl, user, password)
+	client, token, err := siteAuth(context, parent)
 
 	if err != nil {
 		return ret, err
 	}
 
-	response, err := callRestAPI(context, baseUrl, "federation/sync/"+site+"?count=10", "GET", nil, token)
+	response, err := callRestAPIWithClient(context, client, parent.BaseUrl, "federation/sync/"+site+"?count=10", "GET", nil, token)
 	if err != nil {
 		return ret, err
 	}
@@ -220,6 +220,20 @@ func GetABatchForSite(context context.Context, baseUrl string, site string, user
 	}
 	return ret, nil
 }
+func AckABatchForSite(context context.Context, parent v1alpha2.SiteConnection, site string, leases []string) error {
+	client, token, err := siteAuth(context, parent)
+
+	if err != nil {
+		return err
+	}
+
+	jData, _ := json.Marshal(leases)
+	_, err = callRestAPIWithClient(context, client, parent.BaseUrl, "federation/ack/"+site, "POST", jData, token)
+	if err != nil {
+		return err
+	}
+	return nil
+}
 func GetActivation(context context.Context, baseUrl string, activation string, user string, password string) (model.ActivationState, error) {
 	ret := model.ActivationState{}
 	token, err := auth(context, baseUrl, user, password)
@@ -502,13 +516,13 @@ func GetTargets(context context.Context, baseUrl string, user string, password s
 	return ret, nil
 }
 
-func UpdateSite(context context.Context, baseUrl string, site string, user string, password string, payload []byte) error {
-	token, err := auth(context, baseUrl, user, password)
+func UpdateSite(context context.Context, parent v1alpha2.SiteConnection, site string, payload []byte) error {
+	client, token, err := siteAuth(context, parent)
 	if err != nil {
 		return err
 	}
 
-	_, err = callRestAPI(context, baseUrl, "federation/status/"+site, "POST", payload, token)
+	_, err = callRestAPIWithClient(context, client, parent.BaseUrl, "federation/status/"+site, "POST", payload, token)
 	if err != nil {
 		return err
 	}
@@ -767,9 +781,12 @@ func Reconcile(context context.Context, baseUrl string, user string, password st
 	return summary, nil
 }
 func auth(context context.Context, baseUrl string, user string, password string) (string, error) {
+	return authWithClient(context, &http.Client{}, baseUrl, user, password)
+}
+func authWithClient(context context.Context, client *http.Client, baseUrl string, user string, password string) (string, error) {
 	request := authRequest{Username: user, Password: password}
 	requestData, _ := json.Marshal(request)
-	ret, err := callRestAPI(context, baseUrl, "users/auth", "POST", requestData, "")
+	ret, err := callRestAPIWithClient(context, client, baseUrl, "users/auth", "POST", requestData, "")
 	if err != nil {
 		return "", err
 	}
@@ -782,7 +799,27 @@ func auth(context context.Context, baseUrl string, user string, password string)
 
 	return response.AccessToken, nil
 }
+
+// siteAuth authenticates a site to another site. A site with a client certificate presents the
+// certificate on every call, and other sites sign in with their username and password.
+func siteAuth(context context.Context, conn v1alpha2.SiteConnection) (*http.Client, string, error) {
+	client, err := siteClient(conn)
+	if err != nil {
+		return nil, "", err
+	}
+	if conn.UsesClientCert() {
+		return client, "", nil
+	}
+	token, err := authWithClient(context, client, conn.BaseUrl, conn.Username, conn.Password)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, token, nil
+}
 func callRestAPI(context context.Context, baseUrl string, route string, method string, payload []byte, token string) ([]byte, error) {
+	return callRestAPIWithClient(context, &http.Client{}, baseUrl, route, method, payload, token)
+}
+func callRestAPIWithClient(context context.Context, client *http.Client, baseUrl string, route string, method string, payload []byte, token string) ([]byte, error) {
 	context, span := observability.StartSpan("Symphony-API-Client", context, &map[string]string{
 		"method":      "callRestAPI",
 		"http.method": method,
@@ -793,7 +830,6 @@ func callRestAPI(context context.Context, baseUrl string, route string, method s
 
 	log.Infof("Calling Symphony API: %s %s, spanId: %s, traceId: %s", method, baseUrl+route, span.SpanContext().SpanID().String(), span.SpanContext().TraceID().String())
 
-	client := &http.Client{}
 	rUrl := baseUrl + route
 	var req *http.Request
 	if payload != nil {
@@ -67,6 +67,20 @@ func GetString(col map[string]string, key string) (string, error) {
 	return "", fmt.Errorf("key %s is not found", key)
 }
 
+// ReadPaging reads the "limit" and "continue" query parameters of a list request.
+// A missing limit is returned as 0, which means no paging.
+func ReadPaging(col map[string]string) (int64, string, error) {
+	var limit int64
+	if v, ok := col["limit"]; ok && v != "" {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || i < 0 {
+			return 0, "", v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid limit '%s', expected a non-negative integer", v), v1alpha2.BadRequest)
+		}
+		limit = i
+	}
+	return limit, col["continue"], nil
+}
+
 func ReadStringFromMapCompat(col map[string]interface{}, key string, defaultVal string) string {
 	if v, ok := col[key]; ok {
 		i, e := ParseValue(fmt.Sprintf("%v", v))
@@ -311,3 +325,26 @@ func jsonPathQuery(obj interface{}, jsonPath string) (interface{}, error) {
 		return result, nil
 	}
 }
+
+// ReadStringList reads a list of names, given either as a list or as a comma-separated string
+func ReadStringList(v interface{}) ([]string, error) {
+	ret := make([]string, 0)
+	switch vs := v.(type) {
+	case nil:
+	case string:
+		for _, s := range strings.Split(vs, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				ret = append(ret, s)
+			}
+		}
+	case []string:
+		ret = append(ret, vs...)
+	case []interface{}:
+		for _, s := range vs {
+			ret = append(ret, fmt.Sprintf("%v", s))
+		}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid list of names: %v", v), v1alpha2.BadRequest)
+	}
+	return ret, nil
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -404,3 +405,37 @@ func TestToInterfaceMap(t *testing.T) {
 	assert.Equal(t, "bar", m2["foo"])
 	assert.Equal(t, "123", m2["abc"])
 }
+
+func TestReadPaging(t *testing.T) {
+	limit, token, err := ReadPaging(map[string]string{"limit": "10", "continue": "abc"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), limit)
+	assert.Equal(t, "abc", token)
+
+	limit, token, err = ReadPaging(map[string]string{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), limit)
+	assert.Equal(t, "", token)
+}
+
+func TestReadPagingInvalidLimit(t *testing.T) {
+	for _, v := range []string{"abc", "-1"} {
+		_, _, err := ReadPaging(map[string]string{"limit": v})
+		assert.NotNil(t, err)
+		assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	}
+}
+
+func TestReadStringList(t *testing.T) {
+	list, err := ReadStringList("alice, bob,")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, list)
+	list, err = ReadStringList([]interface{}{"alice", "bob"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, list)
+	list, err = ReadStringList(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+	_, err = ReadStringList(3)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+)
+
+const (
+	// ExpressionValidationStrict rejects objects with invalid expressions
+	ExpressionValidationStrict = "strict"
+	// ExpressionValidationWarn accepts objects with invalid expressions and reports the issues
+	ExpressionValidationWarn = "warn"
+	// ExpressionValidationOff skips expression validation
+	ExpressionValidationOff = "off"
+)
+
+// ExpressionIssue is a problem found in a ${{ }} expression. Column is the 1-based position of the
+// problem within the value at Path.
+type ExpressionIssue struct {
+	Path    string `json:"path"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (i ExpressionIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.Path, i.Column, i.Message)
+}
+
+// ExpressionValidationOptions carries what's known about the object an expression belongs to.
+type ExpressionValidationOptions struct {
+	// Stages whose outputs $output() may read. When nil, $output() references aren't checked.
+	Stages map[string]bool
+}
+
+var expressionPattern = regexp.MustCompile(`\${{.*?}}`)
+
+type expressionToken struct {
+	tok    rune
+	text   string
+	offset int
+}
+
+type expressionFrame struct {
+	open     expressionToken
+	function string
+	args     [][]expressionToken
+}
+
+// ValidateExpression checks the ${{ }} expressions in a value without evaluating them. It reports
+// unknown functions, wrong numbers of arguments, unbalanced parentheses and, when stages are given,
+// $output() references to stages that can't have run.
+func ValidateExpression(path string, text string, options ExpressionValidationOptions) []ExpressionIssue {
+	issues := make([]ExpressionIssue, 0)
+	end := 0
+	for _, loc := range expressionPattern.FindAllStringIndex(text, -1) {
+		if open := strings.Index(text[end:loc[0]], "${{"); open >= 0 {
+			issues = append(issues, ExpressionIssue{Path: path, Column: end + open + 1, Message: "'${{' is not closed by '}}'"})
+		}
+		issues = append(issues, validateExpressionBody(path, text[loc[0]+3:loc[1]-2], loc[0]+3, options)...)
+		end = loc[1]
+	}
+	if open := strings.Index(text[end:], "${{"); open >= 0 {
+		issues = append(issues, ExpressionIssue{Path: path, Column: end + open + 1, Message: "'${{' is not closed by '}}'"})
+	}
+	return issues
+}
+
+func validateExpressionBody(path string, body string, start int, options ExpressionValidationOptions) []ExpressionIssue {
+	issues := make([]ExpressionIssue, 0)
+	report := func(offset int, format string, args ...interface{}) {
+		issues = append(issues, ExpressionIssue{Path: path, Column: start + offset + 1, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// scan the same way the parser does
+	var s scanner.Scanner
+	s.Init(strings.NewReader(body))
+	s.Mode = scanner.ScanIdents | scanner.ScanChars | scanner.ScanStrings | scanner.ScanInts
+	s.Error = func(*scanner.Scanner, string) {} // unterminated quotes are treated as literals
+	tokens := make([]expressionToken, 0)
+	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
+		tokens = append(tokens, expressionToken{tok: tok, text: s.TokenText(), offset: s.Position.Offset})
+	}
+
+	stack := make([]*expressionFrame, 0)
+	addToken := func(t expressionToken) {
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			top.args[len(top.args)-1] = append(top.args[len(top.args)-1], t)
+		}
+	}
+	closers := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.tok {
+		case '$':
+			if i+1 >= len(tokens) || tokens[i+1].tok != scanner.Ident {
+				report(t.offset, "expected a function name after '$'")
+				continue
+			}
+			name := tokens[i+1]
+			if i+2 >= len(tokens) || tokens[i+2].tok != '(' {
+				report(name.offset, "expected '(' after '$%s'", name.text)
+				i++
+				continue
+			}
+			addToken(t)
+			stack = append(stack, &expressionFrame{open: tokens[i+2], function: name.text, args: [][]expressionToken{{}}})
+			stack[len(stack)-1].open.offset = t.offset
+			i += 2
+		case '(', '[', '{':
+			addToken(t)
+			stack = append(stack, &expressionFrame{open: t, args: [][]expressionToken{{}}})
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1].open.tok != closers[t.tok] {
+				report(t.offset, "unbalanced '%s'", t.text)
+				continue
+			}
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if frame.function != "" {
+				issues = append(issues, validateFunction(path, start, frame, options)...)
+			}
+			addToken(t)
+		case ',':
+			if len(stack) > 0 && stack[len(stack)-1].function != "" {
+				top := stack[len(stack)-1]
+				top.args = append(top.args, []expressionToken{})
+			} else {
+				addToken(t)
+			}
+		default:
+			addToken(t)
+		}
+	}
+	for _, frame := range stack {
+		if frame.function != "" {
+			report(frame.open.offset, "'$%s(' is not closed", frame.function)
+		} else {
+			report(frame.open.offset, "'%s' is not closed", frame.open.text)
+		}
+	}
+	return issues
+}
+
+func validateFunction(path string, start int, frame *expressionFrame, options ExpressionValidationOptions) []ExpressionIssue {
+	column := start + frame.open.offset + 1
+	function, ok := LookupFunction(frame.function)
+	if !ok {
+		return []ExpressionIssue{{Path: path, Column: column, Message: fmt.Sprintf("unknown function '$%s()'", frame.function)}}
+	}
+	count := len(frame.args)
+	if count == 1 && len(frame.args[0]) == 0 {
+		count = 0
+	}
+	if err := checkArgCount(frame.function, function, count); err != nil {
+		return []ExpressionIssue{{Path: path, Column: column, Message: err.Error()}}
+	}
+	if frame.function == "output" && options.Stages != nil {
+		// only literal stage names can be checked
+		if stage := frame.args[0]; len(stage) == 1 && (stage[0].tok == scanner.Ident || stage[0].tok == scanner.Char) {
+			name := removeQuotes(stage[0].text)
+			if !options.Stages[name] {
+				return []ExpressionIssue{{Path: path, Column: start + stage[0].offset + 1, Message: fmt.Sprintf("stage '%s' can't have run before this expression is evaluated", name)}}
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateExpressions checks the expressions in all strings found in a value, such as a property map.
+func ValidateExpressions(path string, value interface{}, options ExpressionValidationOptions) []ExpressionIssue {
+	issues := make([]ExpressionIssue, 0)
+	switch v := value.(type) {
+	case string:
+		issues = append(issues, ValidateExpression(path, v, options)...)
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			issues = append(issues, ValidateExpression(joinPath(path, k), v[k], options)...)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			issues = append(issues, ValidateExpressions(joinPath(path, k), v[k], options)...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			issues = append(issues, ValidateExpressions(fmt.Sprintf("%s[%d]", path, i), item, options)...)
+		}
+	}
+	return issues
+}
+
+// ValidateSolutionExpressions checks the expressions in solution and component metadata, properties and parameters.
+func ValidateSolutionExpressions(solution model.SolutionSpec) []ExpressionIssue {
+	options := ExpressionValidationOptions{}
+	issues := ValidateExpressions("metadata", solution.Metadata, options)
+	for _, c := range solution.Components {
+		path := fmt.Sprintf("components[%s]", c.Name)
+		issues = append(issues, ValidateExpressions(path+".metadata", c.Metadata, options)...)
+		issues = append(issues, ValidateExpressions(path+".properties", c.Properties, options)...)
+		issues = append(issues, ValidateExpressions(path+".parameters", c.Parameters, options)...)
+	}
+	return issues
+}
+
+// ValidateInstanceExpressions checks the expressions in instance metadata, parameters and arguments.
+func ValidateInstanceExpressions(instance model.InstanceSpec) []ExpressionIssue {
+	options := ExpressionValidationOptions{}
+	issues := ValidateExpressions("metadata", instance.Metadata, options)
+	issues = append(issues, ValidateExpressions("parameters", instance.Parameters, options)...)
+	components := make([]string, 0, len(instance.Arguments))
+	for c := range instance.Arguments {
+		components = append(components, c)
+	}
+	sort.Strings(components)
+	for _, c := range components {
+		issues = append(issues, ValidateExpressions(fmt.Sprintf("arguments[%s]", c), instance.Arguments[c], options)...)
+	}
+	return issues
+}
+
+// ValidateCatalogExpressions checks the expressions in catalog metadata and properties.
+func ValidateCatalogExpressions(catalog model.CatalogSpec) []ExpressionIssue {
+	options := ExpressionValidationOptions{}
+	issues := ValidateExpressions("metadata", catalog.Metadata, options)
+	issues = append(issues, ValidateExpressions("properties", catalog.Properties, options)...)
+	return issues
+}
+
+// ValidateCampaignExpressions checks the expressions in stage inputs, configs and stage selectors. A stage
+// may only read the outputs of stages that can run before it, following the stage selectors from the
+// first stage. If a stage selector is itself an expression, any stage of the campaign may be read.
+func ValidateCampaignExpressions(campaign model.CampaignSpec) []ExpressionIssue {
+	issues := make([]ExpressionIssue, 0)
+	graph := campaignStageGraph(campaign)
+	names := make([]string, 0, len(campaign.Stages))
+	for name := range campaign.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stage := campaign.Stages[name]
+		path := fmt.Sprintf("stages[%s]", name)
+		options := ExpressionValidationOptions{Stages: graph.predecessors(name)}
+		issues = append(issues, ValidateExpressions(path+".inputs", stage.Inputs, options)...)
+		issues = append(issues, ValidateExpressions(path+".config", stage.Config, options)...)
+		// the stage selector is evaluated once the stage has run
+		options.Stages[name] = true
+		issues = append(issues, ValidateExpression(path+".stageSelector", stage.StageSelector, options)...)
+	}
+	return issues
+}
+
+type stageGraph struct {
+	stages  map[string]bool
+	parents map[string][]string
+	fanOut  map[string]string
+	dynamic bool
+}
+
+func campaignStageGraph(campaign model.CampaignSpec) stageGraph {
+	graph := stageGraph{
+		stages:  make(map[string]bool),
+		parents: make(map[string][]string),
+		fanOut:  make(map[string]string),
+	}
+	for name, stage := range campaign.Stages {
+		graph.stages[name] = true
+		next := strings.TrimSpace(stage.StageSelector)
+		if strings.Contains(next, "${{") {
+			graph.dynamic = true
+		} else if next != "" {
+			graph.parents[next] = append(graph.parents[next], name)
+			if stage.Parallel != nil {
+				// the stage that joins the branches reads their outputs
+				graph.parents[next] = append(graph.parents[next], stage.Parallel.Branches...)
+			}
+		}
+		if stage.Compensation != "" {
+			graph.parents[stage.Compensation] = append(graph.parents[stage.Compensation], name)
+		}
+		if stage.Parallel != nil {
+			for _, branch := range stage.Parallel.Branches {
+				graph.fanOut[branch] = name
+			}
+		}
+	}
+	return graph
+}
+
+// parentsOf returns the stages a stage directly follows. Branches of a parallel stage run with the
+// outputs of the stages before the parallel stage.
+func (g stageGraph) parentsOf(stage string) []string {
+	ret := g.parents[stage]
+	if parallel, ok := g.fanOut[stage]; ok {
+		ret = append(append([]string{}, ret...), g.parents[parallel]...)
+	}
+	return ret
+}
+
+// predecessors returns the stages that can run before a stage. The stage itself is included if it's in a loop.
+func (g stageGraph) predecessors(stage string) map[string]bool {
+	ret := make(map[string]bool)
+	if g.dynamic {
+		for name := range g.stages {
+			ret[name] = true
+		}
+		return ret
+	}
+	queue := append([]string{}, g.parentsOf(stage)...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if ret[name] {
+			continue
+		}
+		ret[name] = true
+		queue = append(queue, g.parentsOf(name)...)
+	}
+	return ret
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateExpressionValid(t *testing.T) {
+	for _, text := range []string{
+		"plain text",
+		"${{$property(image)}}",
+		"${{$if($equal($input(foo), bar), stage-1, stage-2)}}",
+		"${{$concat($property(app), '-', $property(version))}}:latest",
+		"${{$val()}}",
+		"${{$in($input(zone), a, b, c)}}",
+		"${{$val('$[?(@.foo.bar==\"baz1\")].foo.bar')}}",
+		"${{3-(1+2)/(2+1)}}",
+		"/api(/|$)(.*)",
+	} {
+		assert.Empty(t, ValidateExpression("p", text, ExpressionValidationOptions{}), text)
+	}
+}
+func TestValidateExpressionUnknownFunction(t *testing.T) {
+	issues := ValidateExpression("p", "abc${{$foo(1)}}", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 7, Message: "unknown function '$foo()'"}}, issues)
+}
+func TestValidateExpressionArity(t *testing.T) {
+	issues := ValidateExpression("p", "${{$equal($property(a))}}", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 4, Message: "$equal() expects 2 arguments, found 1"}}, issues)
+
+	issues = ValidateExpression("p", "${{$instance(a)}}", ExpressionValidationOptions{})
+	assert.Equal(t, "$instance() expects 0 arguments, found 1", issues[0].Message)
+
+	issues = ValidateExpression("p", "${{$substring(a)}}", ExpressionValidationOptions{})
+	assert.Equal(t, "$substring() expects 2 to 3 arguments, found 1", issues[0].Message)
+}
+func TestValidateExpressionNestedArity(t *testing.T) {
+	issues := ValidateExpression("p", "${{$and($not(a), $lt(1, 2, 3))}}", ExpressionValidationOptions{})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, 18, issues[0].Column)
+	assert.Equal(t, "$lt() expects 2 arguments, found 3", issues[0].Message)
+}
+func TestValidateExpressionUnbalanced(t *testing.T) {
+	issues := ValidateExpression("p", "${{$property(a}}", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 4, Message: "'$property(' is not closed"}}, issues)
+
+	issues = ValidateExpression("p", "${{(1+2))}}", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 9, Message: "unbalanced ')'"}}, issues)
+
+	issues = ValidateExpression("p", "${{[1+2)}}", ExpressionValidationOptions{})
+	assert.Equal(t, 2, len(issues))
+}
+func TestValidateExpressionMissingParen(t *testing.T) {
+	issues := ValidateExpression("p", "${{$property}}", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 5, Message: "expected '(' after '$property'"}}, issues)
+}
+func TestValidateExpressionNotClosed(t *testing.T) {
+	issues := ValidateExpression("p", "a ${{$property(a)}} b ${{$property(b)", ExpressionValidationOptions{})
+	assert.Equal(t, []ExpressionIssue{{Path: "p", Column: 23, Message: "'${{' is not closed by '}}'"}}, issues)
+}
+func TestValidateExpressionsPaths(t *testing.T) {
+	issues := ValidateExpressions("properties", map[string]interface{}{
+		"a": "${{$foo()}}",
+		"b": []interface{}{"ok", map[string]interface{}{"c": "${{$bar()}}"}},
+	}, ExpressionValidationOptions{})
+	assert.Equal(t, 2, len(issues))
+	assert.Equal(t, "properties.a", issues[0].Path)
+	assert.Equal(t, "properties.b[1].c", issues[1].Path)
+	assert.Equal(t, "properties.b[1].c:4: unknown function '$bar()'", issues[1].String())
+}
+func TestValidateSolutionExpressions(t *testing.T) {
+	issues := ValidateSolutionExpressions(model.SolutionSpec{
+		Components: []model.ComponentSpec{
+			{
+				Name: "web",
+				Properties: map[string]interface{}{
+					"image": "${{$param(image)}}",
+					"tag":   "${{$lower($param(tag)}}",
+				},
+				Parameters: map[string]string{
+					"image": "nginx",
+				},
+			},
+		},
+	})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "components[web].properties.tag", issues[0].Path)
+}
+func TestValidateInstanceExpressions(t *testing.T) {
+	issues := ValidateInstanceExpressions(model.InstanceSpec{
+		Arguments: map[string]map[string]string{
+			"web": {
+				"tag": "${{$upperr(v1)}}",
+			},
+		},
+	})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "arguments[web].tag", issues[0].Path)
+}
+func TestValidateCatalogExpressions(t *testing.T) {
+	issues := ValidateCatalogExpressions(model.CatalogSpec{
+		Properties: map[string]interface{}{
+			"name": "${{$concat()}}",
+		},
+	})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "properties.name", issues[0].Path)
+}
+func TestValidateCampaignExpressions(t *testing.T) {
+	campaign := model.CampaignSpec{
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build": {
+				Name:          "build",
+				StageSelector: "deploy",
+				Inputs: map[string]interface{}{
+					"image": "${{$output(deploy, url)}}",
+				},
+			},
+			"deploy": {
+				Name:          "deploy",
+				StageSelector: "${{$if($equal($output(deploy, status), 200), test, '')}}",
+				Inputs: map[string]interface{}{
+					"image": "${{$output(build, image)}}",
+					"extra": "${{$output(bulid, image)}}",
+				},
+			},
+			"test": {
+				Name: "test",
+				Inputs: map[string]interface{}{
+					"url": "${{$output(deploy, url)}}",
+				},
+			},
+		},
+	}
+	// the stage selector of deploy is dynamic, so any stage can be read
+	issues := ValidateCampaignExpressions(campaign)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "stages[deploy].inputs.extra", issues[0].Path)
+	assert.Equal(t, 12, issues[0].Column)
+	assert.Equal(t, "stage 'bulid' can't have run before this expression is evaluated", issues[0].Message)
+
+	stage := campaign.Stages["deploy"]
+	stage.StageSelector = "test"
+	campaign.Stages["deploy"] = stage
+	issues = ValidateCampaignExpressions(campaign)
+	assert.Equal(t, 2, len(issues))
+	assert.Equal(t, "stages[build].inputs.image", issues[0].Path)
+	assert.Equal(t, "stage 'deploy' can't have run before this expression is evaluated", issues[0].Message)
+	assert.Equal(t, "stages[deploy].inputs.extra", issues[1].Path)
+}
+func TestValidateCampaignExpressionsLoop(t *testing.T) {
+	issues := ValidateCampaignExpressions(model.CampaignSpec{
+		FirstStage: "counter",
+		Stages: map[string]model.StageSpec{
+			"counter": {
+				Name:          "counter",
+				StageSelector: "counter",
+				Inputs: map[string]interface{}{
+					"val": "${{$output(counter, val)}}",
+				},
+			},
+		},
+	})
+	assert.Empty(t, issues)
+}
+func TestValidateCampaignExpressionsParallel(t *testing.T) {
+	issues := ValidateCampaignExpressions(model.CampaignSpec{
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build": {
+				Name:          "build",
+				StageSelector: "regions",
+			},
+			"regions": {
+				Name:          "regions",
+				StageSelector: "verify",
+				Parallel: &model.ParallelSpec{
+					Branches: []string{"east", "west"},
+				},
+			},
+			"east": {
+				Name: "east",
+				Inputs: map[string]interface{}{
+					"image": "${{$output(build, image)}}",
+					"peer":  "${{$output(west, url)}}",
+				},
+			},
+			"west": {
+				Name: "west",
+				Inputs: map[string]interface{}{
+					"image": "${{$output(build, image)}}",
+				},
+			},
+			"verify": {
+				Name:         "verify",
+				Compensation: "rollback",
+				Inputs: map[string]interface{}{
+					"east": "${{$output(east, url)}}",
+					"west": "${{$output(regions, __succeeded)}}",
+				},
+			},
+			"rollback": {
+				Name: "rollback",
+				Inputs: map[string]interface{}{
+					"attempts": "${{$output(verify, __attempts)}}",
+				},
+			},
+		},
+	})
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "stages[east].inputs.peer", issues[0].Path)
+	assert.Equal(t, "stage 'west' can't have run before this expression is evaluated", issues[0].Message)
+}
@@ -8,6 +8,9 @@ package vendors
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -67,6 +70,20 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onActivations,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/registry",
+			Version:    o.Version,
+			Handler:    o.onControl,
+			Parameters: []string{"name", "action"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/schedule",
+			Version:    o.Version,
+			Handler:    o.onSchedule,
+			Parameters: []string{"name"},
+		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
 			Route:      route + "/status",
@@ -118,6 +135,147 @@ func (c *ActivationsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COARe
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+func (c *ActivationsVendor) onControl(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onControl",
+	})
+	defer span.End()
+
+	vLog.Infof("V (Activations Vendor): onControl, method: %s, traceId: %s", string(request.Method), span.SpanContext().TraceID().String())
+	switch request.Method {
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onControl-POST", pCtx, nil)
+		id := request.Parameters["__name"]
+		action := request.Parameters["__action"]
+		user, roles := v1alpha2.GetAuthenticatedUser(request.Context)
+		var body model.ActivationActionRequest
+		if len(request.Body) > 0 {
+			err := json.Unmarshal(request.Body, &body)
+			if err != nil {
+				vLog.Infof("V (Activations Vendor): onControl failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		var control model.ActivationControl
+		var report model.ActivationStatus
+		var err error
+		switch action {
+		case model.ActivationPause:
+			control, err = c.ActivationsManager.Pause(ctx, id, user)
+		case model.ActivationResume:
+			control, err = c.ActivationsManager.Resume(ctx, id, user)
+		case model.ActivationCancel:
+			control, err = c.ActivationsManager.Cancel(ctx, id, user)
+		case model.ActivationApprove, model.ActivationReject:
+			control, report, err = c.ActivationsManager.Decide(ctx, id, user, roles, action == model.ActivationApprove, body.Comment)
+		default:
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("unknown activation action '%s'", action), v1alpha2.BadRequest)
+		}
+		if err != nil {
+			vLog.Infof("V (Activations Vendor): onControl failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			state := v1alpha2.InternalError
+			if cErr, ok := err.(v1alpha2.COAError); ok {
+				state = cErr.State
+			}
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: state,
+				Body:  []byte(err.Error()),
+			})
+		}
+		if action == model.ActivationApprove {
+			// the approval completes the paused stage like the report of a remote job
+			c.Context.Publish("job-report", v1alpha2.Event{
+				Body: report,
+			})
+		} else {
+			c.Context.Publish("activation-control", v1alpha2.Event{
+				Body: control,
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	vLog.Infof("V (Activations Vendor): onControl failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+func (c *ActivationsVendor) onSchedule(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onSchedule",
+	})
+	defer span.End()
+
+	vLog.Infof("V (Activations Vendor): onSchedule, method: %s, traceId: %s", string(request.Method), span.SpanContext().TraceID().String())
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onSchedule-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		count := 5
+		if request.Parameters["count"] != "" {
+			var err error
+			count, err = strconv.Atoi(request.Parameters["count"])
+			if err != nil || count <= 0 {
+				vLog.Infof("V (Activations Vendor): onSchedule failed - invalid count, traceId: %s", span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(fmt.Sprintf("invalid count '%s'", request.Parameters["count"])),
+				})
+			}
+		}
+		state, err := c.ActivationsManager.GetSpec(ctx, id)
+		if err != nil {
+			vLog.Infof("V (Activations Vendor): onSchedule failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			if v1alpha2.IsNotFound(err) {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.NotFound,
+					Body:  []byte(err.Error()),
+				})
+			}
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		ret := model.ActivationSchedule{
+			Activation: id,
+			Runs:       []time.Time{},
+		}
+		if state.Spec != nil && state.Spec.Schedule != nil {
+			ret.Schedule = state.Spec.Schedule
+			ret.Runs, err = state.Spec.Schedule.UpcomingTimes(time.Now(), count)
+			if err != nil {
+				vLog.Infof("V (Activations Vendor): onSchedule failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		jData, _ := json.Marshal(ret)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	vLog.Infof("V (Activations Vendor): onSchedule failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
 func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
 		"method": "onActivations",
@@ -171,6 +329,18 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 			})
 		}
 
+		if activation.Schedule != nil {
+			err = activation.Schedule.Validate()
+			if err != nil {
+				vLog.Infof("V (Activations Vendor): onActivations failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		previous, previousErr := c.ActivationsManager.GetSpec(ctx, id)
+
 		err = c.ActivationsManager.UpsertSpec(ctx, id, activation)
 		if err != nil {
 			vLog.Infof("V (Activations Vendor): onActivations failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -187,6 +357,15 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 				Body:  []byte(err.Error()),
 			})
 		}
+		if previousErr == nil && previous.Spec != nil && previous.Spec.Schedule != nil && (activation.Schedule == nil || activation.Campaign != previous.Spec.Campaign) {
+			// the pending schedule is dropped, a new schedule of the same campaign replaces it instead
+			c.Context.Publish("unschedule", v1alpha2.Event{
+				Body: v1alpha2.ActivationData{
+					Campaign:   previous.Spec.Campaign,
+					Activation: id,
+				},
+			})
+		}
 		c.Context.Publish("activation", v1alpha2.Event{
 			Body: v1alpha2.ActivationData{
 				Campaign:             activation.Campaign,
@@ -202,6 +381,7 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 	case fasthttp.MethodDelete:
 		ctx, span := observability.StartSpan("onActivations-DELETE", pCtx, nil)
 		id := request.Parameters["__name"]
+		previous, previousErr := c.ActivationsManager.GetSpec(ctx, id)
 		err := c.ActivationsManager.DeleteSpec(ctx, id)
 		if err != nil {
 			vLog.Infof("V (Activations Vendor): onActivations failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -210,6 +390,14 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 				Body:  []byte(err.Error()),
 			})
 		}
+		if previousErr == nil && previous.Spec != nil && previous.Spec.Schedule != nil {
+			c.Context.Publish("unschedule", v1alpha2.Event{
+				Body: v1alpha2.ActivationData{
+					Campaign:   previous.Spec.Campaign,
+					Activation: id,
+				},
+			})
+		}
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State: v1alpha2.OK,
 		})
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -36,7 +37,7 @@ func TestActivationsEndpoints(t *testing.T) {
 	vendor := createActivationsVendor()
 	vendor.Route = "activations"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 2, len(endpoints))
+	assert.Equal(t, 4, len(endpoints))
 }
 func TestActivationsInfo(t *testing.T) {
 	vendor := createActivationsVendor()
@@ -167,6 +168,202 @@ func TestActivationsOnActivations(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.OK, resp.State)
 }
+func TestActivationsOnSchedule(t *testing.T) {
+	vendor := createActivationsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	started := make(chan bool, 1)
+	vendor.Context.Subscribe("activation", func(topic string, event v1alpha2.Event) error {
+		started <- true
+		return nil
+	})
+	unscheduled := make(chan v1alpha2.ActivationData, 1)
+	vendor.Context.Subscribe("unschedule", func(topic string, event v1alpha2.Event) error {
+		var activation v1alpha2.ActivationData
+		jData, _ := json.Marshal(event.Body)
+		json.Unmarshal(jData, &activation)
+		unscheduled <- activation
+		return nil
+	})
+
+	activationSpec := model.ActivationSpec{
+		Name:     "activation1",
+		Campaign: "campaign1",
+		Schedule: &v1alpha2.ScheduleSpec{
+			Cron: "0 3 * * *",
+			Zone: "Mars/Olympus",
+		},
+	}
+	data, _ := json.Marshal(activationSpec)
+	resp := vendor.onActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "activation1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	activationSpec.Schedule.Zone = "Europe/Paris"
+	data, _ = json.Marshal(activationSpec)
+	resp = vendor.onActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "activation1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	<-started
+
+	resp = vendor.onSchedule(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "activation1",
+			"count":  "3",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var schedule model.ActivationSchedule
+	err := json.Unmarshal(resp.Body, &schedule)
+	assert.Nil(t, err)
+	assert.Equal(t, "0 3 * * *", schedule.Schedule.Cron)
+	assert.Equal(t, 3, len(schedule.Runs))
+	paris, _ := time.LoadLocation("Europe/Paris")
+	for i, run := range schedule.Runs {
+		assert.Equal(t, 3, run.In(paris).Hour())
+		if i > 0 {
+			assert.True(t, run.After(schedule.Runs[i-1]))
+		}
+	}
+
+	resp = vendor.onSchedule(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "activation1",
+			"count":  "none",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	resp = vendor.onSchedule(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "activation2",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+
+	resp = vendor.onActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodDelete,
+		Parameters: map[string]string{
+			"__name": "activation1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	select {
+	case activation := <-unscheduled:
+		assert.Equal(t, "campaign1", activation.Campaign)
+		assert.Equal(t, "activation1", activation.Activation)
+	case <-time.After(time.Second):
+		assert.Fail(t, "activation was not unscheduled")
+	}
+}
+func TestActivationsOnControl(t *testing.T) {
+	vendor := createActivationsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	controls := make(chan model.ActivationControl)
+	vendor.Context.Subscribe("activation-control", func(topic string, event v1alpha2.Event) error {
+		var control model.ActivationControl
+		jData, _ := json.Marshal(event.Body)
+		err := json.Unmarshal(jData, &control)
+		assert.Nil(t, err)
+		controls <- control
+		return nil
+	})
+	reports := make(chan model.ActivationStatus)
+	vendor.Context.Subscribe("job-report", func(topic string, event v1alpha2.Event) error {
+		var status model.ActivationStatus
+		jData, _ := json.Marshal(event.Body)
+		err := json.Unmarshal(jData, &status)
+		assert.Nil(t, err)
+		reports <- status
+		return nil
+	})
+	err := vendor.ActivationsManager.UpsertSpec(context.Background(), "activation1", model.ActivationSpec{Campaign: "campaign1"})
+	assert.Nil(t, err)
+	err = vendor.ActivationsManager.ReportStatus(context.Background(), "activation1", model.ActivationStatus{
+		Stage:                "approve",
+		Status:               v1alpha2.Paused,
+		ActivationGeneration: "1",
+		Outputs: map[string]interface{}{
+			model.ApprovalPendingOutput: true,
+			model.ApproversOutput:       []string{"admin"},
+		},
+	})
+	assert.Nil(t, err)
+
+	resp := vendor.onControl(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name":   "activation1",
+			"__action": "pause",
+		},
+		Context: context.WithValue(context.Background(), v1alpha2.AuthenticatedUserKey, "admin"),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	control := <-controls
+	assert.Equal(t, model.ActivationPause, control.Action)
+	assert.Equal(t, "campaign1", control.Campaign)
+	assert.Equal(t, "1", control.ActivationGeneration)
+	assert.Equal(t, "admin", control.User)
+
+	resp = vendor.onControl(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   []byte(`{"comment":"ship it"}`),
+		Parameters: map[string]string{
+			"__name":   "activation1",
+			"__action": "approve",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+
+	resp = vendor.onControl(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   []byte(`{"comment":"ship it"}`),
+		Parameters: map[string]string{
+			"__name":   "activation1",
+			"__action": "approve",
+		},
+		Context: context.WithValue(context.Background(), v1alpha2.AuthenticatedUserKey, "admin"),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	report := <-reports
+	assert.Equal(t, v1alpha2.Done, report.Status)
+	assert.Equal(t, "ship it", report.Outputs[model.CommentOutput])
+
+	resp = vendor.onControl(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name":   "activation1",
+			"__action": "rewind",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
 func TestActivationsWrongMethod(t *testing.T) {
 	vendor := createActivationsVendor()
 	resp := vendor.onActivations(v1alpha2.COARequest{
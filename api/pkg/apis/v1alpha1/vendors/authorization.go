@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
+)
+
+// Resource kinds of the authorization policy
+const (
+	kindSolutions = "solutions"
+	kindInstances = "instances"
+	kindTargets   = "targets"
+	kindCampaigns = "campaigns"
+	kindCatalogs  = "catalogs"
+)
+
+// authorize decides a request against a resource with the authorization policy that the JWT middleware
+// set on the request. It returns an Unauthorized response and true if the request is denied.
+func authorize(ctx context.Context, request authz.Request) (v1alpha2.COAResponse, bool) {
+	if err := authz.Authorize(ctx, request); err != nil {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte(err.Error()),
+		}, true
+	}
+	return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+}
+
+// authorizeWrite decides an upsert, which is a create if the resource doesn't exist and an update
+// otherwise. An update is decided against both the current and the new metadata of the resource, so that
+// rules on metadata can't be escaped by changing the metadata. The current resource is only read when
+// there's a policy to evaluate.
+func authorizeWrite(ctx context.Context, request authz.Request, current func() (map[string]string, error)) (v1alpha2.COAResponse, bool) {
+	if authz.FromContext(ctx) == nil {
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	}
+	metadata, err := current()
+	if err != nil {
+		if !v1alpha2.IsNotFound(err) {
+			return v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			}, true
+		}
+		request.Verb = authz.VerbCreate
+		return authorize(ctx, request)
+	}
+	request.Verb = authz.VerbUpdate
+	if resp, denied := authorize(ctx, request); denied {
+		return resp, true
+	}
+	request.Metadata = metadata
+	return authorize(ctx, request)
+}
+
+// authorizeDelete decides a delete against the current metadata of the resource, so that rules on metadata
+// apply to deletes too
+func authorizeDelete(ctx context.Context, request authz.Request, current func() (map[string]string, error)) (v1alpha2.COAResponse, bool) {
+	request.Verb = authz.VerbDelete
+	return authorizeCurrent(ctx, request, current)
+}
+
+// authorizeCurrent decides a request against the current metadata of the resource, such as a read of its
+// revisions. A resource that doesn't exist, such as a deleted resource, is decided without metadata.
+func authorizeCurrent(ctx context.Context, request authz.Request, current func() (map[string]string, error)) (v1alpha2.COAResponse, bool) {
+	if authz.FromContext(ctx) == nil {
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	}
+	metadata, err := current()
+	if err != nil && !v1alpha2.IsNotFound(err) {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		}, true
+	}
+	request.Metadata = metadata
+	return authorize(ctx, request)
+}
+
+// listScope is the scope that a list is authorized in, which is any scope when all scopes are listed
+func listScope(scope string) string {
+	if scope == "" {
+		return "*"
+	}
+	return scope
+}
+
+// allowsGet tells if the caller can get a listed resource. Lists are filtered with it: a list across all
+// scopes is authorized in any scope, which rules on scopes don't match, and rules on names and metadata
+// don't match lists at all, so a list alone would escape deny rules.
+func allowsGet(ctx context.Context, kind string, scope string, name string, metadata map[string]string) bool {
+	return authz.Authorize(ctx, authz.Request{Kind: kind, Scope: scope, Verb: authz.VerbGet, Name: name, Metadata: metadata}) == nil
+}
+
+func authorizedSolutions(ctx context.Context, solutions []model.SolutionState) []model.SolutionState {
+	ret := make([]model.SolutionState, 0, len(solutions))
+	for _, s := range solutions {
+		var metadata map[string]string
+		if s.Spec != nil {
+			metadata = s.Spec.Metadata
+		}
+		if allowsGet(ctx, kindSolutions, s.Scope, s.Id, metadata) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func authorizedInstances(ctx context.Context, instances []model.InstanceState) []model.InstanceState {
+	ret := make([]model.InstanceState, 0, len(instances))
+	for _, i := range instances {
+		var metadata map[string]string
+		if i.Spec != nil {
+			metadata = i.Spec.Metadata
+		}
+		if allowsGet(ctx, kindInstances, i.Scope, i.Id, metadata) {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+func authorizedTargets(ctx context.Context, targets []model.TargetState) []model.TargetState {
+	ret := make([]model.TargetState, 0, len(targets))
+	for _, t := range targets {
+		var metadata map[string]string
+		if t.Spec != nil {
+			metadata = t.Spec.Metadata
+		}
+		if allowsGet(ctx, kindTargets, t.Scope, t.Id, metadata) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func authorizedCampaigns(ctx context.Context, campaigns []model.CampaignState) []model.CampaignState {
+	ret := make([]model.CampaignState, 0, len(campaigns))
+	for _, c := range campaigns {
+		if allowsGet(ctx, kindCampaigns, "", c.Id, nil) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+func authorizedCatalogs(ctx context.Context, catalogs []model.CatalogState) []model.CatalogState {
+	ret := make([]model.CatalogState, 0, len(catalogs))
+	for _, c := range catalogs {
+		var metadata map[string]string
+		if c.Spec != nil {
+			metadata = c.Spec.Metadata
+		}
+		if allowsGet(ctx, kindCatalogs, "", c.Id, metadata) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/valyala/fasthttp"
+)
+
+var azLog = logger.NewLogger("coa.runtime")
+
+// AuthzVendor answers "can user X do Y" queries with the authorization policy of the JWT middleware
+type AuthzVendor struct {
+	vendors.Vendor
+}
+
+func (o *AuthzVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "Authz",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *AuthzVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	return e.Vendor.Init(config, factories, providers, pubsubProvider)
+}
+
+func (o *AuthzVendor) GetEndpoints() []v1alpha2.Endpoint {
+	route := "authz"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/check",
+			Version: o.Version,
+			Handler: o.onCheck,
+		},
+	}
+}
+
+// onCheck decides a request for the caller, for another user, or for a set of roles. The roles of
+// another user are the roles that the user claim of a token of the user maps to.
+func (c *AuthzVendor) onCheck(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	_, span := observability.StartSpan("Authz Vendor", request.Context, &map[string]string{
+		"method": "onCheck",
+	})
+	defer span.End()
+	azLog.Infof("V (Authz): onCheck, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	var check authz.Request
+	err := json.Unmarshal(request.Body, &check)
+	if err != nil || check.Kind == "" || check.Verb == "" {
+		azLog.Infof("V (Authz): onCheck failed - kind and verb are required, traceId: %s", span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("kind and verb are required"),
+		})
+	}
+	var decision authz.Decision
+	policy := authz.FromContext(request.Context)
+	if policy == nil {
+		decision = authz.Decision{Allowed: true, Reason: "authorization policy is not configured"}
+	} else {
+		user, roles := v1alpha2.GetAuthenticatedUser(request.Context)
+		if check.User == "" && check.Roles == nil {
+			check.User = user
+			check.Roles = roles
+		} else if check.Roles == nil {
+			if check.User == user {
+				check.Roles = roles
+			} else if policy.RoleResolver != nil {
+				check.Roles = policy.RoleResolver(check.User)
+			}
+		}
+		decision = policy.Evaluate(check)
+	}
+	azLog.Infof("V (Authz): onCheck - %s, traceId: %s", decision.Reason, span.SpanContext().TraceID().String())
+	data, _ := json.Marshal(decision)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
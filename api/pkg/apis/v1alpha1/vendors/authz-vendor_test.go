@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// authzContext is the context that the JWT middleware sets on a request of an authenticated user
+func authzContext(t *testing.T, user string, roles ...string) context.Context {
+	policy, err := authz.NewPolicy(authz.PolicyConfig{
+		Roles: map[string][]authz.Rule{
+			"reader": {
+				{Kinds: []string{"*"}, Verbs: []string{authz.VerbGet, authz.VerbList}},
+			},
+			"prod-operator": {
+				{Kinds: []string{"solutions", "instances"}, Scopes: []string{"prod"}, Verbs: []string{"*"}},
+				{Effect: authz.EffectDeny, Kinds: []string{"*"}, Verbs: []string{authz.VerbUpdate, authz.VerbDelete}, Metadata: map[string]string{"frozen": "true"}},
+			},
+			"auditor": {
+				{Kinds: []string{"*"}, Verbs: []string{authz.VerbGet, authz.VerbList}},
+				{Effect: authz.EffectDeny, Kinds: []string{"*"}, Scopes: []string{"prod"}, Verbs: []string{"*"}},
+				{Effect: authz.EffectDeny, Kinds: []string{"*"}, Verbs: []string{"*"}, Metadata: map[string]string{"confidential": "true"}},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	policy.RoleResolver = func(user string) []string {
+		if user == "bob" {
+			return []string{"prod-operator"}
+		}
+		return []string{}
+	}
+	ctx := context.WithValue(context.Background(), v1alpha2.AuthorizationPolicyKey, policy)
+	ctx = context.WithValue(ctx, v1alpha2.AuthenticatedUserKey, user)
+	return context.WithValue(ctx, v1alpha2.AuthenticatedRolesKey, roles)
+}
+
+func createAuthzVendor(t *testing.T) AuthzVendor {
+	vendor := AuthzVendor{}
+	err := vendor.Init(vendors.VendorConfig{
+		Managers: []managers.ManagerConfig{},
+	}, []managers.IManagerFactroy{
+		&sym_mgr.SymphonyManagerFactory{},
+	}, map[string]map[string]providers.IProvider{}, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+func TestAuthzEndpoints(t *testing.T) {
+	vendor := createAuthzVendor(t)
+	vendor.Route = "authz"
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, "authz/check", endpoints[0].Route)
+	assert.Equal(t, "Authz", vendor.GetInfo().Name)
+}
+
+func TestAuthzOnCheck(t *testing.T) {
+	vendor := createAuthzVendor(t)
+	ctx := authzContext(t, "alice", "reader")
+	check := func(request authz.Request) authz.Decision {
+		data, _ := json.Marshal(request)
+		resp := vendor.onCheck(v1alpha2.COARequest{
+			Method:  fasthttp.MethodPost,
+			Body:    data,
+			Context: ctx,
+		})
+		assert.Equal(t, v1alpha2.OK, resp.State)
+		var decision authz.Decision
+		err := json.Unmarshal(resp.Body, &decision)
+		assert.Nil(t, err)
+		return decision
+	}
+
+	// the caller
+	assert.True(t, check(authz.Request{Kind: "targets", Verb: authz.VerbList}).Allowed)
+	assert.False(t, check(authz.Request{Kind: "solutions", Scope: "prod", Verb: authz.VerbCreate, Name: "web"}).Allowed)
+	// another user
+	assert.True(t, check(authz.Request{User: "bob", Kind: "solutions", Scope: "prod", Verb: authz.VerbCreate, Name: "web"}).Allowed)
+	assert.False(t, check(authz.Request{User: "carol", Kind: "solutions", Scope: "prod", Verb: authz.VerbGet, Name: "web"}).Allowed)
+	// a set of roles
+	decision := check(authz.Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod", Verb: authz.VerbDelete, Name: "web", Metadata: map[string]string{"frozen": "true"}})
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "denied to role prod-operator")
+
+	resp := vendor.onCheck(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    []byte(`{"kind":"solutions"}`),
+		Context: ctx,
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	// everything is allowed without a policy
+	ctx = context.Background()
+	assert.True(t, check(authz.Request{Kind: "solutions", Verb: authz.VerbDelete, Name: "web"}).Allowed)
+}
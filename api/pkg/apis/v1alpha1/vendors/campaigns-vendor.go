@@ -8,11 +8,14 @@ package vendors
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaigns"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -67,6 +70,20 @@ func (o *CampaignsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onCampaigns,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/revisions",
+			Version:    o.Version,
+			Handler:    o.onRevisions,
+			Parameters: []string{"name", "revision?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/diff",
+			Version:    o.Version,
+			Handler:    o.onDiff,
+			Parameters: []string{"name"},
+		},
 	}
 }
 
@@ -85,9 +102,19 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 		var state interface{}
 		isArray := false
 		if id == "" {
-			state, err = c.CampaignsManager.ListSpec(ctx)
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindCampaigns, Verb: authz.VerbList}); denied {
+				cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
+			var items []model.CampaignState
+			items, err = c.CampaignsManager.ListSpec(ctx)
+			state = authorizedCampaigns(request.Context, items)
 			isArray = true
 		} else {
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindCampaigns, Verb: authz.VerbGet, Name: id}); denied {
+				cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
 			state, err = c.CampaignsManager.GetSpec(ctx, id)
 		}
 		if err != nil {
@@ -122,6 +149,31 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 			})
 		}
 
+		err = campaign.ValidateSchedules()
+		if err != nil {
+			cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+
+		if resp, denied := authorizeWrite(request.Context, authz.Request{Kind: kindCampaigns, Name: id}, func() (map[string]string, error) {
+			_, err := c.CampaignsManager.GetSpec(ctx, id)
+			return nil, err
+		}); denied {
+			cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+
+		checked, rejected := checkExpressions(c.Config.Properties, func() []utils.ExpressionIssue {
+			return utils.ValidateCampaignExpressions(campaign)
+		})
+		if rejected {
+			cLog.Infof("V (Campaigns): onCampaigns failed - invalid expressions: %s, traceId: %s", string(checked.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, checked)
+		}
+
 		err = c.CampaignsManager.UpsertSpec(ctx, id, campaign)
 		if err != nil {
 			cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -131,11 +183,16 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 			})
 		}
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-			State: v1alpha2.OK,
+			State:    v1alpha2.OK,
+			Metadata: checked.Metadata,
 		})
 	case fasthttp.MethodDelete:
 		ctx, span := observability.StartSpan("onCampaigns-DELETE", pCtx, nil)
 		id := request.Parameters["__name"]
+		if resp, denied := authorize(request.Context, authz.Request{Kind: kindCampaigns, Verb: authz.VerbDelete, Name: id}); denied {
+			cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		err := c.CampaignsManager.DeleteSpec(ctx, id)
 		if err != nil {
 			cLog.Infof("V (Campaigns): onCampaigns failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -157,3 +214,158 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *CampaignsVendor) onRevisions(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Campaigns Vendor", request.Context, &map[string]string{
+		"method": "onRevisions",
+	})
+	defer span.End()
+	cLog.Infof("V (Campaigns): onRevisions, method: %s, traceId: %s", string(request.Method), span.SpanContext().TraceID().String())
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onRevisions-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		if resp, denied := authorize(request.Context, authz.Request{Kind: kindCampaigns, Verb: authz.VerbGet, Name: id}); denied {
+			cLog.Infof("V (Campaigns): onRevisions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var state interface{}
+		var err error
+		if request.Parameters["__revision"] == "" {
+			state, err = listRevisions(func() ([]model.RevisionSpec, error) {
+				return c.CampaignsManager.GetRevisions(ctx, id)
+			})
+		} else {
+			var revision int
+			revision, err = parseRevision(request.Parameters["__revision"])
+			if err == nil {
+				state, err = c.CampaignsManager.GetRevision(ctx, id, revision)
+			}
+		}
+		if err != nil {
+			cLog.Infof("V (Campaigns): onRevisions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, revisionErrorResponse(err))
+		}
+		jData, _ := json.Marshal(state)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	cLog.Infof("V (Campaigns): onRevisions failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *CampaignsVendor) onDiff(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Campaigns Vendor", request.Context, &map[string]string{
+		"method": "onDiff",
+	})
+	defer span.End()
+	cLog.Infof("V (Campaigns): onDiff, method: %s, traceId: %s", string(request.Method), span.SpanContext().TraceID().String())
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onDiff-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		if resp, denied := authorize(request.Context, authz.Request{Kind: kindCampaigns, Verb: authz.VerbGet, Name: id}); denied {
+			cLog.Infof("V (Campaigns): onDiff failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var diff model.RevisionDiff
+		from, to, err := readRevisionRange(request.Parameters, func() ([]model.RevisionSpec, error) {
+			return c.CampaignsManager.GetRevisions(ctx, id)
+		})
+		if err == nil {
+			diff, err = c.CampaignsManager.DiffRevisions(ctx, id, from, to)
+		}
+		if err != nil {
+			cLog.Infof("V (Campaigns): onDiff failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, revisionErrorResponse(err))
+		}
+		jData, _ := json.Marshal(diff)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	cLog.Infof("V (Campaigns): onDiff failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// listRevisions returns the history of an object, which leaves out the specs of the revisions
+func listRevisions(list func() ([]model.RevisionSpec, error)) ([]model.RevisionSpec, error) {
+	revisions, err := list()
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		revisions[i].Spec = nil
+	}
+	return revisions, nil
+}
+
+func parseRevision(text string) (int, error) {
+	revision, err := strconv.Atoi(text)
+	if err != nil || revision <= 0 {
+		return 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid revision '%s', expected a positive integer", text), v1alpha2.BadRequest)
+	}
+	return revision, nil
+}
+
+// readRevisionRange reads the from and to query parameters of a diff request. To defaults to the latest
+// revision and from defaults to the revision before to.
+func readRevisionRange(parameters map[string]string, list func() ([]model.RevisionSpec, error)) (int, int, error) {
+	var to int
+	var err error
+	if parameters["to"] != "" {
+		to, err = parseRevision(parameters["to"])
+		if err != nil {
+			return 0, 0, err
+		}
+	} else {
+		revisions, err := list()
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(revisions) == 0 {
+			return 0, 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' has no revisions", parameters["__name"]), v1alpha2.NotFound)
+		}
+		to = revisions[len(revisions)-1].Revision
+	}
+	from := to - 1
+	if parameters["from"] != "" {
+		from, err = parseRevision(parameters["from"])
+		if err != nil {
+			return 0, 0, err
+		}
+	} else if from == 0 {
+		return 0, 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' has no revision before %d", parameters["__name"], to), v1alpha2.BadRequest)
+	}
+	return from, to, nil
+}
+
+func revisionErrorResponse(err error) v1alpha2.COAResponse {
+	state := v1alpha2.InternalError
+	if coaErr, ok := err.(v1alpha2.COAError); ok && (coaErr.State == v1alpha2.NotFound || coaErr.State == v1alpha2.BadRequest) {
+		state = coaErr.State
+	}
+	return v1alpha2.COAResponse{
+		State: state,
+		Body:  []byte(err.Error()),
+	}
+}
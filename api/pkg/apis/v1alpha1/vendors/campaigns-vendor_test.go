@@ -13,6 +13,7 @@ import (
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -58,7 +59,7 @@ func TestCampaignsEndpoints(t *testing.T) {
 	vendor := createCampaignsVendor()
 	vendor.Route = "campaigns"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, 3, len(endpoints))
 }
 func TestCampaignsInfo(t *testing.T) {
 	vendor := createCampaignsVendor()
@@ -172,3 +173,215 @@ func TestCampaignsWrongMethod(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
 }
+func TestCampaignsOnCampaignsExpressionWarnings(t *testing.T) {
+	vendor := createCampaignsVendor()
+	campaignSpec := model.CampaignSpec{
+		Name:       "campaign1",
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build": {
+				Name: "build",
+				Inputs: map[string]interface{}{
+					"image": "${{$output(deploy, image)}}",
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(campaignSpec)
+	resp := vendor.onCampaigns(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var issues []utils.ExpressionIssue
+	err := json.Unmarshal([]byte(resp.Metadata["expressionWarnings"]), &issues)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(issues))
+	assert.Equal(t, "stages[build].inputs.image", issues[0].Path)
+}
+func TestCampaignsOnCampaignsExpressionStrict(t *testing.T) {
+	vendor := createCampaignsVendor()
+	vendor.Config.Properties["expressionValidation"] = "strict"
+	campaignSpec := model.CampaignSpec{
+		Name:       "campaign1",
+		FirstStage: "build",
+		Stages: map[string]model.StageSpec{
+			"build": {
+				Name: "build",
+				Inputs: map[string]interface{}{
+					"image": "${{$propertyy(image)}}",
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(campaignSpec)
+	resp := vendor.onCampaigns(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	var issues []utils.ExpressionIssue
+	err := json.Unmarshal(resp.Body, &issues)
+	assert.Nil(t, err)
+	assert.Equal(t, "unknown function '$propertyy()'", issues[0].Message)
+
+	resp = vendor.onCampaigns(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.InternalError, resp.State)
+
+	vendor.Config.Properties["expressionValidation"] = "off"
+	resp = vendor.onCampaigns(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Empty(t, resp.Metadata)
+}
+func TestCampaignsOnCampaignsInvalidSchedule(t *testing.T) {
+	vendor := createCampaignsVendor()
+	campaignSpec := model.CampaignSpec{
+		Name:       "campaign1",
+		FirstStage: "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Name: "deploy",
+				Schedule: &v1alpha2.ScheduleSpec{
+					Cron: "0 2 * * *",
+					Blackouts: []v1alpha2.BlackoutWindow{
+						{Start: "25:00", End: "06:00"},
+					},
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(campaignSpec)
+	resp := vendor.onCampaigns(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	assert.Contains(t, string(resp.Body), "stage 'deploy'")
+}
+
+func TestCampaignsOnRevisions(t *testing.T) {
+	vendor := createCampaignsVendor()
+	revisionProvider := &memorystate.MemoryStateProvider{}
+	revisionProvider.Init(memorystate.MemoryStateProviderConfig{})
+	vendor.CampaignsManager.Revisions = &utils.RevisionStore{
+		StateProvider: revisionProvider,
+		Group:         model.WorkflowGroup,
+		Kind:          "CampaignRevision",
+		Resource:      "campaignrevisions",
+	}
+	for _, stage := range []string{"build", "deploy"} {
+		data, _ := json.Marshal(model.CampaignSpec{Name: "campaign1", FirstStage: stage})
+		resp := vendor.onCampaigns(v1alpha2.COARequest{
+			Method: fasthttp.MethodPost,
+			Body:   data,
+			Parameters: map[string]string{
+				"__name": "campaign1",
+			},
+			Context: context.Background(),
+		})
+		assert.Equal(t, v1alpha2.OK, resp.State)
+	}
+
+	resp := vendor.onRevisions(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var revisions []model.RevisionSpec
+	err := json.Unmarshal(resp.Body, &revisions)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Nil(t, revisions[0].Spec)
+
+	resp = vendor.onRevisions(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name":     "campaign1",
+			"__revision": "1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var revision model.RevisionSpec
+	err = json.Unmarshal(resp.Body, &revision)
+	assert.Nil(t, err)
+	assert.Equal(t, "build", revision.Spec.(map[string]interface{})["firstStage"])
+
+	resp = vendor.onRevisions(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name":     "campaign1",
+			"__revision": "3",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+
+	// the diff defaults to the latest revision and the one before it
+	resp = vendor.onDiff(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var diff model.RevisionDiff
+	err = json.Unmarshal(resp.Body, &diff)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	assert.Equal(t, []model.SpecChange{
+		{Path: "firstStage", Type: model.SpecChangeModified, From: "build", To: "deploy"},
+	}, diff.Changes)
+
+	resp = vendor.onDiff(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+			"from":   "x",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+
+func TestCampaignsOnRevisionsNotEnabled(t *testing.T) {
+	vendor := createCampaignsVendor()
+	resp := vendor.onRevisions(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "campaign1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+}
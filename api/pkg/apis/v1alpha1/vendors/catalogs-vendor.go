@@ -15,6 +15,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -166,6 +167,9 @@ func (e *CatalogsVendor) onCatalogsGraph(request v1alpha2.COARequest) v1alpha2.C
 	switch request.Method {
 	case fasthttp.MethodGet:
 		ctx, span := observability.StartSpan("onCatalogsGraph-GET", rCtx, nil)
+		if resp, denied := authorize(request.Context, authz.Request{Kind: kindCatalogs, Verb: authz.VerbList}); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		template := request.Parameters["template"]
 		switch template {
 		case "config-chains":
@@ -176,7 +180,7 @@ func (e *CatalogsVendor) onCatalogsGraph(request v1alpha2.COARequest) v1alpha2.C
 					Body:  []byte(err.Error()),
 				})
 			}
-			jData, _ := utils.FormatObject(chains, true, "", "")
+			jData, _ := utils.FormatObject(e.authorizedNodes(ctx, chains), true, "", "")
 			resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 				State:       v1alpha2.OK,
 				Body:        jData,
@@ -191,7 +195,7 @@ func (e *CatalogsVendor) onCatalogsGraph(request v1alpha2.COARequest) v1alpha2.C
 					Body:  []byte(err.Error()),
 				})
 			}
-			jData, _ := utils.FormatObject(trees, true, "", "")
+			jData, _ := utils.FormatObject(e.authorizedNodes(ctx, trees), true, "", "")
 			resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 				State:       v1alpha2.OK,
 				Body:        jData,
@@ -230,10 +234,26 @@ func (e *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 		var state interface{}
 		isArray := false
 		if id == "" {
-			state, err = e.CatalogsManager.ListSpec(ctx)
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindCatalogs, Verb: authz.VerbList}); denied {
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
+			var items []model.CatalogState
+			items, err = e.CatalogsManager.ListSpec(ctx)
+			state = authorizedCatalogs(request.Context, items)
 			isArray = true
 		} else {
-			state, err = e.CatalogsManager.GetSpec(ctx, id)
+			var catalog model.CatalogState
+			catalog, err = e.CatalogsManager.GetSpec(ctx, id)
+			if err == nil {
+				var metadata map[string]string
+				if catalog.Spec != nil {
+					metadata = catalog.Spec.Metadata
+				}
+				if resp, denied := authorize(request.Context, authz.Request{Kind: kindCatalogs, Verb: authz.VerbGet, Name: id, Metadata: metadata}); denied {
+					return observ_utils.CloseSpanWithCOAResponse(span, resp)
+				}
+			}
+			state = catalog
 		}
 		if err != nil {
 			if !v1alpha2.IsNotFound(err) {
@@ -277,6 +297,19 @@ func (e *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 			})
 		}
 
+		if resp, denied := authorizeWrite(request.Context, authz.Request{Kind: kindCatalogs, Name: id, Metadata: campaign.Metadata}, func() (map[string]string, error) {
+			return e.metadata(ctx, id)
+		}); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+
+		checked, rejected := checkExpressions(e.Config.Properties, func() []utils.ExpressionIssue {
+			return utils.ValidateCatalogExpressions(campaign)
+		})
+		if rejected {
+			return observ_utils.CloseSpanWithCOAResponse(span, checked)
+		}
+
 		err = e.CatalogsManager.UpsertSpec(ctx, id, campaign)
 		if err != nil {
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
@@ -285,11 +318,17 @@ func (e *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 			})
 		}
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-			State: v1alpha2.OK,
+			State:    v1alpha2.OK,
+			Metadata: checked.Metadata,
 		})
 	case fasthttp.MethodDelete:
 		ctx, span := observability.StartSpan("onCatalogs-DELETE", pCtx, nil)
 		id := request.Parameters["__name"]
+		if resp, denied := authorizeDelete(request.Context, authz.Request{Kind: kindCatalogs, Name: id}, func() (map[string]string, error) {
+			return e.metadata(ctx, id)
+		}); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		err := e.CatalogsManager.DeleteSpec(ctx, id)
 		if err != nil {
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
@@ -309,3 +348,36 @@ func (e *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// authorizedNodes keeps the catalogs of graph sets that the caller can get, like lists of catalogs
+func (e *CatalogsVendor) authorizedNodes(ctx context.Context, sets map[string][]v1alpha2.INode) map[string][]v1alpha2.INode {
+	if authz.FromContext(ctx) == nil {
+		return sets
+	}
+	ret := make(map[string][]v1alpha2.INode, len(sets))
+	for key, nodes := range sets {
+		allowed := make([]v1alpha2.INode, 0, len(nodes))
+		for _, node := range nodes {
+			metadata, err := e.metadata(ctx, node.GetId())
+			if err != nil && !v1alpha2.IsNotFound(err) {
+				continue
+			}
+			if allowsGet(ctx, kindCatalogs, "", node.GetId(), metadata) {
+				allowed = append(allowed, node)
+			}
+		}
+		if len(allowed) > 0 {
+			ret[key] = allowed
+		}
+	}
+	return ret
+}
+
+// metadata returns the metadata of a catalog, which authorization rules can match
+func (e *CatalogsVendor) metadata(ctx context.Context, id string) (map[string]string, error) {
+	current, err := e.CatalogsManager.GetSpec(ctx, id)
+	if err != nil || current.Spec == nil {
+		return nil, err
+	}
+	return current.Spec.Metadata, nil
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// checkExpressions validates the expressions of an object being upserted, according to the vendor's
+// expressionValidation property ("strict", "warn" or "off", "warn" by default). It returns a BadRequest
+// response and true if the object is rejected. Otherwise, the returned response carries any warnings
+// in its metadata.
+func checkExpressions(properties map[string]string, validate func() []utils.ExpressionIssue) (v1alpha2.COAResponse, bool) {
+	mode := properties["expressionValidation"]
+	if mode == "" {
+		mode = utils.ExpressionValidationWarn
+	}
+	switch mode {
+	case utils.ExpressionValidationOff:
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	case utils.ExpressionValidationWarn, utils.ExpressionValidationStrict:
+	default:
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(fmt.Sprintf("invalid expressionValidation setting '%s'", mode)),
+		}, true
+	}
+	issues := validate()
+	if len(issues) == 0 {
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	}
+	jData, _ := json.Marshal(issues)
+	if mode == utils.ExpressionValidationStrict {
+		return v1alpha2.COAResponse{
+			State:       v1alpha2.BadRequest,
+			Body:        jData,
+			ContentType: "application/json",
+		}, true
+	}
+	return v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+		Metadata: map[string]string{
+			"expressionWarnings": string(jData),
+		},
+	}, false
+}
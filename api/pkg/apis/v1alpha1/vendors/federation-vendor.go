@@ -8,7 +8,11 @@ package vendors
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"strconv"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
@@ -107,9 +111,7 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 		if err == nil {
 			err := utils.SyncActivationStatus(
 				context.TODO(),
-				f.Vendor.Context.SiteInfo.ParentSite.BaseUrl,
-				f.Vendor.Context.SiteInfo.ParentSite.Username,
-				f.Vendor.Context.SiteInfo.ParentSite.Password, status)
+				f.Vendor.Context.SiteInfo.ParentSite, status)
 			if err != nil {
 				fLog.Errorf("V (Federation): error while syncing activation status: %v", err)
 				return err
@@ -148,6 +150,13 @@ func (f *FederationVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    f.onSync,
 			Parameters: []string{"site?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/ack",
+			Version:    f.Version,
+			Handler:    f.onAck,
+			Parameters: []string{"site"},
+		},
 		{
 			Methods:    []string{fasthttp.MethodPost, fasthttp.MethodGet},
 			Route:      route + "/registry",
@@ -185,6 +194,9 @@ func (c *FederationVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COARes
 	var state model.SiteState
 	json.Unmarshal(request.Body, &state)
 
+	if resp, denied := c.authorizeSite(pCtx, request, state.Id); denied {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
 	err := c.SitesManager.ReportState(pCtx, state)
 
 	if err != nil {
@@ -297,6 +309,11 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 				Body:  []byte(err.Error()),
 			})
 		}
+		// a site can only report the status of the stages it ran
+		site, _ := status.Outputs["__site"].(string)
+		if resp, denied := f.authorizeSite(pCtx, request, site); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		err = f.Vendor.Context.Publish("job-report", v1alpha2.Event{
 			Body: status,
 		})
@@ -314,6 +331,9 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 	case fasthttp.MethodGet:
 		ctx, span := observability.StartSpan("onSync-GET", pCtx, nil)
 		id := request.Parameters["__site"]
+		if resp, denied := f.authorizeSite(ctx, request, id); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		count := request.Parameters["count"]
 		if count == "" {
 			count = "1"
@@ -325,7 +345,7 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 				Body:  []byte(err.Error()),
 			})
 		}
-		batch, err := f.StagingManager.GetABatchForSite(id, intCount)
+		batch, leases, err := f.StagingManager.LeaseABatchForSite(id, intCount)
 
 		pack := model.SyncPackage{
 			Origin: f.Context.SiteInfo.SiteId,
@@ -345,6 +365,7 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 			} else {
 				catalog, err := f.CatalogsManager.GetSpec(ctx, c.Id)
 				if err != nil {
+					f.StagingManager.NackBatch(id, leases)
 					return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 						State: v1alpha2.InternalError,
 						Body:  []byte(err.Error()),
@@ -355,7 +376,16 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 		}
 		pack.Catalogs = catalogs
 		pack.Jobs = jobs
-		jData, _ := utils.FormatObject(pack, true, request.Parameters["path"], request.Parameters["doc-type"])
+		// the batch stays leased until the site acknowledges it through the ack route
+		pack.Leases = leases
+		jData, err := utils.FormatObject(pack, true, request.Parameters["path"], request.Parameters["doc-type"])
+		if err != nil {
+			f.StagingManager.NackBatch(id, leases)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
 			Body:        jData,
@@ -374,6 +404,109 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+func (f *FederationVendor) onAck(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onAck",
+	})
+	defer span.End()
+
+	tLog.Info("V (Federation): onAck")
+	switch request.Method {
+	case fasthttp.MethodPost:
+		id := request.Parameters["__site"]
+		if resp, denied := f.authorizeSite(pCtx, request, id); denied {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var leases []string
+		err := json.Unmarshal(request.Body, &leases)
+		if err != nil {
+			tLog.Errorf("V (Federation): failed to unmarshal sync leases: %v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		err = f.StagingManager.AckBatch(id, leases)
+		if err != nil {
+			tLog.Errorf("V (Federation): failed to acknowledge the sync batch for site %s: %v", id, err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// authorizeSite checks that a caller that presented a client certificate acts for its own site, which is
+// the common name of the certificate. The site must be registered with a public key, and the certificate
+// must have that key. Callers without a certificate are authenticated by their tokens instead.
+func (f *FederationVendor) authorizeSite(ctx context.Context, request v1alpha2.COARequest, site string) (v1alpha2.COAResponse, bool) {
+	cert := v1alpha2.GetClientCertificate(request.Context)
+	if cert == nil {
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	}
+	name := cert.Subject.CommonName
+	if site != name {
+		fLog.Infof("V (Federation): certificate of site %s can't act for site %s", name, site)
+		return v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte(fmt.Sprintf("certificate of site %s can't act for site %s", name, site)),
+		}, true
+	}
+	state, err := f.SitesManager.GetSpec(ctx, name)
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			fLog.Infof("V (Federation): certificate of site %s is for an unknown site", name)
+			return v1alpha2.COAResponse{
+				State: v1alpha2.Unauthorized,
+				Body:  []byte(fmt.Sprintf("site %s is not registered", name)),
+			}, true
+		}
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		}, true
+	}
+	if state.Spec == nil || state.Spec.PublicKey == "" {
+		fLog.Infof("V (Federation): site %s doesn't have a public key to verify its certificate with", name)
+		return v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte(fmt.Sprintf("site %s doesn't have a public key", name)),
+		}, true
+	}
+	if !publicKeyMatches(cert, state.Spec.PublicKey) {
+		fLog.Infof("V (Federation): certificate of site %s doesn't have the public key of the site", name)
+		return v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte(fmt.Sprintf("certificate of site %s doesn't have the public key of the site", name)),
+		}, true
+	}
+	return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+}
+
+// publicKeyMatches tells if a certificate has a PEM encoded public key
+func publicKeyMatches(cert *x509.Certificate, publicKey string) bool {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return false
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return false
+	}
+	certKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && certKey.Equal(key)
+}
 func (f *FederationVendor) onTrail(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	_, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
 		"method": "onTrail",
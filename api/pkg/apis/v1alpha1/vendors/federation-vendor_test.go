@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/sites"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/staging"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	filequeue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/file"
+	memorystate "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func siteCertificate(t *testing.T, site string) (*x509.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: site}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	publicDer, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}))
+}
+
+func TestAuthorizeSite(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	vendor := FederationVendor{SitesManager: &sites.SitesManager{StateProvider: stateProvider}}
+	cert, publicKey := siteCertificate(t, "tokyo")
+	_, otherKey := siteCertificate(t, "tokyo")
+	authorize := func(withCert bool, site string) v1alpha2.State {
+		request := v1alpha2.COARequest{Context: context.Background()}
+		if withCert {
+			request.Context = context.WithValue(request.Context, v1alpha2.ClientCertificateKey, cert)
+		}
+		resp, _ := vendor.authorizeSite(context.Background(), request, site)
+		return resp.State
+	}
+
+	// callers without a certificate are authenticated by tokens
+	assert.Equal(t, v1alpha2.OK, authorize(false, "munich"))
+	assert.Equal(t, v1alpha2.Unauthorized, authorize(true, "munich"))
+	assert.Equal(t, v1alpha2.Unauthorized, authorize(true, ""))
+
+	// the site of a certificate must be registered with a public key
+	assert.Equal(t, v1alpha2.Unauthorized, authorize(true, "tokyo"))
+	err := vendor.SitesManager.UpsertSpec(context.Background(), "tokyo", model.SiteSpec{Name: "tokyo"})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, authorize(true, "tokyo"))
+
+	// the certificate must have the public key of the site
+	err = vendor.SitesManager.UpsertSpec(context.Background(), "tokyo", model.SiteSpec{Name: "tokyo", PublicKey: publicKey})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, authorize(true, "tokyo"))
+	err = vendor.SitesManager.UpsertSpec(context.Background(), "tokyo", model.SiteSpec{Name: "tokyo", PublicKey: otherKey})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, authorize(true, "tokyo"))
+}
+
+func TestSyncStatusOfOtherSite(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	vendor := FederationVendor{SitesManager: &sites.SitesManager{StateProvider: stateProvider}}
+	cert, publicKey := siteCertificate(t, "tokyo")
+	err := vendor.SitesManager.UpsertSpec(context.Background(), "tokyo", model.SiteSpec{Name: "tokyo", PublicKey: publicKey})
+	assert.Nil(t, err)
+
+	for _, outputs := range []map[string]interface{}{
+		{"__site": "munich"},
+		{},
+	} {
+		data, _ := json.Marshal(model.ActivationStatus{Outputs: outputs})
+		resp := vendor.onSync(v1alpha2.COARequest{
+			Method:  fasthttp.MethodPost,
+			Context: context.WithValue(context.Background(), v1alpha2.ClientCertificateKey, cert),
+			Body:    data,
+		})
+		assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+	}
+}
+
+func TestSyncBatchIsAckedBySite(t *testing.T) {
+	queueProvider := &filequeue.FileQueueProvider{}
+	err := queueProvider.Init(filequeue.FileQueueProviderConfig{Path: filepath.Join(t.TempDir(), "queue.json")})
+	assert.Nil(t, err)
+	vendor := FederationVendor{
+		Vendor: vendors.Vendor{
+			Context: &contexts.VendorContext{
+				SiteInfo: v1alpha2.SiteInfo{SiteId: "parent"},
+			},
+		},
+		StagingManager: &staging.StagingManager{QueueProvider: queueProvider},
+	}
+	queueProvider.Enqueue("tokyo", v1alpha2.JobData{Id: "job1", Action: "RUN"})
+
+	resp := vendor.onSync(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "tokyo", "count": "5"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var pack model.SyncPackage
+	assert.Nil(t, json.Unmarshal(resp.Body, &pack))
+	assert.Equal(t, 1, len(pack.Jobs))
+	assert.Equal(t, 1, len(pack.Leases))
+
+	// the batch is leased, not removed, until the site acknowledges it
+	assert.Equal(t, 0, queueProvider.Size("tokyo"))
+	assert.Equal(t, 1, len(queueProvider.Data["tokyo"].Messages))
+
+	data, _ := json.Marshal(pack.Leases)
+	resp = vendor.onAck(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "tokyo"},
+		Body:       data,
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, 0, len(queueProvider.Data["tokyo"].Messages))
+}
@@ -7,6 +7,7 @@
 package vendors
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -89,21 +91,55 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 		}
 		var err error
 		var state interface{}
+		var token string
 		isArray := false
 		if id == "" {
 			// Change partition back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				scope = ""
 			}
-			state, err = c.InstancesManager.ListSpec(ctx, scope)
+			var limit int64
+			var continueToken string
+			limit, continueToken, err = utils.ReadPaging(request.Parameters)
+			if err != nil {
+				iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindInstances, Scope: listScope(scope), Verb: authz.VerbList}); denied {
+				iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
+			var items []model.InstanceState
+			items, token, err = c.InstancesManager.ListSpecPage(ctx, scope, limit, continueToken)
+			state = authorizedInstances(request.Context, items)
 			isArray = true
 		} else {
-			state, err = c.InstancesManager.GetSpec(ctx, id, scope)
+			var instance model.InstanceState
+			instance, err = c.InstancesManager.GetSpec(ctx, id, scope)
+			if err == nil {
+				var metadata map[string]string
+				if instance.Spec != nil {
+					metadata = instance.Spec.Metadata
+				}
+				if resp, denied := authorize(request.Context, authz.Request{Kind: kindInstances, Scope: scope, Verb: authz.VerbGet, Name: id, Metadata: metadata}); denied {
+					iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+					return observ_utils.CloseSpanWithCOAResponse(span, resp)
+				}
+			}
+			state = instance
 		}
 		if err != nil {
 			iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			errorState := v1alpha2.InternalError
+			if coaErr, ok := err.(v1alpha2.COAError); ok && coaErr.State == v1alpha2.BadRequest {
+				// for instance, an invalid continue token
+				errorState = v1alpha2.BadRequest
+			}
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-				State: v1alpha2.InternalError,
+				State: errorState,
 				Body:  []byte(err.Error()),
 			})
 		}
@@ -116,6 +152,11 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 		if request.Parameters["doc-type"] == "yaml" {
 			resp.ContentType = "application/text"
 		}
+		if token != "" {
+			resp.Metadata = map[string]string{
+				"continue": token,
+			}
+		}
 		return resp
 	case fasthttp.MethodPost:
 		ctx, span := observability.StartSpan("onInstances-POST", pCtx, nil)
@@ -165,6 +206,19 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 				})
 			}
 		}
+		if resp, denied := authorizeWrite(request.Context, authz.Request{Kind: kindInstances, Scope: scope, Name: id, Metadata: instance.Metadata}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		checked, rejected := checkExpressions(c.Config.Properties, func() []utils.ExpressionIssue {
+			return utils.ValidateInstanceExpressions(instance)
+		})
+		if rejected {
+			iLog.Infof("V (Instances): onInstances failed - invalid expressions: %s, traceId: %s", string(checked.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, checked)
+		}
 		err := c.InstancesManager.UpsertSpec(ctx, id, instance, scope)
 		if err != nil {
 			iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -186,7 +240,8 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 			})
 		}
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-			State: v1alpha2.OK,
+			State:    v1alpha2.OK,
+			Metadata: checked.Metadata,
 		})
 	case fasthttp.MethodDelete:
 		ctx, span := observability.StartSpan("onInstances-DELETE", pCtx, nil)
@@ -196,6 +251,12 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 		if !exist {
 			scope = "default"
 		}
+		if resp, denied := authorizeDelete(request.Context, authz.Request{Kind: kindInstances, Scope: scope, Name: id}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			iLog.Infof("V (Instances): onInstances failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		if c.Config.Properties["useJobManager"] == "true" && direct != "true" {
 			c.Context.Publish("job", v1alpha2.Event{
 				Metadata: map[string]string{
@@ -233,3 +294,12 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// metadata returns the metadata of a instance, which authorization rules can match
+func (c *InstancesVendor) metadata(ctx context.Context, id string, scope string) (map[string]string, error) {
+	current, err := c.InstancesManager.GetSpec(ctx, id, scope)
+	if err != nil || current.Spec == nil {
+		return nil, err
+	}
+	return current.Spec.Metadata, nil
+}
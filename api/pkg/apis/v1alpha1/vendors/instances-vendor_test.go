@@ -212,3 +212,76 @@ func TestInstancesWrongMethod(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
 }
+
+func TestInstancesListWithPaging(t *testing.T) {
+	vendor := createInstancesVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+
+	for _, name := range []string{"instance1", "instance2", "instance3"} {
+		data, _ := json.Marshal(model.InstanceSpec{})
+		resp := vendor.onInstances(v1alpha2.COARequest{
+			Method: fasthttp.MethodPost,
+			Body:   data,
+			Parameters: map[string]string{
+				"__name":   name,
+				"target":   "target1",
+				"solution": "solution1",
+			},
+			Context: context.Background(),
+		})
+		assert.Equal(t, v1alpha2.OK, resp.State)
+	}
+
+	resp := vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"limit": "2",
+		},
+		Context: context.Background(),
+	})
+	var instances []model.InstanceState
+	err := json.Unmarshal(resp.Body, &instances)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, "instance1", instances[0].Id)
+	assert.Equal(t, "instance2", instances[1].Id)
+	token := resp.Metadata["continue"]
+	assert.NotEmpty(t, token)
+
+	resp = vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"limit":    "2",
+			"continue": token,
+		},
+		Context: context.Background(),
+	})
+	err = json.Unmarshal(resp.Body, &instances)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, "instance3", instances[0].Id)
+	assert.Empty(t, resp.Metadata["continue"])
+
+	resp = vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"limit": "two",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	resp = vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"continue": "!not-a-token!",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
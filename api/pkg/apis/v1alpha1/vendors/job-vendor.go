@@ -73,6 +73,9 @@ func (e *JobVendor) Init(config vendors.VendorConfig, factories []managers.IMana
 	e.Vendor.Context.Subscribe("schedule", func(topic string, event v1alpha2.Event) error {
 		return e.JobsManager.HandleScheduleEvent(context.Background(), event)
 	})
+	e.Vendor.Context.Subscribe("unschedule", func(topic string, event v1alpha2.Event) error {
+		return e.JobsManager.HandleUnscheduleEvent(context.Background(), event)
+	})
 
 	if err != nil {
 		return err
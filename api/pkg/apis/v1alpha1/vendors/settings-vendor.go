@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"strings"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
@@ -63,6 +64,12 @@ func (o *SettingsVendor) GetEndpoints() []v1alpha2.Endpoint {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/explain",
+			Version: o.Version,
+			Handler: o.onExplain,
+		},
 		{
 			Methods:    []string{fasthttp.MethodGet},
 			Route:      route + "/config",
@@ -131,3 +138,54 @@ func (c *SettingsVendor) onConfig(request v1alpha2.COARequest) v1alpha2.COARespo
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *SettingsVendor) onExplain(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	_, span := observability.StartSpan("Settings Vendor", request.Context, &map[string]string{
+		"method": "onExplain",
+	})
+	defer span.End()
+	csLog.Infof("V (Settings): onExplain %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	switch request.Method {
+	case fasthttp.MethodPost:
+		var explainRequest model.ExplainRequest
+		err := json.Unmarshal(request.Body, &explainRequest)
+		if err != nil {
+			csLog.Errorf("V (Settings): onExplain failed to parse request, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		context := utils.EvaluationContext{
+			Properties: explainRequest.Context.Properties,
+			Inputs:     explainRequest.Context.Inputs,
+			Outputs:    explainRequest.Context.Outputs,
+			Component:  explainRequest.Context.Component,
+			Value:      explainRequest.Context.Value,
+		}
+		if c.EvaluationContext != nil {
+			context.ConfigProvider = c.EvaluationContext.ConfigProvider
+			context.SecretProvider = c.EvaluationContext.SecretProvider
+		}
+		if explainRequest.Context.Deployment != nil {
+			context.DeploymentSpec = *explainRequest.Context.Deployment
+		}
+		explanation := api_utils.NewParser(explainRequest.Expression).Explain(context)
+		jData, _ := json.Marshal(explanation)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+
+	csLog.Infof("V (Settings): onExplain returned MethodNotAllowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
@@ -8,15 +8,19 @@ package vendors
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
 	memory "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/memoryconfig"
+	secretmock "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -148,3 +152,84 @@ func TestConfigGetField(t *testing.T) {
 	res = vendor.onConfig(*request)
 	assert.Equal(t, v1alpha2.InternalError, res.State)
 }
+
+func TestExplain(t *testing.T) {
+	vendor := createSettingsVendor()
+	manager := vendor.EvaluationContext.ConfigProvider.(*configs.ConfigsManager)
+	provider := manager.ConfigProviders["memory"]
+	provider.Set("line-config", "port", "8080")
+	provider.Set("site-config", "port", "9090")
+
+	data, _ := json.Marshal(model.ExplainRequest{
+		Expression: "${{$config(line-config, port, site-config)}}-${{$input(zone)}}",
+		Context: model.ExplainContext{
+			Inputs: map[string]interface{}{
+				"zone": "west",
+			},
+		},
+	})
+	res := vendor.onExplain(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var explanation api_utils.ExpressionExplanation
+	err := json.Unmarshal(res.Body, &explanation)
+	assert.Nil(t, err)
+	assert.Equal(t, "9090-west", explanation.Value)
+	assert.Equal(t, &config.ConfigSource{Provider: "memory", Object: "site-config"}, explanation.Segments[0].Steps[0].Source)
+}
+
+func TestExplainSecret(t *testing.T) {
+	vendor := createSettingsVendor()
+	secretProvider := &secretmock.MockSecretProvider{}
+	secretProvider.Init(secretmock.MockSecretProviderConfig{})
+	vendor.EvaluationContext.SecretProvider = secretProvider
+
+	data, _ := json.Marshal(model.ExplainRequest{
+		Expression: "${{$secret(db, password)}}",
+	})
+	res := vendor.onExplain(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	assert.NotContains(t, string(res.Body), "db>>password")
+	var explanation api_utils.ExpressionExplanation
+	err := json.Unmarshal(res.Body, &explanation)
+	assert.Nil(t, err)
+	assert.Equal(t, "***", explanation.Value)
+	assert.Equal(t, "***", explanation.Segments[0].Steps[0].Value)
+}
+
+func TestExplainError(t *testing.T) {
+	vendor := createSettingsVendor()
+	data, _ := json.Marshal(model.ExplainRequest{
+		Expression: "${{$input(zone)}}",
+	})
+	res := vendor.onExplain(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var explanation api_utils.ExpressionExplanation
+	err := json.Unmarshal(res.Body, &explanation)
+	assert.Nil(t, err)
+	assert.Equal(t, "an input collection is needed to evaluate $input()", explanation.Error)
+
+	res = vendor.onExplain(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    []byte("bad data"),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, res.State)
+
+	res = vendor.onExplain(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, res.State)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/solution"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -70,6 +71,19 @@ func (o *SolutionVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Parameters: []string{"delete?"},
 			Handler:    o.onReconcile,
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/plan",
+			Version:    o.Version,
+			Parameters: []string{"delete?"},
+			Handler:    o.onPlan,
+		},
+		{
+			Methods: []string{fasthttp.MethodGet, fasthttp.MethodPost},
+			Route:   route + "/drift",
+			Version: o.Version,
+			Handler: o.onDrift,
+		},
 		{
 			Methods: []string{fasthttp.MethodGet, fasthttp.MethodPost},
 			Route:   route + "/queue",
@@ -195,6 +209,14 @@ func (c *SolutionVendor) onReconcile(request v1alpha2.COARequest) v1alpha2.COARe
 			})
 		}
 		delete := request.Parameters["delete"]
+		verb := authz.VerbUpdate
+		if delete == "true" {
+			verb = authz.VerbDelete
+		}
+		if resp, denied := c.authorizeDeployment(ctx, verb, deployment.Instance, scope); denied {
+			sLog.Infof("V (Solution): onReconcile failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		summary, err := c.SolutionManager.Reconcile(ctx, deployment, delete == "true", scope)
 		data, _ := json.Marshal(summary)
 		if err != nil {
@@ -218,6 +240,156 @@ func (c *SolutionVendor) onReconcile(request v1alpha2.COARequest) v1alpha2.COARe
 	})
 }
 
+func (c *SolutionVendor) onPlan(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	rContext, span := observability.StartSpan("Solution Vendor", request.Context, &map[string]string{
+		"method": "onPlan",
+	})
+	defer span.End()
+
+	sLog.Infof("V (Solution): onPlan, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+	scope, exist := request.Parameters["scope"]
+	if !exist {
+		scope = "default"
+	}
+	switch request.Method {
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onPlan-POST", rContext, nil)
+		defer span.End()
+		var deployment model.DeploymentSpec
+		err := json.Unmarshal(request.Body, &deployment)
+		if err != nil {
+			sLog.Infof("V (Solution): onPlan failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		if resp, denied := c.authorizeDeployment(ctx, authz.VerbGet, deployment.Instance, scope); denied {
+			sLog.Infof("V (Solution): onPlan failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		delete := request.Parameters["delete"]
+		preview, err := c.SolutionManager.Plan(ctx, deployment, delete == "true", scope)
+		if err != nil {
+			sLog.Infof("V (Solution): onPlan failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		data, _ := json.Marshal(preview)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	}
+	sLog.Infof("V (Solution): onPlan failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	})
+}
+func (c *SolutionVendor) onDrift(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	rContext, span := observability.StartSpan("Solution Vendor", request.Context, &map[string]string{
+		"method": "onDrift",
+	})
+	defer span.End()
+
+	sLog.Infof("V (Solution): onDrift, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+	scope, exist := request.Parameters["scope"]
+	if !exist {
+		scope = "default"
+	}
+	instance := request.Parameters["instance"]
+	if instance == "" {
+		sLog.Infof("V (Solution): onDrift failed - 400 instance parameter is not found, traceId: %s", span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.BadRequest,
+			Body:        []byte("{\"result\":\"400 - instance parameter is not found\"}"),
+			ContentType: "application/json",
+		})
+	}
+	// healing redeploys the instance, while detecting drift only reads it
+	verb := authz.VerbGet
+	if request.Method == fasthttp.MethodPost && request.Parameters["heal"] == "true" {
+		verb = authz.VerbUpdate
+	}
+	if resp, denied := c.authorizeDeployment(rContext, verb, model.InstanceSpec{Name: instance}, scope); denied {
+		sLog.Infof("V (Solution): onDrift failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	var report model.DriftReport
+	var err error
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onDrift-GET", rContext, nil)
+		defer span.End()
+		report, err = c.SolutionManager.GetDriftReport(ctx, instance, scope)
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onDrift-POST", rContext, nil)
+		defer span.End()
+		report, err = c.SolutionManager.DetectDrift(ctx, instance, scope)
+		if err == nil && report.Drifted && request.Parameters["heal"] == "true" {
+			err = c.SolutionManager.HealDrift(ctx, instance, scope)
+			if err == nil {
+				report, err = c.SolutionManager.GetDriftReport(ctx, instance, scope)
+			}
+		}
+	default:
+		sLog.Infof("V (Solution): onDrift failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.MethodNotAllowed,
+			Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+			ContentType: "application/json",
+		})
+	}
+	if err != nil {
+		sLog.Infof("V (Solution): onDrift failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		if v1alpha2.IsNotFound(err) {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.NotFound,
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
+	}
+	data, _ := json.Marshal(report)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
+
+// authorizeDeployment decides a request against the instance of a deployment. The request is decided against
+// the metadata of the last deployment of the instance too, so that rules on metadata can't be escaped by
+// sending different metadata.
+func (c *SolutionVendor) authorizeDeployment(ctx context.Context, verb string, instance model.InstanceSpec, scope string) (v1alpha2.COAResponse, bool) {
+	if authz.FromContext(ctx) == nil {
+		return v1alpha2.COAResponse{State: v1alpha2.OK}, false
+	}
+	request := authz.Request{Kind: kindInstances, Scope: scope, Verb: verb, Name: instance.Name, Metadata: instance.Metadata}
+	if instance.Metadata != nil {
+		if resp, denied := authorize(ctx, request); denied {
+			return resp, true
+		}
+	}
+	deployed, err := c.SolutionManager.GetDeployedInstance(ctx, instance.Name, scope)
+	if err != nil && !v1alpha2.IsNotFound(err) {
+		return v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		}, true
+	}
+	request.Metadata = deployed.Metadata
+	return authorize(ctx, request)
+}
+
 func (c *SolutionVendor) onApplyDeployment(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	_, span := observability.StartSpan("Solution Vendor", request.Context, &map[string]string{
 		"method": "onApplyDeployment",
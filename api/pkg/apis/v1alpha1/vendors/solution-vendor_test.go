@@ -15,6 +15,7 @@ import (
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
@@ -157,7 +158,7 @@ func TestSolutionEndpoints(t *testing.T) {
 	vendor := createSolutionVendor()
 	vendor.Route = "solution"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 3, len(endpoints))
+	assert.Equal(t, 5, len(endpoints))
 }
 
 func TestSolutionInfo(t *testing.T) {
@@ -342,6 +343,146 @@ func TestSolutionReconcile(t *testing.T) {
 	json.Unmarshal(resp.Body, &summary)
 	assert.False(t, summary.Skipped)
 }
+func TestSolutionPlan(t *testing.T) {
+	var preview model.PlanPreview
+	vendor := createSolutionVendor()
+
+	// plan before anything is deployed
+	deployment := createDeployment2Mocks1Target(uuid.New().String())
+	data, _ := json.Marshal(deployment)
+	resp := vendor.onPlan(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	err := json.Unmarshal(resp.Body, &preview)
+	assert.Nil(t, err)
+	assert.True(t, preview.Changed)
+	assert.Equal(t, 1, len(preview.Steps))
+	assert.Equal(t, "T1", preview.Steps[0].Target)
+	assert.Equal(t, "providers.target.mock", preview.Steps[0].Provider)
+	assert.Equal(t, 2, len(preview.Steps[0].Components))
+	for _, c := range preview.Steps[0].Components {
+		assert.Equal(t, model.PlanActionAdd, c.Action)
+	}
+
+	// planning doesn't deploy anything, so the first reconcile still runs
+	resp = vendor.onReconcile(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var summary model.SummarySpec
+	json.Unmarshal(resp.Body, &summary)
+	assert.False(t, summary.Skipped)
+
+	// nothing changed since the deployment
+	resp = vendor.onPlan(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	preview = model.PlanPreview{}
+	json.Unmarshal(resp.Body, &preview)
+	assert.False(t, preview.Changed)
+	assert.True(t, preview.Steps[0].Skipped)
+
+	// removing the first component
+	deployment.Solution.Components = deployment.Solution.Components[1:]
+	deployment.Assignments["T1"] = "{b}"
+	data, _ = json.Marshal(deployment)
+	resp = vendor.onPlan(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	preview = model.PlanPreview{}
+	json.Unmarshal(resp.Body, &preview)
+	assert.True(t, preview.Changed)
+	actions := map[string]string{}
+	for _, step := range preview.Steps {
+		for _, c := range step.Components {
+			actions[c.Name] = c.Action
+		}
+	}
+	assert.Equal(t, model.PlanActionDelete, actions["a"])
+	assert.Equal(t, model.PlanActionNone, actions["b"])
+}
+func TestSolutionPlanBadRequest(t *testing.T) {
+	vendor := createSolutionVendor()
+	resp := vendor.onPlan(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    []byte("not a deployment"),
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+func TestSolutionDrift(t *testing.T) {
+	vendor := createSolutionVendor()
+	id := uuid.New().String()
+	data, _ := json.Marshal(createDeployment2Mocks1Target(id))
+	resp := vendor.onReconcile(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	resp = vendor.onDrift(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	resp = vendor.onDrift(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{"instance": "instance1"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+
+	// remove a behind Symphony's back
+	provider := &mock.MockTargetProvider{}
+	provider.Init(mock.MockTargetProviderConfig{ID: id})
+	provider.Apply(context.Background(), model.DeploymentSpec{}, model.DeploymentStep{
+		Components: []model.ComponentStep{{Action: "delete", Component: model.ComponentSpec{Name: "a"}}},
+	}, false)
+
+	resp = vendor.onDrift(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"instance": "instance1"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var report model.DriftReport
+	json.Unmarshal(resp.Body, &report)
+	assert.True(t, report.Drifted)
+	assert.Equal(t, []model.ComponentDrift{{Target: "T1", Name: "a", Drift: model.DriftMissing}}, report.Components)
+
+	resp = vendor.onDrift(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"instance": "instance1", "heal": "true"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	report = model.DriftReport{}
+	json.Unmarshal(resp.Body, &report)
+	assert.True(t, report.Healed)
+
+	resp = vendor.onDrift(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"instance": "instance1"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	report = model.DriftReport{}
+	json.Unmarshal(resp.Body, &report)
+	assert.False(t, report.Drifted)
+}
 func TestSolutionQueue(t *testing.T) {
 	vendor := createSolutionVendor()
 	resp := vendor.onQueue(v1alpha2.COARequest{
@@ -434,3 +575,41 @@ func TestSolutionQueueTargetUpdate(t *testing.T) {
 	time.Sleep(time.Second)
 	assert.Equal(t, 1, succeededCount)
 }
+
+func TestSolutionDeploymentAuthorization(t *testing.T) {
+	vendor := createSolutionVendor()
+	deployment := createDeployment2Mocks1Target(uuid.New().String())
+	deployment.Instance.Metadata = map[string]string{"frozen": "true"}
+	data, _ := json.Marshal(deployment)
+	resp := vendor.onReconcile(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       data,
+		Parameters: map[string]string{"scope": "prod"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	call := func(ctx context.Context, handler func(v1alpha2.COARequest) v1alpha2.COAResponse, method string, deployment model.DeploymentSpec, parameters map[string]string) v1alpha2.State {
+		data, _ := json.Marshal(deployment)
+		parameters["scope"] = "prod"
+		return handler(v1alpha2.COARequest{
+			Method:     method,
+			Body:       data,
+			Parameters: parameters,
+			Context:    ctx,
+		}).State
+	}
+	reader := authzContext(t, "alice", "reader")
+	operator := authzContext(t, "bob", "prod-operator")
+	auditor := authzContext(t, "carol", "auditor")
+
+	// the metadata of the deployed instance is checked, so a frozen instance can't be redeployed
+	deployment.Instance.Metadata = nil
+	assert.Equal(t, v1alpha2.Unauthorized, call(operator, vendor.onReconcile, fasthttp.MethodPost, deployment, map[string]string{}))
+	assert.Equal(t, v1alpha2.Unauthorized, call(reader, vendor.onReconcile, fasthttp.MethodPost, deployment, map[string]string{}))
+	assert.Equal(t, v1alpha2.OK, call(operator, vendor.onPlan, fasthttp.MethodPost, deployment, map[string]string{}))
+	assert.Equal(t, v1alpha2.OK, call(reader, vendor.onPlan, fasthttp.MethodPost, deployment, map[string]string{}))
+	assert.Equal(t, v1alpha2.Unauthorized, call(auditor, vendor.onPlan, fasthttp.MethodPost, deployment, map[string]string{}))
+	assert.Equal(t, v1alpha2.Unauthorized, call(auditor, vendor.onDrift, fasthttp.MethodGet, deployment, map[string]string{"instance": "instance1"}))
+	assert.Equal(t, v1alpha2.Unauthorized, call(reader, vendor.onDrift, fasthttp.MethodPost, deployment, map[string]string{"instance": "instance1", "heal": "true"}))
+}
@@ -7,12 +7,14 @@
 package vendors
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/solutions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -67,6 +69,20 @@ func (o *SolutionsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onSolutions,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/revisions",
+			Version:    o.Version,
+			Handler:    o.onRevisions,
+			Parameters: []string{"name", "revision?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/diff",
+			Version:    o.Version,
+			Handler:    o.onDiff,
+			Parameters: []string{"name"},
+		},
 	}
 }
 
@@ -86,21 +102,55 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 		id := request.Parameters["__name"]
 		var err error
 		var state interface{}
+		var token string
 		isArray := false
 		if id == "" {
 			// Change scope back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				scope = ""
 			}
-			state, err = c.SolutionsManager.ListSpec(ctx, scope)
+			var limit int64
+			var continueToken string
+			limit, continueToken, err = utils.ReadPaging(request.Parameters)
+			if err != nil {
+				uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindSolutions, Scope: listScope(scope), Verb: authz.VerbList}); denied {
+				uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
+			var items []model.SolutionState
+			items, token, err = c.SolutionsManager.ListSpecPage(ctx, scope, limit, continueToken)
+			state = authorizedSolutions(request.Context, items)
 			isArray = true
 		} else {
-			state, err = c.SolutionsManager.GetSpec(ctx, id, scope)
+			var solution model.SolutionState
+			solution, err = c.SolutionsManager.GetSpec(ctx, id, scope)
+			if err == nil {
+				var metadata map[string]string
+				if solution.Spec != nil {
+					metadata = solution.Spec.Metadata
+				}
+				if resp, denied := authorize(request.Context, authz.Request{Kind: kindSolutions, Scope: scope, Verb: authz.VerbGet, Name: id, Metadata: metadata}); denied {
+					uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+					return observ_utils.CloseSpanWithCOAResponse(span, resp)
+				}
+			}
+			state = solution
 		}
 		if err != nil {
 			uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			errorState := v1alpha2.InternalError
+			if coaErr, ok := err.(v1alpha2.COAError); ok && coaErr.State == v1alpha2.BadRequest {
+				// for instance, an invalid continue token
+				errorState = v1alpha2.BadRequest
+			}
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-				State: v1alpha2.InternalError,
+				State: errorState,
 				Body:  []byte(err.Error()),
 			})
 		}
@@ -113,6 +163,11 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 		if request.Parameters["doc-type"] == "yaml" {
 			resp.ContentType = "application/text"
 		}
+		if token != "" {
+			resp.Metadata = map[string]string{
+				"continue": token,
+			}
+		}
 		return resp
 	case fasthttp.MethodPost:
 		ctx, span := observability.StartSpan("onSolutions-POST", pCtx, nil)
@@ -147,6 +202,19 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 				})
 			}
 		}
+		if resp, denied := authorizeWrite(request.Context, authz.Request{Kind: kindSolutions, Scope: scope, Name: id, Metadata: solution.Metadata}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		checked, rejected := checkExpressions(c.Config.Properties, func() []utils.ExpressionIssue {
+			return utils.ValidateSolutionExpressions(solution)
+		})
+		if rejected {
+			uLog.Infof("V (Solutions): onSolutions failed - invalid expressions: %s, traceId: %s", string(checked.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, checked)
+		}
 		err := c.SolutionsManager.UpsertSpec(ctx, id, solution, scope)
 		if err != nil {
 			uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -172,11 +240,18 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 			},
 		})
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-			State: v1alpha2.OK,
+			State:    v1alpha2.OK,
+			Metadata: checked.Metadata,
 		})
 	case fasthttp.MethodDelete:
 		ctx, span := observability.StartSpan("onSolutions-DELETE", pCtx, nil)
 		id := request.Parameters["__name"]
+		if resp, denied := authorizeDelete(request.Context, authz.Request{Kind: kindSolutions, Scope: scope, Name: id}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		err := c.SolutionsManager.DeleteSpec(ctx, id, scope)
 		if err != nil {
 			uLog.Infof("V (Solutions): onSolutions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -198,3 +273,114 @@ func (c *SolutionsVendor) onSolutions(request v1alpha2.COARequest) v1alpha2.COAR
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *SolutionsVendor) onRevisions(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Solutions Vendor", request.Context, &map[string]string{
+		"method": "onRevisions",
+	})
+	defer span.End()
+	uLog.Infof("V (Solutions): onRevisions, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+	scope, exist := request.Parameters["scope"]
+	if !exist {
+		scope = "default"
+	}
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onRevisions-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		if resp, denied := authorizeCurrent(request.Context, authz.Request{Kind: kindSolutions, Scope: scope, Verb: authz.VerbGet, Name: id}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			uLog.Infof("V (Solutions): onRevisions failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var state interface{}
+		var err error
+		if request.Parameters["__revision"] == "" {
+			state, err = listRevisions(func() ([]model.RevisionSpec, error) {
+				return c.SolutionsManager.GetRevisions(ctx, id, scope)
+			})
+		} else {
+			var revision int
+			revision, err = parseRevision(request.Parameters["__revision"])
+			if err == nil {
+				state, err = c.SolutionsManager.GetRevision(ctx, id, scope, revision)
+			}
+		}
+		if err != nil {
+			uLog.Infof("V (Solutions): onRevisions failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, revisionErrorResponse(err))
+		}
+		jData, _ := json.Marshal(state)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	uLog.Infof("V (Solutions): onRevisions failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *SolutionsVendor) onDiff(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Solutions Vendor", request.Context, &map[string]string{
+		"method": "onDiff",
+	})
+	defer span.End()
+	uLog.Infof("V (Solutions): onDiff, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+	scope, exist := request.Parameters["scope"]
+	if !exist {
+		scope = "default"
+	}
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onDiff-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		if resp, denied := authorizeCurrent(request.Context, authz.Request{Kind: kindSolutions, Scope: scope, Verb: authz.VerbGet, Name: id}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			uLog.Infof("V (Solutions): onDiff failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var diff model.RevisionDiff
+		from, to, err := readRevisionRange(request.Parameters, func() ([]model.RevisionSpec, error) {
+			return c.SolutionsManager.GetRevisions(ctx, id, scope)
+		})
+		if err == nil {
+			diff, err = c.SolutionsManager.DiffRevisions(ctx, id, scope, from, to)
+		}
+		if err != nil {
+			uLog.Infof("V (Solutions): onDiff failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, revisionErrorResponse(err))
+		}
+		jData, _ := json.Marshal(diff)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	uLog.Infof("V (Solutions): onDiff failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// metadata returns the metadata of a solution, which authorization rules can match
+func (c *SolutionsVendor) metadata(ctx context.Context, id string, scope string) (map[string]string, error) {
+	current, err := c.SolutionsManager.GetSpec(ctx, id, scope)
+	if err != nil || current.Spec == nil {
+		return nil, err
+	}
+	return current.Spec.Metadata, nil
+}
@@ -9,6 +9,7 @@ package vendors
 import (
 	"context"
 	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
@@ -18,6 +19,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/filestate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/stretchr/testify/assert"
@@ -28,7 +30,7 @@ func TestSolutionsEndpoints(t *testing.T) {
 	vendor := createSolutionsVendor()
 	vendor.Route = "solutions"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, 3, len(endpoints))
 }
 
 func TestSolutionsInfo(t *testing.T) {
@@ -134,3 +136,95 @@ func TestSolutionsOnSolutions(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.OK, resp.State)
 }
+
+func TestSolutionsAuthorization(t *testing.T) {
+	vendor := createSolutionsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	reader := authzContext(t, "alice", "reader")
+	operator := authzContext(t, "bob", "prod-operator")
+	call := func(ctx context.Context, method string, parameters map[string]string, metadata map[string]string) v1alpha2.State {
+		data, _ := json.Marshal(model.SolutionSpec{DisplayName: parameters["__name"], Metadata: metadata})
+		return vendor.onSolutions(v1alpha2.COARequest{
+			Method:     method,
+			Body:       data,
+			Parameters: parameters,
+			Context:    ctx,
+		}).State
+	}
+	web := map[string]string{"__name": "web", "scope": "prod"}
+	api := map[string]string{"__name": "api", "scope": "prod"}
+
+	assert.Equal(t, v1alpha2.Unauthorized, call(reader, fasthttp.MethodPost, web, nil))
+	assert.Equal(t, v1alpha2.Unauthorized, call(operator, fasthttp.MethodPost, map[string]string{"__name": "web", "scope": "dev"}, nil))
+	assert.Equal(t, v1alpha2.OK, call(operator, fasthttp.MethodPost, web, map[string]string{"frozen": "true"}))
+	assert.Equal(t, v1alpha2.OK, call(operator, fasthttp.MethodPost, api, nil))
+	assert.Equal(t, v1alpha2.OK, call(operator, fasthttp.MethodPost, api, map[string]string{"owner": "ops"}))
+	// the current metadata is checked, so a frozen solution can't be unfrozen or deleted
+	assert.Equal(t, v1alpha2.Unauthorized, call(operator, fasthttp.MethodPost, web, nil))
+	assert.Equal(t, v1alpha2.Unauthorized, call(operator, fasthttp.MethodDelete, web, nil))
+
+	assert.Equal(t, v1alpha2.OK, call(reader, fasthttp.MethodGet, web, nil))
+	assert.Equal(t, v1alpha2.OK, call(operator, fasthttp.MethodGet, map[string]string{"scope": "prod"}, nil))
+	// listing all scopes needs a rule for all scopes
+	assert.Equal(t, v1alpha2.OK, call(reader, fasthttp.MethodGet, map[string]string{}, nil))
+	assert.Equal(t, v1alpha2.Unauthorized, call(operator, fasthttp.MethodGet, map[string]string{}, nil))
+
+	assert.Equal(t, v1alpha2.Unauthorized, call(reader, fasthttp.MethodDelete, api, nil))
+	assert.Equal(t, v1alpha2.OK, call(operator, fasthttp.MethodDelete, api, nil))
+}
+
+func TestSolutionsListAuthorization(t *testing.T) {
+	vendor := createSolutionsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	// the file state provider keeps the scopes of solutions
+	stateProvider := &filestate.FileStateProvider{}
+	err := stateProvider.Init(filestate.FileStateProviderConfig{Path: filepath.Join(t.TempDir(), "states.json")})
+	assert.Nil(t, err)
+	vendor.SolutionsManager.StateProvider = stateProvider
+	ctx := context.Background()
+	err = vendor.SolutionsManager.UpsertSpec(ctx, "web", model.SolutionSpec{}, "prod")
+	assert.Nil(t, err)
+	err = vendor.SolutionsManager.UpsertSpec(ctx, "api", model.SolutionSpec{}, "dev")
+	assert.Nil(t, err)
+	err = vendor.SolutionsManager.UpsertSpec(ctx, "hr", model.SolutionSpec{Metadata: map[string]string{"confidential": "true"}}, "dev")
+	assert.Nil(t, err)
+
+	// listed solutions are filtered with the deny rules on scopes and metadata
+	response := vendor.onSolutions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{},
+		Context:    authzContext(t, "carol", "auditor"),
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var solutions []model.SolutionState
+	err = json.Unmarshal(response.Body, &solutions)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(solutions))
+	assert.Equal(t, "api", solutions[0].Id)
+}
+
+func TestSolutionsRevisionsAuthorization(t *testing.T) {
+	vendor := createSolutionsVendor()
+	err := vendor.SolutionsManager.UpsertSpec(context.Background(), "web", model.SolutionSpec{}, "prod")
+	assert.Nil(t, err)
+	auditor := authzContext(t, "carol", "auditor")
+	parameters := map[string]string{"__name": "web", "scope": "prod"}
+	response := vendor.onRevisions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: parameters,
+		Context:    auditor,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	response = vendor.onDiff(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: parameters,
+		Context:    auditor,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+}
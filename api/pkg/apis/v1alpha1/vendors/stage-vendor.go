@@ -80,23 +80,66 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		if err != nil {
 			return v1alpha2.NewCOAError(nil, "event body is not an activation job", v1alpha2.BadRequest)
 		}
-		campaign, err := s.CampaignsManager.GetSpec(context.TODO(), actData.Campaign)
+		scheduled := event.Metadata["scheduled"] == "true"
+		activation, err := s.ActivationsManager.GetSpec(context.TODO(), actData.Activation)
 		if err != nil {
-			log.Error("V (Stage): unable to find campaign: %+v", err)
+			log.Error("V (Stage): unable to find activation: %+v", err)
+			if scheduled && v1alpha2.IsNotFound(err) {
+				// the activation is gone, so are its runs
+				s.Vendor.Context.Publish("unschedule", v1alpha2.Event{
+					Body: actData,
+				})
+			}
 			return err
 		}
-		activation, err := s.ActivationsManager.GetSpec(context.TODO(), actData.Activation)
+		revision, campaign, err := s.pinCampaign(context.TODO(), actData.Campaign, activation)
 		if err != nil {
-			log.Error("V (Stage): unable to find activation: %+v", err)
+			log.Error("V (Stage): unable to find campaign: %+v", err)
 			return err
 		}
 
-		evt, err := s.StageManager.HandleActivationEvent(context.TODO(), actData, *campaign.Spec, activation)
+		evt, err := s.StageManager.HandleActivationEvent(context.TODO(), actData, campaign, activation)
 		if err != nil {
 			return err
 		}
 
+		if evt != nil && !scheduled && activation.Spec != nil && activation.Spec.Schedule != nil {
+			// the activation waits for its schedule, the jobs manager sends it back when it's due
+			scheduledData := actData
+			scheduledData.Campaign = evt.Campaign
+			scheduledData.Schedule = activation.Spec.Schedule
+			s.Vendor.Context.Publish("schedule", v1alpha2.Event{
+				Body: scheduledData,
+				Metadata: map[string]string{
+					"scope": "activation",
+				},
+			})
+			status := model.ActivationStatus{
+				Stage:                evt.Stage,
+				ActivationGeneration: actData.ActivationGeneration,
+				Outputs: map[string]interface{}{
+					"__status": v1alpha2.Delayed,
+				},
+				Status: v1alpha2.Paused,
+			}
+			return s.ActivationsManager.ReportStatus(context.TODO(), actData.Activation, status)
+		}
+
 		if evt != nil {
+			if revision != 0 {
+				// record the revision before any stage runs, later stages look it up
+				status := model.ActivationStatus{
+					Stage:                evt.Stage,
+					ActivationGeneration: actData.ActivationGeneration,
+					Status:               v1alpha2.Running,
+					IsActive:             true,
+					CampaignRevision:     revision,
+				}
+				err = s.ActivationsManager.ReportStatus(context.TODO(), actData.Activation, status)
+				if err != nil {
+					return err
+				}
+			}
 			s.Vendor.Context.Publish("trigger", v1alpha2.Event{
 				Body: *evt,
 			})
@@ -127,7 +170,7 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				sLog.Errorf("V (Stage): failed to report error status: %v (%v)", status.ErrorMessage, err)
 			}
 		}
-		campaign, err := s.CampaignsManager.GetSpec(context.TODO(), triggerData.Campaign)
+		campaign, err := s.getCampaignSpec(context.TODO(), triggerData.Campaign, triggerData.Activation)
 		if err != nil {
 			status.Status = v1alpha2.BadRequest
 			status.ErrorMessage = err.Error()
@@ -155,7 +198,7 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 			}
 		}
 
-		status, activation := s.StageManager.HandleTriggerEvent(context.TODO(), *campaign.Spec, triggerData)
+		status, activation := s.StageManager.HandleTriggerEvent(context.TODO(), campaign, triggerData)
 
 		if triggerData.NeedsReport {
 			sLog.Debugf("V (Stage): reporting status: %v", status)
@@ -169,6 +212,20 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				sLog.Errorf("V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
 				return err
 			}
+			if child, ok := status.Outputs[model.SubActivationOutput]; ok && status.Status == v1alpha2.Paused {
+				err = s.startSubActivation(context.TODO(), child)
+				if err != nil {
+					sLog.Errorf("V (Stage): failed to start sub-campaign activation: %v", err)
+					status.Status = v1alpha2.InternalError
+					status.ErrorMessage = fmt.Sprintf("failed to start sub-campaign activation: %v", err)
+					status.IsActive = false
+					err = s.ActivationsManager.ReportStatus(context.TODO(), triggerData.Activation, status)
+					if err != nil {
+						sLog.Errorf("V (Stage): failed to report error status: %v (%v)", status.ErrorMessage, err)
+						return err
+					}
+				}
+			}
 			if activation != nil && status.Status != v1alpha2.Done && status.Status != v1alpha2.Paused {
 				s.Vendor.Context.Publish("trigger", v1alpha2.Event{
 					Body: *activation,
@@ -184,13 +241,13 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		var status model.ActivationStatus
 		json.Unmarshal(jData, &status)
 		if status.Status == v1alpha2.Done || status.Status == v1alpha2.OK {
-			campaign, err := s.CampaignsManager.GetSpec(context.TODO(), status.Outputs["__campaign"].(string))
+			campaign, err := s.getCampaignSpec(context.TODO(), status.Outputs["__campaign"].(string), status.Outputs["__activation"].(string))
 			if err != nil {
 				sLog.Errorf("V (Stage): failed to get campaign spec '%s': %v", status.Outputs["__campaign"].(string), err)
 				return err
 			}
-			if campaign.Spec.SelfDriving {
-				activation, err := s.StageManager.ResumeStage(status, *campaign.Spec)
+			if campaign.SelfDriving {
+				activation, err := s.StageManager.ResumeStage(status, campaign)
 				if err != nil {
 					status.Status = v1alpha2.InternalError
 					status.IsActive = false
@@ -213,6 +270,27 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		}
 		return nil
 	})
+	s.Vendor.Context.Subscribe("activation-control", func(topic string, event v1alpha2.Event) error {
+		sLog.Debugf("V (Stage): handling activation control event: %v", event)
+		jData, _ := json.Marshal(event.Body)
+		var control model.ActivationControl
+		err := json.Unmarshal(jData, &control)
+		if err != nil {
+			sLog.Errorf("V (Stage): failed to deserialize activation control: %v", err)
+			return v1alpha2.NewCOAError(nil, "event body is not an activation control", v1alpha2.BadRequest)
+		}
+		activation, err := s.StageManager.HandleControlEvent(context.TODO(), control)
+		if err != nil {
+			sLog.Errorf("V (Stage): failed to %s activation %s: %v", control.Action, control.Activation, err)
+			return err
+		}
+		if activation != nil {
+			s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+				Body: *activation,
+			})
+		}
+		return nil
+	})
 	s.Vendor.Context.Subscribe("remote-job", func(topic string, event v1alpha2.Event) error {
 		// Unwrap data package from event body
 		jData, _ := json.Marshal(event.Body)
@@ -281,3 +359,65 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	})
 	return nil
 }
+
+// pinCampaign picks the campaign revision an activation runs against: the revision the activation asks
+// for, or else the current campaign spec. It returns revision 0 if the campaign manager doesn't keep revisions.
+func (s *StageVendor) pinCampaign(ctx context.Context, campaign string, activation model.ActivationState) (int, model.CampaignSpec, error) {
+	if activation.Spec != nil && activation.Spec.CampaignRevision != 0 {
+		spec, err := s.CampaignsManager.GetRevisionSpec(ctx, campaign, activation.Spec.CampaignRevision)
+		if err != nil {
+			return 0, model.CampaignSpec{}, err
+		}
+		return activation.Spec.CampaignRevision, spec, nil
+	}
+	return s.CampaignsManager.PinRevision(ctx, campaign)
+}
+
+// getCampaignSpec returns the campaign spec a running activation is pinned to. Activations that aren't
+// pinned, or whose revision is gone, run against the current spec.
+func (s *StageVendor) getCampaignSpec(ctx context.Context, campaign string, activation string) (model.CampaignSpec, error) {
+	state, err := s.ActivationsManager.GetSpec(ctx, activation)
+	if err == nil && state.Status != nil && state.Status.CampaignRevision != 0 {
+		spec, err := s.CampaignsManager.GetRevisionSpec(ctx, campaign, state.Status.CampaignRevision)
+		if err == nil {
+			return spec, nil
+		}
+		sLog.Warnf("V (Stage): failed to get revision %d of campaign '%s', using the current spec: %v", state.Status.CampaignRevision, campaign, err)
+	}
+	ret, err := s.CampaignsManager.GetSpec(ctx, campaign)
+	if err != nil {
+		return model.CampaignSpec{}, err
+	}
+	return *ret.Spec, nil
+}
+
+// startSubActivation starts the child activation of a sub-campaign stage. The stage is paused before the
+// child starts, so that the child can't end before its parent waits on it.
+func (s *StageVendor) startSubActivation(ctx context.Context, spec interface{}) error {
+	var child model.ActivationSpec
+	jData, _ := json.Marshal(spec)
+	err := json.Unmarshal(jData, &child)
+	if err != nil || child.Name == "" {
+		return v1alpha2.NewCOAError(err, "invalid sub-campaign activation", v1alpha2.BadRequest)
+	}
+	if _, err = s.CampaignsManager.GetSpec(ctx, child.Campaign); err != nil {
+		return err
+	}
+	err = s.ActivationsManager.UpsertSpec(ctx, child.Name, child)
+	if err != nil {
+		return err
+	}
+	entry, err := s.ActivationsManager.GetSpec(ctx, child.Name)
+	if err != nil {
+		return err
+	}
+	return s.Vendor.Context.Publish("activation", v1alpha2.Event{
+		Body: v1alpha2.ActivationData{
+			Campaign:             child.Campaign,
+			ActivationGeneration: entry.Spec.Generation,
+			Activation:           child.Name,
+			Stage:                "",
+			Inputs:               child.Inputs,
+		},
+	})
+}
@@ -7,9 +7,13 @@
 package vendors
 
 import (
+	"context"
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
@@ -99,6 +103,81 @@ func createStageVendor() StageVendor {
 	return vendor
 }
 
+func TestStagePinCampaignRevision(t *testing.T) {
+	vendor := createStageVendor()
+	revisionProvider := &memorystate.MemoryStateProvider{}
+	revisionProvider.Init(memorystate.MemoryStateProviderConfig{})
+	vendor.CampaignsManager.Revisions = &utils.RevisionStore{
+		StateProvider: revisionProvider,
+		Group:         model.WorkflowGroup,
+		Kind:          "CampaignRevision",
+		Resource:      "campaignrevisions",
+	}
+	ctx := context.Background()
+	err := vendor.CampaignsManager.UpsertSpec(ctx, "test-campaign", model.CampaignSpec{FirstStage: "build"})
+	assert.Nil(t, err)
+	err = vendor.ActivationsManager.UpsertSpec(ctx, "test-activation", model.ActivationSpec{Campaign: "test-campaign"})
+	assert.Nil(t, err)
+	activation, err := vendor.ActivationsManager.GetSpec(ctx, "test-activation")
+	assert.Nil(t, err)
+
+	revision, spec, err := vendor.pinCampaign(ctx, "test-campaign", activation)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, revision)
+	assert.Equal(t, "build", spec.FirstStage)
+	err = vendor.ActivationsManager.ReportStatus(ctx, "test-activation", model.ActivationStatus{Stage: "build", Status: v1alpha2.Running, IsActive: true, CampaignRevision: revision})
+	assert.Nil(t, err)
+
+	// the campaign changes while the activation runs
+	err = vendor.CampaignsManager.UpsertSpec(ctx, "test-campaign", model.CampaignSpec{FirstStage: "deploy"})
+	assert.Nil(t, err)
+	spec, err = vendor.getCampaignSpec(ctx, "test-campaign", "test-activation")
+	assert.Nil(t, err)
+	assert.Equal(t, "build", spec.FirstStage)
+
+	// an activation can ask for a past revision
+	activation.Spec.CampaignRevision = 1
+	revision, spec, err = vendor.pinCampaign(ctx, "test-campaign", activation)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, revision)
+	assert.Equal(t, "build", spec.FirstStage)
+	activation.Spec.CampaignRevision = 5
+	_, _, err = vendor.pinCampaign(ctx, "test-campaign", activation)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestStageStartSubActivation(t *testing.T) {
+	vendor := createStageVendor()
+	ctx := context.Background()
+	child := model.ActivationSpec{
+		Campaign: "child-campaign",
+		Name:     "parent-deploy",
+		Inputs: map[string]interface{}{
+			"region": "westus",
+		},
+		Parent: &model.ActivationParent{
+			Campaign:   "parent-campaign",
+			Activation: "parent",
+			Stage:      "deploy",
+		},
+	}
+	err := vendor.startSubActivation(ctx, child)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = vendor.ActivationsManager.GetSpec(ctx, "parent-deploy")
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	err = vendor.CampaignsManager.UpsertSpec(ctx, "child-campaign", model.CampaignSpec{})
+	assert.Nil(t, err)
+	err = vendor.startSubActivation(ctx, child)
+	assert.Nil(t, err)
+	activation, err := vendor.ActivationsManager.GetSpec(ctx, "parent-deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, "child-campaign", activation.Spec.Campaign)
+	assert.Equal(t, "westus", activation.Spec.Inputs["region"])
+	assert.Equal(t, "parent", activation.Spec.Parent.Activation)
+	assert.Equal(t, "deploy", activation.Spec.Parent.Stage)
+}
+
 // Comment out this test temporarily due to data racing issue in memory state provider: https://github.com/eclipse-symphony/symphony/issues/84
 // func TestStageActivateCampaign(t *testing.T) {
 // 	vendor := createStageVendor()
@@ -7,6 +7,7 @@
 package vendors
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"time"
@@ -15,6 +16,8 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -22,7 +25,6 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/valyala/fasthttp"
 )
 
@@ -100,10 +102,6 @@ func (o *TargetsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 }
 
-type MyCustomClaims struct {
-	User string `json:"user"`
-	jwt.RegisteredClaims
-}
 type AuthRequest struct {
 	UserName string `json:"username"`
 	Password string `json:"password"`
@@ -125,21 +123,55 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		id := request.Parameters["__name"]
 		var err error
 		var state interface{}
+		var token string
 		isArray := false
 		if id == "" {
 			// Change scope back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				scope = ""
 			}
-			state, err = c.TargetsManager.ListSpec(ctx, scope)
+			var limit int64
+			var continueToken string
+			limit, continueToken, err = utils.ReadPaging(request.Parameters)
+			if err != nil {
+				tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+			if resp, denied := authorize(request.Context, authz.Request{Kind: kindTargets, Scope: listScope(scope), Verb: authz.VerbList}); denied {
+				tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+				return observ_utils.CloseSpanWithCOAResponse(span, resp)
+			}
+			var items []model.TargetState
+			items, token, err = c.TargetsManager.ListSpecPage(ctx, scope, limit, continueToken)
+			state = authorizedTargets(request.Context, items)
 			isArray = true
 		} else {
-			state, err = c.TargetsManager.GetSpec(ctx, id, scope)
+			var target model.TargetState
+			target, err = c.TargetsManager.GetSpec(ctx, id, scope)
+			if err == nil {
+				var metadata map[string]string
+				if target.Spec != nil {
+					metadata = target.Spec.Metadata
+				}
+				if resp, denied := authorize(request.Context, authz.Request{Kind: kindTargets, Scope: scope, Verb: authz.VerbGet, Name: id, Metadata: metadata}); denied {
+					tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+					return observ_utils.CloseSpanWithCOAResponse(span, resp)
+				}
+			}
+			state = target
 		}
 		if err != nil {
 			tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			errorState := v1alpha2.InternalError
+			if coaErr, ok := err.(v1alpha2.COAError); ok && coaErr.State == v1alpha2.BadRequest {
+				// for instance, an invalid continue token
+				errorState = v1alpha2.BadRequest
+			}
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-				State: v1alpha2.InternalError,
+				State: errorState,
 				Body:  []byte(err.Error()),
 			})
 		}
@@ -152,6 +184,11 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		if request.Parameters["doc-type"] == "yaml" {
 			resp.ContentType = "application/text"
 		}
+		if token != "" {
+			resp.Metadata = map[string]string{
+				"continue": token,
+			}
+		}
 		return resp
 	case fasthttp.MethodPost:
 		ctx, span := observability.StartSpan("onRegistry-POST", pCtx, nil)
@@ -208,6 +245,12 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 				})
 			}
 		}
+		if resp, denied := authorizeWrite(request.Context, authz.Request{Kind: kindTargets, Scope: scope, Name: id, Metadata: target.Metadata}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 		err = c.TargetsManager.UpsertSpec(ctx, id, scope, target)
 		if err != nil {
 			tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
@@ -235,6 +278,12 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		ctx, span := observability.StartSpan("onRegistry-DELETE", pCtx, nil)
 		id := request.Parameters["__name"]
 		direct := request.Parameters["direct"]
+		if resp, denied := authorizeDelete(request.Context, authz.Request{Kind: kindTargets, Scope: scope, Name: id}, func() (map[string]string, error) {
+			return c.metadata(ctx, id, scope)
+		}); denied {
+			tLog.Infof("V (Targets) : onRegistry failed - %s, traceId: %s", string(resp.Body), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
 
 		if c.Config.Properties["useJobManager"] == "true" && direct != "true" {
 			c.Context.Publish("job", v1alpha2.Event{
@@ -284,34 +333,42 @@ func (c *TargetsVendor) onBootstrap(request v1alpha2.COARequest) v1alpha2.COARes
 	case fasthttp.MethodPost:
 		var authRequest AuthRequest
 		err := json.Unmarshal(request.Body, &authRequest)
-		if err != nil || authRequest.UserName != "symphony-test" {
+		if err != nil {
 			tLog.Infof("V (Targets) : onBootstrap failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
 			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 				State: v1alpha2.Unauthorized,
 				Body:  []byte(err.Error()),
 			})
 		}
-		mySigningKey := []byte("SymphonyKey")
-		claims := MyCustomClaims{
-			authRequest.UserName,
-			jwt.RegisteredClaims{
-				// A usual scenario is to set the expiration time relative to the current time
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-				IssuedAt:  jwt.NewNumericDate(time.Now()),
-				NotBefore: jwt.NewNumericDate(time.Now()),
-				Issuer:    "symphony",
-				Subject:   "symphony",
-				ID:        "1",
-				Audience:  []string{"*"},
-			},
+		if !c.isBootstrapUser(authRequest.UserName) {
+			tLog.Infof("V (Targets) : onBootstrap failed - user %s is not allowed to bootstrap, traceId: %s", authRequest.UserName, span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.Unauthorized,
+				Body:  []byte("bootstrap failed"),
+			})
 		}
-
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		ss, _ := token.SignedString(mySigningKey)
-
+		issuer, err := coa_http.GetTokenIssuer(c.Config.Properties["tokenIssuer"])
+		if err != nil {
+			tLog.Errorf("V (Targets) : onBootstrap failed to get token issuer - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		tokens, err := issuer.Issue(authRequest.UserName, nil)
+		if err != nil {
+			tLog.Errorf("V (Targets) : onBootstrap failed to issue tokens - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		// bootstrap users aren't users of the users manager, so their tokens can't be refreshed
+		tokens.RefreshToken = ""
+		data, _ := json.Marshal(tokens)
 		resp := v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
-			Body:        []byte(`{"accessToken":"` + ss + `", "tokenType": "Bearer"}`),
+			Body:        data,
 			ContentType: "application/json",
 		}
 
@@ -328,6 +385,21 @@ func (c *TargetsVendor) onBootstrap(request v1alpha2.COARequest) v1alpha2.COARes
 	return resp
 }
 
+// isBootstrapUser tells if a user can bootstrap, as listed in the comma-separated bootstrapUsers property.
+// Only symphony-test can bootstrap when the property isn't set.
+func (c *TargetsVendor) isBootstrapUser(user string) bool {
+	users := "symphony-test"
+	if v, ok := c.Config.Properties["bootstrapUsers"]; ok {
+		users = v
+	}
+	for _, u := range strings.Split(users, ",") {
+		if u = strings.TrimSpace(u); u != "" && u == user {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *TargetsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
 		"method": "onStatus",
@@ -500,3 +572,12 @@ func (c *TargetsVendor) onHeartBeat(request v1alpha2.COARequest) v1alpha2.COARes
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// metadata returns the metadata of a target, which authorization rules can match
+func (c *TargetsVendor) metadata(ctx context.Context, id string, scope string) (map[string]string, error) {
+	current, err := c.TargetsManager.GetSpec(ctx, id, scope)
+	if err != nil || current.Spec == nil {
+		return nil, err
+	}
+	return current.Spec.Metadata, nil
+}
@@ -14,6 +14,7 @@ import (
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
@@ -161,6 +162,40 @@ func TestTargetsOnBootstrap(t *testing.T) {
 	assert.Equal(t, "Bearer", authResponse.TokenType)
 }
 
+func TestTargetsOnBootstrapUsers(t *testing.T) {
+	vendor := createTargetsVendor()
+	data, _ := json.Marshal(AuthRequest{UserName: "device-1"})
+	resp := vendor.onBootstrap(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+
+	vendor.Config.Properties["bootstrapUsers"] = "device-1, device-2"
+	resp = vendor.onBootstrap(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var tokens coa_http.TokenResponse
+	json.Unmarshal(resp.Body, &tokens)
+	issuer, err := coa_http.GetTokenIssuer("")
+	assert.Nil(t, err)
+	claims, err := issuer.Validate(tokens.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "device-1", claims["user"])
+
+	data, _ = json.Marshal(AuthRequest{UserName: "symphony-test"})
+	resp = vendor.onBootstrap(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+}
+
 func TestTargetsOnStatus(t *testing.T) {
 	vendor := createTargetsVendor()
 
@@ -8,12 +8,17 @@ package vendors
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"time"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/users"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -21,7 +26,6 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/valyala/fasthttp"
 )
 
@@ -54,11 +58,32 @@ func (e *UsersVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 		return v1alpha2.NewCOAError(nil, "users manager is not supplied", v1alpha2.MissingConfig)
 	}
 	if config.Properties != nil && config.Properties["test-users"] == "true" {
-		e.UsersManager.UpsertUser(context.Background(), "admin", "", nil)
-		e.UsersManager.UpsertUser(context.Background(), "reader", "", nil)
-		e.UsersManager.UpsertUser(context.Background(), "developer", "", nil)
-		e.UsersManager.UpsertUser(context.Background(), "device-manager", "", nil)
-		e.UsersManager.UpsertUser(context.Background(), "operator", "", nil)
+		password, configured := config.Properties["test-users-password"]
+		if configured && password == "" {
+			return v1alpha2.NewCOAError(nil, "test-users-password must not be empty, remove it to generate the passwords of the test users", v1alpha2.BadConfig)
+		}
+		generated := make(map[string]string)
+		for _, name := range []string{"admin", "reader", "developer", "device-manager", "operator"} {
+			// test users are only seeded once, so that their passwords can be changed
+			if _, err := e.UsersManager.GetUser(context.Background(), name); v1alpha2.IsNotFound(err) {
+				userPassword := password
+				if !configured {
+					userPassword, err = generatePassword()
+					if err != nil {
+						return err
+					}
+					generated[name] = userPassword
+				}
+				err = e.UsersManager.UpsertUser(context.Background(), name, userPassword, nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		err = reportPasswords(config.Properties["test-users-password-file"], generated)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -70,6 +95,40 @@ func (o *UsersVendor) GetEndpoints() []v1alpha2.Endpoint {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route,
+			Version:    o.Version,
+			Handler:    o.onUsers,
+			Parameters: []string{"name?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPut},
+			Route:      route + "/roles",
+			Version:    o.Version,
+			Handler:    o.onRoles,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/password",
+			Version:    o.Version,
+			Handler:    o.onPassword,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/unlock",
+			Version:    o.Version,
+			Handler:    o.onUnlock,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/refresh",
+			Version: o.Version,
+			Handler: o.onRefresh,
+		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/auth",
@@ -79,6 +138,86 @@ func (o *UsersVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 }
 
+type userTokenResponse struct {
+	coa_http.TokenResponse
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+// generatePassword generates the password of a test user when test-users-password isn't configured
+func generatePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to generate password", v1alpha2.InternalError)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// reportPasswords hands the generated passwords of test users to the operator once. They're written to
+// the password file, which only the operator can read, or printed to stdout, but never logged.
+func reportPasswords(path string, passwords map[string]string) error {
+	if len(passwords) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(passwords))
+	for name := range passwords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s:%s\n", name, passwords[name])
+	}
+	if path == "" {
+		fmt.Print("Generated passwords of the test users:\n" + b.String())
+		log.Info("V (Users): test users are seeded with generated passwords, which are printed to stdout")
+		return nil
+	}
+	err := os.WriteFile(path, []byte(b.String()), 0600)
+	if err == nil {
+		// WriteFile keeps the mode of an existing file
+		err = os.Chmod(path, 0600)
+	}
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write the passwords of the test users", v1alpha2.InternalError)
+	}
+	log.Infof("V (Users): test users are seeded with generated passwords, which are written to %s", path)
+	return nil
+}
+
+// tokenGenerationClaim carries the token generation of the user in the issued tokens, which is checked
+// when the tokens are refreshed
+const tokenGenerationClaim = "gen"
+
+// userClaims returns the claims to issue the tokens of a user with, which carry the roles of the user
+func userClaims(user users.UserState) map[string]interface{} {
+	claims := map[string]interface{}{
+		tokenGenerationClaim: user.TokenGeneration,
+	}
+	if len(user.Roles) > 0 {
+		claims[coa_http.RolesClaim] = user.Roles
+	}
+	return claims
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type UserRequest struct {
+	Password string   `json:"password"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+type RolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+type PasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
 func (c *UsersVendor) onAuth(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
 		"method": "onAuth",
@@ -95,39 +234,265 @@ func (c *UsersVendor) onAuth(request v1alpha2.COARequest) v1alpha2.COAResponse {
 			Body:  []byte(err.Error()),
 		})
 	}
-	roles, b := c.UsersManager.CheckUser(ctx, authRequest.UserName, authRequest.Password)
-	if !b {
+	user, err := c.UsersManager.AuthenticateUser(ctx, authRequest.UserName, authRequest.Password)
+	if err != nil {
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State: v1alpha2.Unauthorized,
 			Body:  []byte("login failed"),
 		})
 	}
 
-	mySigningKey := []byte("SymphonyKey")
-	claims := MyCustomClaims{
-		authRequest.UserName,
-		jwt.RegisteredClaims{
-			// A usual scenario is to set the expiration time relative to the current time
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "symphony",
-			Subject:   "symphony",
-			ID:        "1",
-			Audience:  []string{"*"},
-		},
+	issuer, err := coa_http.GetTokenIssuer(c.Config.Properties["tokenIssuer"])
+	if err != nil {
+		log.Errorf("V (Users): onAuth failed to get token issuer, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
+	}
+	tokens, err := issuer.Issue(authRequest.UserName, userClaims(user))
+	if err != nil {
+		log.Errorf("V (Users): onAuth failed to issue tokens, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, _ := token.SignedString(mySigningKey)
 
 	log.Infof("V (Targets): onAuth succeeded, traceId: %s", span.SpanContext().TraceID().String())
-	rolesJSON, _ := json.Marshal(roles)
+	data, _ := json.Marshal(userTokenResponse{
+		TokenResponse: tokens,
+		Username:      authRequest.UserName,
+		Roles:         user.Roles,
+	})
 	resp := v1alpha2.COAResponse{
 		State:       v1alpha2.OK,
-		Body:        []byte(fmt.Sprintf(`{"accessToken":"%s", "tokenType": "Bearer", "username": "%s", "roles": %s}`, ss, authRequest.UserName, rolesJSON)),
+		Body:        data,
 		ContentType: "application/json",
 	}
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *UsersVendor) onRefresh(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRefresh",
+	})
+	defer span.End()
+	log.Infof("V (Users): refresh token %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	var refreshRequest RefreshRequest
+	err := json.Unmarshal(request.Body, &refreshRequest)
+	if err != nil || refreshRequest.RefreshToken == "" {
+		log.Errorf("V (Users): onRefresh failed to read refresh token, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("refresh token is required"),
+		})
+	}
+	issuer, err := coa_http.GetTokenIssuer(c.Config.Properties["tokenIssuer"])
+	if err != nil {
+		log.Errorf("V (Users): onRefresh failed to get token issuer, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
+	}
+	userName, claims, err := issuer.ValidateRefresh(refreshRequest.RefreshToken)
+	if err != nil {
+		log.Infof("V (Users): onRefresh failed, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("refresh failed"),
+		})
+	}
+	generation, _ := claims[tokenGenerationClaim].(string)
+	user, err := c.UsersManager.CheckRefresh(ctx, userName, generation)
+	if err != nil {
+		log.Infof("V (Users): onRefresh failed, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("refresh failed"),
+		})
+	}
+	tokens, err := issuer.Issue(userName, userClaims(user))
+	if err != nil {
+		log.Errorf("V (Users): onRefresh failed to issue tokens, error: %v traceId: %s", err, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
+	}
+	data, _ := json.Marshal(tokens)
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *UsersVendor) onUsers(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onUsers",
+	})
+	defer span.End()
+	log.Infof("V (Users): onUsers, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	id := request.Parameters["__name"]
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onUsers-GET", pCtx, nil)
+		var state interface{}
+		var err error
+		if id == "" {
+			state, err = c.UsersManager.ListUsers(ctx)
+		} else {
+			state, err = c.UsersManager.GetUser(ctx, id)
+		}
+		if err != nil {
+			log.Infof("V (Users): onUsers failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+		}
+		jData, _ := json.Marshal(state)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onUsers-POST", pCtx, nil)
+		var user UserRequest
+		err := json.Unmarshal(request.Body, &user)
+		if err != nil || id == "" || user.Password == "" {
+			log.Infof("V (Users): onUsers failed - user name and password are required, traceId: %s", span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("user name and password are required"),
+			})
+		}
+		err = c.UsersManager.UpsertUser(ctx, id, user.Password, user.Roles)
+		if err != nil {
+			log.Infof("V (Users): onUsers failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	case fasthttp.MethodDelete:
+		ctx, span := observability.StartSpan("onUsers-DELETE", pCtx, nil)
+		if id == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("user name is required"),
+			})
+		}
+		err := c.UsersManager.DeleteUser(ctx, id)
+		if err != nil {
+			log.Infof("V (Users): onUsers failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+			return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	log.Infof("V (Users): onUsers failed - 405 method not allowed, traceId: %s", span.SpanContext().TraceID().String())
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *UsersVendor) onRoles(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onRoles",
+	})
+	defer span.End()
+	log.Infof("V (Users): onRoles, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	var roles RolesRequest
+	err := json.Unmarshal(request.Body, &roles)
+	if err != nil {
+		log.Infof("V (Users): onRoles failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte(err.Error()),
+		})
+	}
+	err = c.UsersManager.SetRoles(ctx, request.Parameters["__name"], roles.Roles)
+	if err != nil {
+		log.Infof("V (Users): onRoles failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
+
+func (c *UsersVendor) onPassword(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onPassword",
+	})
+	defer span.End()
+	log.Infof("V (Users): onPassword, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	id := request.Parameters["__name"]
+	// users can only change their own passwords, administrators reset passwords with POST /users
+	if user, _ := v1alpha2.GetAuthenticatedUser(request.Context); user != "" && user != id {
+		log.Infof("V (Users): onPassword failed - %s can't change the password of %s, traceId: %s", user, id, span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("users can only change their own passwords"),
+		})
+	}
+	var password PasswordRequest
+	err := json.Unmarshal(request.Body, &password)
+	if err != nil || password.NewPassword == "" {
+		log.Infof("V (Users): onPassword failed - new password is required, traceId: %s", span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("new password is required"),
+		})
+	}
+	err = c.UsersManager.ChangePassword(ctx, id, password.OldPassword, password.NewPassword)
+	if err != nil {
+		log.Infof("V (Users): onPassword failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
+
+func (c *UsersVendor) onUnlock(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onUnlock",
+	})
+	defer span.End()
+	log.Infof("V (Users): onUnlock, method: %s, traceId: %s", request.Method, span.SpanContext().TraceID().String())
+
+	err := c.UsersManager.UnlockUser(ctx, request.Parameters["__name"])
+	if err != nil {
+		log.Infof("V (Users): onUnlock failed - %s, traceId: %s", err.Error(), span.SpanContext().TraceID().String())
+		return observ_utils.CloseSpanWithCOAResponse(span, userErrorResponse(err))
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
+
+// userErrorResponse keeps the state of the errors that the users manager reports
+func userErrorResponse(err error) v1alpha2.COAResponse {
+	state := v1alpha2.InternalError
+	if coaErr, ok := err.(v1alpha2.COAError); ok && (coaErr.State == v1alpha2.NotFound || coaErr.State == v1alpha2.Unauthorized) {
+		state = coaErr.State
+	}
+	return v1alpha2.COAResponse{
+		State: state,
+		Body:  []byte(err.Error()),
+	}
+}
@@ -9,25 +9,40 @@ package vendors
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/users"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
+const testUsersPassword = "test-password"
+
 func initVendor(t *testing.T) UsersVendor {
+	vendor, err := initVendorWithProperties(map[string]string{
+		"test-users":          "true",
+		"test-users-password": testUsersPassword,
+	})
+	assert.Nil(t, err)
+	return vendor
+}
+
+func initVendorWithProperties(properties map[string]string) (UsersVendor, error) {
 	p := memorystate.MemoryStateProvider{}
 	p.Init(memorystate.MemoryStateProviderConfig{})
 	vendor := UsersVendor{}
 	err := vendor.Init(vendors.VendorConfig{
-		Properties: map[string]string{
-			"test-users": "true",
-		},
+		Properties: properties,
 		Managers: []managers.ManagerConfig{
 			{
 				Name: "users-manager",
@@ -50,8 +65,7 @@ func initVendor(t *testing.T) UsersVendor {
 			"mem-state": &p,
 		},
 	}, nil)
-	assert.Nil(t, err)
-	return vendor
+	return vendor, err
 }
 
 func TestInit(t *testing.T) {
@@ -61,7 +75,7 @@ func TestInit(t *testing.T) {
 func TestAuth(t *testing.T) {
 	authRequest := AuthRequest{
 		UserName: "admin",
-		Password: "",
+		Password: testUsersPassword,
 	}
 	data, _ := json.Marshal(authRequest)
 	vendor := initVendor(t)
@@ -73,6 +87,168 @@ func TestAuth(t *testing.T) {
 	assert.Equal(t, response.State, v1alpha2.OK)
 }
 
+func TestRefresh(t *testing.T) {
+	data, _ := json.Marshal(AuthRequest{UserName: "admin", Password: testUsersPassword})
+	vendor := initVendor(t)
+	response := vendor.onAuth(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var tokens userTokenResponse
+	err := json.Unmarshal(response.Body, &tokens)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", tokens.Username)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	data, _ = json.Marshal(RefreshRequest{RefreshToken: tokens.RefreshToken})
+	response = vendor.onRefresh(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var refreshed coa_http.TokenResponse
+	err = json.Unmarshal(response.Body, &refreshed)
+	assert.Nil(t, err)
+	issuer, err := coa_http.GetTokenIssuer("")
+	assert.Nil(t, err)
+	claims, err := issuer.Validate(refreshed.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", claims["user"])
+
+	// access tokens can't be used to refresh
+	data, _ = json.Marshal(RefreshRequest{RefreshToken: tokens.AccessToken})
+	response = vendor.onRefresh(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+}
+
+func TestGeneratedTestUserPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwords")
+	vendor, err := initVendorWithProperties(map[string]string{
+		"test-users":               "true",
+		"test-users-password-file": path,
+	})
+	assert.Nil(t, err)
+	_, b := vendor.UsersManager.CheckUser(context.Background(), "admin", "")
+	assert.False(t, b)
+
+	// the generated passwords are written to a file only the operator can read
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	passwords := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		assert.Equal(t, 2, len(parts))
+		passwords[parts[0]] = parts[1]
+	}
+	assert.Equal(t, 5, len(passwords))
+	signIn(t, vendor, "admin", passwords["admin"])
+}
+
+func TestEmptyTestUserPassword(t *testing.T) {
+	_, err := initVendorWithProperties(map[string]string{
+		"test-users":          "true",
+		"test-users-password": "",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func signIn(t *testing.T, vendor UsersVendor, user string, password string) userTokenResponse {
+	data, _ := json.Marshal(AuthRequest{UserName: user, Password: password})
+	response := vendor.onAuth(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var tokens userTokenResponse
+	err := json.Unmarshal(response.Body, &tokens)
+	assert.Nil(t, err)
+	return tokens
+}
+
+func refresh(vendor UsersVendor, refreshToken string) v1alpha2.State {
+	data, _ := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+	response := vendor.onRefresh(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	return response.State
+}
+
+func TestRefreshRevoked(t *testing.T) {
+	vendor := initVendor(t)
+	ctx := context.Background()
+	err := vendor.UsersManager.UpsertUser(ctx, "alice", "secret1", nil)
+	assert.Nil(t, err)
+
+	// changing the password revokes the refresh tokens
+	tokens := signIn(t, vendor, "alice", "secret1")
+	assert.Equal(t, v1alpha2.OK, refresh(vendor, tokens.RefreshToken))
+	err = vendor.UsersManager.ChangePassword(ctx, "alice", "secret1", "secret2")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, refresh(vendor, tokens.RefreshToken))
+
+	// being locked out revokes the refresh tokens, and locked users can't refresh
+	tokens = signIn(t, vendor, "alice", "secret2")
+	for i := 0; i < vendor.UsersManager.LockoutAttempts; i++ {
+		_, b := vendor.UsersManager.CheckUser(ctx, "alice", "wrong")
+		assert.False(t, b)
+	}
+	assert.Equal(t, v1alpha2.Unauthorized, refresh(vendor, tokens.RefreshToken))
+	err = vendor.UsersManager.UnlockUser(ctx, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, refresh(vendor, tokens.RefreshToken))
+
+	// deleted users can't refresh, even if they are created again
+	tokens = signIn(t, vendor, "alice", "secret2")
+	err = vendor.UsersManager.DeleteUser(ctx, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, refresh(vendor, tokens.RefreshToken))
+	err = vendor.UsersManager.UpsertUser(ctx, "alice", "secret2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, refresh(vendor, tokens.RefreshToken))
+}
+
+func TestAssignedRoles(t *testing.T) {
+	vendor := initVendor(t)
+	ctx := context.Background()
+	err := vendor.UsersManager.UpsertUser(ctx, "carol", "secret", nil)
+	assert.Nil(t, err)
+	err = vendor.UsersManager.SetRoles(ctx, "carol", []string{"operator"})
+	assert.Nil(t, err)
+	tokens := signIn(t, vendor, "carol", "secret")
+	assert.Equal(t, []string{"operator"}, tokens.Roles)
+
+	// the middleware grants the access of the assigned role
+	j := coa_http.JWT{
+		AuthHeader: "Authorization",
+		EnableRBAC: true,
+		Policy: map[string]coa_http.Policy{
+			"operator": {Items: map[string]string{"/v1alpha2/solutions": "*"}},
+		},
+	}
+	request := &fasthttp.RequestCtx{}
+	request.Request.SetRequestURI("/v1alpha2/solutions")
+	request.Request.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	called := false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(request)
+	assert.True(t, called)
+}
+
 func TestUnauthorized(t *testing.T) {
 	authRequest := AuthRequest{
 		UserName: "abc",
@@ -95,3 +271,126 @@ func TestUsersEndpoints(t *testing.T) {
 	assert.NotNil(t, endpoints)
 	assert.Equal(t, "user/auth", endpoints[len(endpoints)-1].Route)
 }
+
+func TestUserManagementEndpoints(t *testing.T) {
+	vendor := initVendor(t)
+	data, _ := json.Marshal(UserRequest{Password: "secret", Roles: []string{"reader"}})
+	response := vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "POST",
+		Body:       data,
+		Parameters: map[string]string{"__name": "alice"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "POST",
+		Body:       []byte("{}"),
+		Parameters: map[string]string{"__name": "bob"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "GET",
+		Parameters: map[string]string{},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var list []users.UserState
+	err := json.Unmarshal(response.Body, &list)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(list))
+	for _, user := range list {
+		assert.Empty(t, user.PasswordHash)
+	}
+
+	data, _ = json.Marshal(RolesRequest{Roles: []string{"administrator"}})
+	response = vendor.onRoles(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "PUT",
+		Body:       data,
+		Parameters: map[string]string{"__name": "alice"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	response = vendor.onRoles(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "PUT",
+		Body:       data,
+		Parameters: map[string]string{"__name": "carol"},
+	})
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "GET",
+		Parameters: map[string]string{"__name": "alice"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var user users.UserState
+	err = json.Unmarshal(response.Body, &user)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, user.Roles)
+
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "DELETE",
+		Parameters: map[string]string{"__name": "alice"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "GET",
+		Parameters: map[string]string{"__name": "alice"},
+	})
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+}
+
+func TestPasswordEndpoints(t *testing.T) {
+	vendor := initVendor(t)
+	ctx := context.WithValue(context.Background(), v1alpha2.AuthenticatedUserKey, "reader")
+	data, _ := json.Marshal(PasswordRequest{OldPassword: testUsersPassword, NewPassword: "secret"})
+	response := vendor.onPassword(v1alpha2.COARequest{
+		Context:    ctx,
+		Method:     "POST",
+		Body:       data,
+		Parameters: map[string]string{"__name": "admin"},
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	response = vendor.onPassword(v1alpha2.COARequest{
+		Context:    ctx,
+		Method:     "POST",
+		Body:       data,
+		Parameters: map[string]string{"__name": "reader"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	// wrong passwords lock the user out until it's unlocked
+	data, _ = json.Marshal(AuthRequest{UserName: "reader"})
+	for i := 0; i < 5; i++ {
+		response = vendor.onAuth(v1alpha2.COARequest{
+			Context: context.Background(),
+			Method:  "POST",
+			Body:    data,
+		})
+		assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	}
+	data, _ = json.Marshal(AuthRequest{UserName: "reader", Password: "secret"})
+	response = vendor.onAuth(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	response = vendor.onUnlock(v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     "POST",
+		Parameters: map[string]string{"__name": "reader"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	response = vendor.onAuth(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  "POST",
+		Body:    data,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+}
@@ -115,4 +115,9 @@ func TestCreateVendor(t *testing.T) {
 	vendor, err = factory.CreateVendor(config)
 	assert.Nil(t, err)
 	assert.NotNil(t, vendor.(*BackgroundJobVendor))
+
+	config.Type = "vendors.authz"
+	vendor, err = factory.CreateVendor(config)
+	assert.Nil(t, err)
+	assert.NotNil(t, vendor.(*AuthzVendor))
 }
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	explainConfigFile    string
+	explainConfigContext string
+	explainContextFile   string
+	explainProperties    []string
+	explainInputs        []string
+	explainComponent     string
+	explainJson          bool
+)
+
+var ExplainCmd = &cobra.Command{
+	Use:   "explain <expression>",
+	Short: "Evaluate a property expression and show how its value is resolved",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c := config.GetMaestroConfig(explainConfigFile)
+		ctx := c.DefaultContext
+		if explainConfigContext != "" {
+			ctx = explainConfigContext
+		}
+		if ctx == "" {
+			ctx = "default"
+		}
+
+		request := model.ExplainRequest{
+			Expression: args[0],
+		}
+		if explainContextFile != "" {
+			data, err := os.ReadFile(explainContextFile)
+			if err != nil {
+				fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+				return
+			}
+			err = yaml.Unmarshal(data, &request.Context)
+			if err != nil {
+				fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+				return
+			}
+		}
+		for _, p := range explainProperties {
+			if request.Context.Properties == nil {
+				request.Context.Properties = make(map[string]string)
+			}
+			key, value, _ := strings.Cut(p, "=")
+			request.Context.Properties[key] = value
+		}
+		for _, i := range explainInputs {
+			if request.Context.Inputs == nil {
+				request.Context.Inputs = make(map[string]interface{})
+			}
+			key, value, _ := strings.Cut(i, "=")
+			request.Context.Inputs[key] = value
+		}
+		if explainComponent != "" {
+			request.Context.Component = explainComponent
+		}
+
+		payload, _ := json.Marshal(request)
+		data, err := utils.Explain(
+			c.Contexts[ctx].Url,
+			c.Contexts[ctx].User,
+			c.Contexts[ctx].Secret,
+			payload)
+		if err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		if explainJson {
+			fmt.Println(string(data))
+			return
+		}
+		var explanation Explanation
+		err = json.Unmarshal(data, &explanation)
+		if err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		outputExplanation(explanation)
+	},
+}
+
+func outputExplanation(explanation Explanation) {
+	fmt.Println()
+	for _, segment := range explanation.Segments {
+		if len(segment.Steps) == 0 && segment.Error == "" {
+			fmt.Printf("  %q (literal)\n", segment.Text)
+			continue
+		}
+		fmt.Printf("  %s%s%s\n", utils.ColorCyan(), segment.Text, utils.ColorReset())
+		for _, step := range segment.Steps {
+			outputExplainStep(step, "    ")
+		}
+		if segment.Error != "" && len(segment.Steps) == 0 {
+			fmt.Printf("    %serror: %s%s\n", utils.ColorRed(), segment.Error, utils.ColorReset())
+		}
+	}
+	fmt.Println()
+	if explanation.Error != "" {
+		fmt.Printf("  %serror: %s%s\n\n", utils.ColorRed(), explanation.Error, utils.ColorReset())
+		return
+	}
+	fmt.Printf("  %svalue: %s%s\n\n", utils.ColorGreen(), formatExplainValue(explanation.Value), utils.ColorReset())
+}
+
+func outputExplainStep(step ExplainStep, indent string) {
+	if step.Error != "" {
+		fmt.Printf("%s%s %s=> error: %s%s\n", indent, step.Expression, utils.ColorRed(), step.Error, utils.ColorReset())
+	} else {
+		line := fmt.Sprintf("%s%s => %s", indent, step.Expression, formatExplainValue(step.Value))
+		if step.Source != nil {
+			line += fmt.Sprintf(" %s(from %s)%s", utils.ColorYellow(), formatConfigSource(step.Source.Provider, step.Source.Object), utils.ColorReset())
+		}
+		fmt.Println(line)
+	}
+	// literals are shown in the expressions that use them
+	for _, s := range step.Steps {
+		if len(s.Steps) > 0 || s.Kind == "function" || s.Error != "" {
+			outputExplainStep(s, indent+"  ")
+		}
+	}
+}
+
+func formatConfigSource(provider string, object string) string {
+	if provider == "" {
+		return object
+	}
+	return provider + ":" + object
+}
+
+func formatExplainValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func init() {
+	ExplainCmd.Flags().StringVarP(&explainConfigFile, "config", "c", "", "Maestro CLI config file")
+	ExplainCmd.Flags().StringVarP(&explainConfigContext, "context", "", "", "Maestro CLI configuration context")
+	ExplainCmd.Flags().StringVarP(&explainContextFile, "file", "f", "", "Evaluation context file (JSON or YAML) with properties, inputs, outputs, component, value and deployment")
+	ExplainCmd.Flags().StringArrayVarP(&explainProperties, "property", "p", nil, "set a property as key=value")
+	ExplainCmd.Flags().StringArrayVarP(&explainInputs, "input", "i", nil, "set a campaign input as key=value")
+	ExplainCmd.Flags().StringVarP(&explainComponent, "component", "", "", "Component used to evaluate $param()")
+	ExplainCmd.Flags().BoolVar(&explainJson, "json", false, "Output the explanation as JSON")
+	RootCmd.AddCommand(ExplainCmd)
+}
+
+type Explanation struct {
+	Expression string           `json:"expression"`
+	Value      interface{}      `json:"value"`
+	Error      string           `json:"error,omitempty"`
+	Segments   []ExplainSegment `json:"segments"`
+}
+type ExplainSegment struct {
+	Text  string        `json:"text"`
+	Value interface{}   `json:"value"`
+	Error string        `json:"error,omitempty"`
+	Steps []ExplainStep `json:"steps,omitempty"`
+}
+type ExplainStep struct {
+	Expression string        `json:"expression"`
+	Kind       string        `json:"kind"`
+	Value      interface{}   `json:"value"`
+	Error      string        `json:"error,omitempty"`
+	Source     *ConfigSource `json:"source,omitempty"`
+	Steps      []ExplainStep `json:"steps,omitempty"`
+}
+type ConfigSource struct {
+	Provider string `json:"provider,omitempty"`
+	Object   string `json:"object"`
+}
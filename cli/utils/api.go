@@ -143,6 +143,14 @@ func Get(url string, username string, password string, objType string, path stri
 	return ret, nil
 }
 
+func Explain(url string, username string, password string, payload []byte) ([]byte, error) {
+	token, err := Login(url, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return callRestAPI(url, "/settings/explain", "POST", payload, token, nil)
+}
+
 func Login(url string, username string, password string) (string, error) {
 	data, _ := json.Marshal(authRequest{
 		UserName: username,
@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package authz
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	// DefaultScope is the scope of resource kinds that don't have scopes, such as campaigns
+	DefaultScope = "default"
+)
+
+// Rule allows or denies verbs on resources. Kinds, scopes, verbs and names are patterns, where "*"
+// matches any value and "prod-*" matches values that start with "prod-". A rule without names
+// matches any resource, and a rule with metadata only matches resources that have these metadata.
+// Rules with names or metadata don't match lists, as they can't be checked on a list.
+type Rule struct {
+	// "allow" or "deny". Defaults to "allow"
+	Effect   string            `json:"effect,omitempty"`
+	Kinds    []string          `json:"kinds"`
+	Scopes   []string          `json:"scopes,omitempty"`
+	Verbs    []string          `json:"verbs"`
+	Names    []string          `json:"names,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// PolicyConfig holds the rules of each role
+type PolicyConfig struct {
+	Roles map[string][]Rule `json:"roles"`
+}
+
+// Request asks if a caller can act on a resource. Name and Metadata are empty for lists.
+type Request struct {
+	User     string            `json:"user,omitempty"`
+	Roles    []string          `json:"roles,omitempty"`
+	Kind     string            `json:"kind"`
+	Scope    string            `json:"scope,omitempty"`
+	Verb     string            `json:"verb"`
+	Name     string            `json:"name,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// Policy decides requests with the rules of the roles of the caller. Deny rules take precedence over
+// allow rules, and requests that no rule allows are denied.
+type Policy struct {
+	roles map[string][]Rule
+	// RoleResolver finds the roles of a user who isn't the caller, for checks on behalf of other users
+	RoleResolver func(user string) []string
+}
+
+func NewPolicy(config PolicyConfig) (*Policy, error) {
+	ret := &Policy{roles: make(map[string][]Rule)}
+	for role, rules := range config.Roles {
+		for i, rule := range rules {
+			if rule.Effect == "" {
+				rule.Effect = EffectAllow
+			}
+			if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+				return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("rule %d of role '%s' has invalid effect '%s'", i, role, rule.Effect), v1alpha2.BadConfig)
+			}
+			if len(rule.Kinds) == 0 || len(rule.Verbs) == 0 {
+				return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("rule %d of role '%s' needs kinds and verbs", i, role), v1alpha2.BadConfig)
+			}
+			for _, patterns := range [][]string{rule.Kinds, rule.Scopes, rule.Verbs, rule.Names} {
+				for _, p := range patterns {
+					if _, err := path.Match(p, ""); err != nil {
+						return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("rule %d of role '%s' has invalid pattern '%s'", i, role, p), v1alpha2.BadConfig)
+					}
+				}
+			}
+			ret.roles[role] = append(ret.roles[role], rule)
+		}
+	}
+	return ret, nil
+}
+
+// Evaluate decides a request
+func (p *Policy) Evaluate(request Request) Decision {
+	if request.Scope == "" {
+		request.Scope = DefaultScope
+	}
+	roles := append([]string{}, request.Roles...)
+	sort.Strings(roles)
+	allowedBy := ""
+	for _, role := range roles {
+		for _, rule := range p.roles[role] {
+			if !rule.matches(request) {
+				continue
+			}
+			if rule.Effect == EffectDeny {
+				return Decision{
+					Allowed: false,
+					Reason:  fmt.Sprintf("%s %s in scope %s is denied to role %s", request.Verb, describe(request), request.Scope, role),
+				}
+			}
+			if allowedBy == "" {
+				allowedBy = role
+			}
+		}
+	}
+	if allowedBy != "" {
+		return Decision{
+			Allowed: true,
+			Reason:  fmt.Sprintf("%s %s in scope %s is allowed to role %s", request.Verb, describe(request), request.Scope, allowedBy),
+		}
+	}
+	return Decision{
+		Allowed: false,
+		Reason:  fmt.Sprintf("no role of %s allows %s %s in scope %s", describeUser(request), request.Verb, describe(request), request.Scope),
+	}
+}
+
+func (r Rule) matches(request Request) bool {
+	if !matchAny(r.Kinds, request.Kind) || !matchAny(r.Verbs, request.Verb) {
+		return false
+	}
+	if len(r.Scopes) > 0 && !matchAny(r.Scopes, request.Scope) {
+		return false
+	}
+	if len(r.Names) > 0 && (request.Name == "" || !matchAny(r.Names, request.Name)) {
+		return false
+	}
+	if len(r.Metadata) > 0 && request.Name == "" {
+		return false
+	}
+	for k, v := range r.Metadata {
+		if mv, ok := request.Metadata[k]; !ok || (v != "*" && mv != v) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func describe(request Request) string {
+	if request.Name == "" {
+		return request.Kind
+	}
+	return fmt.Sprintf("%s %s", request.Kind, request.Name)
+}
+
+func describeUser(request Request) string {
+	if request.User == "" {
+		return "caller"
+	}
+	return fmt.Sprintf("user %s", request.User)
+}
+
+// FromContext returns the policy that the JWT middleware set on a request, or nil if there's none
+func FromContext(ctx context.Context) *Policy {
+	if ctx == nil {
+		return nil
+	}
+	policy, _ := ctx.Value(v1alpha2.AuthorizationPolicyKey).(*Policy)
+	return policy
+}
+
+// Authorize decides a request of the caller with the policy of the context. Requests are allowed when
+// the context has no policy, which is the case when authorization isn't configured.
+func Authorize(ctx context.Context, request Request) error {
+	policy := FromContext(ctx)
+	if policy == nil {
+		return nil
+	}
+	request.User, request.Roles = v1alpha2.GetAuthenticatedUser(ctx)
+	decision := policy.Evaluate(request)
+	if !decision.Allowed {
+		return v1alpha2.NewCOAError(nil, decision.Reason, v1alpha2.Unauthorized)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package authz
+
+import (
+	"context"
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPolicy(t *testing.T) *Policy {
+	policy, err := NewPolicy(PolicyConfig{
+		Roles: map[string][]Rule{
+			"reader": {
+				{Kinds: []string{"*"}, Verbs: []string{VerbGet, VerbList}},
+			},
+			"prod-operator": {
+				{Kinds: []string{"instances"}, Scopes: []string{"prod-*"}, Verbs: []string{"*"}},
+				{Effect: EffectDeny, Kinds: []string{"instances"}, Verbs: []string{VerbDelete}, Names: []string{"core-*"}},
+				{Effect: EffectDeny, Kinds: []string{"instances"}, Verbs: []string{VerbUpdate}, Metadata: map[string]string{"frozen": "*"}},
+			},
+			"auditor": {
+				{Effect: EffectDeny, Kinds: []string{"catalogs"}, Verbs: []string{"*"}},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	return policy
+}
+
+func TestEvaluate(t *testing.T) {
+	policy := newTestPolicy(t)
+	tests := []struct {
+		request Request
+		allowed bool
+	}{
+		{Request{Roles: []string{"reader"}, Kind: "solutions", Verb: VerbList}, true},
+		{Request{Roles: []string{"reader"}, Kind: "solutions", Verb: VerbCreate, Name: "s1"}, false},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod-west", Verb: VerbCreate, Name: "i1"}, true},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "dev", Verb: VerbCreate, Name: "i1"}, false},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Verb: VerbCreate, Name: "i1"}, false},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod-west", Verb: VerbDelete, Name: "core-dns"}, false},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod-west", Verb: VerbDelete, Name: "web"}, true},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod-west", Verb: VerbUpdate, Name: "web", Metadata: map[string]string{"frozen": "true"}}, false},
+		{Request{Roles: []string{"prod-operator"}, Kind: "instances", Scope: "prod-west", Verb: VerbUpdate, Name: "web", Metadata: map[string]string{"owner": "ops"}}, true},
+		// deny rules of a role take precedence over allow rules of other roles
+		{Request{Roles: []string{"reader", "auditor"}, Kind: "catalogs", Verb: VerbGet, Name: "config"}, false},
+		{Request{Roles: []string{"reader", "auditor"}, Kind: "targets", Verb: VerbGet, Name: "t1"}, true},
+		{Request{Kind: "targets", Verb: VerbGet, Name: "t1"}, false},
+	}
+	for i, test := range tests {
+		decision := policy.Evaluate(test.request)
+		assert.Equal(t, test.allowed, decision.Allowed, "request %d: %s", i, decision.Reason)
+		assert.NotEmpty(t, decision.Reason)
+	}
+}
+
+func TestNewPolicyBadConfig(t *testing.T) {
+	for _, rule := range []Rule{
+		{Effect: "maybe", Kinds: []string{"*"}, Verbs: []string{"*"}},
+		{Verbs: []string{"*"}},
+		{Kinds: []string{"*"}},
+		{Kinds: []string{"[solutions"}, Verbs: []string{"*"}},
+	} {
+		_, err := NewPolicy(PolicyConfig{Roles: map[string][]Rule{"role": {rule}}})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	request := Request{Kind: "solutions", Verb: VerbCreate, Name: "s1"}
+	// requests are allowed when authorization isn't configured
+	assert.Nil(t, Authorize(context.Background(), request))
+
+	ctx := context.WithValue(context.Background(), v1alpha2.AuthorizationPolicyKey, newTestPolicy(t))
+	ctx = context.WithValue(ctx, v1alpha2.AuthenticatedUserKey, "alice")
+	ctx = context.WithValue(ctx, v1alpha2.AuthenticatedRolesKey, []string{"reader"})
+	err := Authorize(ctx, request)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	assert.Contains(t, err.Error(), "user alice")
+
+	// roles of the request are replaced by the roles of the caller
+	request.Roles = []string{"prod-operator"}
+	assert.NotNil(t, Authorize(ctx, request))
+	request.Verb = VerbGet
+	assert.Nil(t, Authorize(ctx, request))
+}
@@ -7,8 +7,11 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -17,6 +20,8 @@ import (
 	autogen "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
 	localfile "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	routing "github.com/fasthttp/router"
 	"github.com/valyala/fasthttp"
 )
@@ -38,6 +43,24 @@ type HttpBindingConfig struct {
 	Pipeline     []MiddlewareConfig `json:"pipeline"`
 	TLS          bool               `json:"tls"`
 	CertProvider CertProviderConfig `json:"certProvider"`
+	TokenIssuer  *TokenIssuerConfig `json:"tokenIssuer,omitempty"`
+	ClientAuth   *ClientAuthConfig  `json:"clientAuth,omitempty"`
+}
+
+const (
+	// ClientAuthRequire rejects connections without a valid client certificate
+	ClientAuthRequire = "require"
+	// ClientAuthVerifyIfGiven accepts connections without a client certificate, so that callers can
+	// authenticate with tokens instead, but rejects invalid certificates
+	ClientAuthVerifyIfGiven = "verifyIfGiven"
+)
+
+// ClientAuthConfig configures mutual TLS. Client certificates are verified against the CA certificate
+// of the CA provider.
+type ClientAuthConfig struct {
+	// "require" or "verifyIfGiven". Defaults to "require"
+	Mode       string             `json:"mode,omitempty"`
+	CAProvider CertProviderConfig `json:"caProvider"`
 }
 
 // HttpBinding provides service endpoints as a fasthttp web server
@@ -47,7 +70,11 @@ type HttpBinding struct {
 
 // Launch fasthttp server
 func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endpoint, pubsubProvider pubsub.IPubSubProvider) error {
-	handler := h.useRouter(endpoints)
+	issuer, err := h.useTokenIssuer(config.TokenIssuer)
+	if err != nil {
+		return err
+	}
+	handler := h.useRouter(endpoints, issuer.Name)
 
 	pipeline, err := BuildPipeline(config, pubsubProvider)
 	if err != nil {
@@ -55,18 +82,30 @@ func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endp
 	}
 
 	if config.TLS {
-		switch config.CertProvider.Type {
-		case "certs.autogen":
-			h.CertProvider = &autogen.AutoGenCertProvider{}
-		case "certs.localfile":
-			h.CertProvider = &localfile.LocalCertFileProvider{}
-		default:
-			return v1alpha2.NewCOAError(nil, fmt.Sprintf("cert provider type '%s' is not recognized", config.CertProvider.Type), v1alpha2.BadConfig)
+		h.CertProvider, err = createCertProvider(config.CertProvider)
+		if err != nil {
+			return err
 		}
-		err = h.CertProvider.Init(config.CertProvider.Config)
+	}
+
+	if config.ClientAuth != nil {
+		if !config.TLS {
+			return v1alpha2.NewCOAError(nil, "client authentication requires tls", v1alpha2.BadConfig)
+		}
+		cert, key, err := h.CertProvider.GetCert("localhost") //TODO: user proper host/DNS name
 		if err != nil {
 			return err
 		}
+		tlsConfig, err := clientAuthTLSConfig(cert, key, *config.ClientAuth)
+		if err != nil {
+			return err
+		}
+		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
+		if err != nil {
+			return err
+		}
+		go fasthttp.Serve(tls.NewListener(ln, tlsConfig), withClientCertificate(pipeline.Apply(handler)))
+		return nil
 	}
 
 	go func() {
@@ -80,8 +119,111 @@ func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endp
 	return nil
 }
 
-func (h *HttpBinding) useRouter(endpoints []v1alpha2.Endpoint) fasthttp.RequestHandler {
+// clientAuthTLSConfig creates the TLS configuration of a server that verifies client certificates
+func clientAuthTLSConfig(cert []byte, key []byte, config ClientAuthConfig) (*tls.Config, error) {
+	ret := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch config.Mode {
+	case "", ClientAuthRequire:
+		ret.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthVerifyIfGiven:
+		ret.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("client authentication mode '%s' is not recognized", config.Mode), v1alpha2.BadConfig)
+	}
+	serverCert, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to load the server certificate", v1alpha2.BadConfig)
+	}
+	ret.Certificates = []tls.Certificate{serverCert}
+	caProvider, err := createCertProvider(config.CAProvider)
+	if err != nil {
+		return nil, err
+	}
+	caCert, _, err := caProvider.GetCert("")
+	if err != nil {
+		return nil, err
+	}
+	ret.ClientCAs = x509.NewCertPool()
+	if !ret.ClientCAs.AppendCertsFromPEM(caCert) {
+		return nil, v1alpha2.NewCOAError(nil, "ca provider has no valid ca certificate", v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+// withClientCertificate sets the verified client certificate of a request on the request, where
+// middlewares and vendors can find it
+func withClientCertificate(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if state := ctx.TLSConnectionState(); state != nil && len(state.VerifiedChains) > 0 {
+			ctx.SetUserValue(v1alpha2.ClientCertificateKey, state.VerifiedChains[0][0])
+		}
+		next(ctx)
+	}
+}
+
+func createCertProvider(config CertProviderConfig) (certs.ICertProvider, error) {
+	var provider certs.ICertProvider
+	switch config.Type {
+	case "certs.autogen":
+		provider = &autogen.AutoGenCertProvider{}
+	case "certs.localfile":
+		provider = &localfile.LocalCertFileProvider{}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("cert provider type '%s' is not recognized", config.Type), v1alpha2.BadConfig)
+	}
+	err := provider.Init(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func createSecretProvider(config SecretProviderConfig) (secret.ISecretProvider, error) {
+	var provider secret.ISecretProvider
+	switch config.Type {
+	case "secret.mock":
+		provider = &mocksecret.MockSecretProvider{}
+	default:
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret provider type '%s' is not recognized", config.Type), v1alpha2.BadConfig)
+	}
+	err := provider.Init(config.Config)
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// useTokenIssuer creates and registers the configured token issuer, or falls back to the default issuer
+func (h *HttpBinding) useTokenIssuer(config *TokenIssuerConfig) (*TokenIssuer, error) {
+	if config == nil {
+		return GetTokenIssuer(DefaultTokenIssuer)
+	}
+	var err error
+	var secretProvider secret.ISecretProvider
+	var certProvider certs.ICertProvider
+	if config.SecretProvider.Type != "" {
+		secretProvider, err = createSecretProvider(config.SecretProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.CertProvider.Type != "" {
+		certProvider, err = createCertProvider(config.CertProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+	issuer, err := NewTokenIssuer(*config, secretProvider, certProvider)
+	if err != nil {
+		return nil, err
+	}
+	RegisterTokenIssuer(issuer)
+	return issuer, nil
+}
+
+func (h *HttpBinding) useRouter(endpoints []v1alpha2.Endpoint, issuer string) fasthttp.RequestHandler {
 	router := h.getRouter(endpoints)
+	router.GET(JWKSPath, onJWKS(issuer))
 	return router.Handler
 }
 func (h *HttpBinding) getRouter(endpoints []v1alpha2.Endpoint) *routing.Router {
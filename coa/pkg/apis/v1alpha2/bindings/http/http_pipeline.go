@@ -51,6 +51,14 @@ func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvid
 			if jwts.AuthHeader == "" {
 				jwts.AuthHeader = "Authorization"
 			}
+			err = jwts.initOIDC()
+			if err != nil {
+				return ret, err
+			}
+			err = jwts.initAuthorization()
+			if err != nil {
+				return ret, err
+			}
 			ret.Handlers = append(ret.Handlers, jwts.JWT)
 		case "middleware.http.tracing":
 			tracing := Tracing{
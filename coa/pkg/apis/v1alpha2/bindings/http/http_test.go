@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "symphony-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a subject
+func (c *testCA) issue(t *testing.T, subject pkix.Name, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func (c *testCA) provider(t *testing.T) CertProviderConfig {
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	assert.Nil(t, os.WriteFile(file, c.pem, 0600))
+	return CertProviderConfig{Type: "certs.localfile", Config: map[string]interface{}{"name": "ca", "cert": file}}
+}
+
+// serveClientAuth serves a handler behind mutual TLS and returns its URL
+func serveClientAuth(t *testing.T, ca *testCA, mode string, handler fasthttp.RequestHandler) string {
+	cert, key := ca.issue(t, pkix.Name{CommonName: "localhost"}, x509.ExtKeyUsageServerAuth)
+	tlsConfig, err := clientAuthTLSConfig(cert, key, ClientAuthConfig{Mode: mode, CAProvider: ca.provider(t)})
+	assert.Nil(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &fasthttp.Server{Handler: withClientCertificate(handler)}
+	go server.Serve(tls.NewListener(ln, tlsConfig))
+	t.Cleanup(func() { server.Shutdown() })
+	return "https://" + ln.Addr().String() + "/v1alpha2/federation/sync/tokyo"
+}
+
+func clientWithCert(t *testing.T, ca *testCA, cert []byte, key []byte) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		assert.Nil(t, err)
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func get(client *http.Client, url string) (int, string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), nil
+}
+
+func TestClientAuth(t *testing.T) {
+	ca := newTestCA(t)
+	j := JWT{
+		AuthHeader:         "Authorization",
+		ClientCertificates: true,
+		Roles:              []ClaimRoleMap{{Role: "site", Claim: "ou", Value: "sites"}},
+	}
+	url := serveClientAuth(t, ca, "", j.JWT(func(ctx *fasthttp.RequestCtx) {
+		user, roles := v1alpha2.GetAuthenticatedUser(ctx)
+		ctx.SetBodyString(user + ":" + roles[0] + ":" + v1alpha2.GetClientCertificate(ctx).Subject.CommonName)
+	}))
+
+	cert, key := ca.issue(t, pkix.Name{CommonName: "tokyo", OrganizationalUnit: []string{"sites"}}, x509.ExtKeyUsageClientAuth)
+	status, body, err := get(clientWithCert(t, ca, cert, key), url)
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusOK, status)
+	assert.Equal(t, "tokyo:site:tokyo", body)
+
+	// connections without a certificate, or with a certificate of another CA, are rejected
+	_, _, err = get(clientWithCert(t, ca, nil, nil), url)
+	assert.NotNil(t, err)
+	cert, key = newTestCA(t).issue(t, pkix.Name{CommonName: "tokyo"}, x509.ExtKeyUsageClientAuth)
+	_, _, err = get(clientWithCert(t, ca, cert, key), url)
+	assert.NotNil(t, err)
+}
+
+func TestClientAuthClaims(t *testing.T) {
+	ca := newTestCA(t)
+	j := JWT{
+		AuthHeader:         "Authorization",
+		ClientCertificates: true,
+		MustMatch:          map[string]interface{}{"cn": "admin"},
+		Roles: []ClaimRoleMap{
+			{Role: "administrator", Claim: "user", Value: "admin"},
+			{Role: "site", Claim: "cn", Value: "admin"},
+		},
+	}
+	url := serveClientAuth(t, ca, "", j.JWT(func(ctx *fasthttp.RequestCtx) {
+		user, roles := v1alpha2.GetAuthenticatedUser(ctx)
+		ctx.SetBodyString(user + ":" + strings.Join(roles, ","))
+	}))
+
+	// the role mappings of user names don't apply to certificates
+	cert, key := ca.issue(t, pkix.Name{CommonName: "admin"}, x509.ExtKeyUsageClientAuth)
+	status, body, err := get(clientWithCert(t, ca, cert, key), url)
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusOK, status)
+	assert.Equal(t, "admin:site", body)
+
+	// certificates must have the claims that tokens must have
+	cert, key = ca.issue(t, pkix.Name{CommonName: "tokyo"}, x509.ExtKeyUsageClientAuth)
+	status, _, err = get(clientWithCert(t, ca, cert, key), url)
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusForbidden, status)
+}
+
+func TestClientAuthVerifyIfGiven(t *testing.T) {
+	ca := newTestCA(t)
+	j := JWT{AuthHeader: "Authorization", VerifyKey: "test-key"}
+	url := serveClientAuth(t, ca, ClientAuthVerifyIfGiven, j.JWT(func(ctx *fasthttp.RequestCtx) {}))
+
+	// callers without a certificate need a token, and certificates are only accepted when enabled
+	status, _, err := get(clientWithCert(t, ca, nil, nil), url)
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusForbidden, status)
+	cert, key := ca.issue(t, pkix.Name{CommonName: "tokyo"}, x509.ExtKeyUsageClientAuth)
+	status, _, err = get(clientWithCert(t, ca, cert, key), url)
+	assert.Nil(t, err)
+	assert.Equal(t, fasthttp.StatusForbidden, status)
+}
+
+func TestClientAuthBadConfig(t *testing.T) {
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, pkix.Name{CommonName: "localhost"}, x509.ExtKeyUsageServerAuth)
+	_, err := clientAuthTLSConfig(cert, key, ClientAuthConfig{Mode: "sometimes", CAProvider: ca.provider(t)})
+	assert.NotNil(t, err)
+	_, err = clientAuthTLSConfig(cert, key, ClientAuthConfig{})
+	assert.NotNil(t, err)
+	// the ca provider must have a certificate
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	assert.Nil(t, os.WriteFile(file, []byte("not a certificate"), 0600))
+	_, err = clientAuthTLSConfig(cert, key, ClientAuthConfig{CAProvider: CertProviderConfig{Type: "certs.localfile", Config: map[string]interface{}{"cert": file}}})
+	assert.NotNil(t, err)
+
+	err = (&HttpBinding{}).Launch(HttpBindingConfig{Port: 0, ClientAuth: &ClientAuthConfig{CAProvider: ca.provider(t)}}, nil, nil)
+	assert.NotNil(t, err)
+}
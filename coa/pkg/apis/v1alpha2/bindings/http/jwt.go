@@ -8,11 +8,13 @@ package http
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"strings"
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/valyala/fasthttp"
 )
@@ -27,11 +29,29 @@ type JWT struct {
 	Roles       []ClaimRoleMap    `json:"roles,omitempty"`
 	EnableRBAC  bool              `json:"enableRBAC,omitempty"`
 	Policy      map[string]Policy `json:"policy,omitempty"`
+	// Claim that carries the user name. Defaults to "user"
+	UserClaim string `json:"userClaim,omitempty"`
+	// Name of the token issuer whose tokens are accepted. Defaults to the default issuer
+	TokenIssuer string `json:"tokenIssuer,omitempty"`
+	// OIDC issuers whose tokens are accepted
+	OIDC        []OIDCIssuerConfig `json:"oidc,omitempty"`
+	oidcIssuers []*oidcIssuer
+	// Authorization policy that vendors evaluate against the resources of requests
+	Authorization *authz.PolicyConfig `json:"authorization,omitempty"`
+	policy        *authz.Policy
+	// Accept the verified client certificates of the HTTP binding in place of tokens
+	ClientCertificates bool `json:"clientCertificates,omitempty"`
 }
+
+// ClaimRoleMap assigns a role to the callers whose tokens have a claim with a given value, or any value
+// with "*". A claim with a list of values, such as the groups of an OIDC issuer, matches if one of its
+// values matches. Issuer restricts the mapping to the tokens of an OIDC issuer. Mappings without an issuer
+// only apply to the tokens of the local issuer or verification key, and to client certificates.
 type ClaimRoleMap struct {
-	Role  string `json:"role"`
-	Claim string `json:"claim"`
-	Value string `json:"value"`
+	Role   string `json:"role"`
+	Claim  string `json:"claim"`
+	Value  string `json:"value"`
+	Issuer string `json:"issuer,omitempty"`
 }
 type Policy struct {
 	Items map[string]string `json:"items"`
@@ -39,6 +59,10 @@ type Policy struct {
 
 func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == JWKSPath {
+			next(ctx)
+			return
+		}
 		if j.IgnorePaths != nil {
 			for _, p := range j.IgnorePaths {
 				if p == string(ctx.Path()) {
@@ -52,13 +76,29 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 			return
 		}
 		tokenStr := j.readAuthHeader(ctx)
-		if tokenStr == "" {
+		cert := v1alpha2.GetClientCertificate(ctx)
+		if tokenStr == "" && (cert == nil || !j.ClientCertificates) {
 			ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 		} else {
-			_, roles, err := j.validateToken(tokenStr)
+			var user string
+			var roles []string
+			var err error
+			if tokenStr == "" {
+				user = cert.Subject.CommonName
+				roles, err = j.validateCertificate(cert)
+			} else {
+				var claims map[string]interface{}
+				claims, roles, err = j.validateToken(tokenStr)
+				user = j.userName(claims)
+			}
 			if err != nil {
 				ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 			} else {
+				ctx.SetUserValue(v1alpha2.AuthenticatedUserKey, user)
+				ctx.SetUserValue(v1alpha2.AuthenticatedRolesKey, roles)
+				if j.policy != nil {
+					ctx.SetUserValue(v1alpha2.AuthorizationPolicyKey, j.policy)
+				}
 				if j.EnableRBAC {
 					path := string(ctx.Path())
 					method := string(ctx.Method())
@@ -82,6 +122,86 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		}
 	}
 }
+
+// initOIDC creates the OIDC issuers of the middleware. Their keys are fetched when the first token
+// of each issuer is validated.
+func (j *JWT) initOIDC() error {
+	j.oidcIssuers = make([]*oidcIssuer, 0, len(j.OIDC))
+	for _, c := range j.OIDC {
+		issuer, err := newOIDCIssuer(c)
+		if err != nil {
+			return err
+		}
+		j.oidcIssuers = append(j.oidcIssuers, issuer)
+	}
+	return nil
+}
+
+// initAuthorization creates the authorization policy of the middleware. The roles of other users are
+// resolved with the role mappings of the user claim.
+func (j *JWT) initAuthorization() error {
+	if j.Authorization == nil {
+		return nil
+	}
+	policy, err := authz.NewPolicy(*j.Authorization)
+	if err != nil {
+		return err
+	}
+	policy.RoleResolver = func(user string) []string {
+		claim := j.UserClaim
+		if claim == "" {
+			claim = UserClaim
+		}
+		return j.mapRoles(map[string]interface{}{claim: user}, nil)
+	}
+	j.policy = policy
+	return nil
+}
+func (j JWT) oidcIssuer(claims map[string]interface{}) *oidcIssuer {
+	iss, _ := claims["iss"].(string)
+	for _, o := range j.oidcIssuers {
+		if o.config.Issuer == iss {
+			return o
+		}
+	}
+	return nil
+}
+func (j JWT) userName(claims map[string]interface{}) string {
+	claim := j.UserClaim
+	if claim == "" {
+		claim = "user"
+	}
+	if o := j.oidcIssuer(claims); o != nil {
+		claim = o.config.UserClaim
+	}
+	if v, ok := claims[claim].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// validateCertificate maps the subject of a verified client certificate to the "cn", "o" and "ou" claims,
+// which are checked and mapped to roles like the claims of tokens. The user claim isn't set, so that the
+// role mappings of user names don't apply to certificates that happen to have the same common name.
+func (j JWT) validateCertificate(cert *x509.Certificate) ([]string, error) {
+	claims := map[string]interface{}{
+		"cn": cert.Subject.CommonName,
+		"o":  toInterfaces(cert.Subject.Organization),
+		"ou": toInterfaces(cert.Subject.OrganizationalUnit),
+	}
+	if err := j.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return j.mapRoles(claims, nil), nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	ret := make([]interface{}, len(values))
+	for i, v := range values {
+		ret[i] = v
+	}
+	return ret
+}
 func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 	v := ctx.Request.Header.Peek(j.AuthHeader)
 	if v != nil {
@@ -97,6 +217,109 @@ func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 }
 func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string, error) {
 	ret := make(map[string]interface{})
+	issuer, err := GetTokenIssuer(j.TokenIssuer)
+	if err != nil {
+		return ret, nil, err
+	}
+	// tokens signed by a key of the issuer are verified by the issuer, tokens of OIDC issuers by the keys
+	// they publish, and other tokens by the verification key
+	kid, unverified := parseUnverified(tokenStr)
+	var claims map[string]interface{}
+	var oidc *oidcIssuer
+	issued := issuer.HasKey(kid)
+	if issued {
+		claims, err = issuer.Validate(tokenStr)
+	} else if oidc = j.oidcIssuer(unverified); oidc != nil {
+		claims, err = oidc.validate(tokenStr)
+	} else {
+		claims, err = j.verifyWithKey(tokenStr)
+	}
+	if err != nil {
+		return ret, nil, err
+	}
+	for k, v := range claims {
+		ret[k] = v
+	}
+	if err = j.checkClaims(ret); err != nil {
+		return ret, nil, err
+	}
+	var roles []string
+	if j.EnableRBAC || len(j.Roles) > 0 || j.policy != nil {
+		roles = j.mapRoles(ret, oidc)
+		// the roles claim is only trusted in tokens that the issuer minted
+		if issued {
+			roles = appendRoles(roles, ret[RolesClaim])
+		}
+	}
+	return ret, roles, nil
+}
+
+// checkClaims checks that the claims have the claims of MustHave, and the values of MustMatch
+func (j *JWT) checkClaims(claims map[string]interface{}) error {
+	if j.MustHave != nil && len(j.MustHave) > 0 {
+		for _, k := range j.MustHave {
+			if _, ok := claims[k]; !ok {
+				return fmt.Errorf("required claim '%s' is not found", k)
+			}
+		}
+	}
+	if j.MustMatch != nil && len(j.MustMatch) > 0 {
+		for k, v := range j.MustMatch {
+			if hv, ok := claims[k]; ok {
+				if hv != v {
+					return fmt.Errorf("claim '%s' doesn't have required value", k)
+				}
+			} else {
+				return fmt.Errorf("required claim '%s' is not found", k)
+			}
+		}
+	}
+	return nil
+}
+
+// appendRoles appends the roles of a claim with a list of roles, skipping the roles already listed
+func appendRoles(roles []string, claim interface{}) []string {
+	values, ok := claim.([]interface{})
+	if !ok {
+		return roles
+	}
+	for _, v := range values {
+		role, ok := v.(string)
+		if !ok || role == "" || hasRole(roles, role) {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// mapRoles maps claims to roles. The claims of an OIDC issuer only pick up the mappings of the issuer,
+// the other claims only the mappings without an issuer.
+func (j *JWT) mapRoles(claims map[string]interface{}, oidc *oidcIssuer) []string {
+	roles := make([]string, 0)
+	for _, m := range j.Roles {
+		if oidc == nil && m.Issuer != "" || oidc != nil && m.Issuer != oidc.config.Issuer {
+			continue
+		}
+		if v, ok := claims[m.Claim]; ok && claimMatches(v, m.Value) {
+			roles = append(roles, m.Role)
+		}
+	}
+	return roles
+}
+func (j *JWT) verifyWithKey(tokenStr string) (map[string]interface{}, error) {
+	if j.VerifyKey == "" {
+		return nil, errors.New("token isn't signed by a known key")
+	}
 	claims := jwt.MapClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenStr,
@@ -108,7 +331,7 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 				if strings.HasPrefix(j.VerifyKey, "-----BEGIN PUBLIC KEY-----") {
 					verifyKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(j.VerifyKey))
 					if err != nil {
-						return ret, v1alpha2.NewCOAError(nil, "failed to parse public key", v1alpha2.BadConfig)
+						return nil, v1alpha2.NewCOAError(nil, "failed to parse public key", v1alpha2.BadConfig)
 					}
 					j.verifyKey = verifyKey
 					return j.verifyKey, nil
@@ -119,43 +342,42 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 		},
 	)
 	if err != nil {
-		return ret, nil, err
+		return nil, err
 	}
 	if !token.Valid {
-		return ret, nil, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
-	for k, v := range claims {
-		ret[k] = v
-	}
-	if j.MustHave != nil && len(j.MustHave) > 0 {
-		for _, k := range j.MustHave {
-			if _, ok := ret[k]; !ok {
-				return ret, nil, fmt.Errorf("required claim '%s' is not found", k)
-			}
-		}
+	return claims, nil
+}
+
+func claimMatches(claim interface{}, value string) bool {
+	if value == "*" {
+		return true
 	}
-	if j.MustMatch != nil && len(j.MustMatch) > 0 {
-		for k, v := range j.MustMatch {
-			if hv, ok := ret[k]; ok {
-				if hv != v {
-					return ret, nil, fmt.Errorf("claim '%s' doesn't have required value", k)
-				}
-			} else {
-				return ret, nil, fmt.Errorf("required claim '%s' is not found", k)
+	if values, ok := claim.([]interface{}); ok {
+		for _, v := range values {
+			if v == value {
+				return true
 			}
 		}
+		return false
 	}
-	var roles []string
-	if j.EnableRBAC {
-		roles = make([]string, 0)
-		for _, m := range j.Roles {
-			if v, ok := ret[m.Claim]; ok {
-				if m.Value == "*" || v == m.Value {
-					roles = append(roles, m.Role)
-				}
-			}
-		}
+	return claim == value
+}
+
+// tokenKeyID reads the key ID from the header of a token, without verifying the token
+func tokenKeyID(tokenStr string) string {
+	kid, _ := parseUnverified(tokenStr)
+	return kid
+}
 
+// parseUnverified reads the key ID and the claims of a token, without verifying the token
+func parseUnverified(tokenStr string) (string, map[string]interface{}) {
+	claims := jwt.MapClaims{}
+	token, _, err := jwt.NewParser().ParseUnverified(tokenStr, claims)
+	if err != nil {
+		return "", nil
 	}
-	return ret, roles, nil
+	kid, _ := token.Header["kid"].(string)
+	return kid, claims
 }
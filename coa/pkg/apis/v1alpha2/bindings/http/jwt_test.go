@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/authz"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestJWTAuthorizationPolicy(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "test-key",
+		Roles: []ClaimRoleMap{
+			{Role: "reader", Claim: "user", Value: "*"},
+			{Role: "prod-operator", Claim: "user", Value: "bob"},
+		},
+		Authorization: &authz.PolicyConfig{Roles: map[string][]authz.Rule{
+			"reader":        {{Kinds: []string{"*"}, Verbs: []string{authz.VerbGet, authz.VerbList}}},
+			"prod-operator": {{Kinds: []string{"instances"}, Scopes: []string{"prod"}, Verbs: []string{"*"}}},
+		}},
+	}
+	assert.Nil(t, j.initAuthorization())
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "alice"}).SignedString([]byte("test-key"))
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/v1alpha2/instances/web")
+	ctx.Request.Header.Set("Authorization", "Bearer "+token)
+	called := false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(ctx)
+	assert.True(t, called)
+	policy, ok := ctx.UserValue(v1alpha2.AuthorizationPolicyKey).(*authz.Policy)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", ctx.UserValue(v1alpha2.AuthenticatedUserKey))
+
+	// the roles of other users are the roles of their tokens
+	assert.Equal(t, []string{"reader", "prod-operator"}, policy.RoleResolver("bob"))
+	decision := policy.Evaluate(authz.Request{Roles: policy.RoleResolver("bob"), Kind: "instances", Scope: "prod", Verb: authz.VerbCreate, Name: "web"})
+	assert.True(t, decision.Allowed)
+}
+
+func TestJWTAuthorizationBadConfig(t *testing.T) {
+	_, err := BuildPipeline(HttpBindingConfig{Pipeline: []MiddlewareConfig{{
+		Type: "middleware.http.jwt",
+		Properties: map[string]interface{}{
+			"authorization": map[string]interface{}{
+				"roles": map[string]interface{}{
+					"reader": []map[string]interface{}{{"effect": "maybe", "kinds": []string{"*"}, "verbs": []string{"get"}}},
+				},
+			},
+		},
+	}}}, nil)
+	assert.NotNil(t, err)
+}
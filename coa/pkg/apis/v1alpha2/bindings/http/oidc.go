@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// OIDCDiscoveryPath is appended to the issuer URL to find its discovery document
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+	// DefaultOIDCUserClaim carries the user name in tokens of OIDC issuers
+	DefaultOIDCUserClaim = "sub"
+)
+
+// An unknown key ID may be a key that the issuer just rotated in, so it triggers a refresh of the keys.
+// Refreshes are throttled so that tokens with bogus key IDs don't flood the issuer.
+var oidcRefreshInterval = 30 * time.Second
+
+// Only asymmetric algorithms are accepted, as the keys of the issuer are public
+var oidcAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDCIssuerConfig configures an OpenID Connect issuer whose tokens the JWT middleware accepts
+type OIDCIssuerConfig struct {
+	// Issuer URL, which must match the iss claim of tokens and the issuer of the discovery document
+	Issuer string `json:"issuer"`
+	// Tokens must carry one of these values in their aud claim
+	Audience []string `json:"audience"`
+	// URL of the discovery document. Defaults to the issuer URL followed by /.well-known/openid-configuration
+	DiscoveryURL string `json:"discoveryUrl,omitempty"`
+	// Claim that carries the user name, such as "preferred_username" or "email". Defaults to "sub"
+	UserClaim string `json:"userClaim,omitempty"`
+	// How long the keys of the issuer are cached, as a duration string. Defaults to 1 hour
+	CacheDuration string `json:"cacheDuration,omitempty"`
+}
+
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// oidcIssuer verifies the tokens of an OIDC issuer with the keys it publishes
+type oidcIssuer struct {
+	config        OIDCIssuerConfig
+	cacheDuration time.Duration
+	client        *http.Client
+	lock          sync.Mutex
+	keys          map[string]interface{}
+	fetchedAt     time.Time
+	triedAt       time.Time
+	// fetching is closed when the keys being fetched are in, fetchErr tells if the fetch failed
+	fetching chan struct{}
+	fetchErr error
+}
+
+func newOIDCIssuer(config OIDCIssuerConfig) (*oidcIssuer, error) {
+	if config.Issuer == "" {
+		return nil, v1alpha2.NewCOAError(nil, "oidc issuer is required", v1alpha2.BadConfig)
+	}
+	if len(config.Audience) == 0 {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("audience of oidc issuer '%s' is required", config.Issuer), v1alpha2.BadConfig)
+	}
+	if config.DiscoveryURL == "" {
+		config.DiscoveryURL = strings.TrimSuffix(config.Issuer, "/") + OIDCDiscoveryPath
+	}
+	if config.UserClaim == "" {
+		config.UserClaim = DefaultOIDCUserClaim
+	}
+	ret := &oidcIssuer{
+		config:        config,
+		cacheDuration: time.Hour,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		keys:          make(map[string]interface{}),
+	}
+	if config.CacheDuration != "" {
+		duration, err := time.ParseDuration(config.CacheDuration)
+		if err != nil || duration <= 0 {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid cache duration '%s' of oidc issuer '%s'", config.CacheDuration, config.Issuer), v1alpha2.BadConfig)
+		}
+		ret.cacheDuration = duration
+	}
+	return ret, nil
+}
+
+func (o *oidcIssuer) validate(tokenStr string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.key(kid)
+	}, jwt.WithValidMethods(oidcAlgorithms))
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "invalid token", v1alpha2.Unauthorized)
+	}
+	if !claims.VerifyIssuer(o.config.Issuer, true) {
+		return nil, v1alpha2.NewCOAError(nil, "token has an unexpected issuer", v1alpha2.Unauthorized)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, v1alpha2.NewCOAError(nil, "token doesn't expire", v1alpha2.Unauthorized)
+	}
+	audience := false
+	for _, a := range o.config.Audience {
+		if claims.VerifyAudience(a, true) {
+			audience = true
+			break
+		}
+	}
+	if !audience {
+		return nil, v1alpha2.NewCOAError(nil, "token has an unexpected audience", v1alpha2.Unauthorized)
+	}
+	return claims, nil
+}
+
+// key returns a verification key of the issuer. Keys are fetched again when the cache expires or when the
+// key ID is unknown. Cached keys are kept if the issuer can't be reached. The keys are fetched by one
+// caller at a time without holding the lock, so that validations with cached keys don't wait for the
+// issuer. Callers that need a key that isn't cached wait for the fetch in flight.
+func (o *oidcIssuer) key(kid string) (interface{}, error) {
+	o.lock.Lock()
+	now := time.Now()
+	key, ok := o.lookup(kid)
+	if ok && now.Sub(o.fetchedAt) <= o.cacheDuration {
+		o.lock.Unlock()
+		return key, nil
+	}
+	done := o.fetching
+	if done == nil && now.Sub(o.triedAt) > oidcRefreshInterval {
+		o.triedAt = now
+		done = make(chan struct{})
+		o.fetching = done
+		o.lock.Unlock()
+
+		keys, err := o.fetchKeys()
+
+		o.lock.Lock()
+		if err == nil {
+			o.keys = keys
+			o.fetchedAt = now
+			key, ok = o.lookup(kid)
+		} else if ok {
+			log.Errorf("failed to refresh keys of oidc issuer '%s', keeping cached keys: %v", o.config.Issuer, err)
+		}
+		o.fetchErr = err
+		o.fetching = nil
+		close(done)
+		o.lock.Unlock()
+		return foundKey(key, ok, kid, err)
+	}
+	o.lock.Unlock()
+	if ok || done == nil {
+		return foundKey(key, ok, kid, nil)
+	}
+	<-done
+	o.lock.Lock()
+	key, ok = o.lookup(kid)
+	err := o.fetchErr
+	o.lock.Unlock()
+	return foundKey(key, ok, kid, err)
+}
+
+// foundKey returns a key that was found, or the error of fetching the keys, or a not found error
+func foundKey(key interface{}, ok bool, kid string, err error) (interface{}, error) {
+	if ok {
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("signing key '%s' is not found", kid)
+}
+
+// lookup finds a cached key. Tokens without a key ID are accepted if the issuer has a single key.
+func (o *oidcIssuer) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+func (o *oidcIssuer) fetchKeys() (map[string]interface{}, error) {
+	var discovery oidcDiscovery
+	if err := o.getJSON(o.config.DiscoveryURL, &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != o.config.Issuer {
+		return nil, fmt.Errorf("discovery document is for issuer '%s', expected '%s'", discovery.Issuer, o.config.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of '%s' has no jwks_uri", o.config.Issuer)
+	}
+	var jwks JSONWebKeySet
+	if err := o.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{})
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// keys of unsupported types are skipped, other keys of the set can still be used
+			log.Infof("skipping key '%s' of oidc issuer '%s': %v", k.Kid, o.config.Issuer, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (o *oidcIssuer) getJSON(url string, v interface{}) error {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get '%s': %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// publicKey decodes a RSA or EC key of a JWKS
+func (k JSONWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve '%s' is not supported", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("point is not on curve '%s'", k.Crv)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("key type '%s' is not supported", k.Kty)
+}
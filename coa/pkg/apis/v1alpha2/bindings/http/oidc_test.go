@@ -0,0 +1,328 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubIssuer is a local OIDC issuer that serves a discovery document and a JWKS
+type stubIssuer struct {
+	server    *httptest.Server
+	lock      sync.Mutex
+	keys      map[string]*rsa.PrivateKey
+	jwksCalls int
+	down      bool
+	// gate holds the JWKS responses until it's closed
+	gate chan struct{}
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	s := &stubIssuer{keys: make(map[string]*rsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc(OIDCDiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:  s.server.URL,
+			JWKSURI: s.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.jwksCalls++
+		gate := s.gate
+		s.lock.Unlock()
+		if gate != nil {
+			<-gate
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		jwks := JSONWebKeySet{Keys: make([]JSONWebKey, 0)}
+		for kid, key := range s.keys {
+			jwks.Keys = append(jwks.Keys, JSONWebKey{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: "RS256",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		// keys that can't be used for signatures are skipped
+		jwks.Keys = append(jwks.Keys, JSONWebKey{Kty: "oct", Kid: "shared"}, JSONWebKey{Kty: "RSA", Use: "enc", Kid: "encryption"})
+		json.NewEncoder(w).Encode(jwks)
+	})
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	s.addKey(t, "key1")
+	return s
+}
+
+func (s *stubIssuer) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys[kid] = key
+}
+
+func (s *stubIssuer) calls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.jwksCalls
+}
+
+func (s *stubIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	base := jwt.MapClaims{
+		"iss":                s.server.URL,
+		"aud":                "api://symphony",
+		"sub":                "0b1f9a7c",
+		"preferred_username": "alice@contoso.com",
+		"groups":             []string{"operators", "symphony-admins"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(base, k)
+		} else {
+			base[k] = v
+		}
+	}
+	s.lock.Lock()
+	key := s.keys[kid]
+	s.lock.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = kid
+	tokenStr, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return tokenStr
+}
+
+func newOIDCJWT(t *testing.T, s *stubIssuer) JWT {
+	j := JWT{
+		OIDC: []OIDCIssuerConfig{{
+			Issuer:    s.server.URL,
+			Audience:  []string{"api://symphony"},
+			UserClaim: "preferred_username",
+		}},
+		Roles: []ClaimRoleMap{
+			{Role: "administrator", Claim: "groups", Value: "symphony-admins", Issuer: s.server.URL},
+			{Role: "administrator", Claim: "groups", Value: "symphony-admins", Issuer: "https://elsewhere.example.com"},
+			{Role: "reader", Claim: "sub", Value: "*", Issuer: s.server.URL},
+			{Role: "administrator", Claim: "user", Value: "admin"},
+		},
+	}
+	assert.Nil(t, j.initOIDC())
+	return j
+}
+
+func TestOIDCToken(t *testing.T) {
+	s := newStubIssuer(t)
+	j := newOIDCJWT(t, s)
+	claims, roles, err := j.validateToken(s.token(t, "key1", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "alice@contoso.com", j.userName(claims))
+	assert.Equal(t, []string{"administrator", "reader"}, roles)
+
+	_, roles, err = j.validateToken(s.token(t, "key1", jwt.MapClaims{"groups": []string{"operators"}}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reader"}, roles)
+	_, _, err = j.validateToken(s.token(t, "key1", jwt.MapClaims{"aud": []string{"api://other", "api://symphony"}}))
+	assert.Nil(t, err)
+}
+
+func TestOIDCTokenSkipsLocalRoleMappings(t *testing.T) {
+	s := newStubIssuer(t)
+	j := newOIDCJWT(t, s)
+	// the mapping of the local admin user doesn't apply to the users of an OIDC issuer
+	_, roles, err := j.validateToken(s.token(t, "key1", jwt.MapClaims{"user": "admin", "groups": []string{"operators"}}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reader"}, roles)
+
+	// while it still applies to the tokens verified with the verification key
+	j.VerifyKey = "local-key"
+	local, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin"}).SignedString([]byte("local-key"))
+	_, roles, err = j.validateToken(local)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, roles)
+}
+
+func TestOIDCRejectedTokens(t *testing.T) {
+	s := newStubIssuer(t)
+	j := newOIDCJWT(t, s)
+	for name, claims := range map[string]jwt.MapClaims{
+		"audience":    {"aud": "api://other"},
+		"no audience": {"aud": nil},
+		"expired":     {"exp": time.Now().Add(-time.Minute).Unix()},
+		"no expiry":   {"exp": nil},
+	} {
+		_, _, err := j.validateToken(s.token(t, "key1", claims))
+		assert.NotNil(t, err, name)
+	}
+
+	// signed by another key
+	other := newStubIssuer(t)
+	forged := other.token(t, "key1", jwt.MapClaims{"iss": s.server.URL})
+	_, _, err := j.validateToken(forged)
+	assert.NotNil(t, err)
+
+	// shared secrets aren't accepted
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": s.server.URL,
+		"aud": "api://symphony",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	_, _, err = j.validateToken(hmac)
+	assert.NotNil(t, err)
+
+	// tokens of unknown issuers need a verification key
+	_, _, err = j.validateToken(other.token(t, "key1", nil))
+	assert.NotNil(t, err)
+}
+
+func TestOIDCKeyCache(t *testing.T) {
+	s := newStubIssuer(t)
+	j := newOIDCJWT(t, s)
+	for i := 0; i < 3; i++ {
+		_, _, err := j.validateToken(s.token(t, "key1", nil))
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 1, s.calls())
+
+	// a new key of the issuer is fetched, unless keys were just fetched
+	s.addKey(t, "key2")
+	_, _, err := j.validateToken(s.token(t, "key2", nil))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, s.calls())
+	j.oidcIssuers[0].triedAt = time.Time{}
+	_, _, err = j.validateToken(s.token(t, "key2", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, s.calls())
+
+	// cached keys are kept while the issuer can't be reached
+	s.lock.Lock()
+	s.down = true
+	s.lock.Unlock()
+	j.oidcIssuers[0].fetchedAt = time.Now().Add(-2 * time.Hour)
+	j.oidcIssuers[0].triedAt = time.Time{}
+	_, _, err = j.validateToken(s.token(t, "key1", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, s.calls())
+}
+
+func TestOIDCKeyFetchDoesntBlockCachedKeys(t *testing.T) {
+	s := newStubIssuer(t)
+	j := newOIDCJWT(t, s)
+	_, _, err := j.validateToken(s.token(t, "key1", nil))
+	assert.Nil(t, err)
+
+	// the cache expires and the issuer is slow to answer
+	gate := make(chan struct{})
+	s.lock.Lock()
+	s.gate = gate
+	s.lock.Unlock()
+	s.addKey(t, "key2")
+	j.oidcIssuers[0].lock.Lock()
+	j.oidcIssuers[0].fetchedAt = time.Now().Add(-2 * time.Hour)
+	j.oidcIssuers[0].triedAt = time.Time{}
+	j.oidcIssuers[0].lock.Unlock()
+	results := make(chan error, 2)
+	validate := func(kid string) {
+		_, _, err := j.validateToken(s.token(t, kid, nil))
+		results <- err
+	}
+	go validate("key2")
+	assert.Eventually(t, func() bool { return s.calls() == 2 }, 5*time.Second, 10*time.Millisecond)
+	go validate("key2")
+
+	// tokens with cached keys are validated while the keys are fetched
+	cached := make(chan error, 1)
+	go func() {
+		_, _, err := j.validateToken(s.token(t, "key1", nil))
+		cached <- err
+	}()
+	select {
+	case err = <-cached:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "validation with a cached key waited for the issuer")
+	}
+
+	// the tokens with the new key wait for the fetch in flight instead of fetching the keys again
+	close(gate)
+	assert.Nil(t, <-results)
+	assert.Nil(t, <-results)
+	assert.Equal(t, 2, s.calls())
+}
+
+func TestOIDCBadConfig(t *testing.T) {
+	for _, c := range []OIDCIssuerConfig{
+		{Audience: []string{"api://symphony"}},
+		{Issuer: "https://login.example.com"},
+		{Issuer: "https://login.example.com", Audience: []string{"api://symphony"}, CacheDuration: "often"},
+	} {
+		j := JWT{OIDC: []OIDCIssuerConfig{c}}
+		assert.NotNil(t, j.initOIDC())
+	}
+	_, err := BuildPipeline(HttpBindingConfig{Pipeline: []MiddlewareConfig{{
+		Type: "middleware.http.jwt",
+		Properties: map[string]interface{}{
+			"oidc": []map[string]interface{}{{"issuer": "https://login.example.com"}},
+		},
+	}}}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestOIDCIssuerMismatch(t *testing.T) {
+	s := newStubIssuer(t)
+	// the discovery document must be for the configured issuer
+	j := JWT{OIDC: []OIDCIssuerConfig{{
+		Issuer:       "https://login.example.com",
+		Audience:     []string{"api://symphony"},
+		DiscoveryURL: s.server.URL + OIDCDiscoveryPath,
+	}}}
+	assert.Nil(t, j.initOIDC())
+	_, _, err := j.validateToken(s.token(t, "key1", jwt.MapClaims{"iss": "https://login.example.com"}))
+	assert.NotNil(t, err)
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	jwk := JSONWebKey{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	public, err := jwk.publicKey()
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(public))
+
+	jwk.Y = jwk.X
+	_, err = jwk.publicKey()
+	assert.NotNil(t, err)
+	_, err = JSONWebKey{Kty: "EC", Crv: "P-192"}.publicKey()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,493 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultTokenIssuer is the name of the issuer used when vendors and middlewares don't name one
+	DefaultTokenIssuer = "default"
+	// JWKSPath is where a HttpBinding publishes the public keys of its token issuer
+	JWKSPath = "/.well-known/jwks.json"
+	// TokenUseClaim tells access tokens from refresh tokens
+	TokenUseClaim   = "token_use"
+	TokenUseAccess  = "access"
+	TokenUseRefresh = "refresh"
+	// UserClaim carries the user name in minted tokens
+	UserClaim = "user"
+	// RolesClaim carries the roles that Symphony assigned to the user in minted tokens
+	RolesClaim = "roles"
+)
+
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", TokenUseClaim, UserClaim}
+
+// TokenIssuerConfig configures the token issuer of a HttpBinding.
+type TokenIssuerConfig struct {
+	Name      string   `json:"name,omitempty"`
+	Algorithm string   `json:"algorithm,omitempty"`
+	Issuer    string   `json:"issuer,omitempty"`
+	Audience  []string `json:"audience,omitempty"`
+	// Lifetime of access tokens, as a duration string such as "1h". Defaults to 1 hour
+	Lifetime string `json:"lifetime,omitempty"`
+	// Lifetime of refresh tokens. Defaults to 24 hours, "0s" disables refresh tokens
+	RefreshLifetime string `json:"refreshLifetime,omitempty"`
+	// Key ID of the key that signs new tokens. Defaults to the first key
+	SigningKey     string               `json:"signingKey,omitempty"`
+	Keys           []TokenKeyConfig     `json:"keys,omitempty"`
+	SecretProvider SecretProviderConfig `json:"secretProvider,omitempty"`
+	CertProvider   CertProviderConfig   `json:"certProvider,omitempty"`
+}
+
+// TokenKeyConfig locates a signing key. A key is read from the secret provider (a shared secret for HMAC
+// algorithms, a PEM private key otherwise) or is the private key of a certificate from the cert provider.
+// A key can have its own cert provider, as a cert provider may only hold one certificate.
+type TokenKeyConfig struct {
+	ID           string              `json:"kid"`
+	Source       string              `json:"source"`
+	Object       string              `json:"object,omitempty"`
+	Field        string              `json:"field,omitempty"`
+	Host         string              `json:"host,omitempty"`
+	CertProvider *CertProviderConfig `json:"certProvider,omitempty"`
+}
+
+type SecretProviderConfig struct {
+	Type   string                    `json:"type"`
+	Config providers.IProviderConfig `json:"config"`
+}
+
+// TokenResponse is returned to clients when tokens are issued or refreshed
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// JSONWebKey is the public part of an asymmetric signing key, as published on the JWKS endpoint
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type tokenKey struct {
+	id        string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// TokenIssuer mints and verifies the tokens of Symphony users and targets. All of its keys verify tokens
+// and one of them signs new tokens, so keys are rotated by adding a key, signing with it and removing the
+// old key once the tokens it signed have expired.
+type TokenIssuer struct {
+	Name            string
+	method          jwt.SigningMethod
+	issuer          string
+	audience        []string
+	lifetime        time.Duration
+	refreshLifetime time.Duration
+	signingKey      string
+	keys            map[string]tokenKey
+}
+
+var (
+	issuerLock   sync.Mutex
+	tokenIssuers = make(map[string]*TokenIssuer)
+)
+
+// RegisterTokenIssuer makes an issuer available to the vendors and the JWT middlewares of the process
+func RegisterTokenIssuer(issuer *TokenIssuer) {
+	issuerLock.Lock()
+	defer issuerLock.Unlock()
+	tokenIssuers[issuer.Name] = issuer
+}
+
+// GetTokenIssuer returns a registered issuer. When the default issuer isn't configured, it's created with
+// a generated key, so its tokens are only valid within the process until it restarts.
+func GetTokenIssuer(name string) (*TokenIssuer, error) {
+	if name == "" {
+		name = DefaultTokenIssuer
+	}
+	issuerLock.Lock()
+	defer issuerLock.Unlock()
+	if issuer, ok := tokenIssuers[name]; ok {
+		return issuer, nil
+	}
+	if name != DefaultTokenIssuer {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("token issuer '%s' is not found", name), v1alpha2.NotFound)
+	}
+	issuer, err := NewTokenIssuer(TokenIssuerConfig{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	tokenIssuers[name] = issuer
+	return issuer, nil
+}
+
+// NewTokenIssuer creates an issuer and loads its keys from the given providers
+func NewTokenIssuer(config TokenIssuerConfig, secretProvider secret.ISecretProvider, certProvider certs.ICertProvider) (*TokenIssuer, error) {
+	ret := &TokenIssuer{
+		Name:            config.Name,
+		issuer:          config.Issuer,
+		audience:        config.Audience,
+		lifetime:        time.Hour,
+		refreshLifetime: 24 * time.Hour,
+		signingKey:      config.SigningKey,
+		keys:            make(map[string]tokenKey),
+	}
+	if ret.Name == "" {
+		ret.Name = DefaultTokenIssuer
+	}
+	if ret.issuer == "" {
+		ret.issuer = "symphony"
+	}
+	if len(ret.audience) == 0 {
+		ret.audience = []string{"symphony"}
+	}
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = "RS256"
+	}
+	ret.method = jwt.GetSigningMethod(algorithm)
+	if ret.method == nil || ret.method == jwt.SigningMethodNone {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("token signing algorithm '%s' is not supported", algorithm), v1alpha2.BadConfig)
+	}
+	var err error
+	if config.Lifetime != "" {
+		ret.lifetime, err = time.ParseDuration(config.Lifetime)
+		if err != nil || ret.lifetime <= 0 {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid token lifetime '%s'", config.Lifetime), v1alpha2.BadConfig)
+		}
+	}
+	if config.RefreshLifetime != "" {
+		ret.refreshLifetime, err = time.ParseDuration(config.RefreshLifetime)
+		if err != nil || ret.refreshLifetime < 0 {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid refresh token lifetime '%s'", config.RefreshLifetime), v1alpha2.BadConfig)
+		}
+	}
+
+	if len(config.Keys) == 0 {
+		key, err := ret.generateKey()
+		if err != nil {
+			return nil, err
+		}
+		ret.keys[key.id] = key
+		ret.signingKey = key.id
+		return ret, nil
+	}
+	for _, k := range config.Keys {
+		if k.ID == "" {
+			return nil, v1alpha2.NewCOAError(nil, "signing keys must have a key ID (kid)", v1alpha2.BadConfig)
+		}
+		if _, ok := ret.keys[k.ID]; ok {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("duplicated signing key '%s'", k.ID), v1alpha2.BadConfig)
+		}
+		key, err := ret.loadKey(k, secretProvider, certProvider)
+		if err != nil {
+			return nil, err
+		}
+		ret.keys[k.ID] = key
+	}
+	if ret.signingKey == "" {
+		ret.signingKey = config.Keys[0].ID
+	}
+	if _, ok := ret.keys[ret.signingKey]; !ok {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key '%s' is not found", ret.signingKey), v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+func (t *TokenIssuer) loadKey(config TokenKeyConfig, secretProvider secret.ISecretProvider, certProvider certs.ICertProvider) (tokenKey, error) {
+	var data []byte
+	switch config.Source {
+	case "secret":
+		if secretProvider == nil {
+			return tokenKey{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key '%s' needs a secret provider", config.ID), v1alpha2.MissingConfig)
+		}
+		value, err := secretProvider.Get(config.Object, config.Field)
+		if err != nil {
+			return tokenKey{}, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read signing key '%s'", config.ID), v1alpha2.BadConfig)
+		}
+		data = []byte(value)
+	case "cert":
+		if config.CertProvider != nil {
+			var err error
+			certProvider, err = createCertProvider(*config.CertProvider)
+			if err != nil {
+				return tokenKey{}, err
+			}
+		}
+		if certProvider == nil {
+			return tokenKey{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key '%s' needs a cert provider", config.ID), v1alpha2.MissingConfig)
+		}
+		_, key, err := certProvider.GetCert(config.Host)
+		if err != nil {
+			return tokenKey{}, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read signing key '%s'", config.ID), v1alpha2.BadConfig)
+		}
+		data = key
+	default:
+		return tokenKey{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key source '%s' is not recognized", config.Source), v1alpha2.BadConfig)
+	}
+	if len(data) == 0 {
+		return tokenKey{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key '%s' is empty", config.ID), v1alpha2.BadConfig)
+	}
+
+	ret := tokenKey{id: config.ID}
+	var err error
+	switch t.method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if config.Source == "cert" {
+			return tokenKey{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("signing key '%s' must be a shared secret for %s", config.ID, t.method.Alg()), v1alpha2.BadConfig)
+		}
+		ret.signKey = data
+		ret.verifyKey = data
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		var key *rsa.PrivateKey
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err == nil {
+			ret.signKey = key
+			ret.verifyKey = &key.PublicKey
+		}
+	case *jwt.SigningMethodECDSA:
+		var key *ecdsa.PrivateKey
+		key, err = jwt.ParseECPrivateKeyFromPEM(data)
+		if err == nil {
+			ret.signKey = key
+			ret.verifyKey = &key.PublicKey
+		}
+	}
+	if err != nil {
+		return tokenKey{}, v1alpha2.NewCOAError(err, fmt.Sprintf("signing key '%s' isn't a valid %s private key", config.ID, t.method.Alg()), v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+func (t *TokenIssuer) generateKey() (tokenKey, error) {
+	ret := tokenKey{id: uuid.New().String()}
+	var err error
+	switch t.method.(type) {
+	case *jwt.SigningMethodHMAC:
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		ret.signKey = secret
+		ret.verifyKey = secret
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		var key *rsa.PrivateKey
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err == nil {
+			ret.signKey = key
+			ret.verifyKey = &key.PublicKey
+		}
+	case *jwt.SigningMethodECDSA:
+		var key *ecdsa.PrivateKey
+		key, err = ecdsa.GenerateKey(curveOf(t.method), rand.Reader)
+		if err == nil {
+			ret.signKey = key
+			ret.verifyKey = &key.PublicKey
+		}
+	}
+	if err != nil {
+		return tokenKey{}, v1alpha2.NewCOAError(err, "failed to generate token signing key", v1alpha2.InternalError)
+	}
+	return ret, nil
+}
+
+func curveOf(method jwt.SigningMethod) elliptic.Curve {
+	switch method.(*jwt.SigningMethodECDSA).Hash {
+	case crypto.SHA384:
+		return elliptic.P384()
+	case crypto.SHA512:
+		return elliptic.P521()
+	default:
+		return elliptic.P256()
+	}
+}
+
+// HasKey tells if the issuer has a key with the given ID
+func (t *TokenIssuer) HasKey(kid string) bool {
+	_, ok := t.keys[kid]
+	return ok
+}
+
+// Issue mints an access token, and a refresh token unless refresh tokens are disabled, for a user. Claims
+// are added to both tokens, but can't override the registered claims set by the issuer.
+func (t *TokenIssuer) Issue(user string, claims map[string]interface{}) (TokenResponse, error) {
+	accessToken, err := t.sign(user, claims, TokenUseAccess, t.lifetime)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	ret := TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(t.lifetime.Seconds()),
+	}
+	if t.refreshLifetime > 0 {
+		ret.RefreshToken, err = t.sign(user, claims, TokenUseRefresh, t.refreshLifetime)
+		if err != nil {
+			return TokenResponse{}, err
+		}
+	}
+	return ret, nil
+}
+
+func (t *TokenIssuer) sign(user string, claims map[string]interface{}, use string, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	tokenClaims := jwt.MapClaims{}
+	for k, v := range claims {
+		tokenClaims[k] = v
+	}
+	tokenClaims["iss"] = t.issuer
+	tokenClaims["sub"] = user
+	tokenClaims["aud"] = t.audience
+	tokenClaims["iat"] = now.Unix()
+	tokenClaims["nbf"] = now.Unix()
+	tokenClaims["exp"] = now.Add(lifetime).Unix()
+	tokenClaims["jti"] = uuid.New().String()
+	tokenClaims[UserClaim] = user
+	tokenClaims[TokenUseClaim] = use
+
+	token := jwt.NewWithClaims(t.method, tokenClaims)
+	token.Header["kid"] = t.signingKey
+	ret, err := token.SignedString(t.keys[t.signingKey].signKey)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to sign token", v1alpha2.InternalError)
+	}
+	return ret, nil
+}
+
+// Validate verifies an access token and returns its claims
+func (t *TokenIssuer) Validate(tokenStr string) (map[string]interface{}, error) {
+	return t.parse(tokenStr, TokenUseAccess)
+}
+
+// ValidateRefresh verifies a refresh token and returns the claims to issue new tokens with, which are the
+// user and the claims that the refresh token was issued with. The caller must check that the user can
+// still be issued tokens, as refresh tokens aren't revoked by the issuer.
+func (t *TokenIssuer) ValidateRefresh(tokenStr string) (string, map[string]interface{}, error) {
+	claims, err := t.parse(tokenStr, TokenUseRefresh)
+	if err != nil {
+		return "", nil, err
+	}
+	user, _ := claims[UserClaim].(string)
+	for _, k := range registeredClaims {
+		delete(claims, k)
+	}
+	return user, claims, nil
+}
+
+func (t *TokenIssuer) parse(tokenStr string, use string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != t.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing algorithm '%s'", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := t.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("signing key '%s' is not found", kid)
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "invalid token", v1alpha2.Unauthorized)
+	}
+	if !claims.VerifyIssuer(t.issuer, true) {
+		return nil, v1alpha2.NewCOAError(nil, "token has an unexpected issuer", v1alpha2.Unauthorized)
+	}
+	audience := false
+	for _, a := range t.audience {
+		if claims.VerifyAudience(a, true) {
+			audience = true
+			break
+		}
+	}
+	if !audience {
+		return nil, v1alpha2.NewCOAError(nil, "token has an unexpected audience", v1alpha2.Unauthorized)
+	}
+	if claims[TokenUseClaim] != use {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("expected a %s token", use), v1alpha2.Unauthorized)
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys of the issuer. Shared secrets of HMAC algorithms are never published.
+func (t *TokenIssuer) JWKS() JSONWebKeySet {
+	ret := JSONWebKeySet{Keys: make([]JSONWebKey, 0)}
+	for _, k := range t.keys {
+		key := JSONWebKey{
+			Use: "sig",
+			Alg: t.method.Alg(),
+			Kid: k.id,
+		}
+		switch v := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			key.Kty = "RSA"
+			key.N = base64.RawURLEncoding.EncodeToString(v.N.Bytes())
+			key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(v.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (v.Curve.Params().BitSize + 7) / 8
+			key.Kty = "EC"
+			key.Crv = v.Curve.Params().Name
+			key.X = base64.RawURLEncoding.EncodeToString(v.X.FillBytes(make([]byte, size)))
+			key.Y = base64.RawURLEncoding.EncodeToString(v.Y.FillBytes(make([]byte, size)))
+		default:
+			continue
+		}
+		ret.Keys = append(ret.Keys, key)
+	}
+	sort.Slice(ret.Keys, func(i, j int) bool {
+		return ret.Keys[i].Kid < ret.Keys[j].Kid
+	})
+	return ret
+}
+
+// onJWKS serves the keys of an issuer. The issuer is looked up on each request, as another binding of the
+// process may register it after this binding is launched.
+func onJWKS(name string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		issuer, err := GetTokenIssuer(name)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+			return
+		}
+		data, _ := json.Marshal(issuer.JWKS())
+		ctx.SetContentType("application/json")
+		ctx.SetBody(data)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	autogen "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newHMACIssuer(t *testing.T, config TokenIssuerConfig) *TokenIssuer {
+	secretProvider := &mocksecret.MockSecretProvider{}
+	secretProvider.Init(mocksecret.MockSecretProviderConfig{})
+	config.Algorithm = "HS256"
+	if config.Keys == nil {
+		config.Keys = []TokenKeyConfig{
+			{ID: "key1", Source: "secret", Object: "tokens", Field: "key1"},
+			{ID: "key2", Source: "secret", Object: "tokens", Field: "key2"},
+		}
+	}
+	issuer, err := NewTokenIssuer(config, secretProvider, nil)
+	assert.Nil(t, err)
+	return issuer
+}
+
+func TestTokenIssuerIssue(t *testing.T) {
+	issuer := newHMACIssuer(t, TokenIssuerConfig{Lifetime: "30m"})
+	tokens, err := issuer.Issue("admin", map[string]interface{}{"tenant": "contoso", "iss": "someone"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 1800, tokens.ExpiresIn)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, "key1", tokenKeyID(tokens.AccessToken))
+
+	claims, err := issuer.Validate(tokens.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", claims[UserClaim])
+	assert.Equal(t, "contoso", claims["tenant"])
+	assert.Equal(t, "symphony", claims["iss"])
+
+	// a refresh token isn't accepted as an access token, and the other way around
+	_, err = issuer.Validate(tokens.RefreshToken)
+	assert.NotNil(t, err)
+	_, _, err = issuer.ValidateRefresh(tokens.AccessToken)
+	assert.NotNil(t, err)
+
+	user, claims, err := issuer.ValidateRefresh(tokens.RefreshToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, map[string]interface{}{"tenant": "contoso"}, claims)
+}
+
+func TestTokenIssuerNoRefresh(t *testing.T) {
+	issuer := newHMACIssuer(t, TokenIssuerConfig{RefreshLifetime: "0s"})
+	tokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+	assert.Empty(t, tokens.RefreshToken)
+}
+
+func TestTokenIssuerKeyRotation(t *testing.T) {
+	oldIssuer := newHMACIssuer(t, TokenIssuerConfig{})
+	tokens, err := oldIssuer.Issue("admin", nil)
+	assert.Nil(t, err)
+
+	// tokens signed by the previous key stay valid while the key is kept
+	issuer := newHMACIssuer(t, TokenIssuerConfig{SigningKey: "key2"})
+	_, err = issuer.Validate(tokens.AccessToken)
+	assert.Nil(t, err)
+	newTokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "key2", tokenKeyID(newTokens.AccessToken))
+
+	retired := newHMACIssuer(t, TokenIssuerConfig{Keys: []TokenKeyConfig{
+		{ID: "key2", Source: "secret", Object: "tokens", Field: "key2"},
+	}})
+	_, err = retired.Validate(tokens.AccessToken)
+	assert.NotNil(t, err)
+	_, err = retired.Validate(newTokens.AccessToken)
+	assert.Nil(t, err)
+}
+
+func TestTokenIssuerIssuerAndAudience(t *testing.T) {
+	issuer := newHMACIssuer(t, TokenIssuerConfig{Issuer: "site-a", Audience: []string{"site-a"}})
+	tokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+
+	other := newHMACIssuer(t, TokenIssuerConfig{Issuer: "site-b", Audience: []string{"site-a"}})
+	_, err = other.Validate(tokens.AccessToken)
+	assert.NotNil(t, err)
+	other = newHMACIssuer(t, TokenIssuerConfig{Issuer: "site-a", Audience: []string{"site-b"}})
+	_, err = other.Validate(tokens.AccessToken)
+	assert.NotNil(t, err)
+}
+
+func TestTokenIssuerCertKeys(t *testing.T) {
+	certProvider := &autogen.AutoGenCertProvider{}
+	certProvider.Init(autogen.AutoGenCertProviderConfig{})
+	issuer, err := NewTokenIssuer(TokenIssuerConfig{
+		Algorithm: "RS256",
+		Keys: []TokenKeyConfig{
+			{ID: "rsa1", Source: "cert", Host: "symphony"},
+			{ID: "rsa2", Source: "cert", Host: "symphony", CertProvider: &CertProviderConfig{Type: "certs.autogen"}},
+		},
+	}, nil, certProvider)
+	assert.Nil(t, err)
+	tokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+	_, err = issuer.Validate(tokens.AccessToken)
+	assert.Nil(t, err)
+
+	jwks := issuer.JWKS()
+	assert.Equal(t, 2, len(jwks.Keys))
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "rsa1", jwks.Keys[0].Kid)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.Equal(t, "rsa2", jwks.Keys[1].Kid)
+	assert.NotEqual(t, jwks.Keys[0].N, jwks.Keys[1].N)
+
+	// shared secrets are never published
+	assert.Empty(t, newHMACIssuer(t, TokenIssuerConfig{}).JWKS().Keys)
+}
+
+func TestTokenIssuerGeneratedKey(t *testing.T) {
+	issuer, err := NewTokenIssuer(TokenIssuerConfig{Algorithm: "ES256"}, nil, nil)
+	assert.Nil(t, err)
+	tokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+	_, err = issuer.Validate(tokens.AccessToken)
+	assert.Nil(t, err)
+	jwks := issuer.JWKS()
+	assert.Equal(t, 1, len(jwks.Keys))
+	assert.Equal(t, "EC", jwks.Keys[0].Kty)
+	assert.Equal(t, "P-256", jwks.Keys[0].Crv)
+}
+
+func TestTokenIssuerBadConfig(t *testing.T) {
+	_, err := NewTokenIssuer(TokenIssuerConfig{Algorithm: "none"}, nil, nil)
+	assert.NotNil(t, err)
+	_, err = NewTokenIssuer(TokenIssuerConfig{Lifetime: "soon"}, nil, nil)
+	assert.NotNil(t, err)
+	_, err = NewTokenIssuer(TokenIssuerConfig{Keys: []TokenKeyConfig{{ID: "key1", Source: "secret"}}}, nil, nil)
+	assert.NotNil(t, err)
+
+	secretProvider := &mocksecret.MockSecretProvider{}
+	secretProvider.Init(mocksecret.MockSecretProviderConfig{})
+	_, err = NewTokenIssuer(TokenIssuerConfig{
+		Algorithm:  "HS256",
+		SigningKey: "key2",
+		Keys:       []TokenKeyConfig{{ID: "key1", Source: "secret", Object: "tokens", Field: "key1"}},
+	}, secretProvider, nil)
+	assert.NotNil(t, err)
+	// the mock secret isn't a PEM private key
+	_, err = NewTokenIssuer(TokenIssuerConfig{
+		Algorithm: "RS256",
+		Keys:      []TokenKeyConfig{{ID: "key1", Source: "secret", Object: "tokens", Field: "key1"}},
+	}, secretProvider, nil)
+	assert.NotNil(t, err)
+}
+
+func TestGetTokenIssuer(t *testing.T) {
+	issuer, err := GetTokenIssuer("")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTokenIssuer, issuer.Name)
+	again, err := GetTokenIssuer(DefaultTokenIssuer)
+	assert.Nil(t, err)
+	assert.Equal(t, issuer, again)
+
+	_, err = GetTokenIssuer("token-test-missing")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestJWTValidatesIssuedTokens(t *testing.T) {
+	issuer := newHMACIssuer(t, TokenIssuerConfig{Name: "token-test-jwt"})
+	RegisterTokenIssuer(issuer)
+	tokens, err := issuer.Issue("admin", nil)
+	assert.Nil(t, err)
+
+	j := JWT{TokenIssuer: "token-test-jwt", Roles: []ClaimRoleMap{{Role: "administrator", Claim: "user", Value: "admin"}}}
+	claims, roles, err := j.validateToken(tokens.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", j.userName(claims))
+	assert.Equal(t, []string{"administrator"}, roles)
+	_, _, err = j.validateToken(tokens.RefreshToken)
+	assert.NotNil(t, err)
+
+	// tokens from other signers need a verification key
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "admin"}).SignedString([]byte("legacy-key"))
+	_, _, err = j.validateToken(legacy)
+	assert.NotNil(t, err)
+	j.VerifyKey = "legacy-key"
+	claims, _, err = j.validateToken(legacy)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", j.userName(claims))
+}
+
+func TestJWTRolesClaim(t *testing.T) {
+	issuer := newHMACIssuer(t, TokenIssuerConfig{Name: "token-test-roles"})
+	RegisterTokenIssuer(issuer)
+	tokens, err := issuer.Issue("alice", map[string]interface{}{RolesClaim: []string{"administrator"}})
+	assert.Nil(t, err)
+
+	j := JWT{
+		AuthHeader:  "Authorization",
+		TokenIssuer: "token-test-roles",
+		EnableRBAC:  true,
+		Policy: map[string]Policy{
+			"administrator": {Items: map[string]string{"*": "*"}},
+		},
+	}
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/v1alpha2/solutions")
+	ctx.Request.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	called := false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(ctx)
+	assert.True(t, called)
+	assert.Equal(t, []string{"administrator"}, ctx.UserValue(v1alpha2.AuthenticatedRolesKey))
+
+	// the roles claim of tokens from other signers isn't trusted
+	j.VerifyKey = "legacy-key"
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "alice", RolesClaim: []string{"administrator"}}).SignedString([]byte("legacy-key"))
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/v1alpha2/solutions")
+	ctx.Request.Header.Set("Authorization", "Bearer "+legacy)
+	called = false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(ctx)
+	assert.False(t, called)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode())
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	cron, err := parseCron("0,30 9-17/2 * jan-mar 1-5")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1|1<<30), cron.minute)
+	assert.Equal(t, uint64(1<<9|1<<11|1<<13|1<<15|1<<17), cron.hour)
+	assert.Equal(t, uint64(1<<1|1<<2|1<<3), cron.month)
+	assert.True(t, cron.domStar)
+	assert.False(t, cron.dowStar)
+
+	cron, err = parseCron("@weekly")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), cron.dow)
+
+	cron, err = parseCron("0 0 * * 7")
+	assert.Nil(t, err)
+	assert.NotZero(t, cron.dow&1)
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		_, err := parseCron(expression)
+		assert.NotNil(t, err, expression)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	cron, _ := parseCron("15 10 1 * *")
+	next := cron.next(time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 2, 1, 10, 15, 0, 0, time.UTC), next)
+
+	// day of month and day of week match either way when both are set
+	cron, _ = parseCron("0 0 13 * fri")
+	next = cron.next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), next)
+
+	// a day of month that starts with * restricts the day of week instead of adding to it
+	cron, _ = parseCron("0 0 */2 * 1")
+	assert.True(t, cron.domStar)
+	next = cron.next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), next)
+
+	cron, _ = parseCron("0 0 30 2 *")
+	assert.True(t, cron.next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero())
+
+	// 1:30 occurs twice when daylight saving time ends, it only matches the first time
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	cron, _ = parseCron("30 1 * * *")
+	first := cron.next(time.Date(2024, 11, 3, 0, 0, 0, 0, la))
+	assert.Equal(t, time.Date(2024, 11, 3, 8, 30, 0, 0, time.UTC), first.UTC())
+	next = cron.next(first)
+	assert.Equal(t, time.Date(2024, 11, 4, 9, 30, 0, 0, time.UTC), next.UTC())
+}
@@ -69,3 +69,10 @@ func IsDelayed(err error) bool {
 	}
 	return coaE.State == Delayed
 }
+func IsConflict(err error) bool {
+	coaE, ok := err.(COAError)
+	if !ok {
+		return false
+	}
+	return coaE.State == Conflict
+}
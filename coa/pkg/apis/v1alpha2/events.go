@@ -8,6 +8,8 @@ package v1alpha2
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -45,12 +47,33 @@ type HeartBeatData struct {
 	Action string    `json:"action"`
 	Time   time.Time `json:"time"`
 }
+
+// ScheduleSpec decides when a stage or an activation runs. A schedule either fires once, at Date and
+// Time, or recurs at the times matched by a Cron expression. Zone is an IANA time zone name such as
+// "America/Los_Angeles"; it defaults to UTC.
 type ScheduleSpec struct {
-	Date string `json:"date"`
-	Time string `json:"time"`
-	Zone string `json:"zone"`
+	Date string `json:"date,omitempty"`
+	Time string `json:"time,omitempty"`
+	Zone string `json:"zone,omitempty"`
+	// Five-field cron expression (minute hour day-of-month month day-of-week), or a descriptor such as @daily
+	Cron      string           `json:"cron,omitempty"`
+	Blackouts []BlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// BlackoutWindow is a period in which a schedule doesn't fire. Start and End are either date-times
+// ("2006-01-02 15:04") for a one-off window, or times of day ("15:04") for a window that recurs every
+// day, or only on the given Days of the week ("mon", "tue", ...). A daily window may span midnight.
+type BlackoutWindow struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days,omitempty"`
 }
 
+const (
+	blackoutDateTimeLayout = "2006-01-02 15:04"
+	blackoutTimeLayout     = "15:04"
+)
+
 func (s ScheduleSpec) ShouldFireNow() (bool, error) {
 	dt, err := s.GetTime()
 	if err != nil {
@@ -68,23 +91,211 @@ func (s ScheduleSpec) GetTime() (time.Time, error) {
 	return dt, nil
 }
 
-func parseTimeWithZone(timeStr string, dateStr string, zoneStr string) (time.Time, error) {
-	dtStr := dateStr + " " + timeStr
+// IsRecurring tells if the schedule fires more than once
+func (s ScheduleSpec) IsRecurring() bool {
+	return s.Cron != ""
+}
+
+// Validate checks the time zone, the date and time or the cron expression, and the blackout windows
+func (s ScheduleSpec) Validate() error {
+	loc, err := loadZone(s.Zone)
+	if err != nil {
+		return NewCOAError(nil, fmt.Sprintf("invalid time zone '%s'", s.Zone), BadRequest)
+	}
+	if s.Cron != "" {
+		if s.Date != "" || s.Time != "" {
+			return NewCOAError(nil, "a schedule can't have both a cron expression and a date and time", BadRequest)
+		}
+		if _, err := parseCron(s.Cron); err != nil {
+			return NewCOAError(nil, err.Error(), BadRequest)
+		}
+	} else if _, err := s.GetTime(); err != nil {
+		return NewCOAError(nil, fmt.Sprintf("invalid schedule date '%s' and time '%s'", s.Date, s.Time), BadRequest)
+	}
+	for _, w := range s.Blackouts {
+		if _, err := w.endOf(time.Now().In(loc), loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NextTime returns when the schedule fires next, after the given time. A one-off schedule whose time
+// has passed is due right away. Times in blackout windows are skipped: a cron schedule fires at its
+// next match outside the windows, and a one-off schedule fires when the window ends. The zero time
+// means that the schedule never fires again.
+func (s ScheduleSpec) NextTime(after time.Time) (time.Time, error) {
+	loc, err := loadZone(s.Zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	after = after.In(loc)
+	if s.Cron == "" {
+		dt, err := s.GetTime()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if dt.Before(after) {
+			dt = after
+		}
+		return s.skipBlackouts(dt, loc)
+	}
+	cron, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// bounded, in case blackouts cover every match
+	for i := 0; i < 10000; i++ {
+		t := cron.next(after)
+		if t.IsZero() {
+			return t, nil
+		}
+		end, blacked, err := s.blackoutEnd(t, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !blacked {
+			return t, nil
+		}
+		// the next match may be the end of the window itself
+		after = end.Add(-time.Nanosecond)
+	}
+	return time.Time{}, nil
+}
+
+// UpcomingTimes returns up to count times at which the schedule fires after the given time
+func (s ScheduleSpec) UpcomingTimes(after time.Time, count int) ([]time.Time, error) {
+	ret := make([]time.Time, 0)
+	if !s.IsRecurring() {
+		dt, err := s.GetTime()
+		if err != nil {
+			return nil, err
+		}
+		if dt.Before(after) || count <= 0 {
+			return ret, nil
+		}
+		t, err := s.NextTime(after)
+		if err != nil {
+			return nil, err
+		}
+		return append(ret, t), nil
+	}
+	for len(ret) < count {
+		t, err := s.NextTime(after)
+		if err != nil {
+			return nil, err
+		}
+		if t.IsZero() {
+			break
+		}
+		ret = append(ret, t)
+		after = t
+	}
+	return ret, nil
+}
 
-	switch zoneStr {
-	case "LOCAL":
+func (s ScheduleSpec) skipBlackouts(t time.Time, loc *time.Location) (time.Time, error) {
+	// windows may overlap or follow each other
+	for i := 0; i < len(s.Blackouts)+1; i++ {
+		end, blacked, err := s.blackoutEnd(t, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !blacked {
+			return t, nil
+		}
+		t = end
+	}
+	return t, nil
+}
+
+// blackoutEnd returns the end of the blackout window the given time falls in, if any
+func (s ScheduleSpec) blackoutEnd(t time.Time, loc *time.Location) (time.Time, bool, error) {
+	for _, w := range s.Blackouts {
+		end, err := w.endOf(t, loc)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if !end.IsZero() {
+			return end, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// endOf returns the end of the window if it contains the given time, or the zero time
+func (w BlackoutWindow) endOf(t time.Time, loc *time.Location) (time.Time, error) {
+	t = t.In(loc)
+	if start, err := time.ParseInLocation(blackoutDateTimeLayout, w.Start, loc); err == nil {
+		end, err := time.ParseInLocation(blackoutDateTimeLayout, w.End, loc)
+		if err != nil || !end.After(start) {
+			return time.Time{}, NewCOAError(nil, fmt.Sprintf("invalid blackout end '%s', expected a date-time after '%s'", w.End, w.Start), BadRequest)
+		}
+		if !t.Before(start) && t.Before(end) {
+			return end, nil
+		}
+		return time.Time{}, nil
+	}
+	start, err := time.Parse(blackoutTimeLayout, w.Start)
+	if err != nil {
+		return time.Time{}, NewCOAError(nil, fmt.Sprintf("invalid blackout start '%s', expected '%s' or '%s'", w.Start, blackoutTimeLayout, blackoutDateTimeLayout), BadRequest)
+	}
+	end, err := time.Parse(blackoutTimeLayout, w.End)
+	if err != nil {
+		return time.Time{}, NewCOAError(nil, fmt.Sprintf("invalid blackout end '%s', expected '%s'", w.End, blackoutTimeLayout), BadRequest)
+	}
+	days := make(map[time.Weekday]bool)
+	for _, d := range w.Days {
+		v, ok := weekdayNames[strings.ToLower(d)]
+		if !ok {
+			return time.Time{}, NewCOAError(nil, fmt.Sprintf("invalid blackout day '%s'", d), BadRequest)
+		}
+		days[time.Weekday(v)] = true
+	}
+	// a window that spans midnight may have started the day before
+	for _, offset := range []int{0, -1} {
+		day := t.AddDate(0, 0, offset)
+		if len(days) > 0 && !days[day.Weekday()] {
+			continue
+		}
+		windowStart := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		windowEnd := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+		if !windowEnd.After(windowStart) {
+			windowEnd = windowEnd.AddDate(0, 0, 1)
+		}
+		if !t.Before(windowStart) && t.Before(windowEnd) {
+			return windowEnd, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// zoneAliases maps the abbreviations accepted by earlier versions to IANA time zones
+var zoneAliases = map[string]string{
+	"PST": "America/Los_Angeles",
+	"PDT": "America/Los_Angeles",
+	"EST": "America/New_York",
+	"EDT": "America/New_York",
+	"CST": "America/Chicago",
+	"CDT": "America/Chicago",
+	"MST": "America/Denver",
+	"MDT": "America/Denver",
+}
+
+func loadZone(zoneStr string) (*time.Location, error) {
+	if zoneStr == "LOCAL" {
 		zoneStr = ""
-	case "PST", "PDT":
-		zoneStr = "America/Los_Angeles"
-	case "EST", "EDT":
-		zoneStr = "America/New_York"
-	case "CST", "CDT":
-		zoneStr = "America/Chicago"
-	case "MST", "MDT":
-		zoneStr = "America/Denver"
 	}
+	if alias, ok := zoneAliases[zoneStr]; ok {
+		zoneStr = alias
+	}
+	return time.LoadLocation(zoneStr)
+}
+
+func parseTimeWithZone(timeStr string, dateStr string, zoneStr string) (time.Time, error) {
+	dtStr := dateStr + " " + timeStr
 
-	loc, err := time.LoadLocation(zoneStr)
+	loc, err := loadZone(zoneStr)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -162,3 +162,81 @@ func TestScheduleShouldNotFire(t *testing.T) {
 // 	assert.Nil(t, err)
 // 	assert.Equal(t, "2020-01-01 12:00:00 -0800 PST", dt.String())
 // }
+
+func TestScheduleCronNextTime(t *testing.T) {
+	schedule := ScheduleSpec{
+		Cron: "30 2 * * mon-fri",
+		Zone: "America/Los_Angeles",
+	}
+	after := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC) // Friday
+	dt, err := schedule.NextTime(after)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-11 02:30:00 -0700 PDT", dt.String())
+	times, err := schedule.UpcomingTimes(after, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(times))
+	assert.Equal(t, "2024-03-12 02:30:00 -0700 PDT", times[1].String())
+	assert.Equal(t, "2024-03-13 02:30:00 -0700 PDT", times[2].String())
+
+	// 2:30 doesn't exist on the day clocks spring forward
+	schedule.Cron = "30 2 * * *"
+	dt, err = schedule.NextTime(time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-11 02:30:00 -0700 PDT", dt.String())
+}
+
+func TestScheduleCronBlackouts(t *testing.T) {
+	schedule := ScheduleSpec{
+		Cron: "@hourly",
+		Blackouts: []BlackoutWindow{
+			{Start: "22:00", End: "06:00"},
+			{Start: "2024-01-02 06:00", End: "2024-01-02 08:30"},
+		},
+	}
+	dt, err := schedule.NextTime(time.Date(2024, 1, 1, 21, 30, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	// 22:00 falls in the nightly window and 06:00 to 08:00 in the one-off window
+	assert.Equal(t, "2024-01-02 09:00:00 +0000 UTC", dt.String())
+	dt, err = schedule.NextTime(dt)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02 10:00:00 +0000 UTC", dt.String())
+}
+
+func TestScheduleBlackoutDays(t *testing.T) {
+	schedule := ScheduleSpec{
+		Cron: "0 12 * * *",
+		Blackouts: []BlackoutWindow{
+			{Start: "00:00", End: "23:59", Days: []string{"sat", "sun"}},
+		},
+	}
+	dt, err := schedule.NextTime(time.Date(2024, 1, 5, 13, 0, 0, 0, time.UTC)) // Friday
+	assert.Nil(t, err)
+	assert.Equal(t, time.Monday, dt.Weekday())
+}
+
+func TestScheduleOneOffBlackout(t *testing.T) {
+	schedule := ScheduleSpec{
+		Date: "2024-01-01",
+		Time: "11:00:00PM",
+		Blackouts: []BlackoutWindow{
+			{Start: "22:00", End: "06:00"},
+		},
+	}
+	dt, err := schedule.NextTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02 06:00:00 +0000 UTC", dt.String())
+	times, err := schedule.UpcomingTimes(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 5)
+	assert.Nil(t, err)
+	assert.Empty(t, times)
+}
+
+func TestScheduleValidate(t *testing.T) {
+	assert.Nil(t, ScheduleSpec{Cron: "*/15 * * * *", Zone: "Europe/Berlin"}.Validate())
+	assert.Nil(t, ScheduleSpec{Date: "2024-01-01", Time: "12:00:00PM", Zone: "PST"}.Validate())
+	assert.NotNil(t, ScheduleSpec{Cron: "* * *"}.Validate())
+	assert.NotNil(t, ScheduleSpec{Cron: "@daily", Zone: "Mars/Olympus_Mons"}.Validate())
+	assert.NotNil(t, ScheduleSpec{Cron: "@daily", Date: "2024-01-01"}.Validate())
+	assert.NotNil(t, ScheduleSpec{Date: "yesterday"}.Validate())
+	assert.NotNil(t, ScheduleSpec{Cron: "@daily", Blackouts: []BlackoutWindow{{Start: "noon", End: "13:00"}}}.Validate())
+	assert.NotNil(t, ScheduleSpec{Cron: "@daily", Blackouts: []BlackoutWindow{{Start: "12:00", End: "13:00", Days: []string{"someday"}}}}.Validate())
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// Keys of the authenticated caller and of the authorization policy in the context of a request, set by
+// the JWT middleware, and of the verified client certificate of the caller, set by the HTTP binding
+const (
+	AuthenticatedUserKey   = "__authenticatedUser"
+	AuthenticatedRolesKey  = "__authenticatedRoles"
+	AuthorizationPolicyKey = "__authorizationPolicy"
+	ClientCertificateKey   = "__clientCertificate"
+)
+
+// GetAuthenticatedUser returns the name and roles of the caller of a request. The name is empty if the
+// request isn't authenticated.
+func GetAuthenticatedUser(ctx context.Context) (string, []string) {
+	if ctx == nil {
+		return "", nil
+	}
+	user, _ := ctx.Value(AuthenticatedUserKey).(string)
+	roles, _ := ctx.Value(AuthenticatedRolesKey).([]string)
+	return user, roles
+}
+
+// GetClientCertificate returns the verified client certificate of the caller of a request, or nil if the
+// caller didn't present one
+func GetClientCertificate(ctx context.Context) *x509.Certificate {
+	if ctx == nil {
+		return nil
+	}
+	cert, _ := ctx.Value(ClientCertificateKey).(*x509.Certificate)
+	return cert
+}
@@ -66,6 +66,10 @@ func (w *LocalCertFileProvider) GetCert(host string) ([]byte, []byte, error) {
 		log.Errorf("  P (Localfile): failed to read certificate file %+v", err)
 		return nil, nil, v1alpha2.NewCOAError(err, "failed to read certificate file", v1alpha2.InternalError)
 	}
+	if w.Config.KeyFile == "" {
+		// a CA certificate, whose key isn't needed to verify certificates
+		return certData, nil, nil
+	}
 	keyFile, err := os.Open(w.Config.KeyFile)
 	if err != nil {
 		log.Errorf("  P (Localfile): failed to open key file %+v", err)
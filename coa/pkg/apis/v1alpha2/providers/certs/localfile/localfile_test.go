@@ -47,3 +47,16 @@ func TestCertGetTestFile(t *testing.T) {
 	_, _, err = provider.GetCert("localhost")
 	assert.Nil(t, err)
 }
+
+func TestCertGetWithoutKey(t *testing.T) {
+	provider := LocalCertFileProvider{}
+	err := provider.Init(LocalCertFileProviderConfig{
+		Name:     "ca",
+		CertFile: "test_cert.crt",
+	})
+	assert.Nil(t, err)
+	cert, key, err := provider.GetCert("")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, cert)
+	assert.Nil(t, key)
+}
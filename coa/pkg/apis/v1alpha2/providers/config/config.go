@@ -24,3 +24,21 @@ type IExtConfigProvider interface {
 	Get(object string, field string, overrides []string, localContext interface{}) (interface{}, error)
 	GetObject(object string, overrides []string, localContext interface{}) (map[string]interface{}, error)
 }
+
+// ConfigSource tells which provider and configuration object a value was read from. The object is
+// the overlay, parent catalog or object that actually holds the field.
+type ConfigSource struct {
+	Provider string `json:"provider,omitempty"`
+	Object   string `json:"object"`
+}
+
+// IConfigSourceReader is implemented by config providers that resolve fields from other objects,
+// such as parent catalogs, and can tell which object a field was read from.
+type IConfigSourceReader interface {
+	ReadWithSource(object string, field string, localContext interface{}) (interface{}, string, error)
+}
+
+// IConfigSourceProvider is implemented by extended config providers that can tell where a value was read from.
+type IConfigSourceProvider interface {
+	GetWithSource(object string, field string, overrides []string, localContext interface{}) (interface{}, ConfigSource, error)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 )
 
@@ -71,6 +72,9 @@ func toMockConfigProviderConfig(config providers.IProviderConfig) (MockConfigPro
 func (m *MockConfigProvider) Get(object string, field string, overrides []string, localContext interface{}) (interface{}, error) {
 	return object + "::" + field, nil
 }
+func (m *MockConfigProvider) GetWithSource(object string, field string, overrides []string, localContext interface{}) (interface{}, config.ConfigSource, error) {
+	return object + "::" + field, config.ConfigSource{Provider: m.Config.Name, Object: object}, nil
+}
 func (m *MockConfigProvider) GetObject(object string, overrides []string, localContext interface{}) (map[string]interface{}, error) {
 	return map[string]interface{}{object: object}, nil
 }
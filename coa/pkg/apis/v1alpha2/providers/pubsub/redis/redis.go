@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"time"
 
@@ -52,6 +53,14 @@ type RedisPubSubProviderConfig struct {
 	ConsumerID        string        `json:"consumerID"`
 	ProcessingTimeout time.Duration `json:"processingTimeout,omitempty"`
 	RedeliverInterval time.Duration `json:"redeliverInterval,omitempty"`
+	// ConsumerGroup is shared by all replicas that split the messages of a topic between them.
+	// ConsumerID names this replica within the group and defaults to the host name.
+	ConsumerGroup string `json:"consumerGroup,omitempty"`
+	// MaxRetries is how many times a message is redelivered before it's moved to the dead-letter topic.
+	// 0 means messages are redelivered until they're handled.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// DeadLetterTopic receives messages that ran out of retries. It defaults to "<topic>-deadletter".
+	DeadLetterTopic string `json:"deadLetterTopic,omitempty"`
 }
 
 func RedisPubSubProviderConfigFromMap(properties map[string]string) (RedisPubSubProviderConfig, error) {
@@ -102,6 +111,21 @@ func RedisPubSubProviderConfigFromMap(properties map[string]string) (RedisPubSub
 	if v, ok := properties["consumerID"]; ok {
 		ret.ConsumerID = v // providers.LoadEnv(v)
 	}
+	if v, ok := properties["consumerGroup"]; ok {
+		ret.ConsumerGroup = v
+	}
+	if v, ok := properties["maxRetries"]; ok {
+		if v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxRetries' setting of Redis pub-sub provider", v1alpha2.BadConfig)
+			}
+			ret.MaxRetries = n
+		}
+	}
+	if v, ok := properties["deadLetterTopic"]; ok {
+		ret.DeadLetterTopic = v
+	}
 
 	if v, ok := properties["processingTimeout"]; ok {
 		val := v //providers.LoadEnv(v)
@@ -159,6 +183,16 @@ func (i *RedisPubSubProvider) Init(config providers.IProviderConfig) error {
 	if i.Config.Host == "" {
 		return v1alpha2.NewCOAError(nil, "Redis host is not supplied", v1alpha2.MissingConfig)
 	}
+	if i.Config.MaxRetries < 0 {
+		return v1alpha2.NewCOAError(nil, "Redis pub-sub provider maxRetries can't be negative", v1alpha2.BadConfig)
+	}
+	if i.Config.ConsumerGroup == "" {
+		// consumers used to be grouped by their consumer ID
+		i.Config.ConsumerGroup = i.Config.ConsumerID
+	}
+	if i.Config.ConsumerID == "" {
+		i.Config.ConsumerID, _ = os.Hostname()
+	}
 
 	i.Subscribers = make(map[string][]v1alpha2.EventHandler)
 	options := &redis.Options{
@@ -193,25 +227,65 @@ func (i *RedisPubSubProvider) worker() {
 		case <-i.Ctx.Done():
 			return
 		case msg := <-i.Queue:
-			i.processMessage(msg)
+			if err := i.processMessage(msg); err != nil {
+				mLog.Debugf("  P (Redis PubSub) : %v", err)
+			}
 		}
 	}
 }
+
+// processMessage acknowledges a message only after it's handled. A message that fails stays
+// pending and is redelivered by the reclaim loop once the processing timeout expires.
 func (i *RedisPubSubProvider) processMessage(msg RedisMessageWrapper) error {
 	var evt v1alpha2.Event
-	err := json.Unmarshal([]byte(msg.Message.(string)), &evt)
+	data, ok := msg.Message.(string)
+	if !ok {
+		return i.deadLetter(msg.Topic, msg.MessageID, msg.Message)
+	}
+	err := json.Unmarshal([]byte(data), &evt)
 	if err != nil {
+		// the message can never be handled, so there's no point in redelivering it
+		if dErr := i.deadLetter(msg.Topic, msg.MessageID, msg.Message); dErr != nil {
+			return dErr
+		}
 		return v1alpha2.NewCOAError(err, "failed to unmarshal event", v1alpha2.InternalError)
 	}
 	if err := msg.Handler(msg.Topic, evt); err != nil {
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to handle message %s", msg.MessageID), v1alpha2.InternalError)
 	}
-	if err := i.Client.XAck(msg.Topic, i.Config.ConsumerID, msg.MessageID).Err(); err != nil {
+	if err := i.Client.XAck(msg.Topic, i.Config.ConsumerGroup, msg.MessageID).Err(); err != nil {
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to acknowledge message %s", msg.MessageID), v1alpha2.InternalError)
 	}
 	return nil
 }
 
+func (i *RedisPubSubProvider) deadLetterTopic(topic string) string {
+	if i.Config.DeadLetterTopic != "" {
+		return i.Config.DeadLetterTopic
+	}
+	return topic + "-deadletter"
+}
+
+// deadLetter moves a message to the dead-letter topic, along with the topic and ID it had.
+func (i *RedisPubSubProvider) deadLetter(topic string, messageID string, data interface{}) error {
+	values := map[string]interface{}{"topic": topic, "messageID": messageID}
+	if data != nil {
+		values["data"] = data
+	}
+	_, err := i.Client.XAdd(&redis.XAddArgs{
+		Stream: i.deadLetterTopic(topic),
+		Values: values,
+	}).Result()
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to dead-letter message %s", messageID), v1alpha2.InternalError)
+	}
+	if err := i.Client.XAck(topic, i.Config.ConsumerGroup, messageID).Err(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to acknowledge message %s", messageID), v1alpha2.InternalError)
+	}
+	mLog.Debugf("  P (Redis PubSub) : moved message %s of topic %s to %s", messageID, topic, i.deadLetterTopic(topic))
+	return nil
+}
+
 func (i *RedisPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
 	_, err := i.Client.XAdd(&redis.XAddArgs{
 		Stream: topic,
@@ -224,7 +298,7 @@ func (i *RedisPubSubProvider) Publish(topic string, event v1alpha2.Event) error
 	return nil
 }
 func (i *RedisPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
-	err := i.Client.XGroupCreateMkStream(topic, i.Config.ConsumerID, "0").Err()
+	err := i.Client.XGroupCreateMkStream(topic, i.Config.ConsumerGroup, "0").Err()
 	//Ignore BUSYGROUP errors
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		mLog.Debugf("  P (Redis PubSub) : failed to subscribe %v", err)
@@ -241,7 +315,7 @@ func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, handler v1alpha2
 			return
 		}
 		streams, err := i.Client.XReadGroup(&redis.XReadGroupArgs{
-			Group:    i.Config.ConsumerID,
+			Group:    i.Config.ConsumerGroup,
 			Consumer: i.Config.ConsumerID,
 			Streams:  []string{topic, ">"},
 			Count:    int64(i.Config.QueueDepth),
@@ -302,27 +376,37 @@ func (i *RedisPubSubProvider) reclaimPendingMessages(topic string, handler v1alp
 	for {
 		pendingResult, err := i.Client.XPendingExt(&redis.XPendingExtArgs{
 			Stream: topic,
-			Group:  i.Config.ConsumerID,
+			Group:  i.Config.ConsumerGroup,
 			Start:  "-",
 			End:    "+",
-			Count:  int64(i.Config.QueueDepth),
+			Count:  i.pendingBatchSize(),
 		}).Result()
 		if err != nil && !errors.Is(err, redis.Nil) {
 			mLog.Debugf("  P (Redis PubSub) : failed to get pending message %v", err)
 			break
 		}
+		// pending messages include the ones of crashed consumers in the same group
 		msgIDs := make([]string, 0, len(pendingResult))
+		deadIDs := make([]string, 0)
 		for _, msg := range pendingResult {
 			if msg.Idle >= i.Config.ProcessingTimeout {
-				msgIDs = append(msgIDs, msg.ID)
+				if i.Config.MaxRetries > 0 && msg.RetryCount > int64(i.Config.MaxRetries) {
+					deadIDs = append(deadIDs, msg.ID)
+				} else {
+					msgIDs = append(msgIDs, msg.ID)
+				}
 			}
 		}
+		deadLettered := i.deadLetterPendingMessages(topic, deadIDs)
 		if len(msgIDs) == 0 {
-			break
+			if deadLettered == 0 {
+				break
+			}
+			continue
 		}
 		claimResult, err := i.Client.XClaim(&redis.XClaimArgs{
 			Stream:   topic,
-			Group:    i.Config.ConsumerID,
+			Group:    i.Config.ConsumerGroup,
 			Consumer: i.Config.ConsumerID,
 			MinIdle:  i.Config.ProcessingTimeout,
 			Messages: msgIDs,
@@ -354,7 +438,7 @@ func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic s
 	for pendingID := range messageIDs {
 		claimResultSingleMsg, err := i.Client.XClaim(&redis.XClaimArgs{
 			Stream:   topic,
-			Group:    i.Config.ConsumerID,
+			Group:    i.Config.ConsumerGroup,
 			Consumer: i.Config.ConsumerID,
 			MinIdle:  i.Config.ProcessingTimeout,
 			Messages: []string{pendingID},
@@ -364,7 +448,7 @@ func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic s
 			continue
 		}
 		if errors.Is(err, redis.Nil) {
-			if err = i.Client.XAck(topic, i.Config.ConsumerID, pendingID).Err(); err != nil {
+			if err = i.Client.XAck(topic, i.Config.ConsumerGroup, pendingID).Err(); err != nil {
 				mLog.Debugf("  P (Redis PubSub) : error acknowledging Redis message %s after failed claim for %s - %v", i.Config.ConsumerID, pendingID, err)
 			} else {
 				i.enqueueMessages(topic, handler, claimResultSingleMsg)
@@ -373,12 +457,52 @@ func (i *RedisPubSubProvider) removeMessagesThatNoLongerExistFromPending(topic s
 	}
 }
 
+// deadLetterPendingMessages claims messages that ran out of retries and moves them to the
+// dead-letter topic. It returns how many messages were moved.
+func (i *RedisPubSubProvider) deadLetterPendingMessages(topic string, messageIDs []string) int {
+	if len(messageIDs) == 0 {
+		return 0
+	}
+	// claiming makes sure another consumer isn't moving the same messages
+	claimResult, err := i.Client.XClaim(&redis.XClaimArgs{
+		Stream:   topic,
+		Group:    i.Config.ConsumerGroup,
+		Consumer: i.Config.ConsumerID,
+		MinIdle:  i.Config.ProcessingTimeout,
+		Messages: messageIDs,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		mLog.Debugf("  P (Redis PubSub) : failed to claim message to dead-letter %v", err)
+		return 0
+	}
+	count := 0
+	for _, msg := range claimResult {
+		if err := i.deadLetter(topic, msg.ID, msg.Values["data"]); err != nil {
+			mLog.Debugf("  P (Redis PubSub) : %v", err)
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func (i *RedisPubSubProvider) pendingBatchSize() int64 {
+	if i.Config.QueueDepth > 0 {
+		return int64(i.Config.QueueDepth)
+	}
+	return 100
+}
+
 func toRedisPubSubProviderConfig(config providers.IProviderConfig) (RedisPubSubProviderConfig, error) {
 	ret := RedisPubSubProviderConfig{}
 	data, err := json.Marshal(config)
 	if err != nil {
 		return ret, err
 	}
+	data, err = parseDurations(data, "processingTimeout", "redeliverInterval")
+	if err != nil {
+		return ret, err
+	}
 	err = json.Unmarshal(data, &ret)
 	//ret.Name = providers.LoadEnv(ret.Name)
 	//ret.Host = providers.LoadEnv(ret.Host)
@@ -388,3 +512,21 @@ func toRedisPubSubProviderConfig(config providers.IProviderConfig) (RedisPubSubP
 	}
 	return ret, err
 }
+
+// parseDurations converts duration settings written as strings, such as "30s", to nanoseconds.
+func parseDurations(data []byte, keys ...string) ([]byte, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return data, err
+	}
+	for _, key := range keys {
+		if v, ok := values[key].(string); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return data, err
+			}
+			values[key] = d
+		}
+	}
+	return json.Marshal(values)
+}
@@ -8,11 +8,15 @@ package redis
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/go-redis/redis/v7"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -311,3 +315,197 @@ func TestRedisPubSubProviderConfigFromMap(t *testing.T) {
 	assert.Equal(t, time.Duration(10), config.ProcessingTimeout)
 	assert.Equal(t, time.Duration(10), config.RedeliverInterval)
 }
+
+func TestRedisPubSubProviderConfigFromMapConsumerGroup(t *testing.T) {
+	config, err := RedisPubSubProviderConfigFromMap(map[string]string{
+		"host":            "localhost:6379",
+		"consumerGroup":   "symphony-api",
+		"maxRetries":      "3",
+		"deadLetterTopic": "dead",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "symphony-api", config.ConsumerGroup)
+	assert.Equal(t, 3, config.MaxRetries)
+	assert.Equal(t, "dead", config.DeadLetterTopic)
+
+	_, err = RedisPubSubProviderConfigFromMap(map[string]string{
+		"host":       "localhost:6379",
+		"maxRetries": "-1",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestInitWithDurationStrings(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := RedisPubSubProvider{}
+	err := provider.Init(map[string]interface{}{
+		"host":              server.Addr(),
+		"consumerGroup":     "symphony-api",
+		"processingTimeout": "30s",
+		"redeliverInterval": "5s",
+	})
+	assert.Nil(t, err)
+	defer provider.Cancel()
+	assert.Equal(t, 30*time.Second, provider.Config.ProcessingTimeout)
+	assert.Equal(t, 5*time.Second, provider.Config.RedeliverInterval)
+	assert.Equal(t, "symphony-api", provider.Config.ConsumerGroup)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, provider.Config.ConsumerID)
+}
+
+func initMiniredisProvider(t *testing.T, server *miniredis.Miniredis, config RedisPubSubProviderConfig) *RedisPubSubProvider {
+	config.Host = server.Addr()
+	provider := &RedisPubSubProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	t.Cleanup(provider.Cancel)
+	return provider
+}
+
+func TestConsumerGroupSharesMessages(t *testing.T) {
+	server := miniredis.RunT(t)
+	lock := sync.Mutex{}
+	received := make(map[string]int)
+	consumers := make(map[string]int)
+	done := make(chan bool, 10)
+	for _, id := range []string{"replica-1", "replica-2"} {
+		consumer := id
+		provider := initMiniredisProvider(t, server, RedisPubSubProviderConfig{
+			ConsumerGroup: "symphony-api",
+			ConsumerID:    consumer,
+			QueueDepth:    1,
+		})
+		err := provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+			lock.Lock()
+			received[message.Body.(string)]++
+			consumers[consumer]++
+			lock.Unlock()
+			done <- true
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+	publisher := initMiniredisProvider(t, server, RedisPubSubProviderConfig{ConsumerID: "publisher"})
+	for _, body := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		assert.Nil(t, publisher.Publish("job", v1alpha2.Event{Body: body}))
+	}
+	for k := 0; k < 10; k++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+	// give a second delivery a chance to show up
+	time.Sleep(100 * time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 10, len(received))
+	for body, count := range received {
+		assert.Equal(t, 1, count, body)
+	}
+	assert.Equal(t, 10, consumers["replica-1"]+consumers["replica-2"])
+	pending, err := publisher.Client.XPending("job", "symphony-api").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestReclaimPendingMessagesOfCrashedConsumer(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	assert.Nil(t, client.XGroupCreateMkStream("job", "symphony-api", "0").Err())
+	publisher := initMiniredisProvider(t, server, RedisPubSubProviderConfig{ConsumerID: "publisher"})
+	assert.Nil(t, publisher.Publish("job", v1alpha2.Event{Body: "TEST"}))
+	// a replica reads the message and crashes before acknowledging it
+	streams, err := client.XReadGroup(&redis.XReadGroupArgs{
+		Group:    "symphony-api",
+		Consumer: "crashed",
+		Streams:  []string{"job", ">"},
+	}).Result()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(streams[0].Messages))
+
+	sig := make(chan string, 1)
+	provider := initMiniredisProvider(t, server, RedisPubSubProviderConfig{
+		ConsumerGroup:     "symphony-api",
+		ConsumerID:        "replica-2",
+		ProcessingTimeout: 50 * time.Millisecond,
+		RedeliverInterval: 50 * time.Millisecond,
+	})
+	err = provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+		sig <- message.Body.(string)
+		return nil
+	})
+	assert.Nil(t, err)
+	select {
+	case msg := <-sig:
+		assert.Equal(t, "TEST", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending message wasn't reclaimed")
+	}
+	assert.Eventually(t, func() bool {
+		pending, err := client.XPending("job", "symphony-api").Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func TestDeadLetterAfterMaxRetries(t *testing.T) {
+	server := miniredis.RunT(t)
+	lock := sync.Mutex{}
+	attempts := 0
+	provider := initMiniredisProvider(t, server, RedisPubSubProviderConfig{
+		ConsumerGroup:     "symphony-api",
+		ConsumerID:        "replica-1",
+		ProcessingTimeout: 20 * time.Millisecond,
+		RedeliverInterval: 20 * time.Millisecond,
+		MaxRetries:        2,
+	})
+	err := provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+		lock.Lock()
+		attempts++
+		lock.Unlock()
+		return errors.New("failed")
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Publish("job", v1alpha2.Event{Body: "TEST"}))
+
+	assert.Eventually(t, func() bool {
+		n, err := provider.Client.XLen("job-deadletter").Result()
+		return err == nil && n == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, 3, attempts)
+	lock.Unlock()
+
+	msgs, err := provider.Client.XRange("job-deadletter", "-", "+").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, "job", msgs[0].Values["topic"])
+	var evt v1alpha2.Event
+	assert.Nil(t, json.Unmarshal([]byte(msgs[0].Values["data"].(string)), &evt))
+	assert.Equal(t, "TEST", evt.Body)
+	pending, err := provider.Client.XPending("job", "symphony-api").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestDeadLetterUnreadableMessage(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := initMiniredisProvider(t, server, RedisPubSubProviderConfig{
+		ConsumerGroup:   "symphony-api",
+		ConsumerID:      "replica-1",
+		DeadLetterTopic: "dead",
+	})
+	err := provider.Subscribe("job", func(topic string, message v1alpha2.Event) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Client.XAdd(&redis.XAddArgs{
+		Stream: "job",
+		Values: map[string]interface{}{"data": "not an event"},
+	}).Err())
+	assert.Eventually(t, func() bool {
+		n, err := provider.Client.XLen("dead").Result()
+		return err == nil && n == 1
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,469 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filequeue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var fLog = logger.NewLogger("coa.runtime")
+
+const (
+	fileVersion              = 1
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultMaxAttempts       = 5
+)
+
+type FileQueueProviderConfig struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// VisibilityTimeout is how long a received message stays hidden before it's delivered again, such as "5m"
+	VisibilityTimeout string `json:"visibilityTimeout,omitempty"`
+	// MaxAttempts is how many times a message is delivered before it's moved to the dead letters
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+}
+
+func FileQueueProviderConfigFromMap(properties map[string]string) (FileQueueProviderConfig, error) {
+	ret := FileQueueProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["path"]; ok {
+		ret.Path = utils.ParseProperty(v)
+	}
+	if v, ok := properties["visibilityTimeout"]; ok {
+		ret.VisibilityTimeout = utils.ParseProperty(v)
+	}
+	if v, ok := properties["maxAttempts"]; ok {
+		n, err := strconv.Atoi(utils.ParseProperty(v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid maxAttempts '%s'", v), v1alpha2.BadConfig)
+		}
+		ret.MaxAttempts = n
+	}
+	return ret, nil
+}
+
+type fileMessage struct {
+	ID             string          `json:"id"`
+	Element        json.RawMessage `json:"element"`
+	Attempts       int             `json:"attempts"`
+	InvisibleUntil time.Time       `json:"invisibleUntil,omitempty"`
+}
+
+type fileQueue struct {
+	Messages    []fileMessage `json:"messages"`
+	DeadLetters []fileMessage `json:"deadLetters,omitempty"`
+}
+
+type fileContent struct {
+	Version int                   `json:"version"`
+	Queues  map[string]*fileQueue `json:"queues"`
+}
+
+// FileQueueProvider keeps queues in memory and persists every change to a single JSON file,
+// so that queued elements and unacknowledged messages survive a restart. Elements are stored
+// as JSON, so after a restart they're returned as generic JSON values.
+type FileQueueProvider struct {
+	Config            FileQueueProviderConfig
+	Data              map[string]*fileQueue
+	Context           *contexts.ManagerContext
+	visibilityTimeout time.Duration
+	maxAttempts       int
+	lock              sync.Mutex
+}
+
+func (s *FileQueueProvider) ID() string {
+	return s.Config.Name
+}
+
+func (s *FileQueueProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *FileQueueProvider) InitWithMap(properties map[string]string) error {
+	config, err := FileQueueProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func toFileQueueProviderConfig(config providers.IProviderConfig) (FileQueueProviderConfig, error) {
+	ret := FileQueueProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	data, err = parseNumbers(data, "maxAttempts")
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// parseNumbers converts number settings written as strings, such as "5", to numbers.
+func parseNumbers(data []byte, keys ...string) ([]byte, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return data, err
+	}
+	for _, key := range keys {
+		if v, ok := values[key].(string); ok {
+			n, err := strconv.Atoi(utils.ParseProperty(v))
+			if err != nil {
+				return data, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid %s '%s'", key, v), v1alpha2.BadConfig)
+			}
+			values[key] = n
+		}
+	}
+	return json.Marshal(values)
+}
+
+func (s *FileQueueProvider) Init(config providers.IProviderConfig) error {
+	queueConfig, err := toFileQueueProviderConfig(config)
+	if err != nil {
+		fLog.Errorf("  P (File Queue): failed to parse provider config %+v", err)
+		if coaErr, ok := err.(v1alpha2.COAError); ok {
+			return coaErr
+		}
+		return errors.New("expected FileQueueProviderConfig")
+	}
+	if queueConfig.Path == "" {
+		err = v1alpha2.NewCOAError(nil, "file queue provider requires a 'path' setting", v1alpha2.BadConfig)
+		fLog.Errorf("  P (File Queue): %+v", err)
+		return err
+	}
+	s.visibilityTimeout = defaultVisibilityTimeout
+	if queueConfig.VisibilityTimeout != "" {
+		s.visibilityTimeout, err = time.ParseDuration(queueConfig.VisibilityTimeout)
+		if err != nil || s.visibilityTimeout <= 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid visibilityTimeout '%s', expected a positive duration", queueConfig.VisibilityTimeout), v1alpha2.BadConfig)
+		}
+	}
+	s.maxAttempts = defaultMaxAttempts
+	if queueConfig.MaxAttempts < 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid maxAttempts '%d', expected a positive integer", queueConfig.MaxAttempts), v1alpha2.BadConfig)
+	} else if queueConfig.MaxAttempts > 0 {
+		s.maxAttempts = queueConfig.MaxAttempts
+	}
+	s.Config = queueConfig
+	s.Data = make(map[string]*fileQueue)
+	return s.load()
+}
+
+func (s *FileQueueProvider) load() error {
+	data, err := utils.ReadFileIfExists(s.Config.Path)
+	if err != nil {
+		fLog.Errorf("  P (File Queue): failed to read queue file %s: %+v", s.Config.Path, err)
+		return v1alpha2.NewCOAError(err, "failed to read queue file", v1alpha2.FileAccessError)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	var content fileContent
+	err = json.Unmarshal(data, &content)
+	if err != nil {
+		fLog.Errorf("  P (File Queue): failed to parse queue file %s: %+v", s.Config.Path, err)
+		return v1alpha2.NewCOAError(err, "failed to parse queue file", v1alpha2.SerializationError)
+	}
+	if content.Queues != nil {
+		s.Data = content.Queues
+	}
+	return nil
+}
+
+// save writes all queues to the queue file atomically
+func (s *FileQueueProvider) save() error {
+	data, err := json.Marshal(fileContent{
+		Version: fileVersion,
+		Queues:  s.Data,
+	})
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to serialize queues", v1alpha2.SerializationError)
+	}
+	err = utils.WriteFileAtomic(s.Config.Path, data)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write queue file", v1alpha2.FileAccessError)
+	}
+	return nil
+}
+
+func (s *FileQueueProvider) Enqueue(name string, element interface{}) error {
+	data, err := json.Marshal(element)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to serialize queue element", v1alpha2.SerializationError)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, found := s.Data[name]
+	q := s.getQueue(name)
+	previous := q.snapshot()
+	q.Messages = append(q.Messages, fileMessage{
+		ID:      uuid.New().String(),
+		Element: data,
+	})
+	err = s.save()
+	if err != nil {
+		if found {
+			*q = previous
+		} else {
+			delete(s.Data, name)
+		}
+		fLog.Errorf("  P (File Queue): failed to persist new element of queue %s: %+v", name, err)
+		return err
+	}
+	return nil
+}
+
+// Dequeue removes the next visible element from the queue right away, without waiting for an acknowledgement.
+func (s *FileQueueProvider) Dequeue(name string) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, ok := s.Data[name]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "queue not found", v1alpha2.NotFound)
+	}
+	previous := q.snapshot()
+	index, changed := s.nextVisible(q, time.Now().UTC())
+	var element interface{}
+	var err error
+	if index >= 0 {
+		element, err = decodeElement(q.Messages[index].Element)
+		if err != nil {
+			*q = previous
+			return nil, err
+		}
+		q.Messages = append(q.Messages[:index], q.Messages[index+1:]...)
+		changed = true
+	}
+	if changed {
+		if err = s.save(); err != nil {
+			*q = previous
+			fLog.Errorf("  P (File Queue): failed to persist dequeue from queue %s: %+v", name, err)
+			return nil, err
+		}
+	}
+	if index < 0 {
+		return nil, v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+	}
+	return element, nil
+}
+
+func (s *FileQueueProvider) Peek(name string) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, ok := s.Data[name]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "queue not found", v1alpha2.NotFound)
+	}
+	now := time.Now().UTC()
+	for _, m := range q.Messages {
+		if !m.InvisibleUntil.After(now) && m.Attempts < s.maxAttempts {
+			return decodeElement(m.Element)
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+}
+
+// Size returns the number of visible elements on the queue. Received messages that are
+// waiting to be acknowledged aren't counted.
+func (s *FileQueueProvider) Size(name string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, ok := s.Data[name]
+	if !ok {
+		return 0
+	}
+	now := time.Now().UTC()
+	count := 0
+	for _, m := range q.Messages {
+		if !m.InvisibleUntil.After(now) && m.Attempts < s.maxAttempts {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *FileQueueProvider) Receive(name string, visibilityTimeout time.Duration) (queue.QueueMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, ok := s.Data[name]
+	if !ok {
+		return queue.QueueMessage{}, v1alpha2.NewCOAError(nil, "queue not found", v1alpha2.NotFound)
+	}
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = s.visibilityTimeout
+	}
+	now := time.Now().UTC()
+	previous := q.snapshot()
+	index, changed := s.nextVisible(q, now)
+	var ret queue.QueueMessage
+	if index >= 0 {
+		m := &q.Messages[index]
+		element, err := decodeElement(m.Element)
+		if err != nil {
+			*q = previous
+			return queue.QueueMessage{}, err
+		}
+		m.Attempts++
+		m.InvisibleUntil = now.Add(visibilityTimeout)
+		ret = queue.QueueMessage{ID: m.ID, Element: element, Attempts: m.Attempts}
+		changed = true
+	}
+	if changed {
+		if err := s.save(); err != nil {
+			*q = previous
+			fLog.Errorf("  P (File Queue): failed to persist receive from queue %s: %+v", name, err)
+			return queue.QueueMessage{}, err
+		}
+	}
+	if index < 0 {
+		return queue.QueueMessage{}, v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+	}
+	return ret, nil
+}
+
+func (s *FileQueueProvider) Ack(name string, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, index := s.findMessage(name, id)
+	if index < 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("message '%s' is not found", id), v1alpha2.NotFound)
+	}
+	previous := q.snapshot()
+	q.Messages = append(q.Messages[:index], q.Messages[index+1:]...)
+	err := s.save()
+	if err != nil {
+		*q = previous
+		fLog.Errorf("  P (File Queue): failed to persist ack of message %s of queue %s: %+v", id, name, err)
+		return err
+	}
+	return nil
+}
+
+func (s *FileQueueProvider) Nack(name string, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	q, index := s.findMessage(name, id)
+	if index < 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("message '%s' is not found", id), v1alpha2.NotFound)
+	}
+	previous := q.snapshot()
+	if q.Messages[index].Attempts >= s.maxAttempts {
+		fLog.Infof("  P (File Queue): message %s of queue %s ran out of attempts", id, name)
+		s.deadLetter(q, index)
+	} else {
+		q.Messages[index].InvisibleUntil = time.Time{}
+	}
+	err := s.save()
+	if err != nil {
+		*q = previous
+		fLog.Errorf("  P (File Queue): failed to persist nack of message %s of queue %s: %+v", id, name, err)
+		return err
+	}
+	return nil
+}
+
+func (s *FileQueueProvider) DeadLetters(name string) ([]queue.QueueMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ret := make([]queue.QueueMessage, 0)
+	q, ok := s.Data[name]
+	if !ok {
+		return ret, nil
+	}
+	for _, m := range q.DeadLetters {
+		element, err := decodeElement(m.Element)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, queue.QueueMessage{ID: m.ID, Element: element, Attempts: m.Attempts})
+	}
+	return ret, nil
+}
+
+func (s *FileQueueProvider) getQueue(name string) *fileQueue {
+	q, ok := s.Data[name]
+	if !ok {
+		q = &fileQueue{Messages: make([]fileMessage, 0)}
+		s.Data[name] = q
+	}
+	return q
+}
+
+func (s *FileQueueProvider) findMessage(name string, id string) (*fileQueue, int) {
+	q, ok := s.Data[name]
+	if !ok {
+		return nil, -1
+	}
+	for i, m := range q.Messages {
+		if m.ID == id {
+			return q, i
+		}
+	}
+	return q, -1
+}
+
+// nextVisible returns the index of the next message that can be delivered, or -1. Messages that
+// timed out on their last attempt are moved to the dead letters on the way, which is reported as
+// a change that needs to be saved.
+func (s *FileQueueProvider) nextVisible(q *fileQueue, now time.Time) (int, bool) {
+	changed := false
+	for i := 0; i < len(q.Messages); i++ {
+		m := q.Messages[i]
+		if m.InvisibleUntil.After(now) {
+			continue
+		}
+		if m.Attempts >= s.maxAttempts {
+			s.deadLetter(q, i)
+			changed = true
+			i--
+			continue
+		}
+		return i, changed
+	}
+	return -1, changed
+}
+
+// snapshot copies the messages of a queue, so that a change can be rolled back if it fails to persist
+func (q *fileQueue) snapshot() fileQueue {
+	return fileQueue{
+		Messages:    append([]fileMessage(nil), q.Messages...),
+		DeadLetters: append([]fileMessage(nil), q.DeadLetters...),
+	}
+}
+
+func (s *FileQueueProvider) deadLetter(q *fileQueue, index int) {
+	m := q.Messages[index]
+	m.InvisibleUntil = time.Time{}
+	q.DeadLetters = append(q.DeadLetters, m)
+	q.Messages = append(q.Messages[:index], q.Messages[index+1:]...)
+}
+
+func decodeElement(data json.RawMessage) (interface{}, error) {
+	var element interface{}
+	err := json.Unmarshal(data, &element)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to deserialize queue element", v1alpha2.SerializationError)
+	}
+	return element, nil
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filequeue
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func createQueue(t *testing.T, path string, maxAttempts int) *FileQueueProvider {
+	provider := &FileQueueProvider{}
+	err := provider.Init(FileQueueProviderConfig{Path: path, MaxAttempts: maxAttempts})
+	assert.Nil(t, err)
+	return provider
+}
+func TestInitWithMap(t *testing.T) {
+	provider := &FileQueueProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":              "name",
+		"path":              filepath.Join(t.TempDir(), "queue.json"),
+		"visibilityTimeout": "30s",
+		"maxAttempts":       "3",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, provider.visibilityTimeout)
+	assert.Equal(t, 3, provider.maxAttempts)
+	var _ queue.IAckQueueProvider = provider
+}
+func TestInitWithDocumentedConfig(t *testing.T) {
+	// the provider config of the queue providers docs, as the providers factory passes it on
+	var config map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"name": "file-queue",
+		"path": "`+filepath.ToSlash(filepath.Join(t.TempDir(), "queue.json"))+`",
+		"visibilityTimeout": "5m",
+		"maxAttempts": "5"
+	}`), &config)
+	assert.Nil(t, err)
+	provider := &FileQueueProvider{}
+	err = provider.Init(config)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, provider.visibilityTimeout)
+	assert.Equal(t, 5, provider.maxAttempts)
+
+	config["maxAttempts"] = "many"
+	err = provider.Init(config)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	config["maxAttempts"] = 3
+	err = provider.Init(config)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, provider.maxAttempts)
+}
+func TestInitBadConfig(t *testing.T) {
+	provider := &FileQueueProvider{}
+	err := provider.Init(FileQueueProviderConfig{})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(FileQueueProviderConfig{Path: filepath.Join(t.TempDir(), "queue.json"), VisibilityTimeout: "soon"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.InitWithMap(map[string]string{"path": "queue.json", "maxAttempts": "many"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+func TestEnqueueDequeue(t *testing.T) {
+	provider := createQueue(t, filepath.Join(t.TempDir(), "queue.json"), 0)
+	provider.Enqueue("queue1", "a")
+	provider.Enqueue("queue1", "b")
+	assert.Equal(t, 2, provider.Size("queue1"))
+	element, err := provider.Peek("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	element, err = provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	element, err = provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+	_, err = provider.Dequeue("queue1")
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = provider.Peek("queue2")
+	assert.True(t, v1alpha2.IsNotFound(err))
+	assert.Equal(t, 0, provider.Size("queue2"))
+}
+func TestSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	provider := createQueue(t, path, 0)
+	provider.Enqueue("queue1", map[string]interface{}{"id": "job1"})
+	provider.Enqueue("queue1", "b")
+	message, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+
+	// the received message isn't acknowledged before the restart, so it's still on the queue
+	provider = createQueue(t, path, 0)
+	assert.Equal(t, 1, provider.Size("queue1"))
+	err = provider.Ack("queue1", message.ID)
+	assert.Nil(t, err)
+	element, err := provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+
+	provider = createQueue(t, path, 0)
+	assert.Equal(t, 0, provider.Size("queue1"))
+}
+func TestReceiveHidesMessage(t *testing.T) {
+	provider := createQueue(t, filepath.Join(t.TempDir(), "queue.json"), 0)
+	provider.Enqueue("queue1", "a")
+	provider.Enqueue("queue1", "b")
+
+	message, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", message.Element)
+	assert.Equal(t, 1, message.Attempts)
+	assert.Equal(t, 1, provider.Size("queue1"))
+
+	message2, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", message2.Element)
+	_, err = provider.Receive("queue1", time.Hour)
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	assert.Nil(t, provider.Ack("queue1", message.ID))
+	assert.True(t, v1alpha2.IsNotFound(provider.Ack("queue1", message.ID)))
+	assert.Nil(t, provider.Nack("queue1", message2.ID))
+
+	message2, err = provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", message2.Element)
+	assert.Equal(t, 2, message2.Attempts)
+}
+func TestVisibilityTimeout(t *testing.T) {
+	provider := createQueue(t, filepath.Join(t.TempDir(), "queue.json"), 0)
+	provider.Enqueue("queue1", "a")
+
+	_, err := provider.Receive("queue1", 10*time.Millisecond)
+	assert.Nil(t, err)
+	_, err = provider.Receive("queue1", 10*time.Millisecond)
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	time.Sleep(20 * time.Millisecond)
+	message, err := provider.Receive("queue1", 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", message.Element)
+	assert.Equal(t, 2, message.Attempts)
+}
+func TestDeadLetters(t *testing.T) {
+	provider := createQueue(t, filepath.Join(t.TempDir(), "queue.json"), 2)
+	provider.Enqueue("queue1", "a")
+	provider.Enqueue("queue1", "b")
+
+	message, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Nack("queue1", message.ID))
+	message, err = provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", message.Element)
+	assert.Nil(t, provider.Nack("queue1", message.ID))
+
+	// a ran out of attempts
+	deadLetters, err := provider.DeadLetters("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deadLetters))
+	assert.Equal(t, "a", deadLetters[0].Element)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+
+	// b times out on its last attempt
+	message, err = provider.Receive("queue1", 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Nack("queue1", message.ID))
+	_, err = provider.Receive("queue1", 10*time.Millisecond)
+	assert.Nil(t, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 0, provider.Size("queue1"))
+	_, err = provider.Receive("queue1", time.Hour)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	deadLetters, err = provider.DeadLetters("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(deadLetters))
+}
+func TestRollbackWhenSaveFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queues")
+	provider := createQueue(t, filepath.Join(dir, "queue.json"), 0)
+	assert.Nil(t, provider.Enqueue("queue1", "a"))
+	message, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Nil(t, provider.Enqueue("queue1", "b"))
+
+	// replace the queue folder with a file, so that nothing can be saved
+	assert.Nil(t, os.RemoveAll(dir))
+	assert.Nil(t, os.WriteFile(dir, []byte{}, 0644))
+
+	assert.NotNil(t, provider.Enqueue("queue1", "c"))
+	assert.NotNil(t, provider.Enqueue("queue2", "c"))
+	_, err = provider.Receive("queue1", time.Hour)
+	assert.NotNil(t, err)
+	_, err = provider.Dequeue("queue1")
+	assert.NotNil(t, err)
+	assert.NotNil(t, provider.Ack("queue1", message.ID))
+	assert.NotNil(t, provider.Nack("queue1", message.ID))
+
+	// none of the failed changes are kept in memory
+	assert.Equal(t, 1, provider.Size("queue1"))
+	assert.Equal(t, 0, provider.Size("queue2"))
+	_, ok := provider.Data["queue2"]
+	assert.False(t, ok)
+	_, index := provider.findMessage("queue1", message.ID)
+	assert.True(t, index >= 0)
+	element, err := provider.Peek("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+
+	assert.Nil(t, os.Remove(dir))
+	message2, err := provider.Receive("queue1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", message2.Element)
+	assert.Equal(t, 1, message2.Attempts)
+}
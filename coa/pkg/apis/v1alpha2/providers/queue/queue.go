@@ -6,9 +6,35 @@
 
 package queue
 
+import "time"
+
 type IQueueProvider interface {
 	Enqueue(queue string, element interface{}) error
 	Dequeue(queue string) (interface{}, error)
 	Peek(queue string) (interface{}, error)
 	Size(queue string) int
 }
+
+// QueueMessage is an element received from a queue. It stays on the queue, hidden from other
+// receivers, until it's acknowledged or its visibility timeout expires.
+type QueueMessage struct {
+	ID       string      `json:"id"`
+	Element  interface{} `json:"element"`
+	Attempts int         `json:"attempts"`
+}
+
+// IAckQueueProvider is implemented by queue providers that support at-least-once processing.
+// A received message that is neither acknowledged nor rejected becomes visible again after its
+// visibility timeout, and a message that fails too many times is moved to the dead letters.
+type IAckQueueProvider interface {
+	IQueueProvider
+	// Receive returns the next visible message, hiding it for the visibility timeout. A timeout
+	// of 0 uses the provider's default.
+	Receive(queue string, visibilityTimeout time.Duration) (QueueMessage, error)
+	// Ack removes a processed message from the queue.
+	Ack(queue string, id string) error
+	// Nack makes a message that failed to process visible again right away.
+	Nack(queue string, id string) error
+	// DeadLetters returns the messages that ran out of attempts.
+	DeadLetters(queue string) ([]QueueMessage, error)
+}
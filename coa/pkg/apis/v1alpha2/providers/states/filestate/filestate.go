@@ -0,0 +1,436 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filestate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	defaultScope   = "default"
+	fileVersion    = 1
+	firstWrite     = "first-write"
+	filterJsonPath = "jsonpath"
+)
+
+type FileStateProviderConfig struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+func FileStateProviderConfigFromMap(properties map[string]string) (FileStateProviderConfig, error) {
+	ret := FileStateProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["path"]; ok {
+		ret.Path = utils.ParseProperty(v)
+	}
+	return ret, nil
+}
+
+// fileEntry is the on-disk representation of a state entry. Scope and resource are
+// recorded alongside the body so that entries of different kinds sharing the same
+// ID don't collide, mirroring how the K8s state provider partitions objects.
+type fileEntry struct {
+	ID       string      `json:"id"`
+	Scope    string      `json:"scope"`
+	Resource string      `json:"resource,omitempty"`
+	ETag     string      `json:"etag"`
+	Body     interface{} `json:"body"`
+}
+
+type fileContent struct {
+	Version int                  `json:"version"`
+	Entries map[string]fileEntry `json:"entries"`
+}
+
+// FileStateProvider keeps states in memory and persists every change to a single
+// JSON file, so that a standalone Symphony instance retains its objects across restarts.
+type FileStateProvider struct {
+	Config  FileStateProviderConfig
+	Data    map[string]fileEntry
+	Context *contexts.ManagerContext
+	lock    sync.RWMutex
+	hub     *states.WatchHub
+}
+
+func (s *FileStateProvider) ID() string {
+	return s.Config.Name
+}
+
+func (s *FileStateProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *FileStateProvider) InitWithMap(properties map[string]string) error {
+	config, err := FileStateProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *FileStateProvider) Init(config providers.IProviderConfig) error {
+	stateConfig, err := toFileStateProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to parse provider config %+v", err)
+		return errors.New("expected FileStateProviderConfig")
+	}
+	if stateConfig.Path == "" {
+		err = v1alpha2.NewCOAError(nil, "file state provider requires a 'path' setting", v1alpha2.BadConfig)
+		sLog.Errorf("  P (File State): %+v", err)
+		return err
+	}
+	s.Config = stateConfig
+	s.Data = make(map[string]fileEntry)
+	s.hub = &states.WatchHub{}
+	return s.load()
+}
+
+func (s *FileStateProvider) load() error {
+	data, err := utils.ReadFileIfExists(s.Config.Path)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to read state file %s: %+v", s.Config.Path, err)
+		return v1alpha2.NewCOAError(err, "failed to read state file", v1alpha2.FileAccessError)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	var content fileContent
+	err = json.Unmarshal(data, &content)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to parse state file %s: %+v", s.Config.Path, err)
+		return v1alpha2.NewCOAError(err, "failed to parse state file", v1alpha2.SerializationError)
+	}
+	if content.Entries != nil {
+		s.Data = content.Entries
+	}
+	return nil
+}
+
+// save writes the complete state to the state file atomically
+func (s *FileStateProvider) save() error {
+	data, err := json.Marshal(fileContent{
+		Version: fileVersion,
+		Entries: s.Data,
+	})
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to serialize states", v1alpha2.SerializationError)
+	}
+	err = utils.WriteFileAtomic(s.Config.Path, data)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write state file", v1alpha2.FileAccessError)
+	}
+	return nil
+}
+
+func readScope(metadata map[string]string) string {
+	if v, ok := metadata["scope"]; ok && v != "" {
+		return v
+	}
+	return defaultScope
+}
+
+func readResource(metadata map[string]string) string {
+	group := metadata["group"]
+	resource := metadata["resource"]
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
+
+func entryKey(resource string, scope string, id string) string {
+	return resource + "/" + scope + "/" + id
+}
+
+// normalize round-trips the body through JSON so that entries read back from memory
+// have the same shape as entries loaded from disk.
+func normalize(body interface{}) (interface{}, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func toStateEntry(entry fileEntry) states.StateEntry {
+	body := entry.Body
+	if dict, ok := body.(map[string]interface{}); ok {
+		copied := make(map[string]interface{}, len(dict)+1)
+		for k, v := range dict {
+			copied[k] = v
+		}
+		copied["scope"] = entry.Scope
+		body = copied
+	}
+	return states.StateEntry{
+		ID:   entry.ID,
+		ETag: entry.ETag,
+		Body: body,
+	}
+}
+
+func checkETag(concurrency string, etag *string, existing fileEntry, found bool, id string) error {
+	if concurrency != firstWrite || etag == nil {
+		return nil
+	}
+	if !found {
+		if *etag != "" {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found", id), v1alpha2.NotFound)
+		}
+		return nil
+	}
+	if *etag != existing.ETag {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, expected etag '%s' but found '%s'", id, *etag, existing.ETag), v1alpha2.Conflict)
+	}
+	return nil
+}
+
+func (s *FileStateProvider) Upsert(ctx context.Context, entry states.UpsertRequest) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, span := observability.StartSpan("File State Provider", ctx, &map[string]string{
+		"method": "Upsert",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Debugf("  P (File State): upsert state %s, traceId: %s", entry.Value.ID, span.SpanContext().TraceID().String())
+
+	scope := readScope(entry.Metadata)
+	resource := readResource(entry.Metadata)
+	key := entryKey(resource, scope, entry.Value.ID)
+
+	existing, found := s.Data[key]
+	err = checkETag(entry.Options.Concurrency, entry.ETag, existing, found, entry.Value.ID)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to upsert %s: %+v, traceId: %s", entry.Value.ID, err, span.SpanContext().TraceID().String())
+		return "", err
+	}
+
+	var body interface{}
+	body, err = normalize(entry.Value.Body)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "failed to serialize state entry", v1alpha2.SerializationError)
+		sLog.Errorf("  P (File State): failed to upsert %s: %+v, traceId: %s", entry.Value.ID, err, span.SpanContext().TraceID().String())
+		return "", err
+	}
+
+	// Like the K8s state provider, a status-only update keeps the current spec and a
+	// spec-only update keeps the current status.
+	if dict, ok := body.(map[string]interface{}); ok && found {
+		if oldDict, ok := existing.Body.(map[string]interface{}); ok {
+			if _, ok := dict["spec"]; !ok && oldDict["spec"] != nil {
+				dict["spec"] = oldDict["spec"]
+			}
+			if _, ok := dict["status"]; !ok && oldDict["status"] != nil {
+				dict["status"] = oldDict["status"]
+			}
+		}
+		delete(dict, "scope")
+	}
+
+	tag := "1"
+	if found {
+		if v, pErr := strconv.ParseInt(existing.ETag, 10, 64); pErr == nil {
+			tag = strconv.FormatInt(v+1, 10)
+		}
+	}
+
+	newEntry := fileEntry{
+		ID:       entry.Value.ID,
+		Scope:    scope,
+		Resource: resource,
+		ETag:     tag,
+		Body:     body,
+	}
+	s.Data[key] = newEntry
+	err = s.save()
+	if err != nil {
+		if found {
+			s.Data[key] = existing
+		} else {
+			delete(s.Data, key)
+		}
+		sLog.Errorf("  P (File State): failed to persist %s: %+v, traceId: %s", entry.Value.ID, err, span.SpanContext().TraceID().String())
+		return "", err
+	}
+	s.hub.Publish(states.WatchEvent{
+		Type:            states.WatchEventUpsert,
+		Entry:           toStateEntry(newEntry),
+		ResourceVersion: s.hub.NextVersion(),
+		Metadata:        eventMetadata(entry.Metadata, scope),
+	})
+	return entry.Value.ID, nil
+}
+
+func (s *FileStateProvider) List(ctx context.Context, request states.ListRequest) ([]states.StateEntry, string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, span := observability.StartSpan("File State Provider", ctx, &map[string]string{
+		"method": "List",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Debugf("  P (File State): list states, traceId: %s", span.SpanContext().TraceID().String())
+
+	scope := request.Metadata["scope"]
+	resource := readResource(request.Metadata)
+
+	// filters are applied before paging, so that every page except the last one is full
+	keys := make([]string, 0, len(s.Data))
+	for k, v := range s.Data {
+		if v.Resource != resource {
+			continue
+		}
+		if scope != "" && v.Scope != scope {
+			continue
+		}
+		if request.Filter != "" {
+			switch request.FilterType {
+			case filterJsonPath:
+				if !states.JsonPathMatch(v.Body, request.Filter, request.FilterParameters["value"]) {
+					continue
+				}
+			default:
+				err = v1alpha2.NewCOAError(nil, fmt.Sprintf("filter type '%s' is not supported", request.FilterType), v1alpha2.BadRequest)
+				sLog.Errorf("  P (File State): failed to list states: %+v, traceId: %s", err, span.SpanContext().TraceID().String())
+				return nil, "", err
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	var token string
+	keys, token, err = states.PageKeys(keys, request.Limit, request.Continue)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to list states: %+v, traceId: %s", err, span.SpanContext().TraceID().String())
+		return nil, "", err
+	}
+	var entities []states.StateEntry
+	for _, k := range keys {
+		entities = append(entities, toStateEntry(s.Data[k]))
+	}
+	return entities, token, nil
+}
+
+func (s *FileStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, span := observability.StartSpan("File State Provider", ctx, &map[string]string{
+		"method": "Delete",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Debugf("  P (File State): delete state %s, traceId: %s", request.ID, span.SpanContext().TraceID().String())
+
+	key := entryKey(readResource(request.Metadata), readScope(request.Metadata), request.ID)
+	existing, found := s.Data[key]
+	if !found {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found", request.ID), v1alpha2.NotFound)
+		sLog.Errorf("  P (File State): failed to delete %s: %+v, traceId: %s", request.ID, err, span.SpanContext().TraceID().String())
+		return err
+	}
+	err = checkETag(request.Options.Concurrency, request.ETag, existing, found, request.ID)
+	if err != nil {
+		sLog.Errorf("  P (File State): failed to delete %s: %+v, traceId: %s", request.ID, err, span.SpanContext().TraceID().String())
+		return err
+	}
+	delete(s.Data, key)
+	err = s.save()
+	if err != nil {
+		s.Data[key] = existing
+		sLog.Errorf("  P (File State): failed to persist deletion of %s: %+v, traceId: %s", request.ID, err, span.SpanContext().TraceID().String())
+		return err
+	}
+	s.hub.Publish(states.WatchEvent{
+		Type:            states.WatchEventDelete,
+		Entry:           toStateEntry(existing),
+		ResourceVersion: s.hub.NextVersion(),
+		Metadata:        eventMetadata(request.Metadata, existing.Scope),
+	})
+	return nil
+}
+
+func (s *FileStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, span := observability.StartSpan("File State Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	sLog.Debugf("  P (File State): get state %s, traceId: %s", request.ID, span.SpanContext().TraceID().String())
+
+	key := entryKey(readResource(request.Metadata), readScope(request.Metadata), request.ID)
+	if v, ok := s.Data[key]; ok {
+		return toStateEntry(v), nil
+	}
+	err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found", request.ID), v1alpha2.NotFound)
+	sLog.Errorf("  P (File State): failed to get %s state: %+v, traceId: %s", request.ID, err, span.SpanContext().TraceID().String())
+	return states.StateEntry{}, err
+}
+
+// Watch streams upserts and deletes made after the call, for the resource and scope
+// given in the request metadata. An empty scope watches all scopes.
+func (s *FileStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	if s.hub == nil {
+		return nil, v1alpha2.NewCOAError(nil, "file state provider is not initialized", v1alpha2.InternalError)
+	}
+	sLog.Debugf("  P (File State): watch states")
+	scope := request.Metadata["scope"]
+	resource := readResource(request.Metadata)
+	return s.hub.Subscribe(ctx, func(event states.WatchEvent) bool {
+		return readResource(event.Metadata) == resource && (scope == "" || event.Metadata["scope"] == scope)
+	}), nil
+}
+
+func eventMetadata(metadata map[string]string, scope string) map[string]string {
+	return map[string]string{
+		"scope":    scope,
+		"group":    metadata["group"],
+		"version":  metadata["version"],
+		"resource": metadata["resource"],
+	}
+}
+
+func toFileStateProviderConfig(config providers.IProviderConfig) (FileStateProviderConfig, error) {
+	ret := FileStateProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
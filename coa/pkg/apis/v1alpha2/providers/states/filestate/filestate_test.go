@@ -0,0 +1,358 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filestate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/stretchr/testify/assert"
+)
+
+var instanceMetadata = map[string]string{
+	"scope":    "default",
+	"group":    "solution.symphony",
+	"version":  "v1",
+	"resource": "instances",
+}
+
+func newProvider(t *testing.T) (*FileStateProvider, string) {
+	path := filepath.Join(t.TempDir(), "states.json")
+	provider := &FileStateProvider{}
+	err := provider.Init(FileStateProviderConfig{
+		Name: "file",
+		Path: path,
+	})
+	assert.Nil(t, err)
+	return provider, path
+}
+
+func TestInitWithoutPath(t *testing.T) {
+	provider := FileStateProvider{}
+	err := provider.Init(FileStateProviderConfig{})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestInitWithMap(t *testing.T) {
+	provider := FileStateProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name": "name1",
+		"path": filepath.Join(t.TempDir(), "states.json"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "name1", provider.ID())
+}
+
+func TestInitWithCorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "states.json")
+	err := os.WriteFile(path, []byte("{not json"), 0644)
+	assert.Nil(t, err)
+	provider := FileStateProvider{}
+	err = provider.Init(FileStateProviderConfig{Path: path})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.SerializationError, coaErr.State)
+}
+
+func TestSetContext(t *testing.T) {
+	provider, _ := newProvider(t)
+	provider.SetContext(&contexts.ManagerContext{})
+	assert.NotNil(t, provider.Context)
+}
+
+func TestUpsertAndGet(t *testing.T) {
+	provider, _ := newProvider(t)
+	id, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "instance1",
+			Body: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"solution": "solution1",
+				},
+			},
+		},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "instance1", id)
+
+	entry, err := provider.Get(context.Background(), states.GetRequest{
+		ID:       "instance1",
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.ETag)
+	body := entry.Body.(map[string]interface{})
+	assert.Equal(t, "default", body["scope"])
+	assert.Equal(t, "solution1", body["spec"].(map[string]interface{})["solution"])
+}
+
+func TestGetNotFound(t *testing.T) {
+	provider, _ := newProvider(t)
+	_, err := provider.Get(context.Background(), states.GetRequest{
+		ID:       "instance1",
+		Metadata: instanceMetadata,
+	})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestEntriesArePartitionedByResource(t *testing.T) {
+	provider, _ := newProvider(t)
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "same", Body: map[string]interface{}{"spec": "instance"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "same", Body: map[string]interface{}{"spec": "solution"}},
+		Metadata: map[string]string{
+			"group":    "solution.symphony",
+			"resource": "solutions",
+		},
+	})
+	assert.Nil(t, err)
+
+	entries, _, err := provider.List(context.Background(), states.ListRequest{Metadata: instanceMetadata})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "instance", entries[0].Body.(map[string]interface{})["spec"])
+}
+
+func TestListScopes(t *testing.T) {
+	provider, _ := newProvider(t)
+	for _, scope := range []string{"default", "scope1"} {
+		_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": scope}},
+			Metadata: map[string]string{
+				"scope":    scope,
+				"resource": "instances",
+			},
+		})
+		assert.Nil(t, err)
+	}
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]string{"resource": "instances"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	entries, _, err = provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]string{"resource": "instances", "scope": "scope1"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "scope1", entries[0].Body.(map[string]interface{})["scope"])
+}
+
+func TestListJsonPathFilter(t *testing.T) {
+	provider, _ := newProvider(t)
+	for _, name := range []string{"a", "b"} {
+		_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{ID: name, Body: map[string]interface{}{
+				"spec": map[string]interface{}{"solution": "solution-" + name},
+			}},
+			Metadata: instanceMetadata,
+		})
+		assert.Nil(t, err)
+	}
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:       "jsonpath",
+		Filter:           "$.spec.solution",
+		FilterParameters: map[string]string{"value": "solution-b"},
+		Metadata:         instanceMetadata,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "b", entries[0].ID)
+
+	_, _, err = provider.List(context.Background(), states.ListRequest{
+		FilterType: "unknown",
+		Filter:     "x",
+		Metadata:   instanceMetadata,
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, coaErr.State)
+}
+
+func TestStatusUpdateKeepsSpec(t *testing.T) {
+	provider, _ := newProvider(t)
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "my-spec"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"status": "my-status"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "instance1", Metadata: instanceMetadata})
+	assert.Nil(t, err)
+	body := entry.Body.(map[string]interface{})
+	assert.Equal(t, "my-spec", body["spec"])
+	assert.Equal(t, "my-status", body["status"])
+	assert.Equal(t, "2", entry.ETag)
+}
+
+func TestFirstWriteConcurrency(t *testing.T) {
+	provider, _ := newProvider(t)
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v1"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+
+	stale := "0"
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v2"}},
+		ETag:     &stale,
+		Metadata: instanceMetadata,
+		Options:  states.UpsertOption{Concurrency: "first-write"},
+	})
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Conflict, coaErr.State)
+
+	// last-write (the default) ignores the etag
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v2"}},
+		ETag:     &stale,
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+
+	current := "2"
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v3"}},
+		ETag:     &current,
+		Metadata: instanceMetadata,
+		Options:  states.UpsertOption{Concurrency: "first-write"},
+	})
+	assert.Nil(t, err)
+
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID:       "instance1",
+		ETag:     &current,
+		Metadata: instanceMetadata,
+		Options:  states.DeleteOption{Concurrency: "first-write"},
+	})
+	coaErr, ok = err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Conflict, coaErr.State)
+}
+
+func TestDelete(t *testing.T) {
+	provider, _ := newProvider(t)
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v1"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "instance1", Metadata: instanceMetadata})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "instance1", Metadata: instanceMetadata})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestStatesSurviveRestart(t *testing.T) {
+	provider, path := newProvider(t)
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v1"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance2", Body: map[string]interface{}{"spec": "v2"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "instance2", Metadata: instanceMetadata})
+	assert.Nil(t, err)
+
+	reopened := &FileStateProvider{}
+	err = reopened.Init(FileStateProviderConfig{Path: path})
+	assert.Nil(t, err)
+	entries, _, err := reopened.List(context.Background(), states.ListRequest{Metadata: instanceMetadata})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "instance1", entries[0].ID)
+	assert.Equal(t, "1", entries[0].ETag)
+	assert.Equal(t, "v1", entries[0].Body.(map[string]interface{})["spec"])
+}
+
+func TestWatch(t *testing.T) {
+	provider, _ := newProvider(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := provider.Watch(ctx, states.WatchRequest{Metadata: instanceMetadata})
+	assert.Nil(t, err)
+
+	// changes to other resources are not delivered
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "solution1", Body: map[string]interface{}{"spec": "v1"}},
+		Metadata: map[string]string{"resource": "solutions"},
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "instance1", Body: map[string]interface{}{"spec": "v1"}},
+		Metadata: instanceMetadata,
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "instance1", Metadata: instanceMetadata})
+	assert.Nil(t, err)
+
+	event := <-ch
+	assert.Equal(t, states.WatchEventUpsert, event.Type)
+	assert.Equal(t, "instance1", event.Entry.ID)
+	assert.Equal(t, "default", event.Metadata["scope"])
+	assert.Equal(t, "v1", event.Entry.Body.(map[string]interface{})["spec"])
+	event = <-ch
+	assert.Equal(t, states.WatchEventDelete, event.Type)
+	assert.Equal(t, "instance1", event.Entry.ID)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestListWithPaging(t *testing.T) {
+	provider, _ := newProvider(t)
+	for _, id := range []string{"c", "a", "b"} {
+		_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+			Value:    states.StateEntry{ID: id, Body: map[string]interface{}{"spec": id}},
+			Metadata: instanceMetadata,
+		})
+		assert.Nil(t, err)
+	}
+	// an entry of another resource doesn't take up room in a page
+	_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "a", Body: map[string]interface{}{"spec": "a"}},
+		Metadata: map[string]string{"resource": "solutions"},
+	})
+	assert.Nil(t, err)
+
+	entries, token, err := provider.List(context.Background(), states.ListRequest{Metadata: instanceMetadata, Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "a", entries[0].ID)
+	assert.Equal(t, "b", entries[1].ID)
+	assert.NotEmpty(t, token)
+
+	entries, token, err = provider.List(context.Background(), states.ListRequest{Metadata: instanceMetadata, Limit: 2, Continue: token})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "c", entries[0].ID)
+	assert.Empty(t, token)
+}
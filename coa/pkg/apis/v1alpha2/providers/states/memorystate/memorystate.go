@@ -27,6 +27,8 @@ import (
 var sLog = logger.NewLogger("coa.runtime")
 var mLock sync.RWMutex
 
+const filterJsonPath = "jsonpath"
+
 type MemoryStateProviderConfig struct {
 	Name string `json:"name"`
 }
@@ -43,6 +45,7 @@ type MemoryStateProvider struct {
 	Config  MemoryStateProviderConfig
 	Data    map[string]interface{}
 	Context *contexts.ManagerContext
+	hub     *states.WatchHub
 }
 
 func (s *MemoryStateProvider) ID() string {
@@ -70,6 +73,7 @@ func (s *MemoryStateProvider) Init(config providers.IProviderConfig) error {
 	}
 	s.Config = stateConfig
 	s.Data = make(map[string]interface{}, 0)
+	s.hub = &states.WatchHub{}
 	return nil
 }
 
@@ -108,6 +112,11 @@ func (s *MemoryStateProvider) Upsert(ctx context.Context, entry states.UpsertReq
 	}
 
 	s.Data[entry.Value.ID] = entry.Value
+	s.hub.Publish(states.WatchEvent{
+		Type:            states.WatchEventUpsert,
+		Entry:           entry.Value,
+		ResourceVersion: s.hub.NextVersion(),
+	})
 
 	return entry.Value.ID, nil
 }
@@ -123,22 +132,42 @@ func (s *MemoryStateProvider) List(ctx context.Context, request states.ListReque
 
 	sLog.Debugf("  P (Memory State): list states, traceId: %s", span.SpanContext().TraceID().String())
 
-	var entities []states.StateEntry
-	for _, v := range s.Data {
+	// filters are applied before paging, so that every page except the last one is full
+	keys := make([]string, 0, len(s.Data))
+	for k, v := range s.Data {
 		vE, ok := v.(states.StateEntry)
-		if ok {
-			if request.Filter != "" {
-				//TODO: support filters in the future
-			}
-			entities = append(entities, vE)
-		} else {
+		if !ok {
 			err = v1alpha2.NewCOAError(nil, "found invalid state entry", v1alpha2.InternalError)
 			sLog.Errorf("  P (Memory State): failed to list states: %+v, traceId: %s", err, span.SpanContext().TraceID().String())
-			return entities, "", err
+			return nil, "", err
+		}
+		if request.Filter != "" {
+			switch request.FilterType {
+			case filterJsonPath:
+				if !states.JsonPathMatch(vE.Body, request.Filter, request.FilterParameters["value"]) {
+					continue
+				}
+			default:
+				err = v1alpha2.NewCOAError(nil, fmt.Sprintf("filter type '%s' is not supported", request.FilterType), v1alpha2.BadRequest)
+				sLog.Errorf("  P (Memory State): failed to list states: %+v, traceId: %s", err, span.SpanContext().TraceID().String())
+				return nil, "", err
+			}
 		}
+		keys = append(keys, k)
+	}
+	var token string
+	keys, token, err = states.PageKeys(keys, request.Limit, request.Continue)
+	if err != nil {
+		sLog.Errorf("  P (Memory State): failed to list states: %+v, traceId: %s", err, span.SpanContext().TraceID().String())
+		return nil, "", err
+	}
+
+	var entities []states.StateEntry
+	for _, k := range keys {
+		entities = append(entities, s.Data[k].(states.StateEntry))
 	}
 
-	return entities, "", nil
+	return entities, token, nil
 }
 
 func (s *MemoryStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
@@ -152,12 +181,19 @@ func (s *MemoryStateProvider) Delete(ctx context.Context, request states.DeleteR
 
 	sLog.Debug("  P (Memory State): delete state %s, traceId: %s", request.ID, span.SpanContext().TraceID().String())
 
-	if _, ok := s.Data[request.ID]; !ok {
+	v, ok := s.Data[request.ID]
+	if !ok {
 		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found", request.ID), v1alpha2.NotFound)
 		sLog.Errorf("  P (Memory State): failed to delete %s: %+v, traceId: %s", request.ID, err, span.SpanContext().TraceID().String())
 		return err
 	}
 	delete(s.Data, request.ID)
+	entry, _ := v.(states.StateEntry)
+	s.hub.Publish(states.WatchEvent{
+		Type:            states.WatchEventDelete,
+		Entry:           entry,
+		ResourceVersion: s.hub.NextVersion(),
+	})
 
 	return nil
 }
@@ -189,6 +225,15 @@ func (s *MemoryStateProvider) Get(ctx context.Context, request states.GetRequest
 	return states.StateEntry{}, err
 }
 
+// Watch streams upserts and deletes made after the call. Like List, it ignores request metadata.
+func (s *MemoryStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	if s.hub == nil {
+		return nil, v1alpha2.NewCOAError(nil, "memory state provider is not initialized", v1alpha2.InternalError)
+	}
+	sLog.Debugf("  P (Memory State): watch states")
+	return s.hub.Subscribe(ctx, nil), nil
+}
+
 func toMemoryStateProviderConfig(config providers.IProviderConfig) (MemoryStateProviderConfig, error) {
 	ret := MemoryStateProviderConfig{}
 	data, err := json.Marshal(config)
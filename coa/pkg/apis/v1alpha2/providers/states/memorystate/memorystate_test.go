@@ -286,3 +286,104 @@ func TestClone(t *testing.T) {
 	assert.NotNil(t, p)
 	assert.Nil(t, err)
 }
+
+func TestWatch(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProviderConfig{})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := provider.Watch(ctx, states.WatchRequest{})
+	assert.Nil(t, err)
+
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "123",
+			Body: TestPayload{Name: "Random name", Value: 12345},
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "123"})
+	assert.Nil(t, err)
+
+	event := <-ch
+	assert.Equal(t, states.WatchEventUpsert, event.Type)
+	assert.Equal(t, "123", event.Entry.ID)
+	assert.Equal(t, "1", event.ResourceVersion)
+	event = <-ch
+	assert.Equal(t, states.WatchEventDelete, event.Type)
+	assert.Equal(t, "123", event.Entry.ID)
+	assert.Equal(t, "2", event.ResourceVersion)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestWatchWithoutInit(t *testing.T) {
+	provider := MemoryStateProvider{}
+	_, err := provider.Watch(context.Background(), states.WatchRequest{})
+	assert.NotNil(t, err)
+}
+
+func TestListWithPaging(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProviderConfig{})
+	assert.Nil(t, err)
+	for _, id := range []string{"c", "a", "b"} {
+		_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{ID: id, Body: TestPayload{Name: id}},
+		})
+		assert.Nil(t, err)
+	}
+	entries, token, err := provider.List(context.Background(), states.ListRequest{Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "a", entries[0].ID)
+	assert.Equal(t, "b", entries[1].ID)
+	assert.NotEmpty(t, token)
+
+	entries, token, err = provider.List(context.Background(), states.ListRequest{Limit: 2, Continue: token})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "c", entries[0].ID)
+	assert.Empty(t, token)
+}
+
+func TestListWithFilterAndPaging(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProviderConfig{})
+	assert.Nil(t, err)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		solution := "solution-x"
+		if id == "b" || id == "d" {
+			solution = "solution-y"
+		}
+		_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{ID: id, Body: map[string]interface{}{
+				"spec": map[string]interface{}{"solution": solution},
+			}},
+		})
+		assert.Nil(t, err)
+	}
+	request := states.ListRequest{
+		FilterType:       "jsonpath",
+		Filter:           "$.spec.solution",
+		FilterParameters: map[string]string{"value": "solution-y"},
+		Limit:            1,
+	}
+	entries, token, err := provider.List(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "b", entries[0].ID)
+	assert.NotEmpty(t, token)
+
+	request.Continue = token
+	entries, token, err = provider.List(context.Background(), request)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "d", entries[0].ID)
+	assert.Empty(t, token)
+
+	_, _, err = provider.List(context.Background(), states.ListRequest{FilterType: "label", Filter: "x"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"encoding/base64"
+	"sort"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// EncodeContinuationToken returns an opaque token that resumes a listing after the given key.
+func EncodeContinuationToken(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func DecodeContinuationToken(token string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, "invalid continuation token", v1alpha2.BadRequest)
+	}
+	return string(data), nil
+}
+
+// PageKeys sorts keys and returns the page selected by limit and continuation token, along
+// with the token for the next page. The returned token is empty on the last page. Paging by
+// key rather than by offset keeps pages stable when entries are added or removed in between.
+func PageKeys(keys []string, limit int64, token string) ([]string, string, error) {
+	sort.Strings(keys)
+	start := 0
+	if token != "" {
+		after, err := DecodeContinuationToken(token)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(keys), func(i int) bool {
+			return keys[i] > after
+		})
+	}
+	if limit <= 0 || start+int(limit) >= len(keys) {
+		return keys[start:], "", nil
+	}
+	end := start + int(limit)
+	return keys[start:end], EncodeContinuationToken(keys[end-1]), nil
+}
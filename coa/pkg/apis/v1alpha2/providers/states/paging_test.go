@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageKeys(t *testing.T) {
+	keys := []string{"e", "c", "a", "d", "b"}
+	page, token, err := PageKeys(keys, 2, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, page)
+	assert.NotEmpty(t, token)
+
+	page, token, err = PageKeys(keys, 2, token)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c", "d"}, page)
+
+	page, token, err = PageKeys(keys, 2, token)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"e"}, page)
+	assert.Empty(t, token)
+}
+
+func TestPageKeysWithoutLimit(t *testing.T) {
+	page, token, err := PageKeys([]string{"b", "a"}, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, page)
+	assert.Empty(t, token)
+}
+
+func TestPageKeysResumesAfterRemovedKey(t *testing.T) {
+	_, token, err := PageKeys([]string{"a", "b", "c"}, 2, "")
+	assert.Nil(t, err)
+	page, _, err := PageKeys([]string{"a", "c"}, 2, token)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c"}, page)
+}
+
+func TestPageKeysInvalidToken(t *testing.T) {
+	_, _, err := PageKeys([]string{"a"}, 1, "!!!")
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, coaErr.State)
+}
@@ -37,8 +37,8 @@ type GetRequest struct {
 	Options  GetOption         `json:"options,omitempty"`
 }
 type DeleteOption struct {
-	Concurrency string `json:"concurency"` //concurrency
-	Consistency string `json:"consistency` //eventual or strong
+	Concurrency string `json:"concurency"`  //concurrency
+	Consistency string `json:"consistency"` //eventual or strong
 }
 type DeleteRequest struct {
 	ID       string            `json:"id"`
@@ -61,6 +61,8 @@ type ListRequest struct {
 	Filter           string            `json:"filter"`
 	FilterParameters map[string]string `json:"filterParameters"`
 	Metadata         map[string]string `json:"metadata"`
+	Limit            int64             `json:"limit,omitempty"`    //max number of entries to return, 0 for all
+	Continue         string            `json:"continue,omitempty"` //continuation token returned by a previous List call
 }
 
 func JsonPathMatch(jsonData interface{}, path string, target string) bool {
@@ -72,5 +74,6 @@ func JsonPathMatch(jsonData interface{}, path string, target string) bool {
 	if err != nil {
 		return false
 	}
-	return res.(string) == target
+	v, ok := res.(string)
+	return ok && v == target
 }
@@ -0,0 +1,26 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteOptionJSON(t *testing.T) {
+	// delete requests are sent to remote state stores as JSON, with the same option names as the other requests
+	data, err := json.Marshal(DeleteOption{Concurrency: "first-write", Consistency: "strong"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"concurency":"first-write","consistency":"strong"}`, string(data))
+
+	var option DeleteOption
+	err = json.Unmarshal(data, &option)
+	assert.Nil(t, err)
+	assert.Equal(t, "strong", option.Consistency)
+}
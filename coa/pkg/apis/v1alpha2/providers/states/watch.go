@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+type WatchEventType string
+
+const (
+	WatchEventUpsert WatchEventType = "upsert"
+	WatchEventDelete WatchEventType = "delete"
+
+	watchBufferSize = 100
+)
+
+type WatchRequest struct {
+	// ResourceVersion, when supported by the provider, resumes the watch after the given version
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+type WatchEvent struct {
+	Type            WatchEventType    `json:"type"`
+	Entry           StateEntry        `json:"entry"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// IWatchableStateProvider is implemented by state providers that can stream changes.
+// The returned channel is closed when the context is cancelled, or when the provider
+// can no longer guarantee delivery (for instance, the watcher fell behind). Consumers
+// should re-list and watch again from the last seen resource version when that happens.
+type IWatchableStateProvider interface {
+	Watch(ctx context.Context, request WatchRequest) (<-chan WatchEvent, error)
+}
+
+// ISharedStateProvider is implemented by state providers whose store is shared by all provider instances,
+// such as the Kubernetes state provider. Their change feeds report the changes made through any instance.
+// The change feeds of in-process providers, such as the memory and file state providers, only report the
+// changes made through the same provider instance.
+type ISharedStateProvider interface {
+	IsShared() bool
+}
+
+type watcher struct {
+	ch     chan WatchEvent
+	filter func(WatchEvent) bool
+	// done is closed when the watcher is removed, which ends the goroutine waiting for its context
+	done chan struct{}
+}
+
+// WatchHub fans out change events of an in-process state provider to its watchers.
+type WatchHub struct {
+	lock     sync.Mutex
+	watchers map[int]*watcher
+	nextId   int
+	version  uint64
+}
+
+// NextVersion returns a new, monotonically increasing resource version.
+func (h *WatchHub) NextVersion() string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.version++
+	return strconv.FormatUint(h.version, 10)
+}
+
+// Subscribe registers a watcher that receives the events accepted by filter until ctx is done.
+func (h *WatchHub) Subscribe(ctx context.Context, filter func(WatchEvent) bool) <-chan WatchEvent {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.watchers == nil {
+		h.watchers = make(map[int]*watcher)
+	}
+	id := h.nextId
+	h.nextId++
+	w := &watcher{
+		ch:     make(chan WatchEvent, watchBufferSize),
+		filter: filter,
+		done:   make(chan struct{}),
+	}
+	h.watchers[id] = w
+	go func() {
+		select {
+		case <-ctx.Done():
+			h.remove(id)
+		case <-w.done:
+		}
+	}()
+	return w.ch
+}
+
+func (h *WatchHub) remove(id int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.drop(id)
+}
+
+// drop closes the channel of a watcher and removes it. The caller must hold the lock.
+func (h *WatchHub) drop(id int) {
+	if w, ok := h.watchers[id]; ok {
+		close(w.ch)
+		close(w.done)
+		delete(h.watchers, id)
+	}
+}
+
+// Publish delivers an event to all matching watchers without blocking. A watcher whose
+// buffer is full is dropped and its channel closed, so it knows to re-list.
+func (h *WatchHub) Publish(event WatchEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for id, w := range h.watchers {
+		if w.filter != nil && !w.filter(event) {
+			continue
+		}
+		select {
+		case w.ch <- event:
+		default:
+			h.drop(id)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchHubFilter(t *testing.T) {
+	hub := WatchHub{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := hub.Subscribe(ctx, func(event WatchEvent) bool {
+		return event.Entry.ID == "b"
+	})
+	hub.Publish(WatchEvent{Type: WatchEventUpsert, Entry: StateEntry{ID: "a"}, ResourceVersion: hub.NextVersion()})
+	hub.Publish(WatchEvent{Type: WatchEventUpsert, Entry: StateEntry{ID: "b"}, ResourceVersion: hub.NextVersion()})
+	event := <-ch
+	assert.Equal(t, "b", event.Entry.ID)
+	assert.Equal(t, "2", event.ResourceVersion)
+}
+
+func TestWatchHubDropsSlowWatcher(t *testing.T) {
+	hub := WatchHub{}
+	ch := hub.Subscribe(context.Background(), nil)
+	for i := 0; i <= watchBufferSize; i++ {
+		hub.Publish(WatchEvent{Type: WatchEventUpsert, Entry: StateEntry{ID: "a"}})
+	}
+	count := 0
+	for range ch {
+		count++
+	}
+	assert.Equal(t, watchBufferSize, count)
+}
+
+func TestWatchHubReleasesDroppedWatchers(t *testing.T) {
+	hub := WatchHub{}
+	before := runtime.NumGoroutine()
+	for n := 0; n < 10; n++ {
+		// watchers that never end their context, like the watches of the managers that reconnect
+		hub.Subscribe(context.Background(), nil)
+	}
+	for i := 0; i <= watchBufferSize; i++ {
+		hub.Publish(WatchEvent{Type: WatchEventUpsert, Entry: StateEntry{ID: "a"}})
+	}
+	assert.Equal(t, 0, len(hub.watchers))
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -12,8 +12,22 @@ type SiteInfo struct {
 	ParentSite  SiteConnection    `json:"parentSite,omitempty"`
 	CurrentSite SiteConnection    `json:"currentSite"`
 }
+
+// SiteConnection is how a site calls another site. A site with a client certificate presents the
+// certificate instead of signing in with its username and password.
 type SiteConnection struct {
 	BaseUrl  string `json:"baseUrl"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// PEM files of the client certificate and key of the site
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+	// PEM file of the CA that the certificate of the other site is verified against. Defaults to the
+	// system CAs
+	CACert string `json:"caCert,omitempty"`
+}
+
+// UsesClientCert tells if the site presents a client certificate
+func (c SiteConnection) UsesClientCert() bool {
+	return c.ClientCert != ""
 }
@@ -48,6 +48,7 @@ const (
 	Updated        State = 8004
 	Deleted        State = 8005
 	// Workflow status
+	Cancelled      State = 9993
 	Running        State = 9994
 	Paused         State = 9995
 	Done           State = 9996
@@ -98,6 +99,8 @@ func (s State) String() string {
 		return "Updated"
 	case Deleted:
 		return "Deleted"
+	case Cancelled:
+		return "Cancelled"
 	case Delayed:
 		return "Delayed"
 	case Untouched:
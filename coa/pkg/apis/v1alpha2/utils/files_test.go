@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	path := filepath.Join(dir, "file.json")
+	data, err := ReadFileIfExists(path)
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+	assert.Nil(t, WriteFileAtomic(path, []byte("first")))
+	assert.Nil(t, WriteFileAtomic(path, []byte("second")))
+	data, err = ReadFileIfExists(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(data))
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	assert.Nil(t, os.WriteFile(dir, []byte{}, 0644))
+	assert.NotNil(t, WriteFileAtomic(filepath.Join(dir, "file.json"), []byte("data")))
+}
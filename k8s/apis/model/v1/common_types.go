@@ -54,9 +54,18 @@ type SolutionSpec struct {
 
 // +kubebuilder:object:generate=true
 type ScheduleSpec struct {
-	Date string `json:"date"`
-	Time string `json:"time"`
-	Zone string `json:"zone"`
+	Date      string           `json:"date,omitempty"`
+	Time      string           `json:"time,omitempty"`
+	Zone      string           `json:"zone,omitempty"`
+	Cron      string           `json:"cron,omitempty"`
+	Blackouts []BlackoutWindow `json:"blackouts,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type BlackoutWindow struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -73,6 +82,31 @@ type StageSpec struct {
 	Inputs          runtime.RawExtension `json:"inputs,omitempty"`
 	TriggeringStage string               `json:"triggeringStage,omitempty"`
 	Schedule        *ScheduleSpec        `json:"schedule,omitempty"`
+	Parallel        *ParallelSpec        `json:"parallel,omitempty"`
+	ForEach         *ForEachSpec         `json:"forEach,omitempty"`
+	RetryPolicy     *RetryPolicy         `json:"retryPolicy,omitempty"`
+	Timeout         string               `json:"timeout,omitempty"`
+	Compensation    string               `json:"compensation,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type RetryPolicy struct {
+	MaxAttempts int    `json:"maxAttempts,omitempty"`
+	Backoff     string `json:"backoff,omitempty"`
+	MaxBackoff  string `json:"maxBackoff,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ForEachSpec struct {
+	Items       string `json:"items"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	MinSuccess  int    `json:"minSuccess,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ParallelSpec struct {
+	Branches   []string `json:"branches"`
+	MinSuccess int      `json:"minSuccess,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -82,8 +116,20 @@ type ActivationSpec struct {
 	Stage    string `json:"stage,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
-	Inputs     runtime.RawExtension `json:"inputs,omitempty"`
-	Generation string               `json:"generation,omitempty"`
+	Inputs           runtime.RawExtension `json:"inputs,omitempty"`
+	Generation       string               `json:"generation,omitempty"`
+	Schedule         *ScheduleSpec        `json:"schedule,omitempty"`
+	CampaignRevision int                  `json:"campaignRevision,omitempty"`
+	Parent           *ActivationParent    `json:"parent,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ActivationParent struct {
+	Campaign             string `json:"campaign"`
+	Activation           string `json:"activation"`
+	ActivationGeneration string `json:"activationGeneration,omitempty"`
+	Stage                string `json:"stage"`
+	Site                 string `json:"site,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -94,6 +140,17 @@ type CampaignSpec struct {
 	SelfDriving bool                 `json:"selfDriving,omitempty"`
 }
 
+// +kubebuilder:object:generate=true
+type RevisionSpec struct {
+	Object    string `json:"object"`
+	Revision  int    `json:"revision"`
+	Author    string `json:"author,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Spec runtime.RawExtension `json:"spec,omitempty"`
+}
+
 // +kubebuilder:object:generate=true
 type CatalogSpec struct {
 	SiteId string `json:"siteId"`
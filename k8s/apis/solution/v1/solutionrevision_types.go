@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	k8smodel "github.com/eclipse-symphony/symphony/k8s/apis/model/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Solution",type=string,JSONPath=`.spec.object`
+//+kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.spec.revision`
+//+kubebuilder:printcolumn:name="Author",type=string,JSONPath=`.spec.author`
+
+// SolutionRevision is the Schema for the solutionrevisions API, an immutable snapshot of a solution spec
+type SolutionRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec k8smodel.RevisionSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SolutionRevisionList contains a list of SolutionRevision
+type SolutionRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SolutionRevision `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SolutionRevision{}, &SolutionRevisionList{})
+}
@@ -27,6 +27,9 @@ type ActivationStatus struct {
 	IsActive             bool                 `json:"isActive,omitempty"`
 	ActivationGeneration string               `json:"activationGeneration,omitempty"`
 	UpdateTime           string               `json:"updateTime,omitempty"`
+	OperatorAction       string               `json:"operatorAction,omitempty"`
+	Operator             string               `json:"operator,omitempty"`
+	CampaignRevision     int                  `json:"campaignRevision,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -17,8 +17,10 @@ echo "Deleting Symphony resources"
 # Use the function for each resource types in order
 delete_crds "instances.$SOLUTION_GROUP"
 delete_crds "solutions.$SOLUTION_GROUP"
+delete_crds "solutionrevisions.$SOLUTION_GROUP"
 delete_crds "activations.$WORKFLOW_GROUP"
 delete_crds "campaigns.$WORKFLOW_GROUP"
+delete_crds "campaignrevisions.$WORKFLOW_GROUP"
 delete_crds "targets.$FABRIC_GROUP"
 delete_crds "devices.$FABRIC_GROUP"
 delete_crds "models.$AI_GROUP"